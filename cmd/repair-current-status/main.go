@@ -0,0 +1,49 @@
+// repair-current-status สร้างตาราง washer_current_status ใหม่จากประวัติในตาราง statuses
+// ใช้เมื่อสถานะปัจจุบันไม่ตรงกับประวัติ เช่นหลังแก้ข้อมูลด้วยมือหรือ restore archive
+// -force ใช้หลังหยุด server แล้วเท่านั้น: แทนทุกแถวด้วยประวัติ ลบแถวของเครื่องที่ไม่มีประวัติ และ sync washer:state ใน Redis
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"time"
+
+	"github.com/jaytnw/bms-service/internal/config"
+	"github.com/jaytnw/bms-service/internal/repository"
+	"github.com/jaytnw/bms-service/internal/services"
+	redisPkg "github.com/jaytnw/bms-service/pkg/redisclient"
+	"github.com/joho/godotenv"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func main() {
+	force := flag.Bool("force", false, "replace every row from history, delete washers without history and resync Redis (server must be stopped)")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("Warning: .env not loaded (using system env)")
+	}
+	cfg := config.LoadConfig()
+
+	db, err := gorm.Open(postgres.Open(cfg.PostgresConfig.BuildDSN()), &gorm.Config{})
+	if err != nil {
+		log.Fatalf("failed to connect to database: %v", err)
+	}
+	redisPkg.Init(cfg.RedisConfig)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	start := time.Now()
+	stateStore := services.NewWasherStateStore(redisPkg.Client)
+	rebuild, err := services.RepairCurrentStatuses(ctx, repository.NewStatusRepo(db), stateStore, *force)
+	if err != nil {
+		log.Fatalf("❌ Failed to rebuild current statuses: %v", err)
+	}
+	log.Printf("✅ Rebuilt current status of %d washer(s) and removed %d in %v", rebuild.Written, rebuild.Deleted, time.Since(start))
+	if *force {
+		log.Println("✅ Resynced washer state in Redis")
+	}
+}
@@ -2,19 +2,24 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
+	"syscall"
 	"time"
-	"fmt"
+	_ "time/tzdata" // image alpine ไม่มี tzdata แต่ analytics ต้องใช้ timezone ของหอ
 
 	"github.com/gofiber/fiber/v3"
 	"github.com/gofiber/fiber/v3/middleware/cors"
 	"github.com/gofiber/fiber/v3/middleware/logger"
 	"github.com/gofiber/fiber/v3/middleware/pprof"
+	"github.com/jaytnw/bms-service/internal/analytics"
+	"github.com/jaytnw/bms-service/internal/archive"
 	"github.com/jaytnw/bms-service/internal/config"
 	"github.com/jaytnw/bms-service/internal/handlers"
 	"github.com/jaytnw/bms-service/internal/mqtt"
+	"github.com/jaytnw/bms-service/internal/notifications"
 	"github.com/jaytnw/bms-service/internal/repository"
 	"github.com/jaytnw/bms-service/internal/routes"
 	"github.com/jaytnw/bms-service/internal/services"
@@ -47,13 +52,132 @@ func main() {
 	// Redis
 	redisPkg.Init(cfg.RedisConfig)
 
-	// ExternalAPI
-	externalAPI := services.NewExternalAPIService("https://washeasy.me")
+	// ExternalAPI เป็นเพียงแหล่งนำเข้าทะเบียน ไม่จำเป็นต่อการทำงานของ service
+	var externalAPI services.ExternalAPIService
+	if cfg.RegistryConfig.SyncEnabled {
+		externalAPI = services.NewExternalAPIService(cfg.RegistryConfig.ExternalAPIURL)
+	}
 
 	// Wire DI
 	statusRepo := repository.NewStatusRepo(db)
-	statusService := services.NewStatusService(statusRepo, externalAPI, redisPkg.Client)
+	anomalyRepo := repository.NewAnomalyRepo(db)
+	telemetryRepo := repository.NewTelemetryRepo(db)
+	registryRepo := repository.NewRegistryRepo(db)
+	registryService := services.NewRegistryService(registryRepo, externalAPI)
+	statusBroker := services.NewStatusBroker(cfg.StreamConfig.BufferSize)
+	washerStateStore := services.NewWasherStateStore(redisPkg.Client)
+	sessionRepo := repository.NewSessionRepo(db)
+	etaService := services.NewEtaService(sessionRepo, telemetryRepo)
+	maintenanceRepo := repository.NewMaintenanceRepo(db)
+	maintenanceService := services.NewMaintenanceService(maintenanceRepo, registryRepo)
+	statusService := services.NewStatusService(statusRepo, anomalyRepo, telemetryRepo, registryRepo, registryService, statusBroker, washerStateStore, etaService, maintenanceService, cfg.WatchdogConfig.OfflineAfter)
+	sessionService := services.NewSessionService(sessionRepo, statusRepo)
+	statusBroker.AddListener(sessionService)
+	queueRepo := repository.NewQueueRepo(db)
+	notificationRepo := repository.NewNotificationRepo(db)
+	notifiers := map[string]notifications.Notifier{
+		notifications.ChannelLog:     notifications.NewLogNotifier(),
+		notifications.ChannelWebhook: notifications.NewWebhookNotifier(cfg.NotifyConfig.Timeout),
+		notifications.ChannelLine:    notifications.NewLineNotifier(cfg.NotifyConfig.LineAPIURL, cfg.NotifyConfig.Timeout),
+	}
+	// อีเมลใช้ได้เฉพาะเมื่อตั้งค่า SMTP_HOST
+	if cfg.NotifyConfig.SMTPHost != "" {
+		notifiers[notifications.ChannelEmail] = notifications.NewSMTPNotifier(
+			cfg.NotifyConfig.SMTPHost,
+			cfg.NotifyConfig.SMTPPort,
+			cfg.NotifyConfig.SMTPUsername,
+			cfg.NotifyConfig.SMTPPassword,
+			cfg.NotifyConfig.SMTPFrom,
+		)
+	}
+	dispatcher := notifications.NewDispatcher(notifiers, notificationRepo, cfg.NotifyConfig.MaxAttempts, cfg.NotifyConfig.RetryBaseDelay, cfg.NotifyConfig.Timeout, cfg.NotifyConfig.Workers, cfg.NotifyConfig.QueueSize)
+	queueService := services.NewQueueService(queueRepo, registryRepo, washerStateStore, redisPkg.Client, dispatcher, maintenanceService, cfg.QueueConfig.ClaimWindow, cfg.WatchdogConfig.OfflineAfter)
+	statusBroker.AddListener(queueService)
+	subscriptionService := services.NewSubscriptionService(notificationRepo, registryRepo, dispatcher)
+	statusBroker.AddListener(subscriptionService)
+	webhookRepo := repository.NewWebhookRepo(db)
+	webhookService := services.NewWebhookService(webhookRepo, services.WebhookOptions{
+		MaxAttempts:    cfg.WebhookConfig.MaxAttempts,
+		RetryBaseDelay: cfg.WebhookConfig.RetryBaseDelay,
+		Timeout:        cfg.WebhookConfig.Timeout,
+		BatchSize:      cfg.WebhookConfig.BatchSize,
+	})
+	statusBroker.AddListener(webhookService)
+	incidentRepo := repository.NewIncidentRepo(db)
+	incidentService := services.NewIncidentService(incidentRepo)
+	statusBroker.AddListener(incidentService)
+	availabilityService := services.NewAvailabilityService(washerStateStore, registryRepo, etaService, maintenanceService, cfg.WatchdogConfig.OfflineAfter)
+	// timezone ผิดไม่ควรทำให้ service เริ่มไม่ได้ ใช้ UTC แทน
+	analyticsLocation, err := time.LoadLocation(cfg.AnalyticsConfig.Timezone)
+	if err != nil {
+		log.Printf("⚠️ Unknown analytics timezone %q, using UTC: %v", cfg.AnalyticsConfig.Timezone, err)
+		analyticsLocation = time.UTC
+	}
+	rollupRepo := repository.NewRollupRepo(db)
+	analyticsService := analytics.NewService(sessionRepo, rollupRepo, registryRepo, analytics.NewRedisCache(redisPkg.Client, cfg.AnalyticsConfig.CacheTTL), analytics.Options{
+		Location:        analyticsLocation,
+		MaxWindow:       cfg.AnalyticsConfig.MaxWindow,
+		RollupThreshold: cfg.RollupConfig.Threshold,
+	})
+	// ไฟล์ archive เก็บใน S3/MinIO เมื่อ ARCHIVE_STORE=s3 นอกนั้นเก็บบน local disk
+	archiveStore := archive.NewLocalStore(cfg.RetentionConfig.ArchiveDir)
+	if cfg.RetentionConfig.Store == archive.StoreS3 {
+		archiveStore = archive.NewS3Store(archive.S3Options{
+			Endpoint:  cfg.RetentionConfig.S3Endpoint,
+			Region:    cfg.RetentionConfig.S3Region,
+			Bucket:    cfg.RetentionConfig.S3Bucket,
+			AccessKey: cfg.RetentionConfig.S3AccessKey,
+			SecretKey: cfg.RetentionConfig.S3SecretKey,
+			Timeout:   cfg.RetentionConfig.S3Timeout,
+		})
+	}
+	archiveRepo := repository.NewArchiveRepo(db)
+	retentionService := services.NewRetentionService(statusRepo, archiveRepo, rollupRepo, archiveStore, services.RetentionOptions{
+		Retention:        time.Duration(cfg.RetentionConfig.Days) * 24 * time.Hour,
+		BatchSize:        cfg.RetentionConfig.BatchSize,
+		MaxDaysPerRun:    cfg.RetentionConfig.MaxDaysPerRun,
+		RestoreTTL:       cfg.RetentionConfig.RestoreTTL,
+		MaxRestoreWindow: cfg.RetentionConfig.MaxRestoreWindow,
+		Location:         analyticsLocation,
+	})
+	// partition ที่หมดอายุจะถูกลบเฉพาะเมื่อเปิด retention ซึ่ง archive ข้อมูลไว้ก่อนแล้ว
+	var partitionRetention time.Duration
+	if cfg.RetentionConfig.Enabled {
+		partitionRetention = time.Duration(cfg.RetentionConfig.Days) * 24 * time.Hour
+	}
+	partitionService := services.NewPartitionService(statusRepo, cfg.PartitionConfig.MonthsAhead, partitionRetention)
+	// spool บน Redis Stream เก็บข้อความ MQTT ไว้จนกว่าจะบันทึกลงฐานข้อมูลสำเร็จ
+	var ingestSpool services.IngestSpool
+	if cfg.IngestConfig.SpoolEnabled {
+		ingestSpool = services.NewRedisIngestSpool(redisPkg.Client, cfg.IngestConfig.SpoolStream, cfg.IngestConfig.SpoolGroup, cfg.IngestConfig.SpoolConsumer, cfg.IngestConfig.SpoolClaimIdle, cfg.IngestConfig.SpoolMaxLen)
+	}
+	ingestPipeline := services.NewIngestPipeline(statusService, ingestSpool, washerStateStore, services.IngestOptions{
+		Workers:        cfg.IngestConfig.Workers,
+		QueueSize:      cfg.IngestConfig.QueueSize,
+		BatchSize:      cfg.IngestConfig.BatchSize,
+		FlushInterval:  cfg.IngestConfig.FlushInterval,
+		EnqueueTimeout: cfg.IngestConfig.EnqueueTimeout,
+		MaxAttempts:    cfg.IngestConfig.MaxAttempts,
+		ClaimInterval:  cfg.IngestConfig.SpoolClaimInterval,
+	})
+	// rollup ไม่ปิดชั่วโมงที่ยังมีข้อความค้างใน pipeline หรือ spool
+	rollupService := services.NewRollupService(statusRepo, rollupRepo, ingestPipeline, analyticsLocation, cfg.RollupConfig.BatchHours)
+	watchdog := services.NewWatchdog(washerStateStore, ingestPipeline, cfg.WatchdogConfig.OfflineAfter, cfg.WatchdogConfig.CheckInterval)
+
 	statusHandler := handlers.NewStatusHandler(statusService)
+	streamHandler := handlers.NewStreamHandler(statusService, cfg.StreamConfig.HeartbeatInterval, cfg.StreamConfig.AllowedOrigins)
+	sessionHandler := handlers.NewSessionHandler(sessionService)
+	availabilityHandler := handlers.NewAvailabilityHandler(availabilityService)
+	registryHandler := handlers.NewRegistryHandler(registryService)
+	queueHandler := handlers.NewQueueHandler(queueService)
+	subscriptionHandler := handlers.NewSubscriptionHandler(subscriptionService)
+	webhookHandler := handlers.NewWebhookHandler(webhookService)
+	incidentHandler := handlers.NewIncidentHandler(incidentService)
+	maintenanceHandler := handlers.NewMaintenanceHandler(maintenanceService)
+	analyticsHandler := handlers.NewAnalyticsHandler(analyticsService)
+	rollupHandler := handlers.NewRollupHandler(rollupService)
+	archiveHandler := handlers.NewArchiveHandler(retentionService)
+	ingestHandler := handlers.NewIngestHandler(ingestPipeline)
 
 	// Create Fiber app
 	app := fiber.New()
@@ -63,7 +187,6 @@ func main() {
 
 	baseClientID := cfg.MQTTConfig.ClientID
 
-
 	clientID := fmt.Sprintf("%s-%d", baseClientID, time.Now().UnixNano())
 
 	// MQTT Connect
@@ -74,19 +197,75 @@ func main() {
 		cfg.MQTTConfig.Password,
 	)
 
+	// worker ต้องพร้อมก่อน subscribe เพื่อรับ retained message ตั้งแต่ข้อความแรก
+	ingestPipeline.Start()
+
 	// MQTT Subscribe
 	err = mqttClient.Subscribe("washingMachine/+/+/status", func(topic string, payload []byte, retained bool) {
 		log.Printf("📥 Topic: %s | Payload: %s | Retained: %v", topic, string(payload), retained)
-		statusService.HandleMQTTStatusUpdate(topic, payload)
+		ingestPipeline.HandleMQTTStatusUpdate(topic, payload)
 	})
-	
 
 	if err != nil {
 		log.Fatalf("❌ MQTT subscribe failed: %v", err)
 	}
 
+	// คำสั่งควบคุมเครื่องต้องใช้ MQTT client จึงสร้างหลังเชื่อมต่อ
+	commandRepo := repository.NewCommandRepo(db)
+	commandService := services.NewCommandService(commandRepo, registryRepo, mqttClient, cfg.MQTTConfig.AckTimeout)
+	commandHandler := handlers.NewCommandHandler(commandService)
+
+	// Last Will / presence ของอุปกรณ์ ใช้ตรวจจับ offline ได้ทันที
+	err = mqttClient.Subscribe("washingMachine/+/+/lwt", func(topic string, payload []byte, retained bool) {
+		log.Printf("📥 Topic: %s | Payload: %s | Retained: %v", topic, string(payload), retained)
+		ingestPipeline.HandleMQTTPresence(topic, payload)
+	})
+	if err != nil {
+		log.Fatalf("❌ MQTT subscribe failed: %v", err)
+	}
+
+	// ack ของคำสั่งที่ส่งไปทาง .../cmd
+	err = mqttClient.Subscribe("washingMachine/+/+/ack", func(topic string, payload []byte, retained bool) {
+		log.Printf("📥 Topic: %s | Payload: %s | Retained: %v", topic, string(payload), retained)
+		commandService.HandleMQTTAck(topic, payload)
+	})
+	if err != nil {
+		log.Fatalf("❌ MQTT subscribe failed: %v", err)
+	}
+
+	// Background jobs
+	jobCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	go watchdog.Run(jobCtx)
+	go queueService.RunExpiry(jobCtx, cfg.QueueConfig.ExpiryCheckInterval)
+	go webhookService.RunDeliveries(jobCtx, cfg.WebhookConfig.PollInterval)
+	go rollupService.Run(jobCtx, cfg.RollupConfig.Interval)
+	go partitionService.Run(jobCtx, cfg.PartitionConfig.Interval)
+	if cfg.RetentionConfig.Enabled {
+		go retentionService.Run(jobCtx, cfg.RetentionConfig.Interval)
+	}
+	if cfg.RegistryConfig.SyncEnabled {
+		go registryService.RunSync(jobCtx, cfg.RegistryConfig.SyncInterval)
+	}
+
 	// Setup routes
-	routes.Setup(app, statusHandler)
+	routes.Setup(app, routes.Handlers{
+		Status:       statusHandler,
+		Stream:       streamHandler,
+		Session:      sessionHandler,
+		Availability: availabilityHandler,
+		Registry:     registryHandler,
+		Queue:        queueHandler,
+		Subscription: subscriptionHandler,
+		Webhook:      webhookHandler,
+		Command:      commandHandler,
+		Incident:     incidentHandler,
+		Maintenance:  maintenanceHandler,
+		Analytics:    analyticsHandler,
+		Rollup:       rollupHandler,
+		Archive:      archiveHandler,
+		Ingest:       ingestHandler,
+	})
 
 	// Start server
 	port := os.Getenv("PORT")
@@ -94,21 +273,42 @@ func main() {
 		port = "3000"
 	}
 
-	log.Printf("Server is running at http://localhost:%s", port)
-	log.Fatal(app.Listen(":" + port))
+	go func() {
+		log.Printf("Server is running at http://localhost:%s", port)
+		if err := app.Listen(":" + port); err != nil {
+			log.Fatalf("❌ Server error: %v", err)
+		}
+	}()
 
 	// Graceful shutdown
 	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, os.Interrupt) // listen for Ctrl+C
-	<-quit                            // wait for signal
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM) // Ctrl+C และ SIGTERM จาก container
+	<-quit                                             // wait for signal
 
 	log.Println("🛑 Shutting down server...")
 
+	// หยุดรับข้อความ MQTT ก่อน แล้วบันทึกข้อความที่ค้างในคิวให้หมดภายในเวลาที่กำหนด
+	stopJobs()
+	mqttClient.Close()
+
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), cfg.IngestConfig.DrainTimeout)
+	defer cancelDrain()
+	if err := ingestPipeline.Drain(drainCtx); err != nil {
+		log.Printf("❌ Ingest drain error: %v", err)
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	if err := app.ShutdownWithContext(ctx); err != nil {
-		log.Fatalf("❌ Shutdown error: %v", err)
+		log.Printf("❌ Shutdown error: %v", err)
+	}
+
+	// ส่งแจ้งเตือนที่ค้างหลังจาก ingest และ HTTP หยุดแล้ว เพราะทั้งสองส่วนสร้างแจ้งเตือนใหม่ได้
+	notifyCtx, cancelNotify := context.WithTimeout(context.Background(), cfg.NotifyConfig.DrainTimeout)
+	defer cancelNotify()
+	if err := dispatcher.Drain(notifyCtx); err != nil {
+		log.Printf("❌ Notification drain error: %v", err)
 	}
 
 	log.Println("✅ Server gracefully stopped.")
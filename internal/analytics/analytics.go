@@ -0,0 +1,369 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jaytnw/bms-service/internal/apperr"
+	"github.com/jaytnw/bms-service/internal/models"
+	"github.com/jaytnw/bms-service/internal/repository"
+	"gorm.io/gorm"
+)
+
+// defaultWindow คือช่วงเวลาที่ใช้เมื่อไม่ได้ระบุ from
+const defaultWindow = 7 * 24 * time.Hour
+
+// Window คือช่วงเวลาที่ต้องการวิเคราะห์ [From, To) ค่า zero คือใช้ค่าเริ่มต้น (7 วันล่าสุด)
+type Window struct {
+	From time.Time
+	To   time.Time
+}
+
+// WasherUsage คือการใช้งานของเครื่องหนึ่งเครื่องในช่วงเวลาที่ขอ
+// UtilisationPct คือสัดส่วนเวลาที่เครื่องอยู่ในรอบซักเทียบกับความยาวของช่วงเวลา
+type WasherUsage struct {
+	WasherID        string    `json:"washerId"`
+	DormID          string    `json:"dormId"`
+	From            time.Time `json:"from"`
+	To              time.Time `json:"to"`
+	BusySeconds     int64     `json:"busySeconds"`
+	UtilisationPct  float64   `json:"utilisationPct"`
+	Cycles          int       `json:"cycles"`
+	CompletedCycles int       `json:"completedCycles"`
+	CyclesPerDay    float64   `json:"cyclesPerDay"`
+}
+
+// DormUsage คือการใช้งานรวมของหอ Machines เรียงจากใช้งานน้อยไปมาก เพื่อหาเครื่องที่ถูกใช้น้อย
+type DormUsage struct {
+	DormID         string        `json:"dormId"`
+	DormName       string        `json:"dormName"`
+	From           time.Time     `json:"from"`
+	To             time.Time     `json:"to"`
+	Washers        int           `json:"washers"`
+	BusySeconds    int64         `json:"busySeconds"`
+	UtilisationPct float64       `json:"utilisationPct"`
+	Cycles         int           `json:"cycles"`
+	CyclesPerDay   float64       `json:"cyclesPerDay"`
+	Machines       []WasherUsage `json:"machines"`
+}
+
+// Heatmap คือ utilisation (%) ของหอแยกตามชั่วโมงของสัปดาห์ตาม Timezone
+// Cells[weekday][hour] โดย weekday 0 คือวันอาทิตย์ ช่องที่ไม่อยู่ในช่วงเวลาที่ขอจะเป็น 0
+type Heatmap struct {
+	DormID   string         `json:"dormId"`
+	From     time.Time      `json:"from"`
+	To       time.Time      `json:"to"`
+	Timezone string         `json:"timezone"`
+	Cells    [7][24]float64 `json:"cells"`
+}
+
+// BusyPeriod คือชั่วโมงหนึ่งในปฏิทินพร้อม utilisation และจำนวนรอบที่เริ่มในชั่วโมงนั้น
+type BusyPeriod struct {
+	Start          time.Time `json:"start"`
+	End            time.Time `json:"end"`
+	UtilisationPct float64   `json:"utilisationPct"`
+	Cycles         int       `json:"cycles"`
+}
+
+// BusiestPeriods คือชั่วโมงที่หอมีการใช้งานสูงสุด เรียงจากมากไปน้อย
+type BusiestPeriods struct {
+	DormID  string       `json:"dormId"`
+	From    time.Time    `json:"from"`
+	To      time.Time    `json:"to"`
+	Periods []BusyPeriod `json:"periods"`
+}
+
+// Service คำนวณสถิติการใช้งานจากรอบซัก (wash_sessions) ผลลัพธ์ถูก cache ไว้ตาม TTL ของ Cache
+// ชั่วโมงที่เก่ากว่า Options.RollupThreshold และ rollup job สรุปแล้วจะอ่านจาก status_rollups แทน
+type Service interface {
+	WasherUsage(ctx context.Context, washerID string, window Window) (*WasherUsage, error)
+	DormUsage(ctx context.Context, dormID string, window Window) ([]DormUsage, error)
+	Heatmap(ctx context.Context, dormID string, window Window) (*Heatmap, error)
+	BusiestPeriods(ctx context.Context, dormID string, window Window, limit int) (*BusiestPeriods, error)
+}
+
+// Options กำหนด timezone สำหรับ heatmap ความยาวสูงสุดของช่วงเวลาที่ขอได้
+// และอายุขั้นต่ำของข้อมูลที่จะอ่านจาก rollup (0 คือไม่ใช้ rollup)
+type Options struct {
+	Location        *time.Location
+	MaxWindow       time.Duration
+	RollupThreshold time.Duration
+}
+
+type service struct {
+	sessionRepo     repository.SessionRepository
+	rollupRepo      repository.RollupRepository
+	registryRepo    repository.RegistryRepository
+	cache           Cache
+	location        *time.Location
+	maxWindow       time.Duration
+	rollupThreshold time.Duration
+}
+
+func NewService(sessionRepo repository.SessionRepository, rollupRepo repository.RollupRepository, registryRepo repository.RegistryRepository, cache Cache, opts Options) Service {
+	if opts.Location == nil {
+		opts.Location = time.UTC
+	}
+	return &service{
+		sessionRepo:     sessionRepo,
+		rollupRepo:      rollupRepo,
+		registryRepo:    registryRepo,
+		cache:           cache,
+		location:        opts.Location,
+		maxWindow:       opts.MaxWindow,
+		rollupThreshold: opts.RollupThreshold,
+	}
+}
+
+func (s *service) WasherUsage(ctx context.Context, washerID string, window Window) (*WasherUsage, error) {
+	window, err := s.normalize(window)
+	if err != nil {
+		return nil, err
+	}
+
+	var usage WasherUsage
+	key := cacheKey("washer", washerID, window)
+	if s.cache.Get(ctx, key, &usage) {
+		return &usage, nil
+	}
+
+	machine, err := s.registryRepo.FindMachineByID(ctx, washerID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperr.New("NOT_FOUND", "Washer not found", 404, err)
+		}
+		return nil, apperr.New("DB_ERROR", "Failed to get washer", 500, err)
+	}
+	h, err := s.history(ctx, repository.SessionQuery{WasherID: washerID}, repository.RollupQuery{WasherID: washerID}, window)
+	if err != nil {
+		return nil, err
+	}
+
+	usage = washerUsage(*machine, h, window, time.Now())
+	s.cache.Set(ctx, key, usage)
+	return &usage, nil
+}
+
+// DormUsage คืนการใช้งานของทุกหอ หรือเฉพาะหอที่ระบุ
+func (s *service) DormUsage(ctx context.Context, dormID string, window Window) ([]DormUsage, error) {
+	window, err := s.normalize(window)
+	if err != nil {
+		return nil, err
+	}
+
+	var usage []DormUsage
+	key := cacheKey("dorms", dormID, window)
+	if s.cache.Get(ctx, key, &usage) {
+		return usage, nil
+	}
+
+	dorms, err := s.dorms(ctx, dormID)
+	if err != nil {
+		return nil, err
+	}
+	machines, err := s.machines(ctx, dormID)
+	if err != nil {
+		return nil, err
+	}
+	h, err := s.history(ctx, repository.SessionQuery{DormID: dormID}, repository.RollupQuery{DormID: dormID}, window)
+	if err != nil {
+		return nil, err
+	}
+
+	usage = dormUsage(dorms, machines, h, window, time.Now())
+	s.cache.Set(ctx, key, usage)
+	return usage, nil
+}
+
+func (s *service) Heatmap(ctx context.Context, dormID string, window Window) (*Heatmap, error) {
+	window, err := s.normalize(window)
+	if err != nil {
+		return nil, err
+	}
+
+	var heatmap Heatmap
+	key := cacheKey("heatmap", dormID, window)
+	if s.cache.Get(ctx, key, &heatmap) {
+		return &heatmap, nil
+	}
+
+	buckets, err := s.hourlyBuckets(ctx, dormID, window)
+	if err != nil {
+		return nil, err
+	}
+
+	heatmap = Heatmap{
+		DormID:   dormID,
+		From:     window.From,
+		To:       window.To,
+		Timezone: s.location.String(),
+		Cells:    heatmapCells(buckets),
+	}
+	s.cache.Set(ctx, key, heatmap)
+	return &heatmap, nil
+}
+
+func (s *service) BusiestPeriods(ctx context.Context, dormID string, window Window, limit int) (*BusiestPeriods, error) {
+	window, err := s.normalize(window)
+	if err != nil {
+		return nil, err
+	}
+
+	var peaks BusiestPeriods
+	key := cacheKey("peaks", fmt.Sprintf("%s:%d", dormID, limit), window)
+	if s.cache.Get(ctx, key, &peaks) {
+		return &peaks, nil
+	}
+
+	buckets, err := s.hourlyBuckets(ctx, dormID, window)
+	if err != nil {
+		return nil, err
+	}
+
+	peaks = BusiestPeriods{
+		DormID:  dormID,
+		From:    window.From,
+		To:      window.To,
+		Periods: busiestPeriods(buckets, limit),
+	}
+	s.cache.Set(ctx, key, peaks)
+	return &peaks, nil
+}
+
+// normalize เติมค่าเริ่มต้นและปัดเวลาลงเป็นนาที เพื่อให้ request ที่ใกล้เคียงกันใช้ cache เดียวกัน
+func (s *service) normalize(window Window) (Window, error) {
+	if window.To.IsZero() {
+		window.To = time.Now()
+	}
+	if window.From.IsZero() {
+		window.From = window.To.Add(-defaultWindow)
+	}
+	window.From = window.From.Truncate(time.Minute)
+	window.To = window.To.Truncate(time.Minute)
+
+	if !window.From.Before(window.To) {
+		return window, apperr.New("INVALID_QUERY", "from must be before to", 400, nil)
+	}
+	if s.maxWindow > 0 && window.To.Sub(window.From) > s.maxWindow {
+		return window, apperr.New("INVALID_QUERY", fmt.Sprintf("window must not exceed %v", s.maxWindow), 400, nil)
+	}
+	return window, nil
+}
+
+func (s *service) hourlyBuckets(ctx context.Context, dormID string, window Window) ([]bucket, error) {
+	if _, err := s.dorms(ctx, dormID); err != nil {
+		return nil, err
+	}
+	machines, err := s.machines(ctx, dormID)
+	if err != nil {
+		return nil, err
+	}
+	h, err := s.history(ctx, repository.SessionQuery{DormID: dormID}, repository.RollupQuery{DormID: dormID}, window)
+	if err != nil {
+		return nil, err
+	}
+	return hourlyBuckets(len(machines), h, window, s.location, time.Now()), nil
+}
+
+// dorms คืนหอทั้งหมด หรือเฉพาะหอที่ระบุ (404 หากไม่พบ)
+func (s *service) dorms(ctx context.Context, dormID string) ([]models.Dorm, error) {
+	if dormID == "" {
+		dorms, err := s.registryRepo.FindDorms(ctx)
+		if err != nil {
+			return nil, apperr.New("DB_ERROR", "Failed to get dorms", 500, err)
+		}
+		return dorms, nil
+	}
+
+	dorm, err := s.registryRepo.FindDormByID(ctx, dormID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperr.New("NOT_FOUND", "Dorm not found", 404, err)
+		}
+		return nil, apperr.New("DB_ERROR", "Failed to get dorm", 500, err)
+	}
+	return []models.Dorm{*dorm}, nil
+}
+
+// machines คืนเครื่องที่อนุมัติแล้ว เครื่องที่รออนุมัติไม่นับเป็นกำลังการซักของหอ
+func (s *service) machines(ctx context.Context, dormID string) ([]models.Machine, error) {
+	all, err := s.registryRepo.FindMachines(ctx, dormID)
+	if err != nil {
+		return nil, apperr.New("DB_ERROR", "Failed to get machines", 500, err)
+	}
+
+	machines := make([]models.Machine, 0, len(all))
+	for _, m := range all {
+		if m.Status != models.RegistrationPending {
+			machines = append(machines, m)
+		}
+	}
+	return machines, nil
+}
+
+// history โหลดข้อมูลของช่วงเวลา: ชั่วโมงเต็มที่เก่ากว่า cutoff อ่านจาก rollup ส่วนที่เหลืออ่านจากรอบซัก
+func (s *service) history(ctx context.Context, sessionQuery repository.SessionQuery, rollupQuery repository.RollupQuery, window Window) (history, error) {
+	cutoff, err := s.rollupCutoff(ctx)
+	if err != nil {
+		return history{}, err
+	}
+	h := planHistory(window, cutoff, s.location)
+
+	if h.rolledUp != nil {
+		rollupQuery.Granularity = models.RollupHourly
+		rollupQuery.From = &h.rolledUp.From
+		rollupQuery.To = &h.rolledUp.To
+		h.rollups, err = s.rollupRepo.FindRollups(ctx, rollupQuery)
+		if err != nil {
+			return history{}, apperr.New("DB_ERROR", "Failed to get rollups", 500, err)
+		}
+	}
+
+	seen := make(map[uint]bool)
+	for _, live := range h.live {
+		query := sessionQuery
+		query.From = &live.From
+		query.To = &live.To
+		query.Overlapping = true
+
+		sessions, err := s.sessionRepo.FindSessions(ctx, query)
+		if err != nil {
+			return history{}, apperr.New("DB_ERROR", "Failed to get sessions", 500, err)
+		}
+		// รอบซักที่คาบเกี่ยวทั้งสองช่วงจะถูกโหลดสองครั้ง
+		for _, session := range sessions {
+			if !seen[session.ID] {
+				seen[session.ID] = true
+				h.sessions = append(h.sessions, session)
+			}
+		}
+	}
+	return h, nil
+}
+
+// rollupCutoff คืนเวลาที่อ่าน rollup ได้ถึง (ไม่รวม) หรือ zero หากไม่ใช้ rollup
+func (s *service) rollupCutoff(ctx context.Context) (time.Time, error) {
+	if s.rollupThreshold <= 0 {
+		return time.Time{}, nil
+	}
+
+	checkpoint, err := s.rollupRepo.FindCheckpoint(ctx, models.StatusRollupCheckpoint)
+	if err != nil {
+		return time.Time{}, apperr.New("DB_ERROR", "Failed to get rollup checkpoint", 500, err)
+	}
+	if checkpoint == nil {
+		return time.Time{}, nil
+	}
+
+	cutoff := models.HourStart(time.Now().Add(-s.rollupThreshold), s.location)
+	if checkpoint.Position.Before(cutoff) {
+		cutoff = checkpoint.Position
+	}
+	return cutoff, nil
+}
+
+func cacheKey(kind, id string, window Window) string {
+	return fmt.Sprintf("analytics:%s:%s:%d:%d", kind, id, window.From.Unix(), window.To.Unix())
+}
@@ -0,0 +1,58 @@
+package analytics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Cache เก็บผลการคำนวณที่ serialize เป็น JSON ได้ cache miss หรือ error ถือเป็นการคำนวณใหม่
+type Cache interface {
+	Get(ctx context.Context, key string, dst any) bool
+	Set(ctx context.Context, key string, value any)
+}
+
+type redisCache struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+func NewRedisCache(client *redis.Client, ttl time.Duration) Cache {
+	return &redisCache{
+		client: client,
+		ttl:    ttl,
+	}
+}
+
+func (c *redisCache) Get(ctx context.Context, key string, dst any) bool {
+	raw, err := c.client.Get(ctx, key).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			log.Printf("⚠️ Failed to read analytics cache %s: %v", key, err)
+		}
+		return false
+	}
+	if err := json.Unmarshal(raw, dst); err != nil {
+		log.Printf("⚠️ Failed to decode analytics cache %s: %v", key, err)
+		return false
+	}
+	return true
+}
+
+func (c *redisCache) Set(ctx context.Context, key string, value any) {
+	if c.ttl <= 0 {
+		return
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		log.Printf("⚠️ Failed to encode analytics cache %s: %v", key, err)
+		return
+	}
+	if err := c.client.Set(ctx, key, raw, c.ttl).Err(); err != nil {
+		log.Printf("⚠️ Failed to write analytics cache %s: %v", key, err)
+	}
+}
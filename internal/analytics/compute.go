@@ -0,0 +1,287 @@
+package analytics
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/jaytnw/bms-service/internal/models"
+)
+
+// bucket คือหนึ่งชั่วโมงในปฏิทิน (ถูกตัดให้อยู่ในช่วงเวลาที่ขอ) พร้อมเวลาที่เครื่องทั้งหอถูกใช้
+type bucket struct {
+	Start       time.Time
+	End         time.Time
+	Capacity    float64 // จำนวนเครื่อง x วินาทีของ bucket
+	BusySeconds float64
+	Started     int
+}
+
+// history คือข้อมูลที่ใช้คำนวณ: rollup รายชั่วโมงสำหรับช่วง rolledUp และรอบซักสำหรับช่วง live
+type history struct {
+	rolledUp *Window
+	live     []Window
+	rollups  []models.StatusRollup
+	sessions []models.WashSession
+}
+
+// planHistory แบ่งช่วงเวลาเป็นชั่วโมงเต็มตาม loc ที่เก่ากว่า cutoff (อ่านจาก rollup) กับส่วนที่เหลือ (อ่านจากรอบซัก)
+// loc ต้องเป็น location เดียวกับที่ rollup job ใช้ ไม่เช่นนั้นขอบชั่วโมงจะไม่ตรงกับ rollup
+func planHistory(window Window, cutoff time.Time, loc *time.Location) history {
+	start := models.HourStart(window.From, loc)
+	if start.Before(window.From) {
+		start = start.Add(time.Hour)
+	}
+	end := models.HourStart(window.To, loc)
+	if cutoff.Before(end) {
+		end = cutoff
+	}
+	if cutoff.IsZero() || !start.Before(end) {
+		return history{live: []Window{window}}
+	}
+
+	h := history{rolledUp: &Window{From: start, To: end}}
+	if window.From.Before(start) {
+		h.live = append(h.live, Window{From: window.From, To: start})
+	}
+	if end.Before(window.To) {
+		h.live = append(h.live, Window{From: end, To: window.To})
+	}
+	return h
+}
+
+// busyInterval คืนช่วงเวลาที่รอบซักอยู่ในช่วงเวลาที่ขอ รอบที่ยังไม่จบถือว่าทำงานถึง now
+func busyInterval(session models.WashSession, window Window, now time.Time) (time.Time, time.Time, bool) {
+	end := now
+	if session.EndedAt != nil {
+		end = *session.EndedAt
+	}
+
+	start := session.StartedAt
+	if start.Before(window.From) {
+		start = window.From
+	}
+	if end.After(window.To) {
+		end = window.To
+	}
+	return start, end, end.After(start)
+}
+
+func startedIn(session models.WashSession, window Window) bool {
+	return !session.StartedAt.Before(window.From) && session.StartedAt.Before(window.To)
+}
+
+func washerUsage(machine models.Machine, h history, window Window, now time.Time) WasherUsage {
+	usage := WasherUsage{
+		WasherID: machine.ID,
+		DormID:   machine.DormID,
+		From:     window.From,
+		To:       window.To,
+	}
+
+	var busy time.Duration
+	for _, session := range h.sessions {
+		if session.WasherID != machine.ID {
+			continue
+		}
+		for _, live := range h.live {
+			if start, end, ok := busyInterval(session, live, now); ok {
+				busy += end.Sub(start)
+			}
+			if startedIn(session, live) {
+				usage.Cycles++
+				if session.Outcome == models.SessionCompleted {
+					usage.CompletedCycles++
+				}
+			}
+		}
+	}
+	for _, rollup := range h.rollups {
+		if rollup.WasherID != machine.ID {
+			continue
+		}
+		busy += time.Duration(rollup.BusySeconds) * time.Second
+		usage.Cycles += rollup.Cycles
+		usage.CompletedCycles += rollup.Completed
+	}
+
+	length := window.To.Sub(window.From)
+	usage.BusySeconds = int64(busy.Seconds())
+	usage.UtilisationPct = percent(busy.Seconds(), length.Seconds())
+	usage.CyclesPerDay = round2(float64(usage.Cycles) / length.Hours() * 24)
+	return usage
+}
+
+// dormUsage จัดกลุ่มการใช้งานตามหอ ตามลำดับของ dorms
+func dormUsage(dorms []models.Dorm, machines []models.Machine, h history, window Window, now time.Time) []DormUsage {
+	byDorm := make(map[string][]models.Machine)
+	for _, m := range machines {
+		byDorm[m.DormID] = append(byDorm[m.DormID], m)
+	}
+	byWasher := make(map[string]history)
+	for _, session := range h.sessions {
+		wh := byWasher[session.WasherID]
+		wh.sessions = append(wh.sessions, session)
+		byWasher[session.WasherID] = wh
+	}
+	for _, rollup := range h.rollups {
+		wh := byWasher[rollup.WasherID]
+		wh.rollups = append(wh.rollups, rollup)
+		byWasher[rollup.WasherID] = wh
+	}
+
+	length := window.To.Sub(window.From)
+	result := make([]DormUsage, 0, len(dorms))
+	for _, dorm := range dorms {
+		usage := DormUsage{
+			DormID:   dorm.ID,
+			DormName: dorm.Name,
+			From:     window.From,
+			To:       window.To,
+			Machines: []WasherUsage{},
+		}
+		for _, m := range byDorm[dorm.ID] {
+			wh := byWasher[m.ID]
+			wh.live = h.live
+			washer := washerUsage(m, wh, window, now)
+			usage.Washers++
+			usage.BusySeconds += washer.BusySeconds
+			usage.Cycles += washer.Cycles
+			usage.Machines = append(usage.Machines, washer)
+		}
+
+		capacity := float64(usage.Washers) * length.Seconds()
+		usage.UtilisationPct = percent(float64(usage.BusySeconds), capacity)
+		usage.CyclesPerDay = round2(float64(usage.Cycles) / length.Hours() * 24)
+		sort.SliceStable(usage.Machines, func(i, j int) bool {
+			return usage.Machines[i].UtilisationPct < usage.Machines[j].UtilisationPct
+		})
+		result = append(result, usage)
+	}
+	return result
+}
+
+// hourlyBuckets แบ่งช่วงเวลาเป็นชั่วโมงตามปฏิทินของ loc แล้วกระจายเวลาที่เครื่องถูกใช้ลงแต่ละชั่วโมง
+// rollup รายชั่วโมงเริ่มที่ต้นชั่วโมงของ loc เดียวกัน (models.HourStart) หนึ่ง rollup จึงลงใน bucket เดียว
+func hourlyBuckets(washers int, h history, window Window, loc *time.Location, now time.Time) []bucket {
+	from, to := window.From.In(loc), window.To.In(loc)
+	first := models.HourStart(from, loc)
+
+	var buckets []bucket
+	for start := first; start.Before(to); start = start.Add(time.Hour) {
+		b := bucket{Start: start, End: start.Add(time.Hour)}
+		if b.Start.Before(from) {
+			b.Start = from
+		}
+		if b.End.After(to) {
+			b.End = to
+		}
+		b.Capacity = float64(washers) * b.End.Sub(b.Start).Seconds()
+		buckets = append(buckets, b)
+	}
+	if len(buckets) == 0 {
+		return nil
+	}
+
+	for _, rollup := range h.rollups {
+		if i := bucketIndex(first, rollup.BucketStart); i < len(buckets) {
+			buckets[i].BusySeconds += float64(rollup.BusySeconds)
+			buckets[i].Started += rollup.Cycles
+		}
+	}
+
+	for _, session := range h.sessions {
+		for _, live := range h.live {
+			addSession(buckets, first, session, live, now)
+		}
+	}
+	return buckets
+}
+
+// addSession กระจายเวลาของรอบซักที่อยู่ในช่วง live ลง bucket
+func addSession(buckets []bucket, first time.Time, session models.WashSession, live Window, now time.Time) {
+	if startedIn(session, live) {
+		buckets[bucketIndex(first, session.StartedAt)].Started++
+	}
+
+	start, end, ok := busyInterval(session, live, now)
+	if !ok {
+		return
+	}
+	for i := bucketIndex(first, start); i < len(buckets) && buckets[i].Start.Before(end); i++ {
+		lo, hi := buckets[i].Start, buckets[i].End
+		if start.After(lo) {
+			lo = start
+		}
+		if end.Before(hi) {
+			hi = end
+		}
+		if hi.After(lo) {
+			buckets[i].BusySeconds += hi.Sub(lo).Seconds()
+		}
+	}
+}
+
+func bucketIndex(first, t time.Time) int {
+	i := int(t.Sub(first) / time.Hour)
+	if i < 0 {
+		return 0
+	}
+	return i
+}
+
+// heatmapCells รวม bucket ที่อยู่ในชั่วโมงเดียวกันของสัปดาห์ แล้วคิดเป็น utilisation (%)
+func heatmapCells(buckets []bucket) [7][24]float64 {
+	var busy, capacity [7][24]float64
+	for _, b := range buckets {
+		day, hour := int(b.Start.Weekday()), b.Start.Hour()
+		busy[day][hour] += b.BusySeconds
+		capacity[day][hour] += b.Capacity
+	}
+
+	var cells [7][24]float64
+	for day := range cells {
+		for hour := range cells[day] {
+			cells[day][hour] = percent(busy[day][hour], capacity[day][hour])
+		}
+	}
+	return cells
+}
+
+// busiestPeriods คืน limit ชั่วโมงที่ utilisation สูงสุด ชั่วโมงที่ไม่มีการใช้งานเลยไม่นับ
+func busiestPeriods(buckets []bucket, limit int) []BusyPeriod {
+	periods := make([]BusyPeriod, 0, len(buckets))
+	for _, b := range buckets {
+		if b.BusySeconds == 0 && b.Started == 0 {
+			continue
+		}
+		periods = append(periods, BusyPeriod{
+			Start:          b.Start,
+			End:            b.End,
+			UtilisationPct: percent(b.BusySeconds, b.Capacity),
+			Cycles:         b.Started,
+		})
+	}
+
+	sort.SliceStable(periods, func(i, j int) bool {
+		if periods[i].UtilisationPct != periods[j].UtilisationPct {
+			return periods[i].UtilisationPct > periods[j].UtilisationPct
+		}
+		return periods[i].Cycles > periods[j].Cycles
+	})
+	if limit > 0 && len(periods) > limit {
+		periods = periods[:limit]
+	}
+	return periods
+}
+
+func percent(part, whole float64) float64 {
+	if whole <= 0 {
+		return 0
+	}
+	return round2(part / whole * 100)
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
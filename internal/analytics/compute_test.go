@@ -0,0 +1,315 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jaytnw/bms-service/internal/models"
+)
+
+// day คือวันจันทร์ 00:00 UTC ใช้เป็นจุดเริ่มของทุก test
+var day = time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)
+
+func at(hour, minute int) time.Time {
+	return day.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+}
+
+func session(washerID string, start, end time.Time, outcome models.SessionOutcome) models.WashSession {
+	s := models.WashSession{WasherID: washerID, DormID: "d1", StartedAt: start, Outcome: outcome}
+	if !end.IsZero() {
+		s.EndedAt = &end
+	}
+	return s
+}
+
+func TestPercent(t *testing.T) {
+	tests := []struct {
+		part, whole float64
+		want        float64
+	}{
+		{0, 100, 0},
+		{50, 100, 50},
+		{1, 3, 33.33},
+		{2, 3, 66.67},
+		{10, 0, 0},
+		{10, -5, 0},
+	}
+	for _, tt := range tests {
+		if got := percent(tt.part, tt.whole); got != tt.want {
+			t.Errorf("percent(%v, %v) = %v, want %v", tt.part, tt.whole, got, tt.want)
+		}
+	}
+}
+
+func TestWasherUsage(t *testing.T) {
+	window := Window{From: at(8, 0), To: at(12, 0)}
+	now := at(12, 0)
+	machine := models.Machine{ID: "w1", DormID: "d1"}
+
+	tests := []struct {
+		name          string
+		sessions      []models.WashSession
+		wantBusy      int64
+		wantPct       float64
+		wantCycles    int
+		wantCompleted int
+	}{
+		{
+			name:     "no sessions",
+			wantBusy: 0,
+		},
+		{
+			name:          "one completed hour",
+			sessions:      []models.WashSession{session("w1", at(9, 0), at(10, 0), models.SessionCompleted)},
+			wantBusy:      3600,
+			wantPct:       25,
+			wantCycles:    1,
+			wantCompleted: 1,
+		},
+		{
+			name:       "session started before the window is clipped and not counted as a cycle",
+			sessions:   []models.WashSession{session("w1", at(7, 30), at(8, 30), models.SessionCompleted)},
+			wantBusy:   1800,
+			wantPct:    12.5,
+			wantCycles: 0,
+		},
+		{
+			name:       "open session runs until now",
+			sessions:   []models.WashSession{session("w1", at(11, 0), time.Time{}, models.SessionInProgress)},
+			wantBusy:   3600,
+			wantPct:    25,
+			wantCycles: 1,
+		},
+		{
+			name: "other washers are ignored",
+			sessions: []models.WashSession{
+				session("w2", at(9, 0), at(11, 0), models.SessionCompleted),
+				session("w1", at(10, 0), at(10, 30), models.SessionAborted),
+			},
+			wantBusy:   1800,
+			wantPct:    12.5,
+			wantCycles: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := history{live: []Window{window}, sessions: tt.sessions}
+			got := washerUsage(machine, h, window, now)
+			if got.BusySeconds != tt.wantBusy || got.UtilisationPct != tt.wantPct {
+				t.Errorf("busy = %ds (%v%%), want %ds (%v%%)", got.BusySeconds, got.UtilisationPct, tt.wantBusy, tt.wantPct)
+			}
+			if got.Cycles != tt.wantCycles || got.CompletedCycles != tt.wantCompleted {
+				t.Errorf("cycles = %d (%d completed), want %d (%d completed)", got.Cycles, got.CompletedCycles, tt.wantCycles, tt.wantCompleted)
+			}
+			if want := float64(tt.wantCycles) * 6; got.CyclesPerDay != want {
+				t.Errorf("cyclesPerDay = %v, want %v", got.CyclesPerDay, want)
+			}
+		})
+	}
+}
+
+func TestDormUsageSortsLeastUsedFirst(t *testing.T) {
+	window := Window{From: at(0, 0), To: at(10, 0)}
+	dorms := []models.Dorm{{ID: "d1", Name: "A"}, {ID: "d2", Name: "B"}}
+	machines := []models.Machine{{ID: "w1", DormID: "d1"}, {ID: "w2", DormID: "d1"}}
+	h := history{
+		live: []Window{window},
+		sessions: []models.WashSession{
+			session("w1", at(1, 0), at(6, 0), models.SessionCompleted),
+			session("w2", at(2, 0), at(3, 0), models.SessionCompleted),
+		},
+	}
+
+	got := dormUsage(dorms, machines, h, window, at(10, 0))
+
+	if len(got) != 2 {
+		t.Fatalf("dorms = %d, want 2", len(got))
+	}
+	if got[0].Washers != 2 || got[0].BusySeconds != 6*3600 || got[0].UtilisationPct != 30 {
+		t.Errorf("d1 = %d washers, %ds, %v%%; want 2 washers, 21600s, 30%%", got[0].Washers, got[0].BusySeconds, got[0].UtilisationPct)
+	}
+	if got[0].Machines[0].WasherID != "w2" || got[0].Machines[1].WasherID != "w1" {
+		t.Errorf("machines = %s, %s; want w2 then w1", got[0].Machines[0].WasherID, got[0].Machines[1].WasherID)
+	}
+	if got[1].Washers != 0 || got[1].UtilisationPct != 0 || got[1].Machines == nil {
+		t.Errorf("d2 = %+v, want an empty dorm with a non-nil machine list", got[1])
+	}
+}
+
+func TestHourlyBucketsSplitSessions(t *testing.T) {
+	window := Window{From: at(8, 30), To: at(11, 0)}
+	h := history{
+		live:     []Window{window},
+		sessions: []models.WashSession{session("w1", at(8, 45), at(10, 15), models.SessionCompleted)},
+	}
+
+	buckets := hourlyBuckets(2, h, window, time.UTC, at(11, 0))
+
+	want := []struct {
+		start    time.Time
+		capacity float64
+		busy     float64
+		started  int
+	}{
+		{at(8, 30), 2 * 1800, 900, 1},
+		{at(9, 0), 2 * 3600, 3600, 0},
+		{at(10, 0), 2 * 3600, 900, 0},
+	}
+	if len(buckets) != len(want) {
+		t.Fatalf("buckets = %d, want %d", len(buckets), len(want))
+	}
+	for i, w := range want {
+		b := buckets[i]
+		if !b.Start.Equal(w.start) || b.Capacity != w.capacity || b.BusySeconds != w.busy || b.Started != w.started {
+			t.Errorf("bucket %d = %v cap %v busy %v started %d; want %v cap %v busy %v started %d",
+				i, b.Start, b.Capacity, b.BusySeconds, b.Started, w.start, w.capacity, w.busy, w.started)
+		}
+	}
+}
+
+func TestHeatmapCells(t *testing.T) {
+	monday9 := at(9, 0)
+	buckets := []bucket{
+		{Start: monday9, Capacity: 3600, BusySeconds: 1800},
+		// จันทร์ถัดไปเวลาเดียวกันรวมเป็นช่องเดียว
+		{Start: monday9.AddDate(0, 0, 7), Capacity: 3600, BusySeconds: 3600},
+		{Start: at(22, 0), Capacity: 7200, BusySeconds: 0},
+	}
+
+	cells := heatmapCells(buckets)
+
+	if got := cells[time.Monday][9]; got != 75 {
+		t.Errorf("monday 09:00 = %v, want 75", got)
+	}
+	if got := cells[time.Monday][22]; got != 0 {
+		t.Errorf("monday 22:00 = %v, want 0", got)
+	}
+	if got := cells[time.Sunday][9]; got != 0 {
+		t.Errorf("sunday 09:00 = %v, want 0 for an hour without capacity", got)
+	}
+}
+
+func TestBusiestPeriods(t *testing.T) {
+	buckets := []bucket{
+		{Start: at(8, 0), End: at(9, 0), Capacity: 3600, BusySeconds: 1800, Started: 1},
+		{Start: at(9, 0), End: at(10, 0), Capacity: 3600, BusySeconds: 3600, Started: 1},
+		{Start: at(10, 0), End: at(11, 0), Capacity: 3600},
+		{Start: at(11, 0), End: at(12, 0), Capacity: 3600, BusySeconds: 1800, Started: 3},
+		{Start: at(12, 0), End: at(13, 0), Capacity: 3600, Started: 1},
+	}
+
+	tests := []struct {
+		name  string
+		limit int
+		want  []time.Time
+	}{
+		{name: "ordered by utilisation then cycles", limit: 0, want: []time.Time{at(9, 0), at(11, 0), at(8, 0), at(12, 0)}},
+		{name: "limited", limit: 2, want: []time.Time{at(9, 0), at(11, 0)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			periods := busiestPeriods(buckets, tt.limit)
+			if len(periods) != len(tt.want) {
+				t.Fatalf("periods = %d, want %d", len(periods), len(tt.want))
+			}
+			for i, start := range tt.want {
+				if !periods[i].Start.Equal(start) {
+					t.Errorf("period %d starts at %v, want %v", i, periods[i].Start, start)
+				}
+			}
+		})
+	}
+}
+
+func TestPlanHistoryUsesLocalHours(t *testing.T) {
+	ist := time.FixedZone("IST", 5*3600+30*60)
+	window := Window{From: at(8, 10), To: at(12, 50)}
+
+	tests := []struct {
+		name     string
+		loc      *time.Location
+		cutoff   time.Time
+		wantRoll *Window
+		wantLive []Window
+	}{
+		{
+			name:     "utc hours",
+			loc:      time.UTC,
+			cutoff:   at(11, 0),
+			wantRoll: &Window{From: at(9, 0), To: at(11, 0)},
+			wantLive: []Window{{From: at(8, 10), To: at(9, 0)}, {From: at(11, 0), To: at(12, 50)}},
+		},
+		{
+			name:     "half hour offset",
+			loc:      ist,
+			cutoff:   at(11, 30),
+			wantRoll: &Window{From: at(8, 30), To: at(11, 30)},
+			wantLive: []Window{{From: at(8, 10), To: at(8, 30)}, {From: at(11, 30), To: at(12, 50)}},
+		},
+		{
+			name:     "cutoff before the first full hour",
+			loc:      ist,
+			cutoff:   at(8, 30),
+			wantLive: []Window{window},
+		},
+		{
+			name:     "no rollups",
+			loc:      ist,
+			wantLive: []Window{window},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := planHistory(window, tt.cutoff, tt.loc)
+			if (h.rolledUp == nil) != (tt.wantRoll == nil) || (h.rolledUp != nil && !sameWindow(*h.rolledUp, *tt.wantRoll)) {
+				t.Fatalf("rolledUp = %+v, want %+v", h.rolledUp, tt.wantRoll)
+			}
+			if len(h.live) != len(tt.wantLive) {
+				t.Fatalf("live = %+v, want %+v", h.live, tt.wantLive)
+			}
+			for i := range h.live {
+				if !sameWindow(h.live[i], tt.wantLive[i]) {
+					t.Errorf("live[%d] = %+v, want %+v", i, h.live[i], tt.wantLive[i])
+				}
+			}
+		})
+	}
+}
+
+func TestHourlyBucketsPlaceRollupsInLocalHours(t *testing.T) {
+	ist := time.FixedZone("IST", 5*3600+30*60)
+	window := Window{From: at(8, 30), To: at(11, 30)}
+	h := history{
+		rolledUp: &window,
+		rollups: []models.StatusRollup{
+			{WasherID: "w1", BucketStart: at(8, 30), BusySeconds: 600, Cycles: 1},
+			{WasherID: "w2", BucketStart: at(8, 30), BusySeconds: 300},
+			{WasherID: "w1", BucketStart: at(10, 30), BusySeconds: 3600, Cycles: 2},
+		},
+	}
+
+	buckets := hourlyBuckets(2, h, window, ist, at(12, 0))
+
+	want := []struct {
+		busy    float64
+		started int
+	}{{900, 1}, {0, 0}, {3600, 2}}
+	if len(buckets) != len(want) {
+		t.Fatalf("buckets = %d, want %d", len(buckets), len(want))
+	}
+	for i, w := range want {
+		if buckets[i].BusySeconds != w.busy || buckets[i].Started != w.started {
+			t.Errorf("bucket %d (%v) = busy %v started %d, want busy %v started %d",
+				i, buckets[i].Start, buckets[i].BusySeconds, buckets[i].Started, w.busy, w.started)
+		}
+		if buckets[i].Start.Minute() != 0 {
+			t.Errorf("bucket %d starts at %v, want the start of a local hour", i, buckets[i].Start)
+		}
+	}
+}
+
+func sameWindow(a, b Window) bool {
+	return a.From.Equal(b.From) && a.To.Equal(b.To)
+}
@@ -0,0 +1,75 @@
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// FormatNDJSONGzip คือรูปแบบไฟล์ archive: JSON หนึ่งรายการต่อบรรทัด บีบอัดด้วย gzip
+const FormatNDJSONGzip = "ndjson.gz"
+
+// Encoder เขียนรายการเป็น NDJSON ที่บีบอัดแล้วลงหน่วยความจำ เรียก Close เพื่อรับไฟล์
+type Encoder struct {
+	buf   bytes.Buffer
+	gz    *gzip.Writer
+	enc   *json.Encoder
+	count int64
+}
+
+func NewEncoder() *Encoder {
+	e := &Encoder{}
+	e.gz = gzip.NewWriter(&e.buf)
+	e.enc = json.NewEncoder(e.gz)
+	return e
+}
+
+func (e *Encoder) Encode(v any) error {
+	if err := e.enc.Encode(v); err != nil {
+		return err
+	}
+	e.count++
+	return nil
+}
+
+// Count คือจำนวนรายการที่เขียนแล้ว
+func (e *Encoder) Count() int64 {
+	return e.count
+}
+
+func (e *Encoder) Close() ([]byte, error) {
+	if err := e.gz.Close(); err != nil {
+		return nil, err
+	}
+	return e.buf.Bytes(), nil
+}
+
+// Decode อ่านไฟล์ NDJSON ที่บีบอัดด้วย gzip และเรียก fn ทีละรายการ
+func Decode[T any](data []byte, fn func(T) error) error {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	dec := json.NewDecoder(gz)
+	for {
+		var item T
+		if err := dec.Decode(&item); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+}
+
+// Checksum คือ SHA-256 (hex) ของไฟล์ ใช้ตรวจว่าไฟล์ที่อ่านกลับมาตรงกับที่บันทึกใน manifest
+func Checksum(data []byte) string {
+	return sha256Hex(data)
+}
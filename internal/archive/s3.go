@@ -0,0 +1,188 @@
+package archive
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// StoreS3 เก็บไฟล์ archive ใน bucket ของ S3 หรือ storage ที่เข้ากันได้ เช่น MinIO
+const StoreS3 = "s3"
+
+// S3Options คือการเชื่อมต่อ S3 Endpoint เป็น URL เต็ม (เช่น http://minio:9000)
+// ใช้ path-style (endpoint/bucket/key) เพื่อให้ใช้กับ MinIO ได้โดยไม่ต้องตั้ง DNS
+type S3Options struct {
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	Timeout   time.Duration
+}
+
+type s3Store struct {
+	client *resty.Client
+	opts   S3Options
+}
+
+func NewS3Store(opts S3Options) Store {
+	opts.Endpoint = strings.TrimRight(opts.Endpoint, "/")
+	if opts.Region == "" {
+		opts.Region = "us-east-1"
+	}
+	return &s3Store{
+		client: resty.New().SetTimeout(opts.Timeout),
+		opts:   opts,
+	}
+}
+
+func (s *s3Store) Name() string {
+	return StoreS3
+}
+
+func (s *s3Store) Put(ctx context.Context, key string, data []byte) error {
+	req, target, err := s.request(ctx, http.MethodPut, key, data)
+	if err != nil {
+		return err
+	}
+
+	resp, err := req.SetHeader("Content-Type", "application/gzip").SetBody(data).Put(target)
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		return fmt.Errorf("s3 put %s: unexpected status %d: %s", key, resp.StatusCode(), resp.String())
+	}
+	return nil
+}
+
+func (s *s3Store) Get(ctx context.Context, key string) ([]byte, error) {
+	req, target, err := s.request(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := req.Get(target)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode() == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if resp.IsError() {
+		return nil, fmt.Errorf("s3 get %s: unexpected status %d: %s", key, resp.StatusCode(), resp.String())
+	}
+	return resp.Body(), nil
+}
+
+// request สร้าง request ที่ลงลายเซ็น AWS Signature V4 แล้ว พร้อม URL ของ object
+func (s *s3Store) request(ctx context.Context, method, key string, payload []byte) (*resty.Request, string, error) {
+	endpoint, err := url.Parse(s.opts.Endpoint)
+	if err != nil || endpoint.Host == "" {
+		return nil, "", fmt.Errorf("invalid s3 endpoint %q", s.opts.Endpoint)
+	}
+
+	path := "/" + uriEscape(s.opts.Bucket) + "/" + uriEscapePath(key)
+	payloadHash := sha256Hex(payload)
+	now := time.Now().UTC()
+	headers := map[string]string{
+		"host":                 endpoint.Host,
+		"x-amz-content-sha256": payloadHash,
+		"x-amz-date":           now.Format("20060102T150405Z"),
+	}
+	credentials := signingCredentials{
+		AccessKey: s.opts.AccessKey,
+		SecretKey: s.opts.SecretKey,
+		Region:    s.opts.Region,
+		Service:   "s3",
+	}
+
+	req := s.client.R().
+		SetContext(ctx).
+		SetHeader("x-amz-content-sha256", headers["x-amz-content-sha256"]).
+		SetHeader("x-amz-date", headers["x-amz-date"]).
+		SetHeader("Authorization", credentials.authorization(method, path, headers, payloadHash, now))
+	return req, endpoint.Scheme + "://" + endpoint.Host + path, nil
+}
+
+type signingCredentials struct {
+	AccessKey string
+	SecretKey string
+	Region    string
+	Service   string
+}
+
+// authorization คืนค่า header Authorization ตาม AWS Signature V4 โดยลงลายเซ็นทุก header ใน headers
+// path ต้อง escape แล้ว และ request ต้องไม่มี query string
+func (c signingCredentials) authorization(method, path string, headers map[string]string, payloadHash string, now time.Time) string {
+	values := make(map[string]string, len(headers))
+	names := make([]string, 0, len(headers))
+	for name, value := range headers {
+		name = strings.ToLower(name)
+		values[name] = strings.TrimSpace(value)
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + values[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{method, path, "", canonicalHeaders.String(), signedHeaders, payloadHash}, "\n")
+	date := now.Format("20060102")
+	scope := date + "/" + c.Region + "/" + c.Service + "/aws4_request"
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", now.Format("20060102T150405Z"), scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+c.SecretKey), date)
+	key = hmacSHA256(key, c.Region)
+	key = hmacSHA256(key, c.Service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	return fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", c.AccessKey, scope, signedHeaders, signature)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// uriEscapePath escape แต่ละส่วนของ key โดยคง "/" ไว้
+func uriEscapePath(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = uriEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// uriEscape escape ทุกตัวอักษรยกเว้น unreserved (A-Z a-z 0-9 - _ . ~) ตามที่ SigV4 กำหนด
+func uriEscape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		if ch >= 'A' && ch <= 'Z' || ch >= 'a' && ch <= 'z' || ch >= '0' && ch <= '9' || ch == '-' || ch == '_' || ch == '.' || ch == '~' {
+			b.WriteByte(ch)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", ch)
+	}
+	return b.String()
+}
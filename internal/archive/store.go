@@ -0,0 +1,17 @@
+package archive
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNotFound คืนจาก Store.Get เมื่อไม่มีไฟล์ตาม key
+var ErrNotFound = errors.New("archive object not found")
+
+// Store เก็บและอ่านไฟล์ archive ตาม key ที่คั่นด้วย "/" (เช่น statuses/2026/01/02.ndjson.gz)
+type Store interface {
+	// Name คือชื่อชนิดของ store ที่บันทึกลง manifest เช่น local หรือ s3
+	Name() string
+	Put(ctx context.Context, key string, data []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+}
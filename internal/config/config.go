@@ -3,14 +3,27 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // Config โครงสร้างการตั้งค่าของแอปพลิเคชัน
 type Config struct {
-	ServerAddress  string
-	PostgresConfig PostgresConfig
-	RedisConfig    RedisConfig
-	MQTTConfig     MQTTConfig
+	ServerAddress   string
+	PostgresConfig  PostgresConfig
+	RedisConfig     RedisConfig
+	MQTTConfig      MQTTConfig
+	StreamConfig    StreamConfig
+	WatchdogConfig  WatchdogConfig
+	RegistryConfig  RegistryConfig
+	QueueConfig     QueueConfig
+	NotifyConfig    NotifyConfig
+	WebhookConfig   WebhookConfig
+	AnalyticsConfig AnalyticsConfig
+	RollupConfig    RollupConfig
+	RetentionConfig RetentionConfig
+	PartitionConfig PartitionConfig
+	IngestConfig    IngestConfig
 }
 
 // PostgresConfig โครงสร้างการตั้งค่าสำหรับ PostgreSQL
@@ -35,10 +48,126 @@ type RedisConfig struct {
 }
 
 type MQTTConfig struct {
-	BrokerURL string
-	ClientID  string
-	Username  string
-	Password  string
+	BrokerURL  string
+	ClientID   string
+	Username   string
+	Password   string
+	AckTimeout time.Duration // เวลารอ ack ของคำสั่งที่ส่งไปยังเครื่อง
+}
+
+// StreamConfig โครงสร้างการตั้งค่าสำหรับ real-time status stream (SSE/WebSocket)
+// AllowedOrigins คือ origin ที่เปิด WebSocket ได้ ว่างคืออนุญาตเฉพาะ origin เดียวกับ host และ "*" คืออนุญาตทั้งหมด
+type StreamConfig struct {
+	HeartbeatInterval time.Duration
+	BufferSize        int
+	AllowedOrigins    []string
+}
+
+// WatchdogConfig โครงสร้างการตั้งค่าสำหรับการตรวจจับเครื่องที่ offline
+type WatchdogConfig struct {
+	OfflineAfter  time.Duration
+	CheckInterval time.Duration
+}
+
+// RegistryConfig โครงสร้างการตั้งค่าสำหรับการนำเข้าทะเบียนหอ/เครื่องจาก external API
+type RegistryConfig struct {
+	SyncEnabled    bool
+	ExternalAPIURL string
+	SyncInterval   time.Duration
+}
+
+// QueueConfig โครงสร้างการตั้งค่าสำหรับคิวเครื่องซักผ้า
+type QueueConfig struct {
+	ClaimWindow         time.Duration
+	ExpiryCheckInterval time.Duration
+}
+
+// NotifyConfig โครงสร้างการตั้งค่าสำหรับช่องทางแจ้งเตือน (webhook, LINE, SMTP) และการ retry
+type NotifyConfig struct {
+	MaxAttempts    int
+	RetryBaseDelay time.Duration
+	Timeout        time.Duration
+	Workers        int
+	QueueSize      int
+	DrainTimeout   time.Duration
+	LineAPIURL     string
+	SMTPHost       string
+	SMTPPort       string
+	SMTPUsername   string
+	SMTPPassword   string
+	SMTPFrom       string
+}
+
+// WebhookConfig โครงสร้างการตั้งค่าสำหรับการส่ง outbound webhook
+type WebhookConfig struct {
+	MaxAttempts    int
+	RetryBaseDelay time.Duration
+	Timeout        time.Duration
+	PollInterval   time.Duration
+	BatchSize      int
+}
+
+// AnalyticsConfig โครงสร้างการตั้งค่าสำหรับ usage analytics
+type AnalyticsConfig struct {
+	CacheTTL  time.Duration
+	Timezone  string
+	MaxWindow time.Duration
+}
+
+// RollupConfig โครงสร้างการตั้งค่าสำหรับ rollup job ของประวัติสถานะ
+// Threshold คืออายุของข้อมูลที่ analytics จะอ่านจาก rollup แทนข้อมูลดิบ
+type RollupConfig struct {
+	Interval   time.Duration
+	BatchHours int
+	Threshold  time.Duration
+}
+
+// RetentionConfig โครงสร้างการตั้งค่าสำหรับการเก็บรักษาและ archive ตาราง statuses
+// ไฟล์ archive เป็น NDJSON บีบอัดด้วย gzip เก็บบน local disk (ARCHIVE_STORE=local) หรือ S3/MinIO (ARCHIVE_STORE=s3)
+type RetentionConfig struct {
+	Enabled          bool
+	Days             int
+	Interval         time.Duration
+	BatchSize        int
+	MaxDaysPerRun    int
+	RestoreTTL       time.Duration
+	MaxRestoreWindow time.Duration
+	Store            string
+	ArchiveDir       string
+	S3Endpoint       string
+	S3Region         string
+	S3Bucket         string
+	S3AccessKey      string
+	S3SecretKey      string
+	S3Timeout        time.Duration
+}
+
+// PartitionConfig โครงสร้างการตั้งค่าสำหรับ partition รายเดือนของตาราง statuses
+type PartitionConfig struct {
+	MonthsAhead int
+	Interval    time.Duration
+}
+
+// IngestConfig โครงสร้างการตั้งค่าสำหรับคิวรับข้อความ MQTT และการบันทึกแบบ batch
+// SpoolConsumer ต้องคงที่ข้าม restart เพื่อให้ข้อความที่ค้างจากการรันครั้งก่อนถูกอ่านต่อทันที
+type IngestConfig struct {
+	Workers        int
+	QueueSize      int
+	BatchSize      int
+	FlushInterval  time.Duration
+	EnqueueTimeout time.Duration
+	MaxAttempts    int
+	DrainTimeout   time.Duration
+
+	SpoolEnabled       bool
+	SpoolStream        string
+	SpoolGroup         string
+	SpoolConsumer      string
+	SpoolClaimIdle     time.Duration
+	SpoolClaimInterval time.Duration
+	// SpoolMaxLen คือจำนวนข้อความที่ยังไม่บันทึกที่ spool รับได้ 0 คือไม่จำกัด
+	// เมื่อเต็ม MQTT callback จะรอที่ว่างไม่เกิน EnqueueTimeout แล้วทิ้งข้อความใหม่ ข้อความเก่าใน spool ไม่ถูกตัดทิ้ง
+	SpoolMaxLen int64
 }
 
 // LoadConfig โหลดการตั้งค่าจากตัวแปรสภาพแวดล้อม
@@ -65,17 +194,132 @@ func LoadConfig() *Config {
 	}
 
 	mqttConfig := MQTTConfig{
-		BrokerURL: getEnv("MQTT_BROKER", "tcp://localhost:1883"),
-		ClientID:  getEnv("MQTT_CLIENT_ID", "bms-client"),
-		Username:  getEnv("MQTT_USERNAME", ""),
-		Password:  getEnv("MQTT_PASSWORD", ""),
+		BrokerURL:  getEnv("MQTT_BROKER", "tcp://localhost:1883"),
+		ClientID:   getEnv("MQTT_CLIENT_ID", "bms-client"),
+		Username:   getEnv("MQTT_USERNAME", ""),
+		Password:   getEnv("MQTT_PASSWORD", ""),
+		AckTimeout: getEnvAsDuration("MQTT_COMMAND_ACK_TIMEOUT", 10*time.Second),
+	}
+
+	streamConfig := StreamConfig{
+		HeartbeatInterval: getEnvAsDuration("STREAM_HEARTBEAT_INTERVAL", 15*time.Second),
+		BufferSize:        getEnvAsInt("STREAM_BUFFER_SIZE", 32),
+		AllowedOrigins:    getEnvAsList("STREAM_ALLOWED_ORIGINS"),
+	}
+
+	watchdogConfig := WatchdogConfig{
+		OfflineAfter:  getEnvAsDuration("WATCHDOG_OFFLINE_AFTER", 5*time.Minute),
+		CheckInterval: getEnvAsDuration("WATCHDOG_CHECK_INTERVAL", 30*time.Second),
+	}
+
+	registryConfig := RegistryConfig{
+		SyncEnabled:    getEnvAsBool("REGISTRY_SYNC_ENABLED", true),
+		ExternalAPIURL: getEnv("EXTERNAL_API_URL", "https://washeasy.me"),
+		SyncInterval:   getEnvAsDuration("REGISTRY_SYNC_INTERVAL", time.Hour),
+	}
+
+	queueConfig := QueueConfig{
+		ClaimWindow:         getEnvAsDuration("QUEUE_CLAIM_WINDOW", 3*time.Minute),
+		ExpiryCheckInterval: getEnvAsDuration("QUEUE_EXPIRY_CHECK_INTERVAL", 15*time.Second),
+	}
+
+	notifyConfig := NotifyConfig{
+		MaxAttempts:    getEnvAsInt("NOTIFY_MAX_ATTEMPTS", 4),
+		RetryBaseDelay: getEnvAsDuration("NOTIFY_RETRY_BASE_DELAY", 2*time.Second),
+		Timeout:        getEnvAsDuration("NOTIFY_TIMEOUT", 10*time.Second),
+		Workers:        getEnvAsInt("NOTIFY_WORKERS", 4),
+		QueueSize:      getEnvAsInt("NOTIFY_QUEUE_SIZE", 1000),
+		DrainTimeout:   getEnvAsDuration("NOTIFY_DRAIN_TIMEOUT", 10*time.Second),
+		LineAPIURL:     getEnv("LINE_NOTIFY_URL", "https://notify-api.line.me/api/notify"),
+		SMTPHost:       getEnv("SMTP_HOST", ""),
+		SMTPPort:       getEnv("SMTP_PORT", "587"),
+		SMTPUsername:   getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:   getEnv("SMTP_PASSWORD", ""),
+		SMTPFrom:       getEnv("SMTP_FROM", "bms@localhost"),
+	}
+
+	webhookConfig := WebhookConfig{
+		MaxAttempts:    getEnvAsInt("WEBHOOK_MAX_ATTEMPTS", 8),
+		RetryBaseDelay: getEnvAsDuration("WEBHOOK_RETRY_BASE_DELAY", 30*time.Second),
+		Timeout:        getEnvAsDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+		PollInterval:   getEnvAsDuration("WEBHOOK_POLL_INTERVAL", 5*time.Second),
+		BatchSize:      getEnvAsInt("WEBHOOK_BATCH_SIZE", 50),
+	}
+
+	analyticsConfig := AnalyticsConfig{
+		CacheTTL:  getEnvAsDuration("ANALYTICS_CACHE_TTL", 5*time.Minute),
+		Timezone:  getEnv("ANALYTICS_TIMEZONE", "Asia/Bangkok"),
+		MaxWindow: getEnvAsDuration("ANALYTICS_MAX_WINDOW", 92*24*time.Hour),
+	}
+
+	rollupConfig := RollupConfig{
+		Interval:   getEnvAsDuration("ROLLUP_INTERVAL", 5*time.Minute),
+		BatchHours: getEnvAsInt("ROLLUP_BATCH_HOURS", 48),
+		Threshold:  getEnvAsDuration("ROLLUP_THRESHOLD", 24*time.Hour),
+	}
+
+	// retention ลบข้อมูลจริง จึงปิดไว้จนกว่าจะตั้ง RETENTION_ENABLED=true
+	retentionConfig := RetentionConfig{
+		Enabled:          getEnvAsBool("RETENTION_ENABLED", false),
+		Days:             getEnvAsInt("RETENTION_DAYS", 90),
+		Interval:         getEnvAsDuration("RETENTION_INTERVAL", time.Hour),
+		BatchSize:        getEnvAsInt("RETENTION_BATCH_SIZE", 5000),
+		MaxDaysPerRun:    getEnvAsInt("RETENTION_MAX_DAYS_PER_RUN", 7),
+		RestoreTTL:       getEnvAsDuration("RETENTION_RESTORE_TTL", 7*24*time.Hour),
+		MaxRestoreWindow: getEnvAsDuration("RETENTION_MAX_RESTORE_WINDOW", 31*24*time.Hour),
+		Store:            getEnv("ARCHIVE_STORE", "local"),
+		ArchiveDir:       getEnv("ARCHIVE_DIR", "./archive"),
+		S3Endpoint:       getEnv("ARCHIVE_S3_ENDPOINT", "http://localhost:9000"),
+		S3Region:         getEnv("ARCHIVE_S3_REGION", "us-east-1"),
+		S3Bucket:         getEnv("ARCHIVE_S3_BUCKET", "bms-archive"),
+		S3AccessKey:      getEnv("ARCHIVE_S3_ACCESS_KEY", ""),
+		S3SecretKey:      getEnv("ARCHIVE_S3_SECRET_KEY", ""),
+		S3Timeout:        getEnvAsDuration("ARCHIVE_S3_TIMEOUT", time.Minute),
+	}
+
+	partitionConfig := PartitionConfig{
+		MonthsAhead: getEnvAsInt("PARTITION_MONTHS_AHEAD", 3),
+		Interval:    getEnvAsDuration("PARTITION_INTERVAL", 6*time.Hour),
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "bms-service"
+	}
+	ingestConfig := IngestConfig{
+		Workers:        getEnvAsInt("INGEST_WORKERS", 4),
+		QueueSize:      getEnvAsInt("INGEST_QUEUE_SIZE", 10000),
+		BatchSize:      getEnvAsInt("INGEST_BATCH_SIZE", 200),
+		FlushInterval:  getEnvAsDuration("INGEST_FLUSH_INTERVAL", 200*time.Millisecond),
+		EnqueueTimeout: getEnvAsDuration("INGEST_ENQUEUE_TIMEOUT", 2*time.Second),
+		MaxAttempts:    getEnvAsInt("INGEST_MAX_ATTEMPTS", 5),
+		DrainTimeout:   getEnvAsDuration("INGEST_DRAIN_TIMEOUT", 15*time.Second),
+
+		SpoolEnabled:       getEnvAsBool("INGEST_SPOOL_ENABLED", true),
+		SpoolStream:        getEnv("INGEST_SPOOL_STREAM", "ingest:spool"),
+		SpoolGroup:         getEnv("INGEST_SPOOL_GROUP", "bms-ingest"),
+		SpoolConsumer:      getEnv("INGEST_SPOOL_CONSUMER", hostname),
+		SpoolClaimIdle:     getEnvAsDuration("INGEST_SPOOL_CLAIM_IDLE", 5*time.Minute),
+		SpoolClaimInterval: getEnvAsDuration("INGEST_SPOOL_CLAIM_INTERVAL", time.Minute),
+		SpoolMaxLen:        int64(getEnvAsInt("INGEST_SPOOL_MAX_LEN", 0)),
 	}
 
 	return &Config{
-		ServerAddress:  getEnv("SERVER_ADDRESS", ":8080"),
-		PostgresConfig: postgresConfig,
-		RedisConfig:    redisConfig,
-		MQTTConfig:     mqttConfig,
+		ServerAddress:   getEnv("SERVER_ADDRESS", ":8080"),
+		PostgresConfig:  postgresConfig,
+		RedisConfig:     redisConfig,
+		MQTTConfig:      mqttConfig,
+		StreamConfig:    streamConfig,
+		WatchdogConfig:  watchdogConfig,
+		RegistryConfig:  registryConfig,
+		QueueConfig:     queueConfig,
+		NotifyConfig:    notifyConfig,
+		WebhookConfig:   webhookConfig,
+		AnalyticsConfig: analyticsConfig,
+		RollupConfig:    rollupConfig,
+		RetentionConfig: retentionConfig,
+		PartitionConfig: partitionConfig,
+		IngestConfig:    ingestConfig,
 	}
 }
 
@@ -97,6 +341,24 @@ func getEnvAsInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+// getEnvAsBool แปลงค่าจากตัวแปรสภาพแวดล้อมเป็น bool หากไม่พบจะใช้ค่าเริ่มต้น
+func getEnvAsBool(key string, defaultValue bool) bool {
+	valueStr := getEnv(key, "")
+	if value, err := strconv.ParseBool(valueStr); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+// getEnvAsDuration แปลงค่าจากตัวแปรสภาพแวดล้อมเป็น time.Duration (เช่น "15s", "5m") หากไม่พบจะใช้ค่าเริ่มต้น
+func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
+	valueStr := getEnv(key, "")
+	if value, err := time.ParseDuration(valueStr); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
 func (p PostgresConfig) BuildDSN() string {
 	return "host=" + p.Host +
 		" user=" + p.User +
@@ -105,3 +367,14 @@ func (p PostgresConfig) BuildDSN() string {
 		" port=" + p.Port +
 		" sslmode=disable"
 }
+
+// getEnvAsList ดึงค่าที่คั่นด้วยจุลภาค ตัดช่องว่างและค่าว่างออก
+func getEnvAsList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
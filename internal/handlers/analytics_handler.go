@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v3"
+	"github.com/jaytnw/bms-service/internal/analytics"
+	"github.com/jaytnw/bms-service/internal/utils"
+)
+
+type AnalyticsHandler struct {
+	service analytics.Service
+}
+
+func NewAnalyticsHandler(service analytics.Service) *AnalyticsHandler {
+	return &AnalyticsHandler{service: service}
+}
+
+// GetUtilisation รองรับ ?dorm= ?from= ?to= (RFC3339) ค่าเริ่มต้นคือ 7 วันล่าสุด
+func (h *AnalyticsHandler) GetUtilisation(c fiber.Ctx) error {
+	window, err := parseAnalyticsWindow(c)
+	if err != nil {
+		return respondError(c, err, "Invalid query", "INVALID_QUERY")
+	}
+
+	usage, err := h.service.DormUsage(c.Context(), c.Query("dorm"), window)
+	if err != nil {
+		return respondError(c, err, "Failed to get utilisation", "ANALYTICS_ERROR")
+	}
+	return utils.JSON(c, fiber.StatusOK, usage)
+}
+
+func (h *AnalyticsHandler) GetWasherUtilisation(c fiber.Ctx) error {
+	window, err := parseAnalyticsWindow(c)
+	if err != nil {
+		return respondError(c, err, "Invalid query", "INVALID_QUERY")
+	}
+
+	usage, err := h.service.WasherUsage(c.Context(), c.Params("washerID"), window)
+	if err != nil {
+		return respondError(c, err, "Failed to get utilisation", "ANALYTICS_ERROR")
+	}
+	return utils.JSON(c, fiber.StatusOK, usage)
+}
+
+func (h *AnalyticsHandler) GetHeatmap(c fiber.Ctx) error {
+	window, err := parseAnalyticsWindow(c)
+	if err != nil {
+		return respondError(c, err, "Invalid query", "INVALID_QUERY")
+	}
+
+	heatmap, err := h.service.Heatmap(c.Context(), c.Params("dormID"), window)
+	if err != nil {
+		return respondError(c, err, "Failed to get heatmap", "ANALYTICS_ERROR")
+	}
+	return utils.JSON(c, fiber.StatusOK, heatmap)
+}
+
+// GetBusiestPeriods รองรับ ?limit= (จำนวนชั่วโมง) เพิ่มจาก ?from= ?to=
+func (h *AnalyticsHandler) GetBusiestPeriods(c fiber.Ctx) error {
+	window, err := parseAnalyticsWindow(c)
+	if err != nil {
+		return respondError(c, err, "Invalid query", "INVALID_QUERY")
+	}
+	limit, err := parseLimitQuery(c, 10, 100)
+	if err != nil {
+		return respondError(c, err, "Invalid query", "INVALID_QUERY")
+	}
+
+	peaks, err := h.service.BusiestPeriods(c.Context(), c.Params("dormID"), window, limit)
+	if err != nil {
+		return respondError(c, err, "Failed to get busiest periods", "ANALYTICS_ERROR")
+	}
+	return utils.JSON(c, fiber.StatusOK, peaks)
+}
+
+func parseAnalyticsWindow(c fiber.Ctx) (analytics.Window, error) {
+	var window analytics.Window
+
+	from, err := parseTimeQuery(c, "from")
+	if err != nil {
+		return window, err
+	}
+	to, err := parseTimeQuery(c, "to")
+	if err != nil {
+		return window, err
+	}
+	if from != nil {
+		window.From = *from
+	}
+	if to != nil {
+		window.To = *to
+	}
+	return window, nil
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/jaytnw/bms-service/internal/repository"
+	"github.com/jaytnw/bms-service/internal/services"
+	"github.com/jaytnw/bms-service/internal/utils"
+)
+
+type ArchiveHandler struct {
+	service services.RetentionService
+}
+
+func NewArchiveHandler(service services.RetentionService) *ArchiveHandler {
+	return &ArchiveHandler{service: service}
+}
+
+// ListArchives รองรับ ?from= ?to= (RFC3339) และ ?limit=
+func (h *ArchiveHandler) ListArchives(c fiber.Ctx) error {
+	from, err := parseTimeQuery(c, "from")
+	if err != nil {
+		return respondError(c, err, "Invalid query", "INVALID_QUERY")
+	}
+	to, err := parseTimeQuery(c, "to")
+	if err != nil {
+		return respondError(c, err, "Invalid query", "INVALID_QUERY")
+	}
+	limit, err := parseLimitQuery(c, 100, 1000)
+	if err != nil {
+		return respondError(c, err, "Invalid query", "INVALID_QUERY")
+	}
+
+	archives, err := h.service.ListArchives(c.Context(), repository.ArchiveQuery{From: from, To: to, Limit: limit})
+	if err != nil {
+		return respondError(c, err, "Failed to get archives", "ARCHIVE_ERROR")
+	}
+	return utils.JSON(c, fiber.StatusOK, archives)
+}
+
+// RestoreArchives นำสถานะในช่วง {from, to} กลับจาก archive เพื่อใช้ตรวจสอบย้อนหลัง
+func (h *ArchiveHandler) RestoreArchives(c fiber.Ctx) error {
+	var body struct {
+		From time.Time `json:"from"`
+		To   time.Time `json:"to"`
+	}
+	if err := c.Bind().Body(&body); err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, "Invalid request body", "INVALID_BODY")
+	}
+
+	result, err := h.service.Restore(c.Context(), body.From, body.To)
+	if err != nil {
+		return respondError(c, err, "Failed to restore archives", "ARCHIVE_ERROR")
+	}
+	return utils.JSON(c, fiber.StatusOK, result)
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v3"
+	"github.com/jaytnw/bms-service/internal/services"
+	"github.com/jaytnw/bms-service/internal/utils"
+)
+
+type AvailabilityHandler struct {
+	service services.AvailabilityService
+}
+
+func NewAvailabilityHandler(service services.AvailabilityService) *AvailabilityHandler {
+	return &AvailabilityHandler{service: service}
+}
+
+func (h *AvailabilityHandler) GetDormAvailability(c fiber.Ctx) error {
+	availability, err := h.service.GetDormAvailability(c.Context(), c.Params("dormID"))
+	if err != nil {
+		return respondError(c, err, "Failed to get availability", "AVAILABILITY_ERROR")
+	}
+	return utils.JSON(c, fiber.StatusOK, availability)
+}
+
+func (h *AvailabilityHandler) GetAllAvailability(c fiber.Ctx) error {
+	availability, err := h.service.GetAllAvailability(c.Context())
+	if err != nil {
+		return respondError(c, err, "Failed to get availability", "AVAILABILITY_ERROR")
+	}
+	return utils.JSON(c, fiber.StatusOK, availability)
+}
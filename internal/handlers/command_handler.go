@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v3"
+	"github.com/jaytnw/bms-service/internal/models"
+	"github.com/jaytnw/bms-service/internal/services"
+	"github.com/jaytnw/bms-service/internal/utils"
+)
+
+type CommandHandler struct {
+	service services.CommandService
+}
+
+func NewCommandHandler(service services.CommandService) *CommandHandler {
+	return &CommandHandler{service: service}
+}
+
+// SendCommand ตอบ 200 เมื่อได้ ack (ทั้งสำเร็จและถูกปฏิเสธ) และ 202 เมื่อส่งแล้วแต่ยังไม่ได้ ack ภายในเวลา
+func (h *CommandHandler) SendCommand(c fiber.Ctx) error {
+	var req services.SendCommandRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, "Invalid request body", "INVALID_BODY")
+	}
+
+	command, err := h.service.Send(c.Context(), c.Params("washerID"), req)
+	if err != nil {
+		return respondError(c, err, "Failed to send command", "COMMAND_ERROR")
+	}
+
+	if command.Status == models.CommandTimeout {
+		return utils.JSON(c, fiber.StatusAccepted, command)
+	}
+	return utils.JSON(c, fiber.StatusOK, command)
+}
+
+func (h *CommandHandler) GetHistory(c fiber.Ctx) error {
+	limit, err := parseLimitQuery(c, 50, 500)
+	if err != nil {
+		return respondError(c, err, "Invalid limit", "INVALID_LIMIT")
+	}
+
+	commands, err := h.service.GetHistory(c.Context(), c.Params("washerID"), limit)
+	if err != nil {
+		return respondError(c, err, "Failed to get command history", "COMMAND_ERROR")
+	}
+	return utils.JSON(c, fiber.StatusOK, commands)
+}
+
+func (h *CommandHandler) GetCommand(c fiber.Ctx) error {
+	command, err := h.service.GetCommand(c.Context(), c.Params("commandID"))
+	if err != nil {
+		return respondError(c, err, "Failed to get command", "COMMAND_ERROR")
+	}
+	return utils.JSON(c, fiber.StatusOK, command)
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/jaytnw/bms-service/internal/apperr"
+	"github.com/jaytnw/bms-service/internal/utils"
+)
+
+// parseTimeQuery อ่าน query parameter ที่เป็นเวลาแบบ RFC3339 คืน nil หากไม่ได้ส่งมา
+func parseTimeQuery(c fiber.Ctx, key string) (*time.Time, error) {
+	raw := c.Query(key)
+	if raw == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return nil, apperr.New("INVALID_QUERY", key+" must be an RFC3339 timestamp", fiber.StatusBadRequest, err)
+	}
+	return &t, nil
+}
+
+// parseLimitQuery อ่าน ?limit= พร้อมค่าเริ่มต้นและค่าสูงสุด
+func parseLimitQuery(c fiber.Ctx, defaultLimit, maxLimit int) (int, error) {
+	limit := fiber.Query[int](c, "limit", defaultLimit)
+	if limit <= 0 || limit > maxLimit {
+		return 0, apperr.New("INVALID_LIMIT", "limit is out of range", fiber.StatusBadRequest, nil)
+	}
+	return limit, nil
+}
+
+// respondError แปลง AppError เป็น response และใช้ข้อความ fallback สำหรับ error อื่น
+func respondError(c fiber.Ctx, err error, fallbackMsg, fallbackCode string) error {
+	if ae, ok := err.(*apperr.AppError); ok {
+		return utils.Error(c, ae.Status, ae.Message, ae.Code)
+	}
+	return utils.Error(c, fiber.StatusInternalServerError, fallbackMsg, fallbackCode)
+}
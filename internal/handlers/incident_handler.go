@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v3"
+	"github.com/jaytnw/bms-service/internal/models"
+	"github.com/jaytnw/bms-service/internal/repository"
+	"github.com/jaytnw/bms-service/internal/services"
+	"github.com/jaytnw/bms-service/internal/utils"
+)
+
+type IncidentHandler struct {
+	service services.IncidentService
+}
+
+func NewIncidentHandler(service services.IncidentService) *IncidentHandler {
+	return &IncidentHandler{service: service}
+}
+
+// ListIncidents รองรับ ?dorm= ?washer= ?status= ?from= ?to= และ ?limit=
+func (h *IncidentHandler) ListIncidents(c fiber.Ctx) error {
+	from, err := parseTimeQuery(c, "from")
+	if err != nil {
+		return respondError(c, err, "Invalid query", "INVALID_QUERY")
+	}
+	to, err := parseTimeQuery(c, "to")
+	if err != nil {
+		return respondError(c, err, "Invalid query", "INVALID_QUERY")
+	}
+	limit, err := parseLimitQuery(c, 100, 1000)
+	if err != nil {
+		return respondError(c, err, "Invalid query", "INVALID_QUERY")
+	}
+
+	incidents, err := h.service.ListIncidents(c.Context(), repository.IncidentFilter{
+		DormID:   c.Query("dorm"),
+		WasherID: c.Query("washer"),
+		Status:   models.IncidentStatus(c.Query("status")),
+		From:     from,
+		To:       to,
+		Limit:    limit,
+	})
+	if err != nil {
+		return respondError(c, err, "Failed to get incidents", "INCIDENT_ERROR")
+	}
+	return utils.JSON(c, fiber.StatusOK, incidents)
+}
+
+func (h *IncidentHandler) GetIncident(c fiber.Ctx) error {
+	id, err := parseUintParam(c, "incidentID")
+	if err != nil {
+		return respondError(c, err, "Invalid incident id", "INVALID_PARAM")
+	}
+
+	incident, err := h.service.GetIncident(c.Context(), id)
+	if err != nil {
+		return respondError(c, err, "Failed to get incident", "INCIDENT_ERROR")
+	}
+	return utils.JSON(c, fiber.StatusOK, incident)
+}
+
+func (h *IncidentHandler) Acknowledge(c fiber.Ctx) error {
+	id, err := parseUintParam(c, "incidentID")
+	if err != nil {
+		return respondError(c, err, "Invalid incident id", "INVALID_PARAM")
+	}
+
+	var body struct {
+		AcknowledgedBy string `json:"acknowledgedBy"`
+	}
+	if err := c.Bind().Body(&body); err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, "Invalid request body", "INVALID_BODY")
+	}
+
+	incident, err := h.service.Acknowledge(c.Context(), id, body.AcknowledgedBy)
+	if err != nil {
+		return respondError(c, err, "Failed to acknowledge incident", "INCIDENT_ERROR")
+	}
+	return utils.JSON(c, fiber.StatusOK, incident)
+}
+
+func (h *IncidentHandler) AddComment(c fiber.Ctx) error {
+	id, err := parseUintParam(c, "incidentID")
+	if err != nil {
+		return respondError(c, err, "Invalid incident id", "INVALID_PARAM")
+	}
+
+	var comment models.IncidentComment
+	if err := c.Bind().Body(&comment); err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, "Invalid request body", "INVALID_BODY")
+	}
+
+	if err := h.service.AddComment(c.Context(), id, &comment); err != nil {
+		return respondError(c, err, "Failed to add comment", "INCIDENT_ERROR")
+	}
+	return utils.JSON(c, fiber.StatusCreated, comment)
+}
+
+// GetMTTR รองรับ ?from= และ ?to= เพื่อจำกัดช่วงเวลาที่ incident ถูกปิด
+func (h *IncidentHandler) GetMTTR(c fiber.Ctx) error {
+	from, err := parseTimeQuery(c, "from")
+	if err != nil {
+		return respondError(c, err, "Invalid query", "INVALID_QUERY")
+	}
+	to, err := parseTimeQuery(c, "to")
+	if err != nil {
+		return respondError(c, err, "Invalid query", "INVALID_QUERY")
+	}
+
+	rows, err := h.service.GetMTTR(c.Context(), from, to)
+	if err != nil {
+		return respondError(c, err, "Failed to compute MTTR", "INCIDENT_ERROR")
+	}
+	return utils.JSON(c, fiber.StatusOK, rows)
+}
+
+func (h *IncidentHandler) ListFaultCodes(c fiber.Ctx) error {
+	codes, err := h.service.ListFaultCodes(c.Context())
+	if err != nil {
+		return respondError(c, err, "Failed to get fault codes", "FAULT_CODE_ERROR")
+	}
+	return utils.JSON(c, fiber.StatusOK, codes)
+}
+
+func (h *IncidentHandler) GetFaultCode(c fiber.Ctx) error {
+	code, err := h.service.GetFaultCode(c.Context(), c.Params("code"))
+	if err != nil {
+		return respondError(c, err, "Failed to get fault code", "FAULT_CODE_ERROR")
+	}
+	return utils.JSON(c, fiber.StatusOK, code)
+}
+
+func (h *IncidentHandler) PutFaultCode(c fiber.Ctx) error {
+	var input models.FaultCode
+	if err := c.Bind().Body(&input); err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, "Invalid request body", "INVALID_BODY")
+	}
+
+	code, err := h.service.PutFaultCode(c.Context(), c.Params("code"), &input)
+	if err != nil {
+		return respondError(c, err, "Failed to save fault code", "FAULT_CODE_ERROR")
+	}
+	return utils.JSON(c, fiber.StatusOK, code)
+}
+
+func (h *IncidentHandler) DeleteFaultCode(c fiber.Ctx) error {
+	if err := h.service.DeleteFaultCode(c.Context(), c.Params("code")); err != nil {
+		return respondError(c, err, "Failed to delete fault code", "FAULT_CODE_ERROR")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v3"
+	"github.com/jaytnw/bms-service/internal/services"
+	"github.com/jaytnw/bms-service/internal/utils"
+)
+
+type IngestHandler struct {
+	pipeline services.IngestPipeline
+}
+
+func NewIngestHandler(pipeline services.IngestPipeline) *IngestHandler {
+	return &IngestHandler{pipeline: pipeline}
+}
+
+// GetStats คืนความลึกของคิวและตัวนับของ ingest pipeline
+func (h *IngestHandler) GetStats(c fiber.Ctx) error {
+	return utils.JSON(c, fiber.StatusOK, h.pipeline.Stats())
+}
+
+// GetSpool คืนจำนวนข้อความที่ยังไม่ถูกบันทึกใน spool และอายุของข้อความที่เก่าที่สุด
+func (h *IngestHandler) GetSpool(c fiber.Ctx) error {
+	stats, err := h.pipeline.SpoolStats(c.Context())
+	if err != nil {
+		return respondError(c, err, "Failed to get spool stats", "SPOOL_ERROR")
+	}
+	return utils.JSON(c, fiber.StatusOK, stats)
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/jaytnw/bms-service/internal/models"
+	"github.com/jaytnw/bms-service/internal/repository"
+	"github.com/jaytnw/bms-service/internal/services"
+	"github.com/jaytnw/bms-service/internal/utils"
+)
+
+type MaintenanceHandler struct {
+	service services.MaintenanceService
+}
+
+func NewMaintenanceHandler(service services.MaintenanceService) *MaintenanceHandler {
+	return &MaintenanceHandler{service: service}
+}
+
+// ListMaintenance รองรับ ?dorm= ?washer= ?active=true และ ?limit=
+func (h *MaintenanceHandler) ListMaintenance(c fiber.Ctx) error {
+	limit, err := parseLimitQuery(c, 100, 1000)
+	if err != nil {
+		return respondError(c, err, "Invalid query", "INVALID_QUERY")
+	}
+
+	filter := repository.MaintenanceFilter{
+		DormID:   c.Query("dorm"),
+		WasherID: c.Query("washer"),
+		Limit:    limit,
+	}
+	if c.Query("active") == "true" {
+		now := time.Now()
+		filter.ActiveAt = &now
+	}
+
+	windows, err := h.service.List(c.Context(), filter)
+	if err != nil {
+		return respondError(c, err, "Failed to get maintenance windows", "MAINTENANCE_ERROR")
+	}
+	return utils.JSON(c, fiber.StatusOK, windows)
+}
+
+func (h *MaintenanceHandler) StartMaintenance(c fiber.Ctx) error {
+	var window models.MaintenanceWindow
+	if err := c.Bind().Body(&window); err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, "Invalid request body", "INVALID_BODY")
+	}
+
+	if err := h.service.Start(c.Context(), &window); err != nil {
+		return respondError(c, err, "Failed to start maintenance", "MAINTENANCE_ERROR")
+	}
+	return utils.JSON(c, fiber.StatusCreated, window)
+}
+
+func (h *MaintenanceHandler) EndMaintenance(c fiber.Ctx) error {
+	id, err := parseUintParam(c, "maintenanceID")
+	if err != nil {
+		return respondError(c, err, "Invalid maintenance id", "INVALID_PARAM")
+	}
+
+	// body เป็น optional: ปิดได้โดยไม่ต้องระบุผู้ปิด
+	var body struct {
+		EndedBy string `json:"endedBy"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.Bind().Body(&body); err != nil {
+			return utils.Error(c, fiber.StatusBadRequest, "Invalid request body", "INVALID_BODY")
+		}
+	}
+
+	window, err := h.service.End(c.Context(), id, body.EndedBy)
+	if err != nil {
+		return respondError(c, err, "Failed to end maintenance", "MAINTENANCE_ERROR")
+	}
+	return utils.JSON(c, fiber.StatusOK, window)
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/jaytnw/bms-service/internal/apperr"
+	"github.com/jaytnw/bms-service/internal/services"
+	"github.com/jaytnw/bms-service/internal/utils"
+)
+
+type QueueHandler struct {
+	service services.QueueService
+}
+
+func NewQueueHandler(service services.QueueService) *QueueHandler {
+	return &QueueHandler{service: service}
+}
+
+func (h *QueueHandler) Join(c fiber.Ctx) error {
+	var req services.JoinQueueRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, "Invalid request body", "INVALID_BODY")
+	}
+
+	entry, err := h.service.Join(c.Context(), c.Params("dormID"), req)
+	if err != nil {
+		return respondError(c, err, "Failed to join queue", "QUEUE_ERROR")
+	}
+	return utils.JSON(c, fiber.StatusCreated, entry)
+}
+
+func (h *QueueHandler) GetQueue(c fiber.Ctx) error {
+	entries, err := h.service.GetQueue(c.Context(), c.Params("dormID"))
+	if err != nil {
+		return respondError(c, err, "Failed to get queue", "QUEUE_ERROR")
+	}
+	return utils.JSON(c, fiber.StatusOK, entries)
+}
+
+func (h *QueueHandler) GetEntry(c fiber.Ctx) error {
+	entryID, err := parseEntryID(c)
+	if err != nil {
+		return respondError(c, err, "Invalid queue entry", "INVALID_ENTRY_ID")
+	}
+
+	entry, err := h.service.GetEntry(c.Context(), c.Params("dormID"), entryID)
+	if err != nil {
+		return respondError(c, err, "Failed to get queue entry", "QUEUE_ERROR")
+	}
+	return utils.JSON(c, fiber.StatusOK, entry)
+}
+
+func (h *QueueHandler) Claim(c fiber.Ctx) error {
+	entryID, err := parseEntryID(c)
+	if err != nil {
+		return respondError(c, err, "Invalid queue entry", "INVALID_ENTRY_ID")
+	}
+
+	entry, err := h.service.Claim(c.Context(), c.Params("dormID"), entryID)
+	if err != nil {
+		return respondError(c, err, "Failed to claim washer", "QUEUE_ERROR")
+	}
+	return utils.JSON(c, fiber.StatusOK, entry)
+}
+
+func (h *QueueHandler) Leave(c fiber.Ctx) error {
+	entryID, err := parseEntryID(c)
+	if err != nil {
+		return respondError(c, err, "Invalid queue entry", "INVALID_ENTRY_ID")
+	}
+
+	if err := h.service.Leave(c.Context(), c.Params("dormID"), entryID); err != nil {
+		return respondError(c, err, "Failed to leave queue", "QUEUE_ERROR")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func parseEntryID(c fiber.Ctx) (uint, error) {
+	id, err := strconv.ParseUint(c.Params("entryID"), 10, 64)
+	if err != nil {
+		return 0, apperr.New("INVALID_ENTRY_ID", "entryID must be a positive integer", fiber.StatusBadRequest, err)
+	}
+	return uint(id), nil
+}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v3"
+	"github.com/jaytnw/bms-service/internal/models"
+	"github.com/jaytnw/bms-service/internal/services"
+	"github.com/jaytnw/bms-service/internal/utils"
+)
+
+type RegistryHandler struct {
+	service services.RegistryService
+}
+
+func NewRegistryHandler(service services.RegistryService) *RegistryHandler {
+	return &RegistryHandler{service: service}
+}
+
+func (h *RegistryHandler) ListDorms(c fiber.Ctx) error {
+	dorms, err := h.service.ListDorms(c.Context())
+	if err != nil {
+		return respondError(c, err, "Failed to get dorms", "REGISTRY_ERROR")
+	}
+	return utils.JSON(c, fiber.StatusOK, dorms)
+}
+
+func (h *RegistryHandler) GetDorm(c fiber.Ctx) error {
+	dorm, err := h.service.GetDorm(c.Context(), c.Params("dormID"))
+	if err != nil {
+		return respondError(c, err, "Failed to get dorm", "REGISTRY_ERROR")
+	}
+	return utils.JSON(c, fiber.StatusOK, dorm)
+}
+
+func (h *RegistryHandler) CreateDorm(c fiber.Ctx) error {
+	var dorm models.Dorm
+	if err := c.Bind().Body(&dorm); err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, "Invalid request body", "INVALID_BODY")
+	}
+
+	if err := h.service.CreateDorm(c.Context(), &dorm); err != nil {
+		return respondError(c, err, "Failed to create dorm", "REGISTRY_ERROR")
+	}
+	return utils.JSON(c, fiber.StatusCreated, dorm)
+}
+
+func (h *RegistryHandler) UpdateDorm(c fiber.Ctx) error {
+	var input models.Dorm
+	if err := c.Bind().Body(&input); err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, "Invalid request body", "INVALID_BODY")
+	}
+
+	dorm, err := h.service.UpdateDorm(c.Context(), c.Params("dormID"), &input)
+	if err != nil {
+		return respondError(c, err, "Failed to update dorm", "REGISTRY_ERROR")
+	}
+	return utils.JSON(c, fiber.StatusOK, dorm)
+}
+
+func (h *RegistryHandler) DeleteDorm(c fiber.Ctx) error {
+	if err := h.service.DeleteDorm(c.Context(), c.Params("dormID")); err != nil {
+		return respondError(c, err, "Failed to delete dorm", "REGISTRY_ERROR")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (h *RegistryHandler) ListMachines(c fiber.Ctx) error {
+	machines, err := h.service.ListMachines(c.Context(), c.Query("dorm"))
+	if err != nil {
+		return respondError(c, err, "Failed to get machines", "REGISTRY_ERROR")
+	}
+	return utils.JSON(c, fiber.StatusOK, machines)
+}
+
+func (h *RegistryHandler) GetMachine(c fiber.Ctx) error {
+	machine, err := h.service.GetMachine(c.Context(), c.Params("machineID"))
+	if err != nil {
+		return respondError(c, err, "Failed to get machine", "REGISTRY_ERROR")
+	}
+	return utils.JSON(c, fiber.StatusOK, machine)
+}
+
+func (h *RegistryHandler) CreateMachine(c fiber.Ctx) error {
+	var machine models.Machine
+	if err := c.Bind().Body(&machine); err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, "Invalid request body", "INVALID_BODY")
+	}
+
+	if err := h.service.CreateMachine(c.Context(), &machine); err != nil {
+		return respondError(c, err, "Failed to create machine", "REGISTRY_ERROR")
+	}
+	return utils.JSON(c, fiber.StatusCreated, machine)
+}
+
+func (h *RegistryHandler) UpdateMachine(c fiber.Ctx) error {
+	var input models.Machine
+	if err := c.Bind().Body(&input); err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, "Invalid request body", "INVALID_BODY")
+	}
+
+	machine, err := h.service.UpdateMachine(c.Context(), c.Params("machineID"), &input)
+	if err != nil {
+		return respondError(c, err, "Failed to update machine", "REGISTRY_ERROR")
+	}
+	return utils.JSON(c, fiber.StatusOK, machine)
+}
+
+func (h *RegistryHandler) DeleteMachine(c fiber.Ctx) error {
+	if err := h.service.DeleteMachine(c.Context(), c.Params("machineID")); err != nil {
+		return respondError(c, err, "Failed to delete machine", "REGISTRY_ERROR")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (h *RegistryHandler) Sync(c fiber.Ctx) error {
+	result, err := h.service.SyncFromExternal(c.Context())
+	if err != nil {
+		return respondError(c, err, "Failed to sync registry", "REGISTRY_SYNC_ERROR")
+	}
+	return utils.JSON(c, fiber.StatusOK, result)
+}
+
+func (h *RegistryHandler) ListPendingDorms(c fiber.Ctx) error {
+	dorms, err := h.service.ListPendingDorms(c.Context())
+	if err != nil {
+		return respondError(c, err, "Failed to get pending dorms", "REGISTRY_ERROR")
+	}
+	return utils.JSON(c, fiber.StatusOK, dorms)
+}
+
+func (h *RegistryHandler) ListPendingMachines(c fiber.Ctx) error {
+	machines, err := h.service.ListPendingMachines(c.Context())
+	if err != nil {
+		return respondError(c, err, "Failed to get pending machines", "REGISTRY_ERROR")
+	}
+	return utils.JSON(c, fiber.StatusOK, machines)
+}
+
+func (h *RegistryHandler) ApproveDorm(c fiber.Ctx) error {
+	// body เป็น optional: อนุมัติได้โดยไม่ต้องเปลี่ยนชื่อ
+	var input models.Dorm
+	if len(c.Body()) > 0 {
+		if err := c.Bind().Body(&input); err != nil {
+			return utils.Error(c, fiber.StatusBadRequest, "Invalid request body", "INVALID_BODY")
+		}
+	}
+
+	dorm, err := h.service.ApproveDorm(c.Context(), c.Params("dormID"), &input)
+	if err != nil {
+		return respondError(c, err, "Failed to approve dorm", "REGISTRY_ERROR")
+	}
+	return utils.JSON(c, fiber.StatusOK, dorm)
+}
+
+func (h *RegistryHandler) ApproveMachine(c fiber.Ctx) error {
+	// body เป็น optional: อนุมัติได้โดยไม่ต้องเปลี่ยนชื่อ
+	var input models.Machine
+	if len(c.Body()) > 0 {
+		if err := c.Bind().Body(&input); err != nil {
+			return utils.Error(c, fiber.StatusBadRequest, "Invalid request body", "INVALID_BODY")
+		}
+	}
+
+	machine, err := h.service.ApproveMachine(c.Context(), c.Params("machineID"), &input)
+	if err != nil {
+		return respondError(c, err, "Failed to approve machine", "REGISTRY_ERROR")
+	}
+	return utils.JSON(c, fiber.StatusOK, machine)
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v3"
+	"github.com/jaytnw/bms-service/internal/repository"
+	"github.com/jaytnw/bms-service/internal/services"
+	"github.com/jaytnw/bms-service/internal/utils"
+)
+
+type RollupHandler struct {
+	service services.RollupService
+}
+
+func NewRollupHandler(service services.RollupService) *RollupHandler {
+	return &RollupHandler{service: service}
+}
+
+// ListRollups รองรับ ?granularity=hour|day ?dorm= ?washer= ?from= ?to= (RFC3339) และ ?limit=
+func (h *RollupHandler) ListRollups(c fiber.Ctx) error {
+	from, err := parseTimeQuery(c, "from")
+	if err != nil {
+		return respondError(c, err, "Invalid query", "INVALID_QUERY")
+	}
+	to, err := parseTimeQuery(c, "to")
+	if err != nil {
+		return respondError(c, err, "Invalid query", "INVALID_QUERY")
+	}
+	limit, err := parseLimitQuery(c, 1000, 10000)
+	if err != nil {
+		return respondError(c, err, "Invalid query", "INVALID_QUERY")
+	}
+
+	rollups, err := h.service.GetRollups(c.Context(), repository.RollupQuery{
+		Granularity: c.Query("granularity"),
+		DormID:      c.Query("dorm"),
+		WasherID:    c.Query("washer"),
+		From:        from,
+		To:          to,
+		Limit:       limit,
+	})
+	if err != nil {
+		return respondError(c, err, "Failed to get rollups", "ROLLUP_ERROR")
+	}
+	return utils.JSON(c, fiber.StatusOK, rollups)
+}
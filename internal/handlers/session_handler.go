@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v3"
+	"github.com/jaytnw/bms-service/internal/repository"
+	"github.com/jaytnw/bms-service/internal/services"
+	"github.com/jaytnw/bms-service/internal/utils"
+)
+
+type SessionHandler struct {
+	service services.SessionService
+}
+
+func NewSessionHandler(service services.SessionService) *SessionHandler {
+	return &SessionHandler{service: service}
+}
+
+func (h *SessionHandler) GetWasherSessions(c fiber.Ctx) error {
+	query, err := sessionQuery(c)
+	if err != nil {
+		return respondError(c, err, "Failed to get sessions", "SESSION_FETCH_FAILED")
+	}
+	query.WasherID = c.Params("washerID")
+
+	sessions, err := h.service.GetSessions(c.Context(), query)
+	if err != nil {
+		return respondError(c, err, "Failed to get sessions", "SESSION_FETCH_FAILED")
+	}
+	return utils.JSON(c, fiber.StatusOK, sessions)
+}
+
+func (h *SessionHandler) GetDormSessions(c fiber.Ctx) error {
+	query, err := sessionQuery(c)
+	if err != nil {
+		return respondError(c, err, "Failed to get sessions", "SESSION_FETCH_FAILED")
+	}
+	query.DormID = c.Params("dormID")
+
+	sessions, err := h.service.GetSessions(c.Context(), query)
+	if err != nil {
+		return respondError(c, err, "Failed to get sessions", "SESSION_FETCH_FAILED")
+	}
+	return utils.JSON(c, fiber.StatusOK, sessions)
+}
+
+func (h *SessionHandler) Backfill(c fiber.Ctx) error {
+	count, err := h.service.Backfill(c.Context())
+	if err != nil {
+		return respondError(c, err, "Failed to backfill sessions", "SESSION_BACKFILL_FAILED")
+	}
+	return utils.JSON(c, fiber.StatusOK, fiber.Map{"sessions": count})
+}
+
+func sessionQuery(c fiber.Ctx) (repository.SessionQuery, error) {
+	from, err := parseTimeQuery(c, "from")
+	if err != nil {
+		return repository.SessionQuery{}, err
+	}
+	to, err := parseTimeQuery(c, "to")
+	if err != nil {
+		return repository.SessionQuery{}, err
+	}
+	limit, err := parseLimitQuery(c, 100, 1000)
+	if err != nil {
+		return repository.SessionQuery{}, err
+	}
+
+	return repository.SessionQuery{From: from, To: to, Limit: limit}, nil
+}
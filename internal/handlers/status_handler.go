@@ -1,7 +1,11 @@
 package handlers
 
 import (
+	"strings"
+
 	"github.com/jaytnw/bms-service/internal/apperr"
+	"github.com/jaytnw/bms-service/internal/models"
+	"github.com/jaytnw/bms-service/internal/repository"
 	"github.com/jaytnw/bms-service/internal/services"
 	"github.com/jaytnw/bms-service/internal/utils"
 
@@ -16,17 +20,25 @@ func NewStatusHandler(service services.StatusService) *StatusHandler {
 	return &StatusHandler{service: service}
 }
 
+// GetAllStatus รองรับ ?dorm= ?washer= และตัวกรองเดียวกับ history (ดู parseStatusQuery)
 func (h *StatusHandler) GetAllStatus(c fiber.Ctx) error {
-	statuses, err := h.service.GetAllStatus(c.Context())
+	query, err := parseStatusQuery(c)
+	if err != nil {
+		return respondError(c, err, "Invalid query", "INVALID_QUERY")
+	}
+	query.DormID = c.Query("dorm")
+	query.WasherID = c.Query("washer")
+
+	page, err := h.service.GetAllStatus(c.Context(), query)
 	if err != nil {
-		return utils.Error(c, fiber.StatusInternalServerError, "Failed to get statuses", "GET_STATUS_ERROR")
+		return respondError(c, err, "Failed to get statuses", "GET_STATUS_ERROR")
 	}
 
-	return utils.JSON(c, fiber.StatusOK, statuses)
+	return utils.JSON(c, fiber.StatusOK, page)
 }
 
 func (h *StatusHandler) GetStatusByWasherID(c fiber.Ctx) error {
-	washerID := c.Params("washer_id")
+	washerID := c.Params("washerID")
 
 	status, err := h.service.GetStatusByWasherID(c.Context(), washerID)
 	if err != nil {
@@ -40,9 +52,14 @@ func (h *StatusHandler) GetStatusByWasherID(c fiber.Ctx) error {
 }
 
 func (h *StatusHandler) GetStatusHistoryByWasherID(c fiber.Ctx) error {
-	washerID := c.Params("washer_id")
+	washerID := c.Params("washerID")
+
+	query, err := parseStatusQuery(c)
+	if err != nil {
+		return respondError(c, err, "Invalid query", "INVALID_QUERY")
+	}
 
-	history, err := h.service.GetStatusHistoryByWasherID(c.Context(), washerID)
+	history, err := h.service.GetStatusHistoryByWasherID(c.Context(), washerID, query)
 	if err != nil {
 		if ae, ok := err.(*apperr.AppError); ok {
 			return utils.Error(c, ae.Status, ae.Message, ae.Code)
@@ -54,8 +71,35 @@ func (h *StatusHandler) GetStatusHistoryByWasherID(c fiber.Ctx) error {
 	return utils.JSON(c, 200, history)
 }
 
+// GetDormStatusReport รองรับ ?dorm= ?limit= (ต่อเครื่อง) ?since= (RFC3339) และ ?fields=history,eta,presence
 func (h *StatusHandler) GetDormStatusReport(c fiber.Ctx) error {
-	report, err := h.service.GetDormStatusReport(c.Context())
+	since, err := parseTimeQuery(c, "since")
+	if err != nil {
+		return respondError(c, err, "Invalid query", "INVALID_QUERY")
+	}
+	limit, err := parseLimitQuery(c, 100, 500)
+	if err != nil {
+		return respondError(c, err, "Invalid query", "INVALID_QUERY")
+	}
+
+	var fields map[string]bool
+	if raw := c.Query("fields"); raw != "" {
+		fields = make(map[string]bool)
+		for _, part := range strings.Split(raw, ",") {
+			field := strings.TrimSpace(part)
+			if !services.ValidReportField(field) {
+				return utils.Error(c, fiber.StatusBadRequest, "unknown field "+field, "INVALID_QUERY")
+			}
+			fields[field] = true
+		}
+	}
+
+	report, err := h.service.GetDormStatusReport(c.Context(), services.DormReportOptions{
+		DormID: c.Query("dorm"),
+		Limit:  limit,
+		Since:  since,
+		Fields: fields,
+	})
 	if err != nil {
 		if ae, ok := err.(*apperr.AppError); ok {
 			return utils.Error(c, ae.Status, ae.Message, ae.Code)
@@ -64,3 +108,97 @@ func (h *StatusHandler) GetDormStatusReport(c fiber.Ctx) error {
 	}
 	return utils.JSON(c, fiber.StatusOK, report)
 }
+
+func (h *StatusHandler) GetAnomalies(c fiber.Ctx) error {
+	limit, err := parseLimitQuery(c, 100, 1000)
+	if err != nil {
+		return respondError(c, err, "Failed to get anomalies", "ANOMALY_FETCH_FAILED")
+	}
+
+	anomalies, err := h.service.GetAnomalies(c.Context(), repository.AnomalyFilter{
+		Kind:     c.Query("kind"),
+		DormID:   c.Query("dorm"),
+		WasherID: c.Query("washer"),
+		Limit:    limit,
+	})
+	if err != nil {
+		return respondError(c, err, "Failed to get anomalies", "ANOMALY_FETCH_FAILED")
+	}
+
+	return utils.JSON(c, fiber.StatusOK, anomalies)
+}
+
+// GetTelemetry รองรับ ?from= ?to= (RFC3339) และ ?limit=
+func (h *StatusHandler) GetTelemetry(c fiber.Ctx) error {
+	from, err := parseTimeQuery(c, "from")
+	if err != nil {
+		return respondError(c, err, "Failed to get telemetry", "TELEMETRY_FETCH_FAILED")
+	}
+	to, err := parseTimeQuery(c, "to")
+	if err != nil {
+		return respondError(c, err, "Failed to get telemetry", "TELEMETRY_FETCH_FAILED")
+	}
+	limit, err := parseLimitQuery(c, 100, 1000)
+	if err != nil {
+		return respondError(c, err, "Failed to get telemetry", "TELEMETRY_FETCH_FAILED")
+	}
+
+	telemetry, err := h.service.GetTelemetry(c.Context(), repository.TelemetryQuery{
+		WasherID: c.Params("washerID"),
+		From:     from,
+		To:       to,
+		Limit:    limit,
+	})
+	if err != nil {
+		return respondError(c, err, "Failed to get telemetry", "TELEMETRY_FETCH_FAILED")
+	}
+
+	return utils.JSON(c, fiber.StatusOK, telemetry)
+}
+
+// parseStatusQuery อ่าน ?cursor= ?limit= ?from= ?to= (RFC3339) ?status= (คั่นด้วย comma) และ ?order=asc|desc
+// cursor ต้องมาจากหน้าที่ใช้ order เดียวกัน
+func parseStatusQuery(c fiber.Ctx) (repository.StatusQuery, error) {
+	var query repository.StatusQuery
+
+	from, err := parseTimeQuery(c, "from")
+	if err != nil {
+		return query, err
+	}
+	to, err := parseTimeQuery(c, "to")
+	if err != nil {
+		return query, err
+	}
+	limit, err := parseLimitQuery(c, 100, 1000)
+	if err != nil {
+		return query, err
+	}
+
+	switch c.Query("order", "desc") {
+	case "asc":
+		query.Ascending = true
+	case "desc":
+	default:
+		return query, apperr.New("INVALID_QUERY", "order must be asc or desc", fiber.StatusBadRequest, nil)
+	}
+	cursor, err := services.DecodeStatusCursor(c.Query("cursor"), query.Ascending)
+	if err != nil {
+		return query, err
+	}
+
+	if raw := c.Query("status"); raw != "" {
+		for _, part := range strings.Split(raw, ",") {
+			state := models.WasherState(strings.TrimSpace(part))
+			if !state.Valid() {
+				return query, apperr.New("INVALID_QUERY", "unknown status "+string(state), fiber.StatusBadRequest, nil)
+			}
+			query.Statuses = append(query.Statuses, state)
+		}
+	}
+
+	query.From = from
+	query.To = to
+	query.Limit = limit
+	query.After = cursor
+	return query, nil
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/jaytnw/bms-service/internal/repository"
+	"github.com/jaytnw/bms-service/internal/services"
+	"github.com/jaytnw/bms-service/internal/utils"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+func TestParseStatusQueryCursor(t *testing.T) {
+	at := time.Date(2026, 10, 12, 9, 30, 0, 0, time.UTC)
+	descCursor := services.EncodeStatusCursor(repository.StatusCursor{CreatedAt: at, ID: 9}, false)
+	ascCursor := services.EncodeStatusCursor(repository.StatusCursor{CreatedAt: at, ID: 9}, true)
+
+	tests := []struct {
+		name      string
+		query     url.Values
+		wantCode  string
+		wantAsc   bool
+		wantAfter bool
+	}{
+		{name: "no cursor", query: url.Values{}},
+		{name: "desc cursor with default order", query: url.Values{"cursor": {descCursor}}, wantAfter: true},
+		{name: "asc cursor with asc order", query: url.Values{"cursor": {ascCursor}, "order": {"asc"}}, wantAsc: true, wantAfter: true},
+		{name: "asc cursor with default order", query: url.Values{"cursor": {ascCursor}}, wantCode: "INVALID_CURSOR"},
+		{name: "desc cursor with asc order", query: url.Values{"cursor": {descCursor}, "order": {"asc"}}, wantCode: "INVALID_CURSOR"},
+		{name: "malformed cursor", query: url.Values{"cursor": {"not-a-cursor"}}, wantCode: "INVALID_CURSOR"},
+		{name: "unknown order", query: url.Values{"order": {"sideways"}}, wantCode: "INVALID_QUERY"},
+	}
+
+	app := fiber.New()
+	app.Get("/statuses", func(c fiber.Ctx) error {
+		query, err := parseStatusQuery(c)
+		if err != nil {
+			return respondError(c, err, "Invalid query", "INVALID_QUERY")
+		}
+		return c.JSON(fiber.Map{"ascending": query.Ascending, "after": query.After != nil})
+	})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := app.Test(httptest.NewRequest("GET", "/statuses?"+tt.query.Encode(), nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			if tt.wantCode != "" {
+				var body utils.ErrorResponse
+				if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+					t.Fatal(err)
+				}
+				if resp.StatusCode != fiber.StatusBadRequest || body.Error.Code != tt.wantCode {
+					t.Fatalf("response = %d %+v, want 400 %s", resp.StatusCode, body, tt.wantCode)
+				}
+				return
+			}
+
+			var body map[string]any
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != fiber.StatusOK || body["ascending"] != tt.wantAsc || body["after"] != tt.wantAfter {
+				t.Fatalf("response = %d %v, want ascending=%v after=%v", resp.StatusCode, body, tt.wantAsc, tt.wantAfter)
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/fasthttp/websocket"
+	"github.com/gofiber/fiber/v3"
+	"github.com/jaytnw/bms-service/internal/apperr"
+	"github.com/jaytnw/bms-service/internal/models"
+	"github.com/jaytnw/bms-service/internal/services"
+	"github.com/jaytnw/bms-service/internal/utils"
+	"github.com/valyala/fasthttp"
+)
+
+type StreamHandler struct {
+	service           services.StatusService
+	heartbeatInterval time.Duration
+	upgrader          websocket.FastHTTPUpgrader
+}
+
+func NewStreamHandler(service services.StatusService, heartbeatInterval time.Duration, allowedOrigins []string) *StreamHandler {
+	return &StreamHandler{
+		service:           service,
+		heartbeatInterval: heartbeatInterval,
+		upgrader: websocket.FastHTTPUpgrader{
+			CheckOrigin: originChecker(allowedOrigins),
+		},
+	}
+}
+
+// originChecker อนุญาต client ที่ไม่ส่ง Origin (ไม่ใช่ browser) origin ที่อยู่ใน allowed
+// และเมื่อ allowed ว่างจะอนุญาตเฉพาะ origin ที่ host ตรงกับ request
+func originChecker(allowed []string) func(ctx *fasthttp.RequestCtx) bool {
+	return func(ctx *fasthttp.RequestCtx) bool {
+		origin := string(ctx.Request.Header.Peek("Origin"))
+		if origin == "" {
+			return true
+		}
+		if len(allowed) == 0 {
+			u, err := url.Parse(origin)
+			return err == nil && strings.EqualFold(u.Host, string(ctx.Host()))
+		}
+		for _, a := range allowed {
+			if a == "*" || strings.EqualFold(strings.TrimSuffix(a, "/"), origin) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// StreamSSE ส่งสถานะแบบ Server-Sent Events: เริ่มด้วย snapshot ของสถานะปัจจุบัน ตามด้วย status ใหม่และ heartbeat
+func (h *StreamHandler) StreamSSE(c fiber.Ctx) error {
+	filter := streamFilter(c)
+
+	// subscribe ก่อนดึง snapshot เพื่อไม่ให้พลาด status ที่เข้ามาระหว่างนั้น
+	events, cancel := h.service.SubscribeStatus(filter)
+	snapshot, err := h.service.GetCurrentStatuses(c.Context(), filter)
+	if err != nil {
+		cancel()
+		if ae, ok := err.(*apperr.AppError); ok {
+			return utils.Error(c, ae.Status, ae.Message, ae.Code)
+		}
+		return utils.Error(c, fiber.StatusInternalServerError, "Failed to open status stream", "STREAM_ERROR")
+	}
+
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	return c.SendStreamWriter(func(w *bufio.Writer) {
+		defer cancel()
+
+		ticker := time.NewTicker(h.heartbeatInterval)
+		defer ticker.Stop()
+
+		for _, event := range snapshotEvents(snapshot) {
+			if err := writeSSE(w, event); err != nil {
+				return
+			}
+		}
+
+		for {
+			select {
+			case event, ok := <-events:
+				if !ok {
+					return
+				}
+				if err := writeSSE(w, event); err != nil {
+					return
+				}
+			case t := <-ticker.C:
+				if err := writeSSE(w, services.StatusEvent{Type: services.StatusEventHeartbeat, At: t}); err != nil {
+					return
+				}
+			}
+		}
+	})
+}
+
+// StreamWebSocket ส่งสถานะชุดเดียวกับ StreamSSE ผ่าน WebSocket โดยแต่ละ event เป็น JSON หนึ่งข้อความ
+func (h *StreamHandler) StreamWebSocket(c fiber.Ctx) error {
+	if !websocket.FastHTTPIsWebSocketUpgrade(c.RequestCtx()) {
+		return utils.Error(c, fiber.StatusUpgradeRequired, "WebSocket upgrade required", "UPGRADE_REQUIRED")
+	}
+
+	filter := streamFilter(c)
+	events, cancel := h.service.SubscribeStatus(filter)
+	snapshot, err := h.service.GetCurrentStatuses(c.Context(), filter)
+	if err != nil {
+		cancel()
+		if ae, ok := err.(*apperr.AppError); ok {
+			return utils.Error(c, ae.Status, ae.Message, ae.Code)
+		}
+		return utils.Error(c, fiber.StatusInternalServerError, "Failed to open status stream", "STREAM_ERROR")
+	}
+
+	err = h.upgrader.Upgrade(c.RequestCtx(), func(conn *websocket.Conn) {
+		defer cancel()
+		defer conn.Close()
+
+		// อ่านข้อความจาก client ทิ้งไปเพื่อจับ close frame และการหลุดของการเชื่อมต่อ
+		closed := make(chan struct{})
+		go func() {
+			defer close(closed)
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}()
+
+		ticker := time.NewTicker(h.heartbeatInterval)
+		defer ticker.Stop()
+
+		for _, event := range snapshotEvents(snapshot) {
+			if err := conn.WriteJSON(event); err != nil {
+				return
+			}
+		}
+
+		for {
+			select {
+			case <-closed:
+				return
+			case event, ok := <-events:
+				if !ok {
+					return
+				}
+				if err := conn.WriteJSON(event); err != nil {
+					return
+				}
+			case t := <-ticker.C:
+				if err := conn.WriteJSON(services.StatusEvent{Type: services.StatusEventHeartbeat, At: t}); err != nil {
+					return
+				}
+			}
+		}
+	})
+	if err != nil {
+		// callback ไม่ถูกเรียกเมื่อ handshake ล้มเหลว ต้องยกเลิก subscription เอง
+		cancel()
+		log.Printf("❌ WebSocket upgrade failed: %v", err)
+	}
+	return nil
+}
+
+func streamFilter(c fiber.Ctx) services.StatusFilter {
+	return services.StatusFilter{
+		DormID:   c.Query("dorm"),
+		WasherID: c.Query("washer"),
+	}
+}
+
+func snapshotEvents(statuses []models.Status) []services.StatusEvent {
+	now := time.Now()
+	events := make([]services.StatusEvent, 0, len(statuses))
+	for i := range statuses {
+		events = append(events, services.StatusEvent{
+			Type:   services.StatusEventSnapshot,
+			Status: &statuses[i],
+			At:     now,
+		})
+	}
+	return events
+}
+
+func writeSSE(w *bufio.Writer, event services.StatusEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data); err != nil {
+		return err
+	}
+	return w.Flush()
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/jaytnw/bms-service/internal/repository"
+	"github.com/jaytnw/bms-service/internal/services"
+	"github.com/jaytnw/bms-service/internal/utils"
+)
+
+type SubscriptionHandler struct {
+	service services.SubscriptionService
+}
+
+func NewSubscriptionHandler(service services.SubscriptionService) *SubscriptionHandler {
+	return &SubscriptionHandler{service: service}
+}
+
+func (h *SubscriptionHandler) Subscribe(c fiber.Ctx) error {
+	var req services.SubscribeRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, "Invalid request body", "INVALID_BODY")
+	}
+
+	subscription, err := h.service.Subscribe(c.Context(), c.Params("washerID"), req)
+	if err != nil {
+		return respondError(c, err, "Failed to subscribe", "SUBSCRIPTION_ERROR")
+	}
+	return utils.JSON(c, fiber.StatusCreated, subscription)
+}
+
+func (h *SubscriptionHandler) ListSubscriptions(c fiber.Ctx) error {
+	subscriptions, err := h.service.ListSubscriptions(c.Context(), c.Params("washerID"))
+	if err != nil {
+		return respondError(c, err, "Failed to get subscriptions", "SUBSCRIPTION_ERROR")
+	}
+	return utils.JSON(c, fiber.StatusOK, subscriptions)
+}
+
+func (h *SubscriptionHandler) Unsubscribe(c fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("subscriptionID"), 10, 64)
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, "subscriptionID must be a positive integer", "INVALID_SUBSCRIPTION_ID")
+	}
+
+	if err := h.service.Unsubscribe(c.Context(), uint(id)); err != nil {
+		return respondError(c, err, "Failed to unsubscribe", "SUBSCRIPTION_ERROR")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (h *SubscriptionHandler) GetDeliveries(c fiber.Ctx) error {
+	limit, err := parseLimitQuery(c, 100, 1000)
+	if err != nil {
+		return respondError(c, err, "Invalid limit", "INVALID_LIMIT")
+	}
+
+	deliveries, err := h.service.GetDeliveries(c.Context(), repository.DeliveryFilter{
+		Channel: c.Query("channel"),
+		Status:  c.Query("status"),
+		Limit:   limit,
+	})
+	if err != nil {
+		return respondError(c, err, "Failed to get notification deliveries", "NOTIFICATION_ERROR")
+	}
+	return utils.JSON(c, fiber.StatusOK, deliveries)
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/jaytnw/bms-service/internal/apperr"
+	"github.com/jaytnw/bms-service/internal/models"
+	"github.com/jaytnw/bms-service/internal/repository"
+	"github.com/jaytnw/bms-service/internal/services"
+	"github.com/jaytnw/bms-service/internal/utils"
+)
+
+type WebhookHandler struct {
+	service services.WebhookService
+}
+
+func NewWebhookHandler(service services.WebhookService) *WebhookHandler {
+	return &WebhookHandler{service: service}
+}
+
+func (h *WebhookHandler) ListEndpoints(c fiber.Ctx) error {
+	endpoints, err := h.service.ListEndpoints(c.Context())
+	if err != nil {
+		return respondError(c, err, "Failed to get webhooks", "WEBHOOK_ERROR")
+	}
+	return utils.JSON(c, fiber.StatusOK, endpoints)
+}
+
+func (h *WebhookHandler) GetEndpoint(c fiber.Ctx) error {
+	id, err := parseUintParam(c, "webhookID")
+	if err != nil {
+		return respondError(c, err, "Invalid webhook id", "INVALID_PARAM")
+	}
+
+	endpoint, err := h.service.GetEndpoint(c.Context(), id)
+	if err != nil {
+		return respondError(c, err, "Failed to get webhook", "WEBHOOK_ERROR")
+	}
+	return utils.JSON(c, fiber.StatusOK, endpoint)
+}
+
+func (h *WebhookHandler) CreateEndpoint(c fiber.Ctx) error {
+	var endpoint models.WebhookEndpoint
+	if err := c.Bind().Body(&endpoint); err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, "Invalid request body", "INVALID_BODY")
+	}
+
+	if err := h.service.CreateEndpoint(c.Context(), &endpoint); err != nil {
+		return respondError(c, err, "Failed to create webhook", "WEBHOOK_ERROR")
+	}
+	return utils.JSON(c, fiber.StatusCreated, endpoint)
+}
+
+func (h *WebhookHandler) UpdateEndpoint(c fiber.Ctx) error {
+	id, err := parseUintParam(c, "webhookID")
+	if err != nil {
+		return respondError(c, err, "Invalid webhook id", "INVALID_PARAM")
+	}
+
+	var input models.WebhookEndpoint
+	if err := c.Bind().Body(&input); err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, "Invalid request body", "INVALID_BODY")
+	}
+
+	endpoint, err := h.service.UpdateEndpoint(c.Context(), id, &input)
+	if err != nil {
+		return respondError(c, err, "Failed to update webhook", "WEBHOOK_ERROR")
+	}
+	return utils.JSON(c, fiber.StatusOK, endpoint)
+}
+
+func (h *WebhookHandler) DeleteEndpoint(c fiber.Ctx) error {
+	id, err := parseUintParam(c, "webhookID")
+	if err != nil {
+		return respondError(c, err, "Invalid webhook id", "INVALID_PARAM")
+	}
+
+	if err := h.service.DeleteEndpoint(c.Context(), id); err != nil {
+		return respondError(c, err, "Failed to delete webhook", "WEBHOOK_ERROR")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// ListDeliveries รองรับ ?endpoint= และ ?status= (pending, succeeded, dead)
+func (h *WebhookHandler) ListDeliveries(c fiber.Ctx) error {
+	filter, err := webhookDeliveryFilter(c)
+	if err != nil {
+		return respondError(c, err, "Invalid query", "INVALID_QUERY")
+	}
+	if c.Query("endpoint") != "" {
+		id, err := strconv.ParseUint(c.Query("endpoint"), 10, 64)
+		if err != nil {
+			return utils.Error(c, fiber.StatusBadRequest, "endpoint must be a positive integer", "INVALID_QUERY")
+		}
+		filter.EndpointID = uint(id)
+	}
+
+	deliveries, err := h.service.ListDeliveries(c.Context(), filter)
+	if err != nil {
+		return respondError(c, err, "Failed to get webhook deliveries", "WEBHOOK_ERROR")
+	}
+	return utils.JSON(c, fiber.StatusOK, deliveries)
+}
+
+func (h *WebhookHandler) ListEndpointDeliveries(c fiber.Ctx) error {
+	id, err := parseUintParam(c, "webhookID")
+	if err != nil {
+		return respondError(c, err, "Invalid webhook id", "INVALID_PARAM")
+	}
+	filter, err := webhookDeliveryFilter(c)
+	if err != nil {
+		return respondError(c, err, "Invalid query", "INVALID_QUERY")
+	}
+	filter.EndpointID = id
+
+	deliveries, err := h.service.ListDeliveries(c.Context(), filter)
+	if err != nil {
+		return respondError(c, err, "Failed to get webhook deliveries", "WEBHOOK_ERROR")
+	}
+	return utils.JSON(c, fiber.StatusOK, deliveries)
+}
+
+func (h *WebhookHandler) Redeliver(c fiber.Ctx) error {
+	id, err := parseUintParam(c, "deliveryID")
+	if err != nil {
+		return respondError(c, err, "Invalid delivery id", "INVALID_PARAM")
+	}
+
+	delivery, err := h.service.Redeliver(c.Context(), id)
+	if err != nil {
+		return respondError(c, err, "Failed to redeliver webhook", "WEBHOOK_ERROR")
+	}
+	return utils.JSON(c, fiber.StatusAccepted, delivery)
+}
+
+func webhookDeliveryFilter(c fiber.Ctx) (repository.WebhookDeliveryFilter, error) {
+	limit, err := parseLimitQuery(c, 100, 1000)
+	if err != nil {
+		return repository.WebhookDeliveryFilter{}, err
+	}
+	return repository.WebhookDeliveryFilter{Status: c.Query("status"), Limit: limit}, nil
+}
+
+// parseUintParam อ่าน path parameter ที่เป็นเลข ID
+func parseUintParam(c fiber.Ctx, key string) (uint, error) {
+	id, err := strconv.ParseUint(c.Params(key), 10, 64)
+	if err != nil {
+		return 0, apperr.New("INVALID_PARAM", key+" must be a positive integer", fiber.StatusBadRequest, err)
+	}
+	return uint(id), nil
+}
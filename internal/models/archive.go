@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// StatusArchive คือ manifest ของไฟล์ archive หนึ่งไฟล์ที่เก็บสถานะดิบของหนึ่งวัน [RangeFrom, RangeTo)
+// หนึ่งวันอาจมีหลาย Part: part 0 คือการ export ครั้งแรก part ถัดไปเก็บแถวที่เข้ามาช้า (id มากกว่า MaxStatusID ของ part ก่อนหน้า)
+// MaxStatusID คือ id สูงสุดที่อยู่ในไฟล์ การลบจะลบเฉพาะแถวที่ id ไม่เกินค่านี้
+// PurgedAt ถูกตั้งเมื่อลบแถวออกจากตาราง statuses แล้ว RestoredAt ถูกตั้งเมื่อนำกลับมาเพื่อตรวจสอบ
+type StatusArchive struct {
+	ID          uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	RangeFrom   time.Time  `gorm:"not null;uniqueIndex:idx_archive_range_part,priority:1" json:"rangeFrom"`
+	Part        int        `gorm:"not null;default:0;uniqueIndex:idx_archive_range_part,priority:2" json:"part"`
+	RangeTo     time.Time  `gorm:"not null" json:"rangeTo"`
+	Store       string     `gorm:"type:varchar(20);not null" json:"store"`
+	Key         string     `gorm:"type:varchar(255);not null" json:"key"`
+	Format      string     `gorm:"type:varchar(20);not null" json:"format"`
+	Rows        int64      `gorm:"not null" json:"rows"`
+	Bytes       int64      `gorm:"not null" json:"bytes"`
+	Checksum    string     `gorm:"type:varchar(64);not null" json:"checksum"`
+	MaxStatusID uint       `gorm:"not null" json:"maxStatusId"`
+	PurgedAt    *time.Time `json:"purgedAt,omitempty"`
+	RestoredAt  *time.Time `json:"restoredAt,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+}
+
+// ArchiveRestore คือผลการนำ archive กลับเข้าตาราง statuses
+// Rows คือจำนวนแถวที่เพิ่มจริง แถวที่ยังอยู่ในตารางจะไม่ถูกนับ
+type ArchiveRestore struct {
+	Archives []StatusArchive `json:"archives"`
+	Rows     int64           `json:"rows"`
+}
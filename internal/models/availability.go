@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// MachineAvailability คือสถานะปัจจุบันของเครื่องหนึ่งเครื่องในมุมมอง availability
+// เครื่องที่ซ่อมบำรุงอยู่จะมี Status เป็น "maintenance" และ Maintenance คือช่วงซ่อมบำรุงนั้น
+type MachineAvailability struct {
+	WasherID    string             `json:"washerId"`
+	Status      WasherState        `json:"status"`
+	Since       time.Time          `json:"since"`
+	Online      bool               `json:"online"`
+	ETA         *WasherETA         `json:"eta,omitempty"`
+	Maintenance *MaintenanceWindow `json:"maintenance,omitempty"`
+}
+
+// DormAvailability สรุปจำนวนเครื่องว่าง/ไม่ว่างของหอพักหนึ่งหอ
+type DormAvailability struct {
+	DormID        string                `json:"dormId"`
+	DormName      string                `json:"dormName"`
+	Total         int                   `json:"total"`
+	Free          int                   `json:"free"`
+	Counts        map[WasherState]int   `json:"counts"`
+	FreeMachines  []MachineAvailability `json:"freeMachines"`
+	SoonestFinish *MachineAvailability  `json:"soonestFinish,omitempty"`
+}
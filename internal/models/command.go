@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// WasherCommandType คือคำสั่งที่ส่งไปยังเครื่องซักผ้าผ่าน MQTT
+type WasherCommandType string
+
+const (
+	CommandStart  WasherCommandType = "start"
+	CommandStop   WasherCommandType = "stop"
+	CommandReset  WasherCommandType = "reset"
+	CommandLock   WasherCommandType = "lock"
+	CommandUnlock WasherCommandType = "unlock"
+)
+
+func (c WasherCommandType) Valid() bool {
+	switch c {
+	case CommandStart, CommandStop, CommandReset, CommandLock, CommandUnlock:
+		return true
+	}
+	return false
+}
+
+// CommandStatus คือผลของคำสั่ง
+type CommandStatus string
+
+const (
+	CommandPending  CommandStatus = "pending"
+	CommandAcked    CommandStatus = "acked"
+	CommandRejected CommandStatus = "rejected"
+	CommandTimeout  CommandStatus = "timeout"
+	CommandFailed   CommandStatus = "failed"
+)
+
+// WasherCommand คือประวัติคำสั่งหนึ่งรายการ ID ถูกส่งไปพร้อมคำสั่งและเครื่องต้องตอบกลับด้วย ID เดิมทาง .../ack
+type WasherCommand struct {
+	ID          string            `gorm:"type:varchar(50);primaryKey" json:"id"`
+	DormID      string            `gorm:"type:varchar(100);not null" json:"dormId"`
+	WasherID    string            `gorm:"type:varchar(100);not null;index:idx_command_washer_created" json:"washerId"`
+	Command     WasherCommandType `gorm:"type:varchar(20);not null" json:"command"`
+	Status      CommandStatus     `gorm:"type:varchar(20);not null;index" json:"status"`
+	RequestedBy string            `gorm:"type:varchar(100)" json:"requestedBy,omitempty"`
+	Reason      string            `gorm:"type:varchar(255)" json:"reason,omitempty"`
+	Message     string            `gorm:"type:text" json:"message,omitempty"`
+	SentAt      *time.Time        `json:"sentAt,omitempty"`
+	AckedAt     *time.Time        `json:"ackedAt,omitempty"`
+	CreatedAt   time.Time         `gorm:"index:idx_command_washer_created" json:"createdAt"`
+	UpdatedAt   time.Time         `json:"updatedAt"`
+}
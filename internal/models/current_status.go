@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// WasherCurrentStatus คือสถานะล่าสุดของแต่ละเครื่อง อัปเดตใน transaction เดียวกับการบันทึก Status
+// ใช้อ่านสถานะปัจจุบันโดยไม่ต้องเรียงประวัติ ReportedAt คือ CreatedAt ของ Status แถวนั้น
+// หากข้อมูลเพี้ยนสร้างใหม่จากตาราง statuses ได้ด้วย cmd/repair-current-status
+type WasherCurrentStatus struct {
+	WasherID    string      `gorm:"primaryKey;type:varchar(100)" json:"washerId"`
+	DormID      string      `gorm:"type:varchar(100);not null;index:idx_current_status_dorm" json:"dormId"`
+	StatusID    uint        `gorm:"not null" json:"statusId"`
+	Status      WasherState `gorm:"type:varchar(50);not null" json:"status"`
+	Maintenance bool        `gorm:"not null;default:false" json:"maintenance,omitempty"`
+	ReportedAt  time.Time   `gorm:"not null" json:"reportedAt"`
+	UpdatedAt   time.Time   `json:"updatedAt"`
+}
+
+// TableName ใช้ชื่อเอกพจน์ เพราะมีหนึ่งแถวต่อเครื่อง
+func (WasherCurrentStatus) TableName() string {
+	return "washer_current_status"
+}
+
+func NewWasherCurrentStatus(s Status) WasherCurrentStatus {
+	return WasherCurrentStatus{
+		WasherID:    s.WasherID,
+		DormID:      s.DormID,
+		StatusID:    s.ID,
+		Status:      s.Status,
+		Maintenance: s.Maintenance,
+		ReportedAt:  s.CreatedAt,
+	}
+}
+
+// ToStatus แปลงกลับเป็น Status แถวที่เป็นสถานะล่าสุด
+func (c WasherCurrentStatus) ToStatus() Status {
+	return Status{
+		ID:          c.StatusID,
+		DormID:      c.DormID,
+		WasherID:    c.WasherID,
+		Status:      c.Status,
+		Maintenance: c.Maintenance,
+		CreatedAt:   c.ReportedAt,
+		UpdatedAt:   c.ReportedAt,
+	}
+}
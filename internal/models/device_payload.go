@@ -0,0 +1,114 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// DevicePayloadVersion คือเวอร์ชันล่าสุดของ JSON payload ที่ระบบรองรับ
+const DevicePayloadVersion = 1
+
+const (
+	DoorOpen   = "open"
+	DoorClosed = "closed"
+	DoorLocked = "locked"
+)
+
+// DevicePayload คือ JSON payload v1 ที่อุปกรณ์ส่งมาทาง .../status
+// ทุกฟิลด์ยกเว้น status เป็น optional หากไม่ระบุ v จะถือเป็นเวอร์ชัน 1 ฟิลด์อื่นนอกจากนี้จะถูกข้าม
+type DevicePayload struct {
+	Version          int      `json:"v"`
+	Status           string   `json:"status"`
+	Program          string   `json:"program,omitempty"`
+	RemainingSeconds *int     `json:"remainingSeconds,omitempty"`
+	Door             string   `json:"door,omitempty"`
+	WaterTemp        *float64 `json:"waterTemp,omitempty"`
+	ErrorCode        string   `json:"errorCode,omitempty"`
+	Firmware         string   `json:"firmware,omitempty"`
+	RSSI             *int     `json:"rssi,omitempty"`
+}
+
+// DeviceReading คือผลการอ่าน payload Telemetry เป็น nil สำหรับ payload แบบ string เดิม
+type DeviceReading struct {
+	State     WasherState
+	Telemetry *WasherTelemetry
+}
+
+// ParseDevicePayload รองรับทั้ง payload แบบ string เดิม (เช่น "washing") และ JSON ที่มี telemetry
+// คืน error พร้อมเหตุผลเมื่อ payload ไม่ผ่าน schema
+func ParseDevicePayload(raw []byte) (*DeviceReading, error) {
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		state, ok := ParseWasherState(string(trimmed))
+		if !ok {
+			return nil, fmt.Errorf("unknown status %q", trimmed)
+		}
+		return &DeviceReading{State: state}, nil
+	}
+
+	// ฟิลด์ที่ไม่รู้จักถูกข้ามไป firmware ใหม่จึงเพิ่มฟิลด์ได้โดยไม่ทำให้ payload ถูก quarantine
+	// payload จะถูกปฏิเสธเฉพาะเมื่อเวอร์ชันไม่รองรับหรือฟิลด์ที่รู้จักไม่ถูกต้อง
+	var payload DevicePayload
+	if err := json.Unmarshal(trimmed, &payload); err != nil {
+		return nil, fmt.Errorf("invalid json payload: %w", err)
+	}
+	if payload.Version == 0 {
+		payload.Version = 1
+	}
+	if payload.Version != DevicePayloadVersion {
+		return nil, fmt.Errorf("unsupported payload version %d", payload.Version)
+	}
+
+	state, ok := ParseWasherState(payload.Status)
+	if !ok {
+		return nil, fmt.Errorf("unknown status %q", payload.Status)
+	}
+	if err := payload.validate(); err != nil {
+		return nil, err
+	}
+
+	return &DeviceReading{
+		State: state,
+		Telemetry: &WasherTelemetry{
+			Status:           state,
+			PayloadVersion:   payload.Version,
+			Program:          payload.Program,
+			RemainingSeconds: payload.RemainingSeconds,
+			Door:             payload.Door,
+			WaterTemp:        payload.WaterTemp,
+			ErrorCode:        payload.ErrorCode,
+			Firmware:         payload.Firmware,
+			RSSI:             payload.RSSI,
+		},
+	}, nil
+}
+
+func (p DevicePayload) validate() error {
+	var errs []error
+	if len(p.Program) > 50 {
+		errs = append(errs, errors.New("program is longer than 50 characters"))
+	}
+	if p.RemainingSeconds != nil && (*p.RemainingSeconds < 0 || *p.RemainingSeconds > 24*60*60) {
+		errs = append(errs, fmt.Errorf("remainingSeconds %d is out of range", *p.RemainingSeconds))
+	}
+	switch p.Door {
+	case "", DoorOpen, DoorClosed, DoorLocked:
+	default:
+		errs = append(errs, fmt.Errorf("unknown door state %q", p.Door))
+	}
+	if p.WaterTemp != nil && (*p.WaterTemp < -10 || *p.WaterTemp > 100) {
+		errs = append(errs, fmt.Errorf("waterTemp %.1f is out of range", *p.WaterTemp))
+	}
+	if len(p.ErrorCode) > 50 {
+		errs = append(errs, errors.New("errorCode is longer than 50 characters"))
+	}
+	if len(p.Firmware) > 50 {
+		errs = append(errs, errors.New("firmware is longer than 50 characters"))
+	}
+	if p.RSSI != nil && (*p.RSSI < -150 || *p.RSSI > 0) {
+		errs = append(errs, fmt.Errorf("rssi %d is out of range", *p.RSSI))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,42 @@
+package models
+
+import "testing"
+
+func TestParseDevicePayload(t *testing.T) {
+	tests := []struct {
+		name          string
+		raw           string
+		want          WasherState
+		wantTelemetry bool
+		wantErr       bool
+	}{
+		{name: "legacy string", raw: " washing ", want: WasherWashing},
+		{name: "legacy unknown", raw: "exploded", wantErr: true},
+		{name: "json without version", raw: `{"status":"idle"}`, want: WasherIdle, wantTelemetry: true},
+		{name: "json with telemetry", raw: `{"v":1,"status":"spin","remainingSeconds":120,"door":"locked"}`, want: WasherSpinning, wantTelemetry: true},
+		{name: "unknown fields are ignored", raw: `{"v":1,"status":"done","humidity":40,"extra":{"a":1}}`, want: WasherDone, wantTelemetry: true},
+		{name: "unsupported version", raw: `{"v":2,"status":"idle"}`, wantErr: true},
+		{name: "missing status", raw: `{"v":1,"door":"open"}`, wantErr: true},
+		{name: "out of range field", raw: `{"status":"idle","rssi":20}`, wantErr: true},
+		{name: "known field with wrong type", raw: `{"status":"idle","rssi":"strong"}`, wantErr: true},
+		{name: "malformed json", raw: `{"status":`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reading, err := ParseDevicePayload([]byte(tt.raw))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseDevicePayload(%q) error = %v, wantErr %v", tt.raw, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if reading.State != tt.want {
+				t.Errorf("state = %q, want %q", reading.State, tt.want)
+			}
+			if (reading.Telemetry != nil) != tt.wantTelemetry {
+				t.Errorf("telemetry = %+v, want present %v", reading.Telemetry, tt.wantTelemetry)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package models
+
+import "time"
+
+const (
+	SeverityLow      = "low"
+	SeverityMedium   = "medium"
+	SeverityHigh     = "high"
+	SeverityCritical = "critical"
+)
+
+// ValidSeverity บอกว่าเป็นระดับความรุนแรงที่ระบบรู้จักหรือไม่
+func ValidSeverity(severity string) bool {
+	switch severity {
+	case SeverityLow, SeverityMedium, SeverityHigh, SeverityCritical:
+		return true
+	}
+	return false
+}
+
+// FaultCode คือรหัสข้อผิดพลาดที่เครื่องรายงานผ่าน errorCode ใน telemetry
+type FaultCode struct {
+	Code            string    `gorm:"type:varchar(50);primaryKey" json:"code"`
+	Description     string    `gorm:"type:varchar(255);not null" json:"description"`
+	Severity        string    `gorm:"type:varchar(20);not null" json:"severity"`
+	SuggestedAction string    `gorm:"type:text" json:"suggestedAction,omitempty"`
+	CreatedAt       time.Time `json:"createdAt"`
+	UpdatedAt       time.Time `json:"updatedAt"`
+}
+
+type IncidentStatus string
+
+const (
+	IncidentOpen         IncidentStatus = "open"
+	IncidentAcknowledged IncidentStatus = "acknowledged"
+	IncidentResolved     IncidentStatus = "resolved"
+)
+
+// Incident เปิดเมื่อเครื่องเข้าสถานะ fault และปิดอัตโนมัติเมื่อเครื่องกลับมาทำงานปกติ
+// DurationSeconds (เวลาตั้งแต่เปิดจนปิด) ใช้คำนวณ MTTR
+type Incident struct {
+	ID              uint              `gorm:"primaryKey;autoIncrement" json:"id"`
+	DormID          string            `gorm:"type:varchar(100);not null;index:idx_incident_dorm_opened" json:"dormId"`
+	WasherID        string            `gorm:"type:varchar(100);not null;index:idx_incident_washer_status" json:"washerId"`
+	FaultCode       string            `gorm:"type:varchar(50)" json:"faultCode,omitempty"`
+	Severity        string            `gorm:"type:varchar(20);not null" json:"severity"`
+	Status          IncidentStatus    `gorm:"type:varchar(20);not null;index:idx_incident_washer_status" json:"status"`
+	OpenedAt        time.Time         `gorm:"not null;index:idx_incident_dorm_opened" json:"openedAt"`
+	AcknowledgedAt  *time.Time        `json:"acknowledgedAt,omitempty"`
+	AcknowledgedBy  string            `gorm:"type:varchar(100)" json:"acknowledgedBy,omitempty"`
+	ResolvedAt      *time.Time        `gorm:"index" json:"resolvedAt,omitempty"`
+	DurationSeconds *int64            `json:"durationSeconds,omitempty"`
+	CreatedAt       time.Time         `json:"createdAt"`
+	UpdatedAt       time.Time         `json:"updatedAt"`
+	Fault           *FaultCode        `gorm:"-" json:"fault,omitempty"`
+	Comments        []IncidentComment `gorm:"-" json:"comments,omitempty"`
+}
+
+// IncidentComment คือบันทึกของเจ้าหน้าที่ใน incident
+type IncidentComment struct {
+	ID         uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	IncidentID uint      `gorm:"not null;index" json:"incidentId"`
+	Author     string    `gorm:"type:varchar(100);not null" json:"author"`
+	Body       string    `gorm:"type:text;not null" json:"body"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// DormMTTR คือเวลาเฉลี่ยในการซ่อม (mean time to repair) ของ incident ที่ปิดแล้วในหอหนึ่ง
+type DormMTTR struct {
+	DormID      string  `json:"dormId"`
+	Incidents   int     `json:"incidents"`
+	MTTRSeconds float64 `json:"mttrSeconds"`
+}
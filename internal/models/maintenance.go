@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// WasherMaintenance ใช้แสดงผลใน availability เท่านั้น อุปกรณ์ส่งสถานะนี้มาเองไม่ได้
+const WasherMaintenance WasherState = "maintenance"
+
+// MaintenanceWindow คือช่วงที่เครื่อง (หรือทั้งหอเมื่อ WasherID ว่าง) อยู่ระหว่างซ่อมบำรุง
+// ช่วงซ่อมบำรุงสิ้นสุดเมื่อเจ้าหน้าที่ปิด (EndedAt) หรือเมื่อถึง ExpectedEndAt
+type MaintenanceWindow struct {
+	ID            uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	DormID        string     `gorm:"type:varchar(100);not null;index" json:"dormId"`
+	WasherID      string     `gorm:"type:varchar(100);index" json:"washerId,omitempty"`
+	Reason        string     `gorm:"type:varchar(255);not null" json:"reason"`
+	StartedBy     string     `gorm:"type:varchar(100)" json:"startedBy,omitempty"`
+	StartsAt      time.Time  `gorm:"not null" json:"startsAt"`
+	ExpectedEndAt *time.Time `json:"expectedEndAt,omitempty"`
+	EndedAt       *time.Time `gorm:"index" json:"endedAt,omitempty"`
+	EndedBy       string     `gorm:"type:varchar(100)" json:"endedBy,omitempty"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	UpdatedAt     time.Time  `json:"updatedAt"`
+}
+
+// ActiveAt บอกว่าช่วงซ่อมบำรุงมีผล ณ เวลา t หรือไม่
+func (m MaintenanceWindow) ActiveAt(t time.Time) bool {
+	if m.EndedAt != nil || t.Before(m.StartsAt) {
+		return false
+	}
+	return m.ExpectedEndAt == nil || t.Before(*m.ExpectedEndAt)
+}
+
+// Covers บอกว่าช่วงซ่อมบำรุงนี้ครอบคลุมเครื่องนี้หรือไม่
+func (m MaintenanceWindow) Covers(dormID, washerID string) bool {
+	if m.WasherID != "" {
+		return m.WasherID == washerID
+	}
+	return m.DormID == dormID
+}
@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+const (
+	DeliverySent   = "sent"
+	DeliveryFailed = "failed"
+)
+
+// NotificationDelivery คือ log การส่งแจ้งเตือนหนึ่งข้อความ (รวมทุกครั้งที่ retry)
+type NotificationDelivery struct {
+	ID          uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	Channel     string     `gorm:"type:varchar(20);not null;index:idx_delivery_channel_created" json:"channel"`
+	Recipient   string     `gorm:"type:varchar(500);not null" json:"recipient"`
+	Subject     string     `gorm:"type:varchar(255)" json:"subject"`
+	Body        string     `gorm:"type:text" json:"body"`
+	Status      string     `gorm:"type:varchar(20);not null;index" json:"status"`
+	Attempts    int        `gorm:"not null" json:"attempts"`
+	LastError   string     `gorm:"type:text" json:"lastError,omitempty"`
+	DeliveredAt *time.Time `json:"deliveredAt,omitempty"`
+	CreatedAt   time.Time  `gorm:"index:idx_delivery_channel_created" json:"createdAt"`
+}
+
+const (
+	SubscriptionEventDone  = "done"
+	SubscriptionEventFault = "fault"
+)
+
+// WasherSubscription คือการขอรับแจ้งเตือนเมื่อเครื่องซักเสร็จหรือเสีย
+// หาก Persistent เป็น false จะถูกปิดหลังแจ้งเตือนครั้งแรก
+type WasherSubscription struct {
+	ID         uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	WasherID   string     `gorm:"type:varchar(100);not null;index:idx_subscription_washer_active" json:"washerId"`
+	UserID     string     `gorm:"type:varchar(100);not null;index" json:"userId"`
+	Channel    string     `gorm:"type:varchar(20);not null" json:"channel"`
+	Target     string     `gorm:"type:varchar(500)" json:"target,omitempty"`
+	OnDone     bool       `gorm:"not null" json:"onDone"`
+	OnFault    bool       `gorm:"not null" json:"onFault"`
+	Persistent bool       `gorm:"not null" json:"persistent"`
+	Active     bool       `gorm:"not null;index:idx_subscription_washer_active" json:"active"`
+	NotifiedAt *time.Time `json:"notifiedAt,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	UpdatedAt  time.Time  `json:"updatedAt"`
+}
+
+// Wants บอกว่า subscription นี้สนใจเหตุการณ์ event หรือไม่
+func (s WasherSubscription) Wants(event string) bool {
+	switch event {
+	case SubscriptionEventDone:
+		return s.OnDone
+	case SubscriptionEventFault:
+		return s.OnFault
+	}
+	return false
+}
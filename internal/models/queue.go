@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// QueueEntryStatus คือสถานะของคิวหนึ่งรายการ
+type QueueEntryStatus string
+
+const (
+	QueueWaiting   QueueEntryStatus = "waiting"
+	QueueOffered   QueueEntryStatus = "offered"
+	QueueClaimed   QueueEntryStatus = "claimed"
+	QueueStarted   QueueEntryStatus = "started"
+	QueueExpired   QueueEntryStatus = "expired"
+	QueueCancelled QueueEntryStatus = "cancelled"
+)
+
+// QueueEntry คือการต่อคิวเครื่องซักผ้าของผู้ใช้หนึ่งคนในหอหนึ่งหอ
+// เมื่อมีเครื่องว่าง คิวแรกจะได้รับข้อเสนอ (offered) และต้อง claim ภายใน ClaimExpiresAt
+// คิวที่ offered หรือ claimed ถือครองเครื่องไว้จนเครื่องเริ่มทำงาน (started) เครื่องหนึ่งถูกถือครองได้ครั้งละหนึ่งคิว
+type QueueEntry struct {
+	ID             uint             `gorm:"primaryKey;autoIncrement" json:"id"`
+	DormID         string           `gorm:"type:varchar(100);not null;index:idx_queue_dorm_status" json:"dormId"`
+	UserID         string           `gorm:"type:varchar(100);not null;index" json:"userId"`
+	Status         QueueEntryStatus `gorm:"type:varchar(20);not null;index:idx_queue_dorm_status" json:"status"`
+	WasherID       string           `gorm:"type:varchar(100);uniqueIndex:idx_queue_washer_held,where:status = 'offered' OR status = 'claimed'" json:"washerId,omitempty"`
+	NotifyChannel  string           `gorm:"type:varchar(20);not null" json:"notifyChannel"`
+	NotifyTarget   string           `gorm:"type:varchar(500)" json:"notifyTarget,omitempty"`
+	OfferedAt      *time.Time       `json:"offeredAt,omitempty"`
+	ClaimExpiresAt *time.Time       `json:"claimExpiresAt,omitempty"`
+	ClaimedAt      *time.Time       `json:"claimedAt,omitempty"`
+	CreatedAt      time.Time        `json:"createdAt"`
+	UpdatedAt      time.Time        `json:"updatedAt"`
+	Position       int              `gorm:"-" json:"position,omitempty"`
+}
+
+// Active บอกว่าคิวยังรออยู่หรือยังถือครองเครื่องอยู่
+func (e QueueEntry) Active() bool {
+	return e.Status == QueueWaiting || e.Status == QueueOffered || e.Status == QueueClaimed
+}
+
+// HeldStatuses คือสถานะของคิวที่ถือครองเครื่องอยู่
+var HeldStatuses = []QueueEntryStatus{QueueOffered, QueueClaimed}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	SourceExternal = "external"
+	SourceManual   = "manual"
+	SourceMQTT     = "mqtt"
+)
+
+const (
+	RegistrationActive  = "active"
+	RegistrationPending = "pending"
+)
+
+// Dorm คือหอพักในทะเบียนของระบบ ID ตรงกับ <dorm> ใน MQTT topic
+// หอที่ระบบพบจาก MQTT แต่ยังไม่มีในทะเบียนจะถูกบันทึกเป็น pending รอผู้ดูแลอนุมัติ
+// EditedAt คือเวลาที่ผู้ดูแลแก้ไข อนุมัติ หรือลบผ่าน API ครั้งล่าสุด การ sync จากระบบภายนอกจะไม่ทับแถวที่มีค่านี้
+type Dorm struct {
+	ID        string         `gorm:"primaryKey;type:varchar(100)" json:"id"`
+	Name      string         `gorm:"type:varchar(255);not null" json:"name"`
+	Source    string         `gorm:"type:varchar(20);not null" json:"source"`
+	Status    string         `gorm:"type:varchar(20);not null;default:active;index" json:"status"`
+	EditedAt  *time.Time     `json:"editedAt,omitempty"`
+	CreatedAt time.Time      `json:"createdAt"`
+	UpdatedAt time.Time      `json:"updatedAt"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// Machine คือเครื่องซักผ้าในทะเบียนของระบบ ID ตรงกับ <washer> ใน MQTT topic
+// EditedAt มีความหมายเดียวกับของ Dorm
+type Machine struct {
+	ID        string         `gorm:"primaryKey;type:varchar(100)" json:"id"`
+	DormID    string         `gorm:"type:varchar(100);not null;index" json:"dormId"`
+	Name      string         `gorm:"type:varchar(255)" json:"name"`
+	Source    string         `gorm:"type:varchar(20);not null" json:"source"`
+	Status    string         `gorm:"type:varchar(20);not null;default:active;index" json:"status"`
+	EditedAt  *time.Time     `json:"editedAt,omitempty"`
+	CreatedAt time.Time      `json:"createdAt"`
+	UpdatedAt time.Time      `json:"updatedAt"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
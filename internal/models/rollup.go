@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// ความละเอียดของ StatusRollup
+const (
+	RollupHourly = "hour"
+	RollupDaily  = "day"
+)
+
+// StatusRollup คือเวลาที่เครื่องอยู่ในแต่ละสถานะภายในหนึ่ง bucket (ชั่วโมงหรือวัน)
+// สร้างจากตาราง statuses โดย rollup job เพื่อไม่ต้อง scan ข้อมูลดิบเมื่อ query ช่วงเวลายาว
+// Cycles คือจำนวนครั้งที่เข้าสู่สถานะ busy และ Completed คือจำนวนครั้งที่จบรอบด้วย done
+// EndState คือสถานะเมื่อสิ้นสุด bucket ใช้เป็นสถานะตั้งต้นของ bucket ถัดไป
+// EndInCycle บอกว่ารอบซักยังไม่จบเมื่อสิ้นสุด bucket (รวมถึงเครื่องที่ offline กลางรอบ) เหมือนรอบที่ยังเปิดใน wash_sessions
+// bucket รายชั่วโมงเริ่มที่ต้นชั่วโมงตาม timezone ของ analytics (ดู HourStart) ไม่ใช่ต้นชั่วโมงของ UTC
+type StatusRollup struct {
+	ID           uint                  `gorm:"primaryKey;autoIncrement" json:"id"`
+	Granularity  string                `gorm:"type:varchar(10);not null;uniqueIndex:idx_rollup_bucket,priority:1" json:"granularity"`
+	WasherID     string                `gorm:"type:varchar(100);not null;uniqueIndex:idx_rollup_bucket,priority:2" json:"washerId"`
+	BucketStart  time.Time             `gorm:"not null;uniqueIndex:idx_rollup_bucket,priority:3;index:idx_rollup_dorm_bucket,priority:2" json:"bucketStart"`
+	DormID       string                `gorm:"type:varchar(100);not null;index:idx_rollup_dorm_bucket,priority:1" json:"dormId"`
+	StateSeconds map[WasherState]int64 `gorm:"type:text;serializer:json" json:"stateSeconds"`
+	BusySeconds  int64                 `gorm:"not null" json:"busySeconds"`
+	Transitions  int                   `gorm:"not null" json:"transitions"`
+	Cycles       int                   `gorm:"not null" json:"cycles"`
+	Completed    int                   `gorm:"not null" json:"completed"`
+	EndState     WasherState           `gorm:"type:varchar(50);not null" json:"endState"`
+	EndInCycle   bool                  `gorm:"not null;default:false" json:"endInCycle"`
+	CreatedAt    time.Time             `json:"createdAt"`
+	UpdatedAt    time.Time             `json:"updatedAt"`
+}
+
+// HourStart คืนต้นชั่วโมงของ t ตามเวลาท้องถิ่นของ loc ใช้ offset ของ t เอง
+// จึงถูกต้องกับ timezone ที่ offset ไม่เต็มชั่วโมง (เช่น +05:30) และชั่วโมงที่ซ้ำกันตอนเปลี่ยน DST
+func HourStart(t time.Time, loc *time.Location) time.Time {
+	_, offset := t.In(loc).Zone()
+	shift := time.Duration(offset) * time.Second
+	return t.Add(shift).Truncate(time.Hour).Add(-shift)
+}
+
+// StatusRollupCheckpoint คือชื่อ checkpoint ของ rollup จากตาราง statuses
+const StatusRollupCheckpoint = "status_rollup"
+
+// RollupCheckpoint บันทึกว่า rollup job ประมวลผลถึงเวลาใดแล้ว (ไม่รวม Position)
+type RollupCheckpoint struct {
+	Name      string    `gorm:"primaryKey;type:varchar(50)" json:"name"`
+	Position  time.Time `gorm:"not null" json:"position"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHourStart(t *testing.T) {
+	kolkata := time.FixedZone("IST", 5*3600+30*60)
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("tzdata not available: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		t    time.Time
+		loc  *time.Location
+		want time.Time
+	}{
+		{"utc", time.Date(2026, 10, 12, 9, 45, 0, 0, time.UTC), time.UTC, time.Date(2026, 10, 12, 9, 0, 0, 0, time.UTC)},
+		{"whole hour offset", time.Date(2026, 10, 12, 9, 45, 0, 0, time.UTC), time.FixedZone("ICT", 7*3600), time.Date(2026, 10, 12, 9, 0, 0, 0, time.UTC)},
+		{"half hour offset", time.Date(2026, 10, 12, 9, 45, 0, 0, time.UTC), kolkata, time.Date(2026, 10, 12, 9, 30, 0, 0, time.UTC)},
+		{"half hour offset before the half", time.Date(2026, 10, 12, 9, 10, 0, 0, time.UTC), kolkata, time.Date(2026, 10, 12, 8, 30, 0, 0, time.UTC)},
+		{"exact boundary", time.Date(2026, 10, 12, 9, 30, 0, 0, time.UTC), kolkata, time.Date(2026, 10, 12, 9, 30, 0, 0, time.UTC)},
+		// 01:30 EST ครั้งที่สองหลังย้อนเวลา DST ต้องได้ 01:00 EST ไม่ใช่ 01:00 EDT
+		{"repeated dst hour", time.Date(2026, 11, 1, 6, 30, 0, 0, time.UTC), newYork, time.Date(2026, 11, 1, 6, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		if got := HourStart(tt.t, tt.loc); !got.Equal(tt.want) {
+			t.Errorf("%s: HourStart(%v) = %v, want %v", tt.name, tt.t, got.UTC(), tt.want)
+		}
+	}
+}
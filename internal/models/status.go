@@ -4,24 +4,48 @@ import (
 	"time"
 )
 
+// Status คือสถานะหนึ่งแถวที่ได้รับจากเครื่อง Maintenance เป็น true เมื่อได้รับระหว่างซ่อมบำรุง
+// ตาราง statuses แบ่ง partition รายเดือนตาม created_at (ดู migration) primary key จึงต้องรวม created_at
 type Status struct {
-	ID        uint      `gorm:"primaryKey;autoIncrement;index" json:"id"`
-	DormID    string    `gorm:"type:varchar(100);not null" json:"dormId"`
-	WasherID  string    `gorm:"type:varchar(100);not null;index:idx_washer_created" json:"washerId"`
-	Status    string    `gorm:"type:varchar(50);not null" json:"status"`
-	CreatedAt time.Time `gorm:"index:idx_washer_created" json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
+	ID          uint        `gorm:"primaryKey;autoIncrement;index;index:idx_status_created_id,priority:2" json:"id"`
+	DormID      string      `gorm:"type:varchar(100);not null" json:"dormId"`
+	WasherID    string      `gorm:"type:varchar(100);not null;index:idx_washer_created" json:"washerId"`
+	Status      WasherState `gorm:"type:varchar(50);not null" json:"status"`
+	Maintenance bool        `gorm:"not null;default:false" json:"maintenance,omitempty"`
+	CreatedAt   time.Time   `gorm:"primaryKey;index:idx_washer_created;index:idx_status_created_id,priority:1" json:"createdAt"`
+	UpdatedAt   time.Time   `json:"updatedAt"`
+}
+
+// StatusPage คือสถานะหนึ่งหน้า NextCursor ว่างเมื่อไม่มีหน้าถัดไป
+type StatusPage struct {
+	Items      []Status `json:"items"`
+	NextCursor string   `json:"nextCursor,omitempty"`
 }
 
 type StatusDTO struct {
 	// WasherID  string    `json:"washerId"`
-	Status    string    `json:"status"`
-	CreatedAt time.Time `json:"createdAt"`
+	Status    WasherState `json:"status"`
+	CreatedAt time.Time   `json:"createdAt"`
 }
 
+// WasherStatusHistory คือข้อมูลเครื่องหนึ่งเครื่องใน dorm report
+// History, ETA และ Online/LastSeenAt จะว่างเมื่อไม่ได้ขอใน ?fields=
 type WasherStatusHistory struct {
-	WasherID string      `json:"washer_id"`
-	History  []StatusDTO `json:"history"`
+	WasherID   string      `json:"washer_id"`
+	Pending    bool        `json:"pending,omitempty"`
+	History    []StatusDTO `json:"history,omitempty"`
+	ETA        *WasherETA  `json:"eta,omitempty"`
+	Online     *bool       `json:"online,omitempty"`
+	LastSeenAt *time.Time  `json:"lastSeenAt,omitempty"`
+}
+
+// WasherStatusDetail คือสถานะล่าสุดของเครื่องพร้อมข้อมูลประกอบ
+type WasherStatusDetail struct {
+	Status
+	ETA               *WasherETA         `json:"eta,omitempty"`
+	MaintenanceWindow *MaintenanceWindow `json:"maintenanceWindow,omitempty"`
+	Online            bool               `json:"online"`
+	LastSeenAt        *time.Time         `json:"lastSeenAt,omitempty"`
 }
 
 type DormStatusReport struct {
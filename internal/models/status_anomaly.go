@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+const (
+	AnomalyUnknownPayload    = "unknown_payload"
+	AnomalyIllegalTransition = "illegal_transition"
+	AnomalyInvalidPayload    = "invalid_payload"
+	AnomalyUnsaved           = "unsaved"
+)
+
+// StatusAnomaly เก็บ payload ที่ถูกกักไว้ (ไม่รู้จักหรือไม่ผ่าน schema) และการเปลี่ยนสถานะที่ผิด transition table
+// รวมถึงข้อความที่ฐานข้อมูลปฏิเสธตอนบันทึก (unsaved) ซึ่งถูก ack ออกจาก spool แล้ว
+type StatusAnomaly struct {
+	ID         uint        `gorm:"primaryKey;autoIncrement" json:"id"`
+	Kind       string      `gorm:"type:varchar(50);not null;index" json:"kind"`
+	DormID     string      `gorm:"type:varchar(100);not null" json:"dormId"`
+	WasherID   string      `gorm:"type:varchar(100);not null;index:idx_anomaly_washer_created" json:"washerId"`
+	Payload    string      `gorm:"type:text;not null" json:"payload"`
+	FromStatus WasherState `gorm:"type:varchar(50)" json:"fromStatus,omitempty"`
+	ToStatus   WasherState `gorm:"type:varchar(50)" json:"toStatus,omitempty"`
+	Detail     string      `gorm:"type:text" json:"detail,omitempty"`
+	CreatedAt  time.Time   `gorm:"index:idx_anomaly_washer_created" json:"createdAt"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// WasherTelemetry คือข้อมูลจาก JSON payload หนึ่งข้อความ StatusID ชี้ไปยังแถวใน statuses
+// ที่บันทึกจากข้อความเดียวกัน (nil เมื่อสถานะซ้ำกับของเดิมจึงไม่ได้บันทึกแถวใหม่)
+type WasherTelemetry struct {
+	ID               uint        `gorm:"primaryKey;autoIncrement" json:"id"`
+	StatusID         *uint       `json:"statusId,omitempty"`
+	DormID           string      `gorm:"type:varchar(100);not null" json:"dormId"`
+	WasherID         string      `gorm:"type:varchar(100);not null;index:idx_telemetry_washer_created" json:"washerId"`
+	Status           WasherState `gorm:"type:varchar(50);not null" json:"status"`
+	PayloadVersion   int         `gorm:"not null" json:"payloadVersion"`
+	Program          string      `gorm:"type:varchar(50)" json:"program,omitempty"`
+	RemainingSeconds *int        `json:"remainingSeconds,omitempty"`
+	Door             string      `gorm:"type:varchar(20)" json:"door,omitempty"`
+	WaterTemp        *float64    `json:"waterTemp,omitempty"`
+	ErrorCode        string      `gorm:"type:varchar(50)" json:"errorCode,omitempty"`
+	Firmware         string      `gorm:"type:varchar(50)" json:"firmware,omitempty"`
+	RSSI             *int        `json:"rssi,omitempty"`
+	CreatedAt        time.Time   `gorm:"index:idx_telemetry_washer_created" json:"createdAt"`
+}
+
+// TableName ใช้ชื่อเอกพจน์ เพราะ telemetry เป็นคำนามนับไม่ได้
+func (WasherTelemetry) TableName() string {
+	return "washer_telemetry"
+}
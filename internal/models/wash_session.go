@@ -0,0 +1,69 @@
+package models
+
+import "time"
+
+// SessionOutcome คือผลลัพธ์สุดท้ายของรอบซัก
+type SessionOutcome string
+
+const (
+	SessionInProgress SessionOutcome = "in_progress"
+	SessionCompleted  SessionOutcome = "completed"
+	SessionAborted    SessionOutcome = "aborted"
+	SessionFault      SessionOutcome = "fault"
+)
+
+// WashSession คือรอบซักหนึ่งรอบ สร้างจากการเปลี่ยนสถานะเข้า/ออกจากสถานะ busy
+// Program มาจาก telemetry ของเครื่องที่รายงานโปรแกรมซัก (ว่างสำหรับเครื่องรุ่นเก่าและรอบที่สร้างจาก backfill)
+type WashSession struct {
+	ID              uint           `gorm:"primaryKey;autoIncrement" json:"id"`
+	WasherID        string         `gorm:"type:varchar(100);not null;uniqueIndex:idx_session_washer_started" json:"washerId"`
+	DormID          string         `gorm:"type:varchar(100);not null;index:idx_session_dorm_started" json:"dormId"`
+	StartedAt       time.Time      `gorm:"not null;uniqueIndex:idx_session_washer_started;index:idx_session_dorm_started" json:"startedAt"`
+	EndedAt         *time.Time     `json:"endedAt"`
+	DurationSeconds *int64         `json:"durationSeconds"`
+	Outcome         SessionOutcome `gorm:"type:varchar(20);not null" json:"outcome"`
+	Program         string         `gorm:"type:varchar(50)" json:"program,omitempty"`
+	CreatedAt       time.Time      `json:"createdAt"`
+	UpdatedAt       time.Time      `json:"updatedAt"`
+}
+
+// OutcomeFor คืนผลลัพธ์ของรอบซักที่ปิดเมื่อเครื่องเปลี่ยนไปเป็น state (done, fault หรือ idle)
+func OutcomeFor(state WasherState) SessionOutcome {
+	switch state {
+	case WasherDone:
+		return SessionCompleted
+	case WasherFault:
+		return SessionFault
+	default:
+		return SessionAborted
+	}
+}
+
+// Close ปิดรอบซักที่เวลา endedAt พร้อมคำนวณระยะเวลา
+func (s *WashSession) Close(endedAt time.Time, outcome SessionOutcome) {
+	duration := int64(endedAt.Sub(s.StartedAt).Seconds())
+	if duration < 0 {
+		duration = 0
+	}
+	s.EndedAt = &endedAt
+	s.DurationSeconds = &duration
+	s.Outcome = outcome
+}
+
+const (
+	ETASourceHistory = "history"
+	ETASourceDevice  = "device"
+)
+
+// WasherETA คือเวลาที่คาดว่ารอบซักปัจจุบันจะเสร็จ คำนวณจากระยะเวลารอบซักในอดีต
+// หรือจาก remainingSeconds ที่เครื่องรายงานมา (Source = "device")
+type WasherETA struct {
+	StartedAt        time.Time `json:"startedAt"`
+	ExpectedFinishAt time.Time `json:"expectedFinishAt"`
+	RemainingSeconds int64     `json:"remainingSeconds"`
+	Confidence       float64   `json:"confidence"`
+	SampleSize       int       `json:"sampleSize"`
+	Overdue          bool      `json:"overdue"`
+	Source           string    `json:"source"`
+	Program          string    `json:"program,omitempty"`
+}
@@ -0,0 +1,86 @@
+package models
+
+import "strings"
+
+// WasherState คือสถานะของเครื่องซักผ้าที่ระบบยอมรับ
+type WasherState string
+
+const (
+	WasherIdle     WasherState = "idle"
+	WasherWashing  WasherState = "washing"
+	WasherRinsing  WasherState = "rinsing"
+	WasherSpinning WasherState = "spinning"
+	WasherDone     WasherState = "done"
+	WasherFault    WasherState = "fault"
+	WasherOffline  WasherState = "offline"
+)
+
+// washerStateAliases แปลง payload ที่อุปกรณ์รุ่นเก่าส่งมาให้เป็นสถานะมาตรฐาน
+var washerStateAliases = map[string]WasherState{
+	"idle":      WasherIdle,
+	"available": WasherIdle,
+	"free":      WasherIdle,
+	"ready":     WasherIdle,
+	"washing":   WasherWashing,
+	"wash":      WasherWashing,
+	"running":   WasherWashing,
+	"busy":      WasherWashing,
+	"rinsing":   WasherRinsing,
+	"rinse":     WasherRinsing,
+	"spinning":  WasherSpinning,
+	"spin":      WasherSpinning,
+	"done":      WasherDone,
+	"finished":  WasherDone,
+	"complete":  WasherDone,
+	"completed": WasherDone,
+	"fault":     WasherFault,
+	"error":     WasherFault,
+	"offline":   WasherOffline,
+}
+
+// washerTransitions คือ transition table: สถานะต้นทาง -> สถานะปลายทางที่อนุญาต
+// การเปลี่ยนไป fault หรือ offline และการคงสถานะเดิมอนุญาตเสมอ ไม่ต้องระบุในตาราง
+var washerTransitions = map[WasherState][]WasherState{
+	WasherIdle:     {WasherWashing},
+	WasherWashing:  {WasherRinsing, WasherSpinning, WasherDone, WasherIdle},
+	WasherRinsing:  {WasherSpinning, WasherWashing, WasherDone, WasherIdle},
+	WasherSpinning: {WasherRinsing, WasherDone, WasherIdle},
+	WasherDone:     {WasherIdle, WasherWashing},
+	WasherFault:    {WasherIdle},
+	WasherOffline:  {WasherIdle, WasherWashing, WasherRinsing, WasherSpinning, WasherDone},
+}
+
+// ParseWasherState แปลง payload ดิบเป็น WasherState คืน false หากไม่รู้จัก
+func ParseWasherState(raw string) (WasherState, bool) {
+	state, ok := washerStateAliases[strings.ToLower(strings.TrimSpace(raw))]
+	return state, ok
+}
+
+// Valid บอกว่าเป็นสถานะที่อยู่ในรายการมาตรฐานหรือไม่
+func (s WasherState) Valid() bool {
+	_, ok := washerTransitions[s]
+	return ok
+}
+
+// Busy บอกว่าเครื่องกำลังอยู่ในรอบซัก
+func (s WasherState) Busy() bool {
+	return s == WasherWashing || s == WasherRinsing || s == WasherSpinning
+}
+
+// Free บอกว่าเครื่องว่างพร้อมให้ใช้งาน
+func (s WasherState) Free() bool {
+	return s == WasherIdle || s == WasherDone
+}
+
+// CanTransition ตรวจสอบว่าการเปลี่ยนสถานะ from -> to ถูกต้องตาม transition table
+func CanTransition(from, to WasherState) bool {
+	if from == to || to == WasherFault || to == WasherOffline {
+		return true
+	}
+	for _, next := range washerTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
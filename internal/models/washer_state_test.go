@@ -0,0 +1,70 @@
+package models
+
+import "testing"
+
+func TestParseWasherState(t *testing.T) {
+	tests := []struct {
+		raw   string
+		want  WasherState
+		known bool
+	}{
+		{"idle", WasherIdle, true},
+		{" Available ", WasherIdle, true},
+		{"RUNNING", WasherWashing, true},
+		{"rinse", WasherRinsing, true},
+		{"spin", WasherSpinning, true},
+		{"finished", WasherDone, true},
+		{"error", WasherFault, true},
+		{"offline", WasherOffline, true},
+		{"", "", false},
+		{"exploded", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseWasherState(tt.raw)
+		if got != tt.want || ok != tt.known {
+			t.Errorf("ParseWasherState(%q) = %q, %v; want %q, %v", tt.raw, got, ok, tt.want, tt.known)
+		}
+	}
+}
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from, to WasherState
+		want     bool
+	}{
+		{WasherIdle, WasherWashing, true},
+		{WasherIdle, WasherIdle, true},
+		{WasherIdle, WasherDone, false},
+		{WasherIdle, WasherSpinning, false},
+		{WasherWashing, WasherRinsing, true},
+		{WasherSpinning, WasherWashing, false},
+		{WasherDone, WasherIdle, true},
+		{WasherDone, WasherSpinning, false},
+		{WasherFault, WasherWashing, false},
+		{WasherFault, WasherIdle, true},
+		{WasherOffline, WasherDone, true},
+		{WasherWashing, WasherFault, true},
+		{WasherDone, WasherOffline, true},
+	}
+	for _, tt := range tests {
+		if got := CanTransition(tt.from, tt.to); got != tt.want {
+			t.Errorf("CanTransition(%s, %s) = %v; want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestWasherStateClasses(t *testing.T) {
+	for _, s := range []WasherState{WasherWashing, WasherRinsing, WasherSpinning} {
+		if !s.Busy() || s.Free() {
+			t.Errorf("%s should be busy", s)
+		}
+	}
+	for _, s := range []WasherState{WasherIdle, WasherDone} {
+		if s.Busy() || !s.Free() {
+			t.Errorf("%s should be free", s)
+		}
+	}
+	if WasherState("bogus").Valid() {
+		t.Error("unknown state should not be valid")
+	}
+}
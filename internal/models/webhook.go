@@ -0,0 +1,76 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// WebhookEventAll ใน Events หมายถึงรับทุก event
+const WebhookEventAll = "*"
+
+// WebhookEventType คือชนิด event ของการเปลี่ยนสถานะ เช่น "washer.done", "washer.fault"
+func WebhookEventType(state WasherState) string {
+	return "washer." + string(state)
+}
+
+// WebhookEndpoint คือปลายทางที่รับ event การเปลี่ยนสถานะ กรองตามชนิด event, หอ และเครื่อง (ค่าว่างคือไม่กรอง)
+type WebhookEndpoint struct {
+	ID          uint           `gorm:"primaryKey;autoIncrement" json:"id"`
+	URL         string         `gorm:"type:varchar(500);not null" json:"url"`
+	Secret      string         `gorm:"type:varchar(100);not null" json:"secret,omitempty"`
+	Description string         `gorm:"type:varchar(255)" json:"description"`
+	Events      []string       `gorm:"type:text;serializer:json" json:"events"`
+	DormID      string         `gorm:"type:varchar(100)" json:"dormId,omitempty"`
+	WasherID    string         `gorm:"type:varchar(100)" json:"washerId,omitempty"`
+	Active      bool           `gorm:"not null" json:"active"`
+	CreatedAt   time.Time      `json:"createdAt"`
+	UpdatedAt   time.Time      `json:"updatedAt"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// Matches บอกว่า endpoint นี้ต้องได้รับ event ของสถานะนี้หรือไม่
+func (w WebhookEndpoint) Matches(eventType string, status Status) bool {
+	if !w.Active {
+		return false
+	}
+	if w.DormID != "" && w.DormID != status.DormID {
+		return false
+	}
+	if w.WasherID != "" && w.WasherID != status.WasherID {
+		return false
+	}
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, e := range w.Events {
+		if e == WebhookEventAll || e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryDead      = "dead"
+)
+
+// WebhookDelivery คือการส่ง event หนึ่งครั้งไปยัง endpoint หนึ่ง เก็บ payload ไว้เพื่อ retry และ redeliver
+// รายการที่ retry ครบแล้วยังไม่สำเร็จจะถูกย้ายเป็น dead (dead-letter)
+type WebhookDelivery struct {
+	ID             uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	EndpointID     uint       `gorm:"not null;index" json:"endpointId"`
+	EventID        string     `gorm:"type:varchar(50);not null" json:"eventId"`
+	EventType      string     `gorm:"type:varchar(50);not null" json:"eventType"`
+	Payload        string     `gorm:"type:text;not null" json:"payload"`
+	Status         string     `gorm:"type:varchar(20);not null;index:idx_webhook_delivery_due" json:"status"`
+	Attempts       int        `gorm:"not null" json:"attempts"`
+	NextAttemptAt  time.Time  `gorm:"index:idx_webhook_delivery_due" json:"nextAttemptAt"`
+	LastStatusCode int        `json:"lastStatusCode,omitempty"`
+	LastError      string     `gorm:"type:text" json:"lastError,omitempty"`
+	DeliveredAt    *time.Time `json:"deliveredAt,omitempty"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	UpdatedAt      time.Time  `json:"updatedAt"`
+}
@@ -15,6 +15,8 @@ type MessageHandler func(topic string, payload []byte, retained bool)
 type Client interface {
 	Publish(topic string, payload string) error
 	Subscribe(topic string, handler MessageHandler) error
+	// Close หยุดรับข้อความและตัดการเชื่อมต่อ ใช้ตอน shutdown
+	Close()
 }
 
 // mqttClient implements the Client interface
@@ -76,6 +78,12 @@ func (m *mqttClient) Subscribe(topic string, handler MessageHandler) error {
 	return token.Error()
 }
 
+// Close disconnects after giving in-flight work a short time to complete
+func (m *mqttClient) Close() {
+	m.client.Disconnect(250)
+	log.Println("🔌 MQTT disconnected")
+}
+
 // resubscribeAll resubscribes to all previous topics on reconnect
 func (m *mqttClient) resubscribeAll() {
 	m.mu.Lock()
@@ -0,0 +1,211 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jaytnw/bms-service/internal/models"
+)
+
+// permanentError คือความผิดพลาดที่ retry แล้วไม่มีทางสำเร็จ (เช่น ผู้รับไม่ถูกต้อง)
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent ห่อ err เพื่อบอก Dispatcher ว่าไม่ต้อง retry
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// DeliveryRecorder บันทึกผลการส่งแจ้งเตือนแต่ละครั้ง
+type DeliveryRecorder interface {
+	SaveDelivery(ctx context.Context, delivery *models.NotificationDelivery) error
+}
+
+// Dispatcher เลือก Notifier ตาม Message.Channel ส่งแบบ background พร้อม retry/backoff และบันทึก delivery log
+type Dispatcher interface {
+	Notifier
+	Supports(channel string) bool
+	// Drain หยุดรับข้อความใหม่และรอให้ข้อความที่ค้างในคิวส่งเสร็จ หาก ctx หมดเวลาก่อน
+	// retry ที่ค้างอยู่จะถูกยกเลิกและบันทึกเป็น failed
+	Drain(ctx context.Context) error
+}
+
+// ErrDispatcherFull คืนจาก Notify เมื่อคิวส่งเต็มหรือกำลังปิดระบบ
+var ErrDispatcherFull = errors.New("notification queue is full")
+
+type queuedMessage struct {
+	ctx      context.Context
+	notifier Notifier
+	msg      Message
+}
+
+// dispatcher ส่งข้อความผ่าน worker จำนวนจำกัด คิวมีขนาดจำกัดเพื่อไม่ให้ retry สะสม goroutine ไม่รู้จบ
+type dispatcher struct {
+	notifiers   map[string]Notifier
+	recorder    DeliveryRecorder
+	maxAttempts int
+	baseDelay   time.Duration
+	timeout     time.Duration
+
+	queue chan queuedMessage
+	wg    sync.WaitGroup
+	// stop ปิดเมื่อ Drain หมดเวลา เพื่อตัด backoff ที่ค้างอยู่
+	stop     chan struct{}
+	stopOnce sync.Once
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+func NewDispatcher(notifiers map[string]Notifier, recorder DeliveryRecorder, maxAttempts int, baseDelay, timeout time.Duration, workers, queueSize int) Dispatcher {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	if queueSize < 1 {
+		queueSize = 1
+	}
+	d := &dispatcher{
+		notifiers:   notifiers,
+		recorder:    recorder,
+		maxAttempts: maxAttempts,
+		baseDelay:   baseDelay,
+		timeout:     timeout,
+		queue:       make(chan queuedMessage, queueSize),
+		stop:        make(chan struct{}),
+	}
+
+	d.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go d.worker()
+	}
+	return d
+}
+
+func (d *dispatcher) Supports(channel string) bool {
+	_, ok := d.notifiers[channel]
+	return ok
+}
+
+// Notify คืน error ทันทีเมื่อไม่รู้จัก channel หรือคิวเต็ม ส่วนการส่งจริงทำใน worker
+// เพื่อไม่ให้ retry บล็อก MQTT handler หรือ request ที่เรียก
+func (d *dispatcher) Notify(ctx context.Context, msg Message) error {
+	notifier, ok := d.notifiers[msg.Channel]
+	if !ok {
+		return fmt.Errorf("unsupported notification channel %q", msg.Channel)
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.closed {
+		return ErrDispatcherFull
+	}
+	select {
+	case d.queue <- queuedMessage{ctx: context.WithoutCancel(ctx), notifier: notifier, msg: msg}:
+		return nil
+	default:
+		return ErrDispatcherFull
+	}
+}
+
+func (d *dispatcher) Drain(ctx context.Context) error {
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		close(d.queue)
+	}
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		d.stopOnce.Do(func() { close(d.stop) })
+		return fmt.Errorf("notification drain timed out with %d message(s) still queued", len(d.queue))
+	}
+}
+
+func (d *dispatcher) worker() {
+	defer d.wg.Done()
+	for job := range d.queue {
+		d.deliver(job.ctx, job.notifier, job.msg)
+	}
+}
+
+func (d *dispatcher) deliver(ctx context.Context, notifier Notifier, msg Message) {
+	delivery := &models.NotificationDelivery{
+		Channel:   msg.Channel,
+		Recipient: maskRecipient(msg.Channel, msg.Recipient),
+		Subject:   msg.Subject,
+		Body:      msg.Body,
+		Status:    models.DeliveryFailed,
+	}
+
+	var err error
+	for attempt := 1; attempt <= d.maxAttempts; attempt++ {
+		delivery.Attempts = attempt
+
+		attemptCtx, cancel := context.WithTimeout(ctx, d.timeout)
+		err = notifier.Notify(attemptCtx, msg)
+		cancel()
+		if err == nil {
+			now := time.Now()
+			delivery.Status = models.DeliverySent
+			delivery.DeliveredAt = &now
+			break
+		}
+
+		var perm *permanentError
+		if errors.As(err, &perm) || attempt == d.maxAttempts {
+			break
+		}
+
+		delay := d.baseDelay * time.Duration(1<<(attempt-1))
+		log.Printf("🔁 %s notification attempt %d failed, retrying in %v: %v", msg.Channel, attempt, delay, err)
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+			continue
+		case <-d.stop:
+			timer.Stop()
+			err = fmt.Errorf("shutting down before retry: %w", err)
+		}
+		break
+	}
+
+	if err != nil {
+		delivery.LastError = err.Error()
+		log.Printf("❌ %s notification to %s failed after %d attempt(s): %v", msg.Channel, delivery.Recipient, delivery.Attempts, err)
+	}
+
+	if d.recorder == nil {
+		return
+	}
+	if err := d.recorder.SaveDelivery(ctx, delivery); err != nil {
+		log.Printf("❌ Failed to record notification delivery: %v", err)
+	}
+}
+
+// maskRecipient ซ่อน token ของ LINE ไม่ให้ถูกเก็บลง delivery log
+func maskRecipient(channel, recipient string) string {
+	if channel != ChannelLine || len(recipient) <= 4 {
+		return recipient
+	}
+	return "****" + recipient[len(recipient)-4:]
+}
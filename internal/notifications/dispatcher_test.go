@@ -0,0 +1,302 @@
+package notifications
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jaytnw/bms-service/internal/models"
+)
+
+type fakeRecorder struct {
+	mu         sync.Mutex
+	deliveries []models.NotificationDelivery
+}
+
+func (r *fakeRecorder) SaveDelivery(ctx context.Context, delivery *models.NotificationDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.deliveries = append(r.deliveries, *delivery)
+	return nil
+}
+
+func (r *fakeRecorder) all() []models.NotificationDelivery {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]models.NotificationDelivery(nil), r.deliveries...)
+}
+
+func drain(t *testing.T, d Dispatcher) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := d.Drain(ctx); err != nil {
+		t.Fatalf("Drain() error = %v", err)
+	}
+}
+
+func TestDispatcherWebhookDelivery(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     []int
+		wantStatus   string
+		wantAttempts int
+	}{
+		{name: "sent first time", statuses: []int{200}, wantStatus: models.DeliverySent, wantAttempts: 1},
+		{name: "retries server errors", statuses: []int{503, 500, 204}, wantStatus: models.DeliverySent, wantAttempts: 3},
+		{name: "retries rate limit", statuses: []int{429, 200}, wantStatus: models.DeliverySent, wantAttempts: 2},
+		{name: "client error is permanent", statuses: []int{400}, wantStatus: models.DeliveryFailed, wantAttempts: 1},
+		{name: "gives up after max attempts", statuses: []int{500, 500, 500, 500}, wantStatus: models.DeliveryFailed, wantAttempts: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			var got Message
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := int(calls.Add(1))
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+					t.Errorf("decode body: %v", err)
+				}
+				w.WriteHeader(tt.statuses[min(n, len(tt.statuses))-1])
+			}))
+			defer server.Close()
+
+			recorder := &fakeRecorder{}
+			d := NewDispatcher(map[string]Notifier{ChannelWebhook: NewWebhookNotifier(time.Second)}, recorder, 3, time.Millisecond, time.Second, 1, 10)
+			msg := Message{Channel: ChannelWebhook, Recipient: server.URL, Subject: "Washer available", Body: "w1 is free"}
+			if err := d.Notify(context.Background(), msg); err != nil {
+				t.Fatalf("Notify() error = %v", err)
+			}
+			drain(t, d)
+
+			deliveries := recorder.all()
+			if len(deliveries) != 1 {
+				t.Fatalf("deliveries = %d, want 1", len(deliveries))
+			}
+			if deliveries[0].Status != tt.wantStatus || deliveries[0].Attempts != tt.wantAttempts {
+				t.Fatalf("delivery = %s after %d attempt(s), want %s after %d", deliveries[0].Status, deliveries[0].Attempts, tt.wantStatus, tt.wantAttempts)
+			}
+			if got.Subject != msg.Subject || got.Body != msg.Body {
+				t.Fatalf("webhook body = %+v, want %+v", got, msg)
+			}
+		})
+	}
+}
+
+func TestDispatcherRejectsWhenQueueIsFull(t *testing.T) {
+	release := make(chan struct{})
+	blocking := notifierFunc(func(ctx context.Context, msg Message) error {
+		<-release
+		return nil
+	})
+	d := NewDispatcher(map[string]Notifier{ChannelLog: blocking}, nil, 1, time.Millisecond, time.Second, 1, 1)
+
+	// ข้อความแรกถูก worker รับไป ข้อความที่สองเต็มคิว
+	var err error
+	for i := 0; i < 3 && err == nil; i++ {
+		err = d.Notify(context.Background(), Message{Channel: ChannelLog})
+	}
+	if !errors.Is(err, ErrDispatcherFull) {
+		t.Fatalf("Notify() error = %v, want ErrDispatcherFull", err)
+	}
+
+	close(release)
+	drain(t, d)
+	if err := d.Notify(context.Background(), Message{Channel: ChannelLog}); !errors.Is(err, ErrDispatcherFull) {
+		t.Fatalf("Notify() after Drain error = %v, want ErrDispatcherFull", err)
+	}
+}
+
+func TestDispatcherDrainTimeoutStopsRetries(t *testing.T) {
+	failing := notifierFunc(func(ctx context.Context, msg Message) error {
+		return errors.New("unavailable")
+	})
+	recorder := &fakeRecorder{}
+	d := NewDispatcher(map[string]Notifier{ChannelLog: failing}, recorder, 5, time.Hour, time.Second, 1, 10)
+	if err := d.Notify(context.Background(), Message{Channel: ChannelLog}); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := d.Drain(ctx); err == nil {
+		t.Fatal("Drain() error = nil, want timeout while a retry is waiting")
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(recorder.all()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	deliveries := recorder.all()
+	if len(deliveries) != 1 || deliveries[0].Status != models.DeliveryFailed || deliveries[0].Attempts != 1 {
+		t.Fatalf("deliveries = %+v, want one failed delivery after 1 attempt", deliveries)
+	}
+}
+
+func TestSMTPNotifierSendsToSink(t *testing.T) {
+	sink := newSMTPSink(t)
+	host, port, _ := net.SplitHostPort(sink.addr)
+	recorder := &fakeRecorder{}
+	d := NewDispatcher(map[string]Notifier{ChannelEmail: NewSMTPNotifier(host, port, "", "", "bms@example.com")}, recorder, 1, time.Millisecond, 5*time.Second, 1, 10)
+
+	msg := Message{Channel: ChannelEmail, Recipient: "user@example.com", Subject: "Washer\r\navailable", Body: "w1 is free"}
+	if err := d.Notify(context.Background(), msg); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	drain(t, d)
+
+	if deliveries := recorder.all(); len(deliveries) != 1 || deliveries[0].Status != models.DeliverySent {
+		t.Fatalf("deliveries = %+v, want one sent delivery", deliveries)
+	}
+	mail := sink.message()
+	if !strings.Contains(mail, "RCPT TO:<user@example.com>") {
+		t.Fatalf("mail = %q, want recipient user@example.com", mail)
+	}
+	if !strings.Contains(mail, "Subject: Washer  available") || !strings.Contains(mail, "w1 is free") {
+		t.Fatalf("mail = %q, want sanitised subject and body", mail)
+	}
+}
+
+func TestSMTPNotifierAbandonsStalledServer(t *testing.T) {
+	tests := []struct {
+		name string
+		ctx  func() (context.Context, context.CancelFunc)
+	}{
+		{name: "deadline", ctx: func() (context.Context, context.CancelFunc) {
+			return context.WithTimeout(context.Background(), 100*time.Millisecond)
+		}},
+		{name: "cancel without deadline", ctx: func() (context.Context, context.CancelFunc) {
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(100*time.Millisecond, cancel)
+			return ctx, cancel
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatalf("listen: %v", err)
+			}
+			defer listener.Close()
+
+			// server ตอบ greeting แล้วเงียบ connection ต้องถูกปิดเมื่อ Notify คืนค่า ไม่ค้างส่งต่อเบื้องหลัง
+			closed := make(chan struct{})
+			go func() {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+				conn.Write([]byte("220 stalled\r\n"))
+				io.Copy(io.Discard, conn)
+				close(closed)
+			}()
+
+			host, port, _ := net.SplitHostPort(listener.Addr().String())
+			notifier := NewSMTPNotifier(host, port, "", "", "bms@example.com")
+			ctx, cancel := tt.ctx()
+			defer cancel()
+
+			start := time.Now()
+			err = notifier.Notify(ctx, Message{Channel: ChannelEmail, Recipient: "user@example.com", Subject: "s", Body: "b"})
+			if !errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, context.Canceled) {
+				t.Fatalf("Notify() error = %v, want context error", err)
+			}
+			if elapsed := time.Since(start); elapsed > 2*time.Second {
+				t.Fatalf("Notify() took %v after the context ended", elapsed)
+			}
+			select {
+			case <-closed:
+			case <-time.After(2 * time.Second):
+				t.Fatal("connection is still open after Notify returned")
+			}
+		})
+	}
+}
+
+type notifierFunc func(ctx context.Context, msg Message) error
+
+func (f notifierFunc) Notify(ctx context.Context, msg Message) error {
+	return f(ctx, msg)
+}
+
+// smtpSink คือ SMTP server ขั้นต่ำที่รับอีเมลหนึ่งฉบับและเก็บคำสั่งทั้งหมดไว้ตรวจ
+type smtpSink struct {
+	addr string
+	mu   sync.Mutex
+	log  strings.Builder
+}
+
+func newSMTPSink(t *testing.T) *smtpSink {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	sink := &smtpSink{addr: listener.Addr().String()}
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		sink.serve(conn)
+	}()
+	return sink
+}
+
+func (s *smtpSink) serve(conn net.Conn) {
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 sink ready")
+	inData := false
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.log.WriteString(line)
+		s.mu.Unlock()
+
+		command := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case inData:
+			if command == "." {
+				inData = false
+				reply("250 queued")
+			}
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			reply("250 sink")
+		case command == "DATA":
+			inData = true
+			reply("354 end with .")
+		case command == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+func (s *smtpSink) message() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.log.String()
+}
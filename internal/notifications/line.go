@@ -0,0 +1,47 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// ChannelLine ส่งข้อความแบบ LINE Notify โดย Recipient คือ access token ของผู้รับ
+const ChannelLine = "line"
+
+type lineNotifier struct {
+	client *resty.Client
+	apiURL string
+}
+
+// NewLineNotifier สร้าง notifier ที่ POST form "message" ไปยัง apiURL พร้อม Bearer token
+// apiURL ตั้งค่าได้เพื่อชี้ไปยัง endpoint ที่เข้ากันได้ (หรือ stand-in ในเครื่อง)
+func NewLineNotifier(apiURL string, timeout time.Duration) Notifier {
+	return &lineNotifier{
+		client: resty.New().SetTimeout(timeout),
+		apiURL: apiURL,
+	}
+}
+
+func (n *lineNotifier) Notify(ctx context.Context, msg Message) error {
+	if msg.Recipient == "" {
+		return Permanent(fmt.Errorf("line token is required"))
+	}
+
+	text := msg.Body
+	if msg.Subject != "" {
+		text = msg.Subject + "\n" + msg.Body
+	}
+
+	resp, err := n.client.R().
+		SetContext(ctx).
+		SetAuthToken(msg.Recipient).
+		SetFormData(map[string]string{"message": text}).
+		Post(n.apiURL)
+	if err != nil {
+		return err
+	}
+	return checkHTTPStatus(resp)
+}
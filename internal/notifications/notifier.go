@@ -0,0 +1,34 @@
+package notifications
+
+import (
+	"context"
+	"log"
+)
+
+// ChannelLog เขียนข้อความลง log เท่านั้น ใช้เป็นค่าเริ่มต้นเมื่อผู้ใช้ไม่ได้ระบุช่องทาง
+const ChannelLog = "log"
+
+// Message คือข้อความแจ้งเตือนหนึ่งรายการ Recipient มีความหมายตาม Channel (เช่น URL, token หรืออีเมล)
+type Message struct {
+	Channel   string         `json:"channel"`
+	Recipient string         `json:"recipient"`
+	Subject   string         `json:"subject"`
+	Body      string         `json:"body"`
+	Data      map[string]any `json:"data,omitempty"`
+}
+
+// Notifier ส่งข้อความแจ้งเตือนไปยังผู้รับ
+type Notifier interface {
+	Notify(ctx context.Context, msg Message) error
+}
+
+type logNotifier struct{}
+
+func NewLogNotifier() Notifier {
+	return &logNotifier{}
+}
+
+func (n *logNotifier) Notify(ctx context.Context, msg Message) error {
+	log.Printf("🔔 [%s → %s] %s: %s", msg.Channel, msg.Recipient, msg.Subject, msg.Body)
+	return nil
+}
@@ -0,0 +1,125 @@
+package notifications
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// ChannelEmail ส่งอีเมลผ่าน SMTP โดย Recipient คืออีเมลผู้รับ
+const ChannelEmail = "email"
+
+type smtpNotifier struct {
+	addr     string
+	host     string
+	username string
+	password string
+	from     string
+}
+
+// NewSMTPNotifier สร้าง notifier ที่ส่งอีเมลผ่าน SMTP ใช้ PLAIN auth เฉพาะเมื่อกำหนด username
+// (SMTP sink ในเครื่องส่วนใหญ่ไม่ต้อง auth)
+func NewSMTPNotifier(host, port, username, password, from string) Notifier {
+	return &smtpNotifier{
+		addr:     net.JoinHostPort(host, port),
+		host:     host,
+		username: username,
+		password: password,
+		from:     from,
+	}
+}
+
+func (n *smtpNotifier) Notify(ctx context.Context, msg Message) error {
+	if msg.Recipient == "" || strings.ContainsAny(msg.Recipient, "\r\n") {
+		return Permanent(fmt.Errorf("invalid email recipient %q", msg.Recipient))
+	}
+
+	var auth smtp.Auth
+	if n.username != "" {
+		auth = smtp.PlainAuth("", n.username, n.password, n.host)
+	}
+
+	subject := strings.NewReplacer("\r", " ", "\n", " ").Replace(msg.Subject)
+	body := strings.Join([]string{
+		"From: " + n.from,
+		"To: " + msg.Recipient,
+		"Subject: " + subject,
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"",
+		msg.Body,
+	}, "\r\n")
+
+	if err := n.send(ctx, auth, msg.Recipient, []byte(body)); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+	return nil
+}
+
+// send ทำขั้นตอนเดียวกับ smtp.SendMail บน connection ที่ผูกกับ ctx
+// deadline ของ ctx เป็น deadline ของ connection และเมื่อ ctx ถูกยกเลิก I/O ที่ค้างอยู่จะจบทันที
+// การส่งจึงไม่ค้างทำงานต่อหลัง Notify คืนค่า (ซึ่งทำให้ dispatcher retry แล้วได้อีเมลซ้ำ)
+func (n *smtpNotifier) send(ctx context.Context, auth smtp.Auth, recipient string, body []byte) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", n.addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return err
+		}
+	}
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	client, err := smtp.NewClient(conn, n.host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: n.host}); err != nil {
+			return err
+		}
+	}
+	if auth != nil {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return errors.New("smtp: server doesn't support AUTH")
+		}
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(n.from); err != nil {
+		return err
+	}
+	if err := client.Rcpt(recipient); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(body); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
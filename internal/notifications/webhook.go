@@ -0,0 +1,59 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// ChannelWebhook ส่ง Message เป็น JSON ด้วย HTTP POST ไปยัง URL ใน Recipient
+const ChannelWebhook = "webhook"
+
+type webhookNotifier struct {
+	client *resty.Client
+}
+
+func NewWebhookNotifier(timeout time.Duration) Notifier {
+	return &webhookNotifier{
+		client: resty.New().SetTimeout(timeout),
+	}
+}
+
+func (n *webhookNotifier) Notify(ctx context.Context, msg Message) error {
+	if msg.Recipient == "" {
+		return Permanent(fmt.Errorf("webhook url is required"))
+	}
+
+	resp, err := n.client.R().
+		SetContext(ctx).
+		SetHeader("Content-Type", "application/json").
+		SetBody(msg).
+		Post(msg.Recipient)
+	if err != nil {
+		return err
+	}
+	return checkHTTPStatus(resp)
+}
+
+// checkHTTPStatus ถือว่า 4xx (ยกเว้น 429) เป็นความผิดพลาดถาวรที่ไม่ต้อง retry
+func checkHTTPStatus(resp *resty.Response) error {
+	code := resp.StatusCode()
+	if code >= 200 && code < 300 {
+		return nil
+	}
+
+	err := fmt.Errorf("unexpected status %d: %s", code, truncate(resp.String(), 200))
+	if code >= 400 && code < 500 && code != 429 {
+		return Permanent(err)
+	}
+	return err
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jaytnw/bms-service/internal/models"
+	"gorm.io/gorm"
+)
+
+// AnomalyFilter กรองรายการ anomaly (ค่าว่างคือไม่กรอง)
+type AnomalyFilter struct {
+	Kind     string
+	DormID   string
+	WasherID string
+	Limit    int
+}
+
+type AnomalyRepository interface {
+	SaveAnomaly(ctx context.Context, anomaly *models.StatusAnomaly) error
+	FindAnomalies(ctx context.Context, filter AnomalyFilter) ([]models.StatusAnomaly, error)
+}
+
+type anomalyRepo struct {
+	conn *gorm.DB
+}
+
+func NewAnomalyRepo(conn *gorm.DB) AnomalyRepository {
+	return &anomalyRepo{
+		conn: conn,
+	}
+}
+
+func (r *anomalyRepo) SaveAnomaly(ctx context.Context, anomaly *models.StatusAnomaly) error {
+	return r.conn.WithContext(ctx).Create(anomaly).Error
+}
+
+func (r *anomalyRepo) FindAnomalies(ctx context.Context, filter AnomalyFilter) ([]models.StatusAnomaly, error) {
+	var anomalies []models.StatusAnomaly
+	query := r.conn.WithContext(ctx).Order("created_at DESC")
+	if filter.Kind != "" {
+		query = query.Where("kind = ?", filter.Kind)
+	}
+	if filter.DormID != "" {
+		query = query.Where("dorm_id = ?", filter.DormID)
+	}
+	if filter.WasherID != "" {
+		query = query.Where("washer_id = ?", filter.WasherID)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	if err := query.Find(&anomalies).Error; err != nil {
+		return nil, err
+	}
+	return anomalies, nil
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jaytnw/bms-service/internal/models"
+	"gorm.io/gorm"
+)
+
+// ArchiveQuery กรอง archive ที่ช่วงเวลาทับกับ [From, To)
+type ArchiveQuery struct {
+	From  *time.Time
+	To    *time.Time
+	Limit int
+}
+
+type ArchiveRepository interface {
+	FindArchivesByRangeFrom(ctx context.Context, rangeFrom time.Time) ([]models.StatusArchive, error)
+	FindArchives(ctx context.Context, query ArchiveQuery) ([]models.StatusArchive, error)
+	SaveArchive(ctx context.Context, archive *models.StatusArchive) error
+}
+
+type archiveRepo struct {
+	conn *gorm.DB
+}
+
+func NewArchiveRepo(conn *gorm.DB) ArchiveRepository {
+	return &archiveRepo{
+		conn: conn,
+	}
+}
+
+// FindArchivesByRangeFrom คืนทุก part ของวันที่เริ่มที่ rangeFrom เรียงตาม part หรือ slice ว่างหากยังไม่เคย archive
+func (r *archiveRepo) FindArchivesByRangeFrom(ctx context.Context, rangeFrom time.Time) ([]models.StatusArchive, error) {
+	var archives []models.StatusArchive
+	if err := r.conn.WithContext(ctx).Where("range_from = ?", rangeFrom).Order("part").Find(&archives).Error; err != nil {
+		return nil, err
+	}
+	return archives, nil
+}
+
+func (r *archiveRepo) FindArchives(ctx context.Context, query ArchiveQuery) ([]models.StatusArchive, error) {
+	var archives []models.StatusArchive
+	db := r.conn.WithContext(ctx).Order("range_from, part")
+	if query.From != nil {
+		db = db.Where("range_to > ?", *query.From)
+	}
+	if query.To != nil {
+		db = db.Where("range_from < ?", *query.To)
+	}
+	if query.Limit > 0 {
+		db = db.Limit(query.Limit)
+	}
+
+	if err := db.Find(&archives).Error; err != nil {
+		return nil, err
+	}
+	return archives, nil
+}
+
+func (r *archiveRepo) SaveArchive(ctx context.Context, archive *models.StatusArchive) error {
+	return r.conn.WithContext(ctx).Save(archive).Error
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jaytnw/bms-service/internal/models"
+	"gorm.io/gorm"
+)
+
+type CommandRepository interface {
+	FindCommandByID(ctx context.Context, id string) (*models.WasherCommand, error)
+	FindCommandsByWasherID(ctx context.Context, washerID string, limit int) ([]models.WasherCommand, error)
+	SaveCommand(ctx context.Context, command *models.WasherCommand) error
+}
+
+type commandRepo struct {
+	conn *gorm.DB
+}
+
+func NewCommandRepo(conn *gorm.DB) CommandRepository {
+	return &commandRepo{
+		conn: conn,
+	}
+}
+
+func (r *commandRepo) FindCommandByID(ctx context.Context, id string) (*models.WasherCommand, error) {
+	var command models.WasherCommand
+	if err := r.conn.WithContext(ctx).First(&command, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &command, nil
+}
+
+func (r *commandRepo) FindCommandsByWasherID(ctx context.Context, washerID string, limit int) ([]models.WasherCommand, error) {
+	var commands []models.WasherCommand
+	err := r.conn.WithContext(ctx).
+		Where("washer_id = ?", washerID).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&commands).Error
+	if err != nil {
+		return nil, err
+	}
+	return commands, nil
+}
+
+func (r *commandRepo) SaveCommand(ctx context.Context, command *models.WasherCommand) error {
+	return r.conn.WithContext(ctx).Save(command).Error
+}
@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// sqlRecorder เก็บ SQL ที่ gorm สร้างในโหมด DryRun ใช้ตรวจคำสั่งโดยไม่ต้องมีฐานข้อมูลจริง
+type sqlRecorder struct {
+	mu         sync.Mutex
+	statements []string
+}
+
+func (r *sqlRecorder) LogMode(logger.LogLevel) logger.Interface      { return r }
+func (r *sqlRecorder) Info(context.Context, string, ...interface{})  {}
+func (r *sqlRecorder) Warn(context.Context, string, ...interface{})  {}
+func (r *sqlRecorder) Error(context.Context, string, ...interface{}) {}
+
+func (r *sqlRecorder) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	sql, _ := fc()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.statements = append(r.statements, sql)
+}
+
+// find คืนคำสั่งแรกที่ขึ้นต้นด้วย prefix
+func (r *sqlRecorder) find(t *testing.T, prefix string) string {
+	t.Helper()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, statement := range r.statements {
+		if strings.HasPrefix(statement, prefix) {
+			return statement
+		}
+	}
+	t.Fatalf("no %q statement in %q", prefix, r.statements)
+	return ""
+}
+
+func newDryRunDB(t *testing.T) (*gorm.DB, *sqlRecorder) {
+	t.Helper()
+	recorder := &sqlRecorder{}
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{DryRun: true, DisableAutomaticPing: true, SkipDefaultTransaction: true, Logger: recorder})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, recorder
+}
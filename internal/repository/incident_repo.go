@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jaytnw/bms-service/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// IncidentFilter กรอง incident (ค่าว่างคือไม่กรอง)
+type IncidentFilter struct {
+	DormID   string
+	WasherID string
+	Status   models.IncidentStatus
+	From     *time.Time
+	To       *time.Time
+	Limit    int
+}
+
+type IncidentRepository interface {
+	FindFaultCodes(ctx context.Context) ([]models.FaultCode, error)
+	FindFaultCode(ctx context.Context, code string) (*models.FaultCode, error)
+	UpsertFaultCode(ctx context.Context, faultCode *models.FaultCode) error
+	DeleteFaultCode(ctx context.Context, code string) error
+
+	FindIncidentByID(ctx context.Context, id uint) (*models.Incident, error)
+	FindUnresolvedByWasherID(ctx context.Context, washerID string) (*models.Incident, error)
+	FindIncidents(ctx context.Context, filter IncidentFilter) ([]models.Incident, error)
+	SaveIncident(ctx context.Context, incident *models.Incident) error
+	FindComments(ctx context.Context, incidentID uint) ([]models.IncidentComment, error)
+	SaveComment(ctx context.Context, comment *models.IncidentComment) error
+	MTTRByDorm(ctx context.Context, from, to *time.Time) ([]models.DormMTTR, error)
+}
+
+type incidentRepo struct {
+	conn *gorm.DB
+}
+
+func NewIncidentRepo(conn *gorm.DB) IncidentRepository {
+	return &incidentRepo{
+		conn: conn,
+	}
+}
+
+func (r *incidentRepo) FindFaultCodes(ctx context.Context) ([]models.FaultCode, error) {
+	var codes []models.FaultCode
+	if err := r.conn.WithContext(ctx).Order("code").Find(&codes).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func (r *incidentRepo) FindFaultCode(ctx context.Context, code string) (*models.FaultCode, error) {
+	var faultCode models.FaultCode
+	if err := r.conn.WithContext(ctx).First(&faultCode, "code = ?", code).Error; err != nil {
+		return nil, err
+	}
+	return &faultCode, nil
+}
+
+func (r *incidentRepo) UpsertFaultCode(ctx context.Context, faultCode *models.FaultCode) error {
+	return r.conn.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "code"}},
+			DoUpdates: clause.AssignmentColumns([]string{"description", "severity", "suggested_action", "updated_at"}),
+		}).
+		Create(faultCode).Error
+}
+
+func (r *incidentRepo) DeleteFaultCode(ctx context.Context, code string) error {
+	result := r.conn.WithContext(ctx).Delete(&models.FaultCode{}, "code = ?", code)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *incidentRepo) FindIncidentByID(ctx context.Context, id uint) (*models.Incident, error) {
+	var incident models.Incident
+	if err := r.conn.WithContext(ctx).First(&incident, id).Error; err != nil {
+		return nil, err
+	}
+	return &incident, nil
+}
+
+// FindUnresolvedByWasherID คืน incident ที่ยังไม่ปิดของเครื่อง หรือ nil หากไม่มี
+func (r *incidentRepo) FindUnresolvedByWasherID(ctx context.Context, washerID string) (*models.Incident, error) {
+	var incidents []models.Incident
+	err := r.conn.WithContext(ctx).
+		Where("washer_id = ? AND status <> ?", washerID, models.IncidentResolved).
+		Order("opened_at DESC").
+		Limit(1).
+		Find(&incidents).Error
+	if err != nil || len(incidents) == 0 {
+		return nil, err
+	}
+	return &incidents[0], nil
+}
+
+func (r *incidentRepo) FindIncidents(ctx context.Context, filter IncidentFilter) ([]models.Incident, error) {
+	var incidents []models.Incident
+	query := r.conn.WithContext(ctx).Order("opened_at DESC")
+	if filter.DormID != "" {
+		query = query.Where("dorm_id = ?", filter.DormID)
+	}
+	if filter.WasherID != "" {
+		query = query.Where("washer_id = ?", filter.WasherID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.From != nil {
+		query = query.Where("opened_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("opened_at < ?", *filter.To)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	if err := query.Find(&incidents).Error; err != nil {
+		return nil, err
+	}
+	return incidents, nil
+}
+
+func (r *incidentRepo) SaveIncident(ctx context.Context, incident *models.Incident) error {
+	return r.conn.WithContext(ctx).Save(incident).Error
+}
+
+func (r *incidentRepo) FindComments(ctx context.Context, incidentID uint) ([]models.IncidentComment, error) {
+	var comments []models.IncidentComment
+	if err := r.conn.WithContext(ctx).Where("incident_id = ?", incidentID).Order("created_at").Find(&comments).Error; err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+func (r *incidentRepo) SaveComment(ctx context.Context, comment *models.IncidentComment) error {
+	return r.conn.WithContext(ctx).Create(comment).Error
+}
+
+// MTTRByDorm เฉลี่ย duration_seconds ของ incident ที่ปิดในช่วงเวลา (ตาม resolved_at) แยกตามหอ
+func (r *incidentRepo) MTTRByDorm(ctx context.Context, from, to *time.Time) ([]models.DormMTTR, error) {
+	var rows []models.DormMTTR
+	query := r.conn.WithContext(ctx).
+		Model(&models.Incident{}).
+		Select("dorm_id, COUNT(*) AS incidents, AVG(duration_seconds) AS mttr_seconds").
+		Where("status = ? AND duration_seconds IS NOT NULL", models.IncidentResolved).
+		Group("dorm_id").
+		Order("dorm_id")
+	if from != nil {
+		query = query.Where("resolved_at >= ?", *from)
+	}
+	if to != nil {
+		query = query.Where("resolved_at < ?", *to)
+	}
+
+	if err := query.Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestMTTRByDormQuery(t *testing.T) {
+	from := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		from    *time.Time
+		to      *time.Time
+		want    []string
+		notWant []string
+	}{
+		{
+			name:    "all time",
+			want:    []string{"AVG(duration_seconds) AS mttr_seconds", "status = 'resolved' AND duration_seconds IS NOT NULL", "GROUP BY \"dorm_id\""},
+			notWant: []string{"resolved_at >="},
+		},
+		{
+			name: "window by resolved time",
+			from: &from,
+			to:   &to,
+			want: []string{"resolved_at >= '2026-10-01 00:00:00'", "resolved_at < '2026-11-01 00:00:00'"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, recorder := newDryRunDB(t)
+			// Scan อ่านผลลัพธ์ไม่ได้ใน DryRun แต่ SQL ถูกสร้างครบแล้ว
+			if _, err := NewIncidentRepo(db).MTTRByDorm(context.Background(), tt.from, tt.to); err != nil && !errors.Is(err, gorm.ErrDryRunModeUnsupported) {
+				t.Fatal(err)
+			}
+
+			query := recorder.find(t, "SELECT dorm_id")
+			for _, want := range tt.want {
+				if !strings.Contains(query, want) {
+					t.Errorf("query is missing %s\n%s", want, query)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(query, notWant) {
+					t.Errorf("query should not contain %s\n%s", notWant, query)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jaytnw/bms-service/internal/models"
+	"gorm.io/gorm"
+)
+
+// MaintenanceFilter กรองช่วงซ่อมบำรุง ActiveAt ไม่เป็น nil จะคืนเฉพาะช่วงที่มีผล ณ เวลานั้น
+type MaintenanceFilter struct {
+	DormID   string
+	WasherID string
+	// Open คืนเฉพาะช่วงที่ยังไม่ปิด (ended_at IS NULL) รวมถึงช่วงที่ยังไม่ถึงเวลาเริ่ม
+	Open     bool
+	ActiveAt *time.Time
+	Limit    int
+}
+
+type MaintenanceRepository interface {
+	FindWindowByID(ctx context.Context, id uint) (*models.MaintenanceWindow, error)
+	FindWindows(ctx context.Context, filter MaintenanceFilter) ([]models.MaintenanceWindow, error)
+	SaveWindow(ctx context.Context, window *models.MaintenanceWindow) error
+}
+
+type maintenanceRepo struct {
+	conn *gorm.DB
+}
+
+func NewMaintenanceRepo(conn *gorm.DB) MaintenanceRepository {
+	return &maintenanceRepo{
+		conn: conn,
+	}
+}
+
+func (r *maintenanceRepo) FindWindowByID(ctx context.Context, id uint) (*models.MaintenanceWindow, error) {
+	var window models.MaintenanceWindow
+	if err := r.conn.WithContext(ctx).First(&window, id).Error; err != nil {
+		return nil, err
+	}
+	return &window, nil
+}
+
+func (r *maintenanceRepo) FindWindows(ctx context.Context, filter MaintenanceFilter) ([]models.MaintenanceWindow, error) {
+	var windows []models.MaintenanceWindow
+	query := r.conn.WithContext(ctx).Order("starts_at DESC")
+	if filter.DormID != "" {
+		query = query.Where("dorm_id = ?", filter.DormID)
+	}
+	if filter.WasherID != "" {
+		query = query.Where("washer_id = ?", filter.WasherID)
+	}
+	if filter.Open {
+		query = query.Where("ended_at IS NULL")
+	}
+	if filter.ActiveAt != nil {
+		query = query.Where("ended_at IS NULL AND starts_at <= ? AND (expected_end_at IS NULL OR expected_end_at > ?)", *filter.ActiveAt, *filter.ActiveAt)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	if err := query.Find(&windows).Error; err != nil {
+		return nil, err
+	}
+	return windows, nil
+}
+
+func (r *maintenanceRepo) SaveWindow(ctx context.Context, window *models.MaintenanceWindow) error {
+	return r.conn.WithContext(ctx).Save(window).Error
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jaytnw/bms-service/internal/models"
+	"gorm.io/gorm"
+)
+
+// DeliveryFilter กรอง delivery log (ค่าว่างคือไม่กรอง)
+type DeliveryFilter struct {
+	Channel string
+	Status  string
+	Limit   int
+}
+
+type NotificationRepository interface {
+	SaveDelivery(ctx context.Context, delivery *models.NotificationDelivery) error
+	FindDeliveries(ctx context.Context, filter DeliveryFilter) ([]models.NotificationDelivery, error)
+
+	FindSubscriptionByID(ctx context.Context, id uint) (*models.WasherSubscription, error)
+	FindActiveSubscriptions(ctx context.Context, washerID string) ([]models.WasherSubscription, error)
+	SaveSubscription(ctx context.Context, subscription *models.WasherSubscription) error
+}
+
+type notificationRepo struct {
+	conn *gorm.DB
+}
+
+func NewNotificationRepo(conn *gorm.DB) NotificationRepository {
+	return &notificationRepo{
+		conn: conn,
+	}
+}
+
+func (r *notificationRepo) SaveDelivery(ctx context.Context, delivery *models.NotificationDelivery) error {
+	return r.conn.WithContext(ctx).Create(delivery).Error
+}
+
+func (r *notificationRepo) FindDeliveries(ctx context.Context, filter DeliveryFilter) ([]models.NotificationDelivery, error) {
+	var deliveries []models.NotificationDelivery
+	query := r.conn.WithContext(ctx).Order("created_at DESC")
+	if filter.Channel != "" {
+		query = query.Where("channel = ?", filter.Channel)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	if err := query.Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+func (r *notificationRepo) FindSubscriptionByID(ctx context.Context, id uint) (*models.WasherSubscription, error) {
+	var subscription models.WasherSubscription
+	if err := r.conn.WithContext(ctx).First(&subscription, id).Error; err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+func (r *notificationRepo) FindActiveSubscriptions(ctx context.Context, washerID string) ([]models.WasherSubscription, error) {
+	var subscriptions []models.WasherSubscription
+	err := r.conn.WithContext(ctx).
+		Where("washer_id = ? AND active", washerID).
+		Order("created_at").
+		Find(&subscriptions).Error
+	if err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+func (r *notificationRepo) SaveSubscription(ctx context.Context, subscription *models.WasherSubscription) error {
+	return r.conn.WithContext(ctx).Save(subscription).Error
+}
@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jaytnw/bms-service/internal/models"
+	"gorm.io/gorm"
+)
+
+type QueueRepository interface {
+	FindEntryByID(ctx context.Context, id uint) (*models.QueueEntry, error)
+	FindActiveEntries(ctx context.Context, dormID string) ([]models.QueueEntry, error)
+	FindActiveByUser(ctx context.Context, dormID, userID string) (*models.QueueEntry, error)
+	FindHeldByWasherID(ctx context.Context, washerID string) (*models.QueueEntry, error)
+	FindExpiredOffers(ctx context.Context, now time.Time) ([]models.QueueEntry, error)
+	FindStaleClaims(ctx context.Context, claimedBefore time.Time) ([]models.QueueEntry, error)
+	FindWaitingDormIDs(ctx context.Context) ([]string, error)
+	SaveEntry(ctx context.Context, entry *models.QueueEntry) error
+	UpdateEntryIf(ctx context.Context, entry *models.QueueEntry, from ...models.QueueEntryStatus) (bool, error)
+}
+
+// activeQueueStatuses ตรงกับ models.QueueEntry.Active
+var activeQueueStatuses = []models.QueueEntryStatus{models.QueueWaiting, models.QueueOffered, models.QueueClaimed}
+
+type queueRepo struct {
+	conn *gorm.DB
+}
+
+func NewQueueRepo(conn *gorm.DB) QueueRepository {
+	return &queueRepo{
+		conn: conn,
+	}
+}
+
+func (r *queueRepo) FindEntryByID(ctx context.Context, id uint) (*models.QueueEntry, error) {
+	var entry models.QueueEntry
+	if err := r.conn.WithContext(ctx).First(&entry, id).Error; err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// FindActiveEntries คืนคิวที่ยังรออยู่หรือถือครองเครื่องอยู่ของหอ เรียงตามลำดับการต่อคิว
+func (r *queueRepo) FindActiveEntries(ctx context.Context, dormID string) ([]models.QueueEntry, error) {
+	var entries []models.QueueEntry
+	err := r.conn.WithContext(ctx).
+		Where("dorm_id = ? AND status IN ?", dormID, activeQueueStatuses).
+		Order("created_at, id").
+		Find(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// FindActiveByUser คืนคิวที่ยัง active ของผู้ใช้ในหอ หรือ nil หากไม่มี
+func (r *queueRepo) FindActiveByUser(ctx context.Context, dormID, userID string) (*models.QueueEntry, error) {
+	var entries []models.QueueEntry
+	err := r.conn.WithContext(ctx).
+		Where("dorm_id = ? AND user_id = ? AND status IN ?", dormID, userID, activeQueueStatuses).
+		Limit(1).
+		Find(&entries).Error
+	if err != nil || len(entries) == 0 {
+		return nil, err
+	}
+	return &entries[0], nil
+}
+
+// FindHeldByWasherID คืนคิวที่ได้รับข้อเสนอหรือ claim เครื่องนี้ไว้ หรือ nil หากไม่มี
+func (r *queueRepo) FindHeldByWasherID(ctx context.Context, washerID string) (*models.QueueEntry, error) {
+	var entries []models.QueueEntry
+	err := r.conn.WithContext(ctx).
+		Where("washer_id = ? AND status IN ?", washerID, models.HeldStatuses).
+		Limit(1).
+		Find(&entries).Error
+	if err != nil || len(entries) == 0 {
+		return nil, err
+	}
+	return &entries[0], nil
+}
+
+func (r *queueRepo) FindExpiredOffers(ctx context.Context, now time.Time) ([]models.QueueEntry, error) {
+	var entries []models.QueueEntry
+	err := r.conn.WithContext(ctx).
+		Where("status = ? AND claim_expires_at < ?", models.QueueOffered, now).
+		Order("claim_expires_at").
+		Find(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// FindStaleClaims คืนคิวที่ claim ไว้ก่อน claimedBefore แต่เครื่องยังไม่เริ่มทำงาน
+func (r *queueRepo) FindStaleClaims(ctx context.Context, claimedBefore time.Time) ([]models.QueueEntry, error) {
+	var entries []models.QueueEntry
+	err := r.conn.WithContext(ctx).
+		Where("status = ? AND claimed_at < ?", models.QueueClaimed, claimedBefore).
+		Order("claimed_at").
+		Find(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// FindWaitingDormIDs คืนรหัสหอที่ยังมีคิวรอเครื่องอยู่
+func (r *queueRepo) FindWaitingDormIDs(ctx context.Context) ([]string, error) {
+	var dormIDs []string
+	err := r.conn.WithContext(ctx).
+		Model(&models.QueueEntry{}).
+		Where("status = ?", models.QueueWaiting).
+		Distinct().
+		Pluck("dorm_id", &dormIDs).Error
+	if err != nil {
+		return nil, err
+	}
+	return dormIDs, nil
+}
+
+func (r *queueRepo) SaveEntry(ctx context.Context, entry *models.QueueEntry) error {
+	return r.conn.WithContext(ctx).Save(entry).Error
+}
+
+// UpdateEntryIf บันทึก entry เฉพาะเมื่อสถานะในฐานข้อมูลยังเป็นหนึ่งใน from คืน false หากสถานะเปลี่ยนไปแล้ว
+// หรือเครื่องถูกคิวอื่นถือครองอยู่ (unique index idx_queue_washer_held) ใช้แทน lock ระหว่าง goroutine และ instance
+func (r *queueRepo) UpdateEntryIf(ctx context.Context, entry *models.QueueEntry, from ...models.QueueEntryStatus) (bool, error) {
+	result := r.conn.WithContext(ctx).
+		Model(entry).
+		Where("status IN ?", from).
+		Select("status", "washer_id", "offered_at", "claim_expires_at", "claimed_at", "updated_at").
+		Updates(entry)
+	if result.Error != nil {
+		err := result.Error
+		if translator, ok := r.conn.Dialector.(gorm.ErrorTranslator); ok {
+			err = translator.Translate(err)
+		}
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return false, nil
+		}
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
@@ -0,0 +1,202 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jaytnw/bms-service/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RegistryRepository interface {
+	FindDorms(ctx context.Context) ([]models.Dorm, error)
+	FindDormByID(ctx context.Context, id string) (*models.Dorm, error)
+	SaveDorm(ctx context.Context, dorm *models.Dorm) error
+	DeleteDorm(ctx context.Context, id string) error
+	UpsertDorms(ctx context.Context, dorms []models.Dorm) error
+	FindDormsByStatus(ctx context.Context, status string) ([]models.Dorm, error)
+	CreateDormIfMissing(ctx context.Context, dorm *models.Dorm) (bool, error)
+
+	FindMachines(ctx context.Context, dormID string) ([]models.Machine, error)
+	FindMachineByID(ctx context.Context, id string) (*models.Machine, error)
+	SaveMachine(ctx context.Context, machine *models.Machine) error
+	DeleteMachine(ctx context.Context, id string) error
+	UpsertMachines(ctx context.Context, machines []models.Machine) error
+	SoftDeleteMachinesNotIn(ctx context.Context, source string, keepIDs []string) (int64, error)
+	FindMachinesByStatus(ctx context.Context, status string) ([]models.Machine, error)
+	CreateMachineIfMissing(ctx context.Context, machine *models.Machine) (bool, error)
+}
+
+type registryRepo struct {
+	conn *gorm.DB
+}
+
+func NewRegistryRepo(conn *gorm.DB) RegistryRepository {
+	return &registryRepo{
+		conn: conn,
+	}
+}
+
+func (r *registryRepo) FindDorms(ctx context.Context) ([]models.Dorm, error) {
+	var dorms []models.Dorm
+	if err := r.conn.WithContext(ctx).Order("id").Find(&dorms).Error; err != nil {
+		return nil, err
+	}
+	return dorms, nil
+}
+
+func (r *registryRepo) FindDormByID(ctx context.Context, id string) (*models.Dorm, error) {
+	var dorm models.Dorm
+	if err := r.conn.WithContext(ctx).First(&dorm, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &dorm, nil
+}
+
+func (r *registryRepo) SaveDorm(ctx context.Context, dorm *models.Dorm) error {
+	return r.conn.WithContext(ctx).Save(dorm).Error
+}
+
+// DeleteDorm soft-delete หอที่ลบผ่าน API พร้อมบันทึก edited_at เพื่อไม่ให้การ sync กู้คืน
+func (r *registryRepo) DeleteDorm(ctx context.Context, id string) error {
+	now := time.Now()
+	result := r.conn.WithContext(ctx).Model(&models.Dorm{}).Where("id = ?", id).
+		Updates(map[string]any{"edited_at": now, "deleted_at": now})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// UpsertDorms เพิ่มหรืออัปเดตหอจากระบบภายนอก และกู้คืนหอที่เคยถูก soft-delete
+// ชื่อว่างไม่ทับชื่อเดิม หอที่สร้างหรืออนุมัติในระบบนี้คง source/status เดิมไว้
+// และหอที่ผู้ดูแลแก้ไขหรือลบผ่าน API แล้วคงชื่อและ deleted_at เดิมไว้
+func (r *registryRepo) UpsertDorms(ctx context.Context, dorms []models.Dorm) error {
+	if len(dorms) == 0 {
+		return nil
+	}
+
+	return r.conn.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "id"}},
+			DoUpdates: externalUpsertAssignments("dorms"),
+		}).
+		Create(&dorms).Error
+}
+
+// externalUpsertAssignments สร้างคำสั่ง DO UPDATE ของการ sync จากระบบภายนอก
+// name อัปเดตเมื่อค่าใหม่ไม่ว่าง status อัปเดตเฉพาะแถวที่มาจากระบบภายนอกอยู่แล้ว และไม่แตะ source
+// แถวที่ผู้ดูแลแก้ไขหรือลบผ่าน API (edited_at ไม่ว่าง) คง name, deleted_at และคอลัมน์ใน guarded ไว้ตามเดิม
+func externalUpsertAssignments(table string, guarded ...string) clause.Set {
+	set := clause.Set{
+		{Column: clause.Column{Name: "name"}, Value: gorm.Expr(fmt.Sprintf(`CASE WHEN %[1]q."edited_at" IS NULL AND "excluded"."name" <> '' THEN "excluded"."name" ELSE %[1]q."name" END`, table))},
+		{Column: clause.Column{Name: "status"}, Value: gorm.Expr(fmt.Sprintf(`CASE WHEN %[1]q."source" = ? THEN "excluded"."status" ELSE %[1]q."status" END`, table), models.SourceExternal)},
+		{Column: clause.Column{Name: "updated_at"}, Value: gorm.Expr(`"excluded"."updated_at"`)},
+	}
+	for _, column := range append(guarded, "deleted_at") {
+		set = append(set, clause.Assignment{
+			Column: clause.Column{Name: column},
+			Value:  gorm.Expr(fmt.Sprintf(`CASE WHEN %[1]q."edited_at" IS NULL THEN "excluded".%[2]q ELSE %[1]q.%[2]q END`, table, column)),
+		})
+	}
+	return set
+}
+
+func (r *registryRepo) FindDormsByStatus(ctx context.Context, status string) ([]models.Dorm, error) {
+	var dorms []models.Dorm
+	if err := r.conn.WithContext(ctx).Where("status = ?", status).Order("created_at").Find(&dorms).Error; err != nil {
+		return nil, err
+	}
+	return dorms, nil
+}
+
+// CreateDormIfMissing เพิ่มหอเฉพาะเมื่อยังไม่เคยมี ID นี้ (รวมถึงหอที่ถูก soft-delete) คืน true เมื่อเพิ่มใหม่
+func (r *registryRepo) CreateDormIfMissing(ctx context.Context, dorm *models.Dorm) (bool, error) {
+	result := r.conn.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(dorm)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *registryRepo) FindMachines(ctx context.Context, dormID string) ([]models.Machine, error) {
+	var machines []models.Machine
+	query := r.conn.WithContext(ctx).Order("dorm_id, id")
+	if dormID != "" {
+		query = query.Where("dorm_id = ?", dormID)
+	}
+
+	if err := query.Find(&machines).Error; err != nil {
+		return nil, err
+	}
+	return machines, nil
+}
+
+func (r *registryRepo) FindMachineByID(ctx context.Context, id string) (*models.Machine, error) {
+	var machine models.Machine
+	if err := r.conn.WithContext(ctx).First(&machine, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &machine, nil
+}
+
+func (r *registryRepo) SaveMachine(ctx context.Context, machine *models.Machine) error {
+	return r.conn.WithContext(ctx).Save(machine).Error
+}
+
+// DeleteMachine soft-delete เครื่องที่ลบผ่าน API พร้อมบันทึก edited_at เพื่อไม่ให้การ sync กู้คืน
+func (r *registryRepo) DeleteMachine(ctx context.Context, id string) error {
+	now := time.Now()
+	result := r.conn.WithContext(ctx).Model(&models.Machine{}).Where("id = ?", id).
+		Updates(map[string]any{"edited_at": now, "deleted_at": now})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// UpsertMachines เพิ่มหรืออัปเดตเครื่องจากระบบภายนอก และกู้คืนเครื่องที่เคยถูก soft-delete
+// ใช้กฎเดียวกับ UpsertDorms และไม่ย้ายหอของเครื่องที่ผู้ดูแลแก้ไขแล้ว
+func (r *registryRepo) UpsertMachines(ctx context.Context, machines []models.Machine) error {
+	if len(machines) == 0 {
+		return nil
+	}
+
+	return r.conn.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "id"}},
+			DoUpdates: externalUpsertAssignments("machines", "dorm_id"),
+		}).
+		CreateInBatches(&machines, 500).Error
+}
+
+// SoftDeleteMachinesNotIn soft-delete เครื่องจาก source ที่ระบุซึ่งไม่อยู่ใน keepIDs
+// เครื่องที่ผู้ดูแลแก้ไขแล้วไม่ถูกลบ เพราะการ sync ไม่แตะแถวเหล่านั้น
+func (r *registryRepo) SoftDeleteMachinesNotIn(ctx context.Context, source string, keepIDs []string) (int64, error) {
+	query := r.conn.WithContext(ctx).Where("source = ? AND edited_at IS NULL", source)
+	if len(keepIDs) > 0 {
+		query = query.Where("id NOT IN ?", keepIDs)
+	}
+
+	result := query.Delete(&models.Machine{})
+	return result.RowsAffected, result.Error
+}
+
+func (r *registryRepo) FindMachinesByStatus(ctx context.Context, status string) ([]models.Machine, error) {
+	var machines []models.Machine
+	if err := r.conn.WithContext(ctx).Where("status = ?", status).Order("created_at").Find(&machines).Error; err != nil {
+		return nil, err
+	}
+	return machines, nil
+}
+
+// CreateMachineIfMissing เพิ่มเครื่องเฉพาะเมื่อยังไม่เคยมี ID นี้ (รวมถึงเครื่องที่ถูก soft-delete) คืน true เมื่อเพิ่มใหม่
+func (r *registryRepo) CreateMachineIfMissing(ctx context.Context, machine *models.Machine) (bool, error) {
+	result := r.conn.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(machine)
+	return result.RowsAffected > 0, result.Error
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jaytnw/bms-service/internal/models"
+)
+
+func TestExternalUpsertKeepsAdminEdits(t *testing.T) {
+	db, recorder := newDryRunDB(t)
+	repo := NewRegistryRepo(db)
+
+	machines := []models.Machine{{ID: "w1", DormID: "d1", Source: models.SourceExternal, Status: models.RegistrationActive}}
+	if err := repo.UpsertMachines(context.Background(), machines); err != nil {
+		t.Fatal(err)
+	}
+	upsert := recorder.find(t, `INSERT INTO "machines"`)
+
+	for _, want := range []string{
+		`"dorm_id"=CASE WHEN "machines"."edited_at" IS NULL THEN "excluded"."dorm_id" ELSE "machines"."dorm_id" END`,
+		`"deleted_at"=CASE WHEN "machines"."edited_at" IS NULL THEN "excluded"."deleted_at" ELSE "machines"."deleted_at" END`,
+		`"name"=CASE WHEN "machines"."edited_at" IS NULL AND "excluded"."name" <> '' THEN "excluded"."name" ELSE "machines"."name" END`,
+		`"status"=CASE WHEN "machines"."source" = 'external' THEN "excluded"."status" ELSE "machines"."status" END`,
+	} {
+		if !strings.Contains(upsert, want) {
+			t.Errorf("upsert is missing %s\n%s", want, upsert)
+		}
+	}
+	if strings.Contains(upsert, `"deleted_at"="excluded"."deleted_at"`) || strings.Contains(upsert, `"dorm_id"="excluded"."dorm_id"`) {
+		t.Errorf("upsert overwrites admin edits unconditionally\n%s", upsert)
+	}
+}
+
+func TestDeleteThroughAPIMarksEdited(t *testing.T) {
+	tests := []struct {
+		name   string
+		delete func(RegistryRepository) error
+		table  string
+	}{
+		{name: "machine", delete: func(r RegistryRepository) error { return r.DeleteMachine(context.Background(), "w1") }, table: "machines"},
+		{name: "dorm", delete: func(r RegistryRepository) error { return r.DeleteDorm(context.Background(), "d1") }, table: "dorms"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, recorder := newDryRunDB(t)
+			// DryRun ไม่มีแถวที่ถูกกระทบ repository จึงคืน not found แต่ยังได้ SQL ครบ
+			_ = tt.delete(NewRegistryRepo(db))
+
+			update := recorder.find(t, `UPDATE "`+tt.table+`"`)
+			if !strings.Contains(update, `"deleted_at"=`) || !strings.Contains(update, `"edited_at"=`) {
+				t.Fatalf("delete does not set deleted_at and edited_at\n%s", update)
+			}
+			if !strings.Contains(update, `"`+tt.table+`"."deleted_at" IS NULL`) {
+				t.Fatalf("delete touches rows that are already deleted\n%s", update)
+			}
+		})
+	}
+}
+
+func TestSyncRemovalSkipsEditedMachines(t *testing.T) {
+	db, recorder := newDryRunDB(t)
+	if _, err := NewRegistryRepo(db).SoftDeleteMachinesNotIn(context.Background(), models.SourceExternal, []string{"w1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	update := recorder.find(t, `UPDATE "machines"`)
+	if !strings.Contains(update, "edited_at IS NULL") {
+		t.Fatalf("sync removal does not skip edited machines\n%s", update)
+	}
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jaytnw/bms-service/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RollupQuery กรอง rollup ตามความละเอียด เครื่องหรือหอ และช่วง bucket_start [From, To)
+type RollupQuery struct {
+	Granularity string
+	WasherID    string
+	DormID      string
+	From        *time.Time
+	To          *time.Time
+	Limit       int
+}
+
+type RollupRepository interface {
+	UpsertRollups(ctx context.Context, rollups []models.StatusRollup) error
+	FindRollups(ctx context.Context, query RollupQuery) ([]models.StatusRollup, error)
+	FindCheckpoint(ctx context.Context, name string) (*models.RollupCheckpoint, error)
+	SaveCheckpoint(ctx context.Context, checkpoint *models.RollupCheckpoint) error
+}
+
+type rollupRepo struct {
+	conn *gorm.DB
+}
+
+func NewRollupRepo(conn *gorm.DB) RollupRepository {
+	return &rollupRepo{
+		conn: conn,
+	}
+}
+
+// UpsertRollups เขียนทับ bucket เดิม (granularity, washer_id, bucket_start เดียวกัน) จึงรันซ้ำได้
+func (r *rollupRepo) UpsertRollups(ctx context.Context, rollups []models.StatusRollup) error {
+	if len(rollups) == 0 {
+		return nil
+	}
+
+	return r.conn.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "granularity"}, {Name: "washer_id"}, {Name: "bucket_start"}},
+			DoUpdates: clause.AssignmentColumns([]string{"dorm_id", "state_seconds", "busy_seconds", "transitions", "cycles", "completed", "end_state", "end_in_cycle", "updated_at"}),
+		}).
+		CreateInBatches(rollups, 500).Error
+}
+
+func (r *rollupRepo) FindRollups(ctx context.Context, query RollupQuery) ([]models.StatusRollup, error) {
+	var rollups []models.StatusRollup
+	db := r.conn.WithContext(ctx).Order("bucket_start, washer_id")
+	if query.Granularity != "" {
+		db = db.Where("granularity = ?", query.Granularity)
+	}
+	if query.WasherID != "" {
+		db = db.Where("washer_id = ?", query.WasherID)
+	}
+	if query.DormID != "" {
+		db = db.Where("dorm_id = ?", query.DormID)
+	}
+	if query.From != nil {
+		db = db.Where("bucket_start >= ?", *query.From)
+	}
+	if query.To != nil {
+		db = db.Where("bucket_start < ?", *query.To)
+	}
+	if query.Limit > 0 {
+		db = db.Limit(query.Limit)
+	}
+
+	if err := db.Find(&rollups).Error; err != nil {
+		return nil, err
+	}
+	return rollups, nil
+}
+
+// FindCheckpoint คืน checkpoint ตามชื่อ หรือ nil หากยังไม่เคยบันทึก
+func (r *rollupRepo) FindCheckpoint(ctx context.Context, name string) (*models.RollupCheckpoint, error) {
+	var checkpoint models.RollupCheckpoint
+	err := r.conn.WithContext(ctx).First(&checkpoint, "name = ?", name).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &checkpoint, nil
+}
+
+func (r *rollupRepo) SaveCheckpoint(ctx context.Context, checkpoint *models.RollupCheckpoint) error {
+	return r.conn.WithContext(ctx).Save(checkpoint).Error
+}
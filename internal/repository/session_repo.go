@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jaytnw/bms-service/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SessionQuery กรองรอบซักตามเครื่องหรือหอ และช่วงเวลาที่เริ่มรอบ
+// Overlapping เป็น true จะคืนรอบซักที่คาบเกี่ยวกับช่วง From/To แทน (รวมรอบที่ยังไม่จบ)
+type SessionQuery struct {
+	WasherID    string
+	DormID      string
+	Outcome     models.SessionOutcome
+	Program     string
+	From        *time.Time
+	To          *time.Time
+	Overlapping bool
+	Limit       int
+}
+
+type SessionRepository interface {
+	FindOpenByWasherID(ctx context.Context, washerID string) (*models.WashSession, error)
+	SaveSession(ctx context.Context, session *models.WashSession) error
+	UpsertSessions(ctx context.Context, sessions []models.WashSession) error
+	FindSessions(ctx context.Context, query SessionQuery) ([]models.WashSession, error)
+}
+
+type sessionRepo struct {
+	conn *gorm.DB
+}
+
+func NewSessionRepo(conn *gorm.DB) SessionRepository {
+	return &sessionRepo{
+		conn: conn,
+	}
+}
+
+// FindOpenByWasherID คืนรอบซักที่ยังไม่จบของเครื่อง หรือ nil หากไม่มี
+func (r *sessionRepo) FindOpenByWasherID(ctx context.Context, washerID string) (*models.WashSession, error) {
+	var session models.WashSession
+	err := r.conn.WithContext(ctx).
+		Where("washer_id = ? AND ended_at IS NULL", washerID).
+		Order("started_at DESC").
+		First(&session).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *sessionRepo) SaveSession(ctx context.Context, session *models.WashSession) error {
+	return r.conn.WithContext(ctx).Save(session).Error
+}
+
+// UpsertSessions ใช้ตอน backfill: รอบซักที่มีอยู่แล้ว (washer_id, started_at เดียวกัน) จะถูกอัปเดตแทน
+// program ไม่ถูกเขียนทับ เพราะ backfill สร้างรอบจากตาราง statuses ซึ่งไม่มีโปรแกรมซัก
+func (r *sessionRepo) UpsertSessions(ctx context.Context, sessions []models.WashSession) error {
+	if len(sessions) == 0 {
+		return nil
+	}
+
+	return r.conn.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "washer_id"}, {Name: "started_at"}},
+			DoUpdates: clause.AssignmentColumns([]string{"dorm_id", "ended_at", "duration_seconds", "outcome", "updated_at"}),
+		}).
+		CreateInBatches(sessions, 500).Error
+}
+
+func (r *sessionRepo) FindSessions(ctx context.Context, query SessionQuery) ([]models.WashSession, error) {
+	var sessions []models.WashSession
+	db := r.conn.WithContext(ctx).Order("started_at DESC")
+	if query.WasherID != "" {
+		db = db.Where("washer_id = ?", query.WasherID)
+	}
+	if query.DormID != "" {
+		db = db.Where("dorm_id = ?", query.DormID)
+	}
+	if query.Outcome != "" {
+		db = db.Where("outcome = ?", query.Outcome)
+	}
+	if query.Program != "" {
+		db = db.Where("program = ?", query.Program)
+	}
+	switch {
+	case query.Overlapping:
+		if query.From != nil {
+			db = db.Where("(ended_at IS NULL OR ended_at > ?)", *query.From)
+		}
+		if query.To != nil {
+			db = db.Where("started_at < ?", *query.To)
+		}
+	default:
+		if query.From != nil {
+			db = db.Where("started_at >= ?", *query.From)
+		}
+		if query.To != nil {
+			db = db.Where("started_at < ?", *query.To)
+		}
+	}
+	if query.Limit > 0 {
+		db = db.Limit(query.Limit)
+	}
+
+	if err := db.Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
@@ -2,18 +2,86 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
 
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jaytnw/bms-service/internal/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// recentStatusWindow คือช่วงที่ค้นสถานะล่าสุดก่อน ทำให้ Postgres อ่านเฉพาะ partition ล่าสุด
+// หากไม่พบในช่วงนี้จึงค้นทั้งตาราง
+const recentStatusWindow = 45 * 24 * time.Hour
+
+// statusPartitionPrefix คือคำนำหน้าชื่อ partition รายเดือนของ statuses (statuses_pYYYYMM ตามเดือน UTC)
+const statusPartitionPrefix = "statuses_p"
+
+// IsDataError บอกว่า err มาจากข้อมูลที่ Postgres ไม่ยอมรับ (class 22 data exception หรือ 23 integrity constraint)
+// การลองซ้ำจะได้ผลเดิม ต่างจากการเชื่อมต่อหลุดหรือ timeout ที่ลองใหม่ได้
+func IsDataError(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return strings.HasPrefix(pgErr.Code, "22") || strings.HasPrefix(pgErr.Code, "23")
+}
+
+// StatusPartition คือ partition รายเดือนของตาราง statuses ครอบคลุมช่วง [From, To)
+type StatusPartition struct {
+	Name string
+	From time.Time
+	To   time.Time
+	// DetachPending คือ partition ที่ DETACH ... CONCURRENTLY ค้างอยู่เพราะถูกขัดจังหวะ
+	DetachPending bool
+}
+
+// CurrentStatusRebuild คือจำนวนแถวของ washer_current_status ที่ RebuildCurrentStatuses เขียนและลบ
+type CurrentStatusRebuild struct {
+	Written int64
+	Deleted int64
+}
+
+// StatusCursor คือตำแหน่งของแถวสุดท้ายในหน้าก่อนหน้า ใช้แบ่งหน้าแบบ keyset ตาม (created_at, id)
+type StatusCursor struct {
+	CreatedAt time.Time
+	ID        uint
+}
+
+// StatusQuery กำหนดเงื่อนไขการค้นสถานะ (ค่าว่างคือไม่กรอง) Limit เป็น 0 คือไม่จำกัด
+type StatusQuery struct {
+	DormID   string
+	WasherID string
+	Statuses []models.WasherState
+	From     *time.Time
+	To       *time.Time
+	// Ascending เรียงจากเก่าไปใหม่ ค่าเริ่มต้นคือใหม่ไปเก่า
+	Ascending bool
+	After     *StatusCursor
+	// AfterID กรองเฉพาะแถวที่ id มากกว่าค่านี้ ใช้หาแถวที่เข้ามาหลัง archive
+	AfterID uint
+	Limit   int
+}
+
 type StatusRepository interface {
-	FindAll(ctx context.Context) ([]models.Status, error)
-	SaveStatus(ctx context.Context, status *models.Status) error
+	FindStatuses(ctx context.Context, query StatusQuery) ([]models.Status, error)
+	SaveStatuses(ctx context.Context, statuses []*models.Status) error
 	FindLatestByWasherID(ctx context.Context, washerID string) (*models.Status, error)
-	FindHistoryByWasherID(ctx context.Context, washerID string) ([]models.Status, error)
+	FindLatestPerWasher(ctx context.Context, dormID string) ([]models.Status, error)
+	FindLatestByWasherIDs(ctx context.Context, washerIDs []string) ([]models.Status, error)
+	RebuildCurrentStatuses(ctx context.Context, force bool) (CurrentStatusRebuild, error)
+	FindLatestPerWasherBefore(ctx context.Context, before time.Time) ([]models.Status, error)
 	FindHistoryByWasherIDs(ctx context.Context, washerIDs []string) ([]models.Status, error)
-	FindLatest50HistoryByWasherIDs(ctx context.Context, washerIDs []string) ([]models.Status, error)
+	FindRecentHistoryByWasherIDs(ctx context.Context, washerIDs []string, perWasher int, since *time.Time) ([]models.Status, error)
+	ForEachStatus(ctx context.Context, fn func(models.Status) error) error
+	DeleteStatusesBetween(ctx context.Context, from, to time.Time, maxID uint, limit int) (int64, error)
+	InsertStatuses(ctx context.Context, statuses []models.Status) (int64, error)
+	EnsurePartitions(ctx context.Context, from, to time.Time) error
+	FindPartitions(ctx context.Context) ([]StatusPartition, error)
+	DropPartitionIfEmpty(ctx context.Context, partition StatusPartition) (bool, error)
 }
 
 type statusRepo struct {
@@ -26,72 +94,403 @@ func NewStatusRepo(conn *gorm.DB) StatusRepository {
 	}
 }
 
-func (r *statusRepo) FindAll(ctx context.Context) ([]models.Status, error) {
+func (r *statusRepo) FindStatuses(ctx context.Context, q StatusQuery) ([]models.Status, error) {
 	var statuses []models.Status
-	result := r.conn.Find(&statuses)
-	if result.Error != nil {
-		return nil, result.Error
+	query := r.conn.WithContext(ctx)
+	if q.DormID != "" {
+		query = query.Where("dorm_id = ?", q.DormID)
+	}
+	if q.WasherID != "" {
+		query = query.Where("washer_id = ?", q.WasherID)
+	}
+	if len(q.Statuses) > 0 {
+		query = query.Where("status IN ?", q.Statuses)
+	}
+	if q.From != nil {
+		query = query.Where("created_at >= ?", *q.From)
+	}
+	if q.To != nil {
+		query = query.Where("created_at < ?", *q.To)
+	}
+	if q.AfterID > 0 {
+		query = query.Where("id > ?", q.AfterID)
+	}
+
+	if q.Ascending {
+		if q.After != nil {
+			// เงื่อนไข created_at แยกช่วยให้ Postgres ตัด partition ได้ ซึ่งการเทียบแบบ row ทำไม่ได้
+			query = query.Where("created_at >= ? AND (created_at, id) > (?, ?)", q.After.CreatedAt, q.After.CreatedAt, q.After.ID)
+		}
+		query = query.Order("created_at, id")
+	} else {
+		if q.After != nil {
+			query = query.Where("created_at <= ? AND (created_at, id) < (?, ?)", q.After.CreatedAt, q.After.CreatedAt, q.After.ID)
+		}
+		query = query.Order("created_at DESC, id DESC")
+	}
+	if q.Limit > 0 {
+		query = query.Limit(q.Limit)
 	}
 
+	if err := query.Find(&statuses).Error; err != nil {
+		return nil, err
+	}
 	return statuses, nil
 }
 
-func (r *statusRepo) SaveStatus(ctx context.Context, status *models.Status) error {
-	return r.conn.WithContext(ctx).Create(status).Error
+// SaveStatuses บันทึกสถานะหลายแถวด้วย multi-row insert และอัปเดต washer_current_status ใน transaction เดียวกัน
+// แถวใน washer_current_status ถูกแทนเฉพาะเมื่อสถานะใหม่ไม่เก่ากว่าของเดิม
+func (r *statusRepo) SaveStatuses(ctx context.Context, statuses []*models.Status) error {
+	if len(statuses) == 0 {
+		return nil
+	}
+
+	// upsert แถวเดียวกันซ้ำในคำสั่งเดียวไม่ได้ จึงเหลือสถานะล่าสุดของแต่ละเครื่อง
+	latest := make(map[string]int, len(statuses))
+	currents := make([]models.WasherCurrentStatus, 0, len(statuses))
+	return r.conn.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.CreateInBatches(statuses, 500).Error; err != nil {
+			return err
+		}
+
+		for _, status := range statuses {
+			current := models.NewWasherCurrentStatus(*status)
+			if i, ok := latest[status.WasherID]; ok {
+				if !currents[i].ReportedAt.After(current.ReportedAt) {
+					currents[i] = current
+				}
+				continue
+			}
+			latest[status.WasherID] = len(currents)
+			currents = append(currents, current)
+		}
+
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "washer_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"dorm_id", "status_id", "status", "maintenance", "reported_at", "updated_at"}),
+			Where: clause.Where{Exprs: []clause.Expression{
+				clause.Expr{SQL: `"washer_current_status"."reported_at" <= "excluded"."reported_at"`},
+			}},
+		}).Create(&currents).Error
+	})
 }
 
+// FindLatestByWasherID อ่านจาก washer_current_status คืน Status ว่างหากเครื่องยังไม่เคยมีสถานะ
 func (r *statusRepo) FindLatestByWasherID(ctx context.Context, washerID string) (*models.Status, error) {
-	var status models.Status
+	var current models.WasherCurrentStatus
 	err := r.conn.WithContext(ctx).
 		Where("washer_id = ?", washerID).
-		Order("created_at DESC").
-		Find(&status).Error
-
+		Limit(1).
+		Find(&current).Error
 	if err != nil {
 		return nil, err
 	}
+	if current.WasherID == "" {
+		return &models.Status{}, nil
+	}
+
+	status := current.ToStatus()
 	return &status, nil
 }
 
-func (r *statusRepo) FindHistoryByWasherID(ctx context.Context, washerID string) ([]models.Status, error) {
-	var statuses []models.Status
-	err := r.conn.WithContext(ctx).
-		Where("washer_id = ?", washerID).
-		Order("created_at DESC").
-		Find(&statuses).Error
+// FindLatestPerWasher คืนสถานะล่าสุดของทุกเครื่องจาก washer_current_status เลือกเฉพาะหอได้
+func (r *statusRepo) FindLatestPerWasher(ctx context.Context, dormID string) ([]models.Status, error) {
+	query := r.conn.WithContext(ctx).Order("washer_id")
+	if dormID != "" {
+		query = query.Where("dorm_id = ?", dormID)
+	}
+	return r.findCurrent(query)
+}
 
-	if err != nil {
+// FindLatestByWasherIDs คืนสถานะล่าสุดของเครื่องที่ระบุจาก washer_current_status
+func (r *statusRepo) FindLatestByWasherIDs(ctx context.Context, washerIDs []string) ([]models.Status, error) {
+	if len(washerIDs) == 0 {
+		return nil, nil
+	}
+	return r.findCurrent(r.conn.WithContext(ctx).Where("washer_id IN ?", washerIDs).Order("washer_id"))
+}
+
+func (r *statusRepo) findCurrent(query *gorm.DB) ([]models.Status, error) {
+	var currents []models.WasherCurrentStatus
+	if err := query.Find(&currents).Error; err != nil {
 		return nil, err
 	}
+
+	statuses := make([]models.Status, 0, len(currents))
+	for _, current := range currents {
+		statuses = append(statuses, current.ToStatus())
+	}
 	return statuses, nil
 }
 
+const rebuildCurrentStatusSQL = `
+		INSERT INTO washer_current_status (washer_id, dorm_id, status_id, status, maintenance, reported_at, updated_at)
+		SELECT DISTINCT ON (washer_id) washer_id, dorm_id, id, status, maintenance, created_at, now()
+		FROM statuses
+		ORDER BY washer_id, created_at DESC, id DESC
+		ON CONFLICT (washer_id) DO UPDATE SET
+			dorm_id = excluded.dorm_id,
+			status_id = excluded.status_id,
+			status = excluded.status,
+			maintenance = excluded.maintenance,
+			reported_at = excluded.reported_at,
+			updated_at = excluded.updated_at`
+
+// RebuildCurrentStatuses เขียน washer_current_status ใหม่จากสถานะล่าสุดของแต่ละเครื่องในตาราง statuses
+// โหมดปกติรันได้ระหว่างที่ ingest ทำงาน: แถวจะไม่ถูกแทนด้วยสถานะที่เก่ากว่า และเครื่องที่ไม่มีประวัติเหลือแล้ว (เช่นถูก archive ไป) จะคงแถวเดิมไว้
+// force ใช้เมื่อหยุด ingest แล้วเท่านั้น: แทนทุกแถวด้วยสถานะจากประวัติแม้ของเดิมจะใหม่กว่า และลบแถวของเครื่องที่ไม่มีประวัติ
+func (r *statusRepo) RebuildCurrentStatuses(ctx context.Context, force bool) (CurrentStatusRebuild, error) {
+	if !force {
+		result := r.conn.WithContext(ctx).Exec(rebuildCurrentStatusSQL + `
+		WHERE washer_current_status.reported_at <= excluded.reported_at`)
+		return CurrentStatusRebuild{Written: result.RowsAffected}, result.Error
+	}
+
+	// ลบและเขียนในคำสั่งเดียวจึงเป็น atomic โดยไม่ต้องเปิด transaction เครื่องสองกลุ่มไม่ซ้ำกันจึงไม่ชนกัน
+	var rebuild CurrentStatusRebuild
+	err := r.conn.WithContext(ctx).Raw(`
+		WITH deleted AS (
+			DELETE FROM washer_current_status c
+			WHERE NOT EXISTS (SELECT 1 FROM statuses s WHERE s.washer_id = c.washer_id)
+			RETURNING 1
+		), written AS (` + rebuildCurrentStatusSQL + `
+		RETURNING 1
+		)
+		SELECT (SELECT count(*) FROM written) AS written, (SELECT count(*) FROM deleted) AS deleted`).
+		Scan(&rebuild).Error
+	return rebuild, err
+}
+
 func (r *statusRepo) FindHistoryByWasherIDs(ctx context.Context, washerIDs []string) ([]models.Status, error) {
 	var statuses []models.Status
 	err := r.conn.WithContext(ctx).Where("washer_id IN ?", washerIDs).Find(&statuses).Error
 	return statuses, err
 }
 
-func (r *statusRepo) FindLatest50HistoryByWasherIDs(ctx context.Context, washerIDs []string) ([]models.Status, error) {
+// FindLatestPerWasherBefore คืนสถานะสุดท้ายก่อนเวลา before ของทุกเครื่อง
+func (r *statusRepo) FindLatestPerWasherBefore(ctx context.Context, before time.Time) ([]models.Status, error) {
+	var statuses []models.Status
+	err := r.conn.WithContext(ctx).
+		Select("DISTINCT ON (washer_id) *").
+		Where("created_at < ?", before).
+		Order("washer_id, created_at DESC, id DESC").
+		Find(&statuses).Error
+	if err != nil {
+		return nil, err
+	}
+	return statuses, nil
+}
+
+// FindRecentHistoryByWasherIDs คืนสถานะล่าสุดไม่เกิน perWasher แถวต่อเครื่อง (ใหม่ไปเก่า)
+// since ไม่เป็น nil จะตัดสถานะที่เก่ากว่านั้นออก หากเป็น nil จะค้นใน recentStatusWindow ก่อน
+// แล้วค้นทั้งตารางเฉพาะเครื่องที่ได้ไม่ครบ perWasher แถว
+func (r *statusRepo) FindRecentHistoryByWasherIDs(ctx context.Context, washerIDs []string, perWasher int, since *time.Time) ([]models.Status, error) {
+	if since != nil {
+		return r.findRecentHistory(ctx, washerIDs, perWasher, since)
+	}
+
+	recent := time.Now().Add(-recentStatusWindow)
+	statuses, err := r.findRecentHistory(ctx, washerIDs, perWasher, &recent)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int, len(washerIDs))
+	for _, st := range statuses {
+		counts[st.WasherID]++
+	}
+	var incomplete []string
+	for _, id := range washerIDs {
+		if counts[id] < perWasher {
+			incomplete = append(incomplete, id)
+		}
+	}
+	if len(incomplete) == 0 {
+		return statuses, nil
+	}
+
+	older, err := r.findRecentHistory(ctx, incomplete, perWasher, nil)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]models.Status, 0, len(statuses)+len(older))
+	for _, st := range statuses {
+		if counts[st.WasherID] >= perWasher {
+			result = append(result, st)
+		}
+	}
+	return append(result, older...), nil
+}
+
+func (r *statusRepo) findRecentHistory(ctx context.Context, washerIDs []string, perWasher int, since *time.Time) ([]models.Status, error) {
 	if len(washerIDs) == 0 {
 		return nil, nil
 	}
 
 	var statuses []models.Status
 
+	inner := r.conn.WithContext(ctx).
+		Model(&models.Status{}).
+		Select("*, ROW_NUMBER() OVER (PARTITION BY washer_id ORDER BY created_at DESC, id DESC) AS rn").
+		Where("washer_id IN ?", washerIDs)
+	if since != nil {
+		inner = inner.Where("created_at >= ?", *since)
+	}
+
 	err := r.conn.WithContext(ctx).
-		Raw(`
-		SELECT washer_id, status, created_at
-		FROM (
-			SELECT *, 
-			       ROW_NUMBER() OVER (PARTITION BY washer_id ORDER BY created_at DESC) AS rn
-			FROM statuses
-			WHERE washer_id IN ?
-		) AS ranked
-		WHERE rn <= 100
-		ORDER BY washer_id, created_at DESC
-	`, washerIDs).
+		Table("(?) AS ranked", inner).
+		Select("id, washer_id, status, maintenance, created_at").
+		Where("rn <= ?", perWasher).
+		Order("washer_id, created_at DESC, id DESC").
 		Scan(&statuses).Error
 
 	return statuses, err
 }
+
+// ForEachStatus streams every status ordered by washer and time without loading the table into memory
+func (r *statusRepo) ForEachStatus(ctx context.Context, fn func(models.Status) error) error {
+	rows, err := r.conn.WithContext(ctx).
+		Model(&models.Status{}).
+		Order("washer_id, created_at, id").
+		Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var status models.Status
+		if err := r.conn.ScanRows(rows, &status); err != nil {
+			return err
+		}
+		if err := fn(status); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// DeleteStatusesBetween ลบสถานะในช่วง [from, to) ที่ id ไม่เกิน maxID ครั้งละไม่เกิน limit แถว
+// คืนจำนวนแถวที่ลบ ผู้เรียกวนซ้ำจนได้น้อยกว่า limit เพื่อไม่ให้ transaction ใหญ่เกินไป
+func (r *statusRepo) DeleteStatusesBetween(ctx context.Context, from, to time.Time, maxID uint, limit int) (int64, error) {
+	batch := r.conn.WithContext(ctx).
+		Model(&models.Status{}).
+		Select("id").
+		Where("created_at >= ? AND created_at < ? AND id <= ?", from, to, maxID).
+		Limit(limit)
+
+	// ขอบเขต created_at ซ้ำที่คำสั่ง DELETE ให้ Postgres ตัด partition อื่นออกได้ ไม่ต้องค้น id ทุก partition
+	result := r.conn.WithContext(ctx).
+		Where("created_at >= ? AND created_at < ? AND id IN (?)", from, to, batch).
+		Delete(&models.Status{})
+	return result.RowsAffected, result.Error
+}
+
+// InsertStatuses เพิ่มสถานะโดยคง id เดิม แถวที่มี id อยู่แล้วจะถูกข้าม คืนจำนวนแถวที่เพิ่มจริง
+func (r *statusRepo) InsertStatuses(ctx context.Context, statuses []models.Status) (int64, error) {
+	if len(statuses) == 0 {
+		return 0, nil
+	}
+
+	result := r.conn.WithContext(ctx).
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "id"}, {Name: "created_at"}}, DoNothing: true}).
+		Create(&statuses)
+	return result.RowsAffected, result.Error
+}
+
+// EnsurePartitions สร้าง partition รายเดือน (UTC) ที่ครอบคลุมช่วง [from, to) หากยังไม่มี
+func (r *statusRepo) EnsurePartitions(ctx context.Context, from, to time.Time) error {
+	for month := monthStart(from); month.Before(to); month = month.AddDate(0, 1, 0) {
+		sql := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS "%s" PARTITION OF "statuses" FOR VALUES FROM ('%s') TO ('%s')`,
+			statusPartitionName(month), month.Format(time.RFC3339), month.AddDate(0, 1, 0).Format(time.RFC3339))
+		if err := r.conn.WithContext(ctx).Exec(sql).Error; err != nil {
+			return fmt.Errorf("create partition %s: %w", statusPartitionName(month), err)
+		}
+	}
+	return nil
+}
+
+// FindPartitions คืน partition รายเดือนของ statuses เรียงตามเวลา
+func (r *statusRepo) FindPartitions(ctx context.Context) ([]StatusPartition, error) {
+	var rows []struct {
+		Relname          string
+		Inhdetachpending bool
+	}
+	err := r.conn.WithContext(ctx).
+		Raw(`SELECT c.relname, i.inhdetachpending FROM pg_inherits i
+			JOIN pg_class c ON c.oid = i.inhrelid
+			JOIN pg_class p ON p.oid = i.inhparent
+			WHERE p.relname = 'statuses' ORDER BY c.relname`).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	partitions := make([]StatusPartition, 0, len(rows))
+	for _, row := range rows {
+		if !strings.HasPrefix(row.Relname, statusPartitionPrefix) {
+			continue
+		}
+		month, err := time.Parse("200601", strings.TrimPrefix(row.Relname, statusPartitionPrefix))
+		if err != nil {
+			continue
+		}
+		partitions = append(partitions, StatusPartition{Name: row.Relname, From: month, To: month.AddDate(0, 1, 0), DetachPending: row.Inhdetachpending})
+	}
+	return partitions, nil
+}
+
+// DropPartitionIfEmpty ลบ partition เมื่อไม่มีแถวเหลือแล้ว คืน false หากยังมีข้อมูล
+// แยก partition ด้วย DETACH PARTITION ... CONCURRENTLY ซึ่งไม่ล็อก statuses จนบล็อกการเขียนสถานะใหม่ แล้วจึง DROP
+// แถวที่ restore กลับมาอาจเข้ามาระหว่างตรวจครั้งแรกกับ detach จึงตรวจซ้ำหลัง detach และ attach คืนหากพบแถว
+func (r *statusRepo) DropPartitionIfEmpty(ctx context.Context, partition StatusPartition) (bool, error) {
+	if partition.Name != statusPartitionName(partition.From) {
+		return false, fmt.Errorf("invalid partition name %q", partition.Name)
+	}
+
+	conn := r.conn.WithContext(ctx)
+	if partition.DetachPending {
+		// detach ครั้งก่อนถูกขัดจังหวะ ต้อง FINALIZE ให้เสร็จก่อน
+		if err := conn.Exec(fmt.Sprintf(`ALTER TABLE "statuses" DETACH PARTITION "%s" FINALIZE`, partition.Name)).Error; err != nil {
+			return false, err
+		}
+	} else {
+		exists, err := r.partitionHasRows(ctx, partition.Name)
+		if err != nil || exists {
+			return false, err
+		}
+		// CONCURRENTLY ใช้ใน transaction ไม่ได้
+		if err := conn.Exec(fmt.Sprintf(`ALTER TABLE "statuses" DETACH PARTITION "%s" CONCURRENTLY`, partition.Name)).Error; err != nil {
+			return false, err
+		}
+	}
+
+	exists, err := r.partitionHasRows(ctx, partition.Name)
+	if err != nil {
+		return false, err
+	}
+	if exists {
+		err := conn.Exec(fmt.Sprintf(`ALTER TABLE "statuses" ATTACH PARTITION "%s" FOR VALUES FROM ('%s') TO ('%s')`,
+			partition.Name, partition.From.Format(time.RFC3339), partition.To.Format(time.RFC3339))).Error
+		return false, err
+	}
+	if err := conn.Exec(fmt.Sprintf(`DROP TABLE "%s"`, partition.Name)).Error; err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *statusRepo) partitionHasRows(ctx context.Context, name string) (bool, error) {
+	var exists bool
+	err := r.conn.WithContext(ctx).Raw(fmt.Sprintf(`SELECT EXISTS (SELECT 1 FROM "%s")`, name)).Find(&exists).Error
+	return exists, err
+}
+
+func monthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+func statusPartitionName(month time.Time) string {
+	return statusPartitionPrefix + month.UTC().Format("200601")
+}
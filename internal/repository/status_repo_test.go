@@ -0,0 +1,206 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestFindStatusesKeyset(t *testing.T) {
+	after := &StatusCursor{CreatedAt: time.Date(2026, 10, 12, 9, 30, 0, 0, time.UTC), ID: 42}
+
+	tests := []struct {
+		name  string
+		query StatusQuery
+		want  []string
+	}{
+		{
+			name:  "desc first page",
+			query: StatusQuery{WasherID: "w1", Limit: 11},
+			want:  []string{"washer_id = 'w1'", "ORDER BY created_at DESC, id DESC LIMIT 11"},
+		},
+		{
+			name:  "desc after cursor",
+			query: StatusQuery{WasherID: "w1", After: after, Limit: 11},
+			want: []string{
+				"created_at <= '2026-10-12 09:30:00' AND (created_at, id) < ('2026-10-12 09:30:00', 42)",
+				"ORDER BY created_at DESC, id DESC",
+			},
+		},
+		{
+			name:  "asc after cursor",
+			query: StatusQuery{WasherID: "w1", After: after, Ascending: true, Limit: 11},
+			want: []string{
+				"created_at >= '2026-10-12 09:30:00' AND (created_at, id) > ('2026-10-12 09:30:00', 42)",
+				"ORDER BY created_at, id LIMIT 11",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, recorder := newDryRunDB(t)
+			if _, err := NewStatusRepo(db).FindStatuses(context.Background(), tt.query); err != nil {
+				t.Fatal(err)
+			}
+
+			query := recorder.find(t, "SELECT * FROM \"statuses\"")
+			for _, want := range tt.want {
+				if !strings.Contains(query, want) {
+					t.Errorf("query is missing %s\n%s", want, query)
+				}
+			}
+		})
+	}
+}
+
+func TestDeleteStatusesBetweenBoundsOuterDelete(t *testing.T) {
+	db, recorder := newDryRunDB(t)
+	from := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 9, 2, 0, 0, 0, 0, time.UTC)
+	if _, err := NewStatusRepo(db).DeleteStatusesBetween(context.Background(), from, to, 900, 500); err != nil {
+		t.Fatal(err)
+	}
+
+	query := recorder.find(t, "DELETE FROM \"statuses\"")
+	want := "WHERE created_at >= '2026-09-01 00:00:00' AND created_at < '2026-09-02 00:00:00' AND id IN (SELECT \"id\" FROM \"statuses\" WHERE created_at >= '2026-09-01 00:00:00' AND created_at < '2026-09-02 00:00:00' AND id <= 900 LIMIT 500)"
+	if !strings.Contains(query, want) {
+		t.Fatalf("delete is missing %s\n%s", want, query)
+	}
+}
+
+func TestEnsurePartitionsCreatesEachMonth(t *testing.T) {
+	db, recorder := newDryRunDB(t)
+	from := time.Date(2026, 12, 15, 8, 0, 0, 0, time.UTC)
+	to := time.Date(2027, 2, 1, 0, 0, 0, 0, time.UTC)
+	if err := NewStatusRepo(db).EnsurePartitions(context.Background(), from, to); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		`CREATE TABLE IF NOT EXISTS "statuses_p202612" PARTITION OF "statuses" FOR VALUES FROM ('2026-12-01T00:00:00Z') TO ('2027-01-01T00:00:00Z')`,
+		`CREATE TABLE IF NOT EXISTS "statuses_p202701" PARTITION OF "statuses" FOR VALUES FROM ('2027-01-01T00:00:00Z') TO ('2027-02-01T00:00:00Z')`,
+	}
+	if !slices.Equal(recorder.statements, want) {
+		t.Fatalf("statements = %q, want %q", recorder.statements, want)
+	}
+}
+
+func TestDropPartitionIfEmpty(t *testing.T) {
+	month := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	partition := StatusPartition{Name: "statuses_p202509", From: month, To: month.AddDate(0, 1, 0)}
+	pending := partition
+	pending.DetachPending = true
+
+	tests := []struct {
+		name      string
+		partition StatusPartition
+		want      []string
+	}{
+		{
+			// DryRun ไม่คืนแถว partition จึงถูกมองว่าว่าง
+			name:      "empty partition is detached concurrently before drop",
+			partition: partition,
+			want: []string{
+				`SELECT EXISTS (SELECT 1 FROM "statuses_p202509")`,
+				`ALTER TABLE "statuses" DETACH PARTITION "statuses_p202509" CONCURRENTLY`,
+				`SELECT EXISTS (SELECT 1 FROM "statuses_p202509")`,
+				`DROP TABLE "statuses_p202509"`,
+			},
+		},
+		{
+			name:      "interrupted detach is finalized",
+			partition: pending,
+			want: []string{
+				`ALTER TABLE "statuses" DETACH PARTITION "statuses_p202509" FINALIZE`,
+				`SELECT EXISTS (SELECT 1 FROM "statuses_p202509")`,
+				`DROP TABLE "statuses_p202509"`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, recorder := newDryRunDB(t)
+			dropped, err := NewStatusRepo(db).DropPartitionIfEmpty(context.Background(), tt.partition)
+			if err != nil || !dropped {
+				t.Fatalf("DropPartitionIfEmpty() = %v, %v; want true, nil", dropped, err)
+			}
+			if !slices.Equal(recorder.statements, tt.want) {
+				t.Fatalf("statements = %q, want %q", recorder.statements, tt.want)
+			}
+		})
+	}
+
+	t.Run("name must match the month", func(t *testing.T) {
+		db, recorder := newDryRunDB(t)
+		forged := StatusPartition{Name: `statuses"; DROP TABLE "statuses`, From: month, To: month.AddDate(0, 1, 0)}
+		if _, err := NewStatusRepo(db).DropPartitionIfEmpty(context.Background(), forged); err == nil {
+			t.Fatal("DropPartitionIfEmpty() accepted a partition name that does not match its month")
+		}
+		if len(recorder.statements) != 0 {
+			t.Fatalf("statements = %q, want none", recorder.statements)
+		}
+	})
+}
+
+func TestRebuildCurrentStatuses(t *testing.T) {
+	tests := []struct {
+		name    string
+		force   bool
+		prefix  string
+		want    []string
+		notWant []string
+	}{
+		{
+			name:    "online rebuild keeps newer rows and washers without history",
+			prefix:  "INSERT INTO washer_current_status",
+			want:    []string{"WHERE washer_current_status.reported_at <= excluded.reported_at"},
+			notWant: []string{"DELETE"},
+		},
+		{
+			name:   "forced rebuild replaces rows and deletes washers without history",
+			force:  true,
+			prefix: "WITH deleted AS",
+			want: []string{
+				"DELETE FROM washer_current_status c\n\t\t\tWHERE NOT EXISTS (SELECT 1 FROM statuses s WHERE s.washer_id = c.washer_id)",
+				"INSERT INTO washer_current_status",
+				"SELECT (SELECT count(*) FROM written) AS written, (SELECT count(*) FROM deleted) AS deleted",
+			},
+			notWant: []string{"reported_at <= excluded.reported_at"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, recorder := newDryRunDB(t)
+			// Scan อ่านผลลัพธ์ไม่ได้ใน DryRun แต่ SQL ถูกสร้างครบแล้ว
+			if _, err := NewStatusRepo(db).RebuildCurrentStatuses(context.Background(), tt.force); err != nil && !errors.Is(err, gorm.ErrDryRunModeUnsupported) {
+				t.Fatal(err)
+			}
+
+			if len(recorder.statements) != 1 {
+				t.Fatalf("statements = %q, want one", recorder.statements)
+			}
+			query := strings.TrimSpace(recorder.statements[0])
+			if !strings.HasPrefix(query, tt.prefix) {
+				t.Fatalf("query does not start with %s\n%s", tt.prefix, query)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(query, want) {
+					t.Errorf("query is missing %s\n%s", want, query)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(query, notWant) {
+					t.Errorf("query should not contain %s\n%s", notWant, query)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jaytnw/bms-service/internal/models"
+	"gorm.io/gorm"
+)
+
+// TelemetryQuery กรอง telemetry ของเครื่องตามช่วงเวลา
+type TelemetryQuery struct {
+	WasherID string
+	From     *time.Time
+	To       *time.Time
+	Limit    int
+}
+
+type TelemetryRepository interface {
+	SaveTelemetries(ctx context.Context, telemetries []*models.WasherTelemetry) error
+	FindLatestTelemetry(ctx context.Context, washerID string) (*models.WasherTelemetry, error)
+	FindTelemetry(ctx context.Context, query TelemetryQuery) ([]models.WasherTelemetry, error)
+}
+
+type telemetryRepo struct {
+	conn *gorm.DB
+}
+
+func NewTelemetryRepo(conn *gorm.DB) TelemetryRepository {
+	return &telemetryRepo{
+		conn: conn,
+	}
+}
+
+// SaveTelemetries บันทึก telemetry หลายแถวด้วย multi-row insert
+func (r *telemetryRepo) SaveTelemetries(ctx context.Context, telemetries []*models.WasherTelemetry) error {
+	if len(telemetries) == 0 {
+		return nil
+	}
+	return r.conn.WithContext(ctx).CreateInBatches(telemetries, 500).Error
+}
+
+// FindLatestTelemetry คืน telemetry ล่าสุดของเครื่อง หรือ nil หากเครื่องไม่เคยส่ง JSON payload
+func (r *telemetryRepo) FindLatestTelemetry(ctx context.Context, washerID string) (*models.WasherTelemetry, error) {
+	var rows []models.WasherTelemetry
+	err := r.conn.WithContext(ctx).
+		Where("washer_id = ?", washerID).
+		Order("created_at DESC").
+		Limit(1).
+		Find(&rows).Error
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+	return &rows[0], nil
+}
+
+func (r *telemetryRepo) FindTelemetry(ctx context.Context, query TelemetryQuery) ([]models.WasherTelemetry, error) {
+	var rows []models.WasherTelemetry
+	db := r.conn.WithContext(ctx).Where("washer_id = ?", query.WasherID).Order("created_at DESC")
+	if query.From != nil {
+		db = db.Where("created_at >= ?", *query.From)
+	}
+	if query.To != nil {
+		db = db.Where("created_at < ?", *query.To)
+	}
+	if query.Limit > 0 {
+		db = db.Limit(query.Limit)
+	}
+
+	if err := db.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jaytnw/bms-service/internal/models"
+	"gorm.io/gorm"
+)
+
+// WebhookDeliveryFilter กรองรายการส่ง webhook (ค่าว่างคือไม่กรอง)
+type WebhookDeliveryFilter struct {
+	EndpointID uint
+	Status     string
+	Limit      int
+}
+
+type WebhookRepository interface {
+	FindEndpoints(ctx context.Context) ([]models.WebhookEndpoint, error)
+	FindActiveEndpoints(ctx context.Context) ([]models.WebhookEndpoint, error)
+	FindEndpointByID(ctx context.Context, id uint) (*models.WebhookEndpoint, error)
+	SaveEndpoint(ctx context.Context, endpoint *models.WebhookEndpoint) error
+	DeleteEndpoint(ctx context.Context, id uint) error
+
+	CreateDeliveries(ctx context.Context, deliveries []models.WebhookDelivery) error
+	FindDeliveryByID(ctx context.Context, id uint) (*models.WebhookDelivery, error)
+	FindDeliveries(ctx context.Context, filter WebhookDeliveryFilter) ([]models.WebhookDelivery, error)
+	ClaimDueDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error)
+	SaveDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+}
+
+type webhookRepo struct {
+	conn *gorm.DB
+}
+
+func NewWebhookRepo(conn *gorm.DB) WebhookRepository {
+	return &webhookRepo{
+		conn: conn,
+	}
+}
+
+func (r *webhookRepo) FindEndpoints(ctx context.Context) ([]models.WebhookEndpoint, error) {
+	var endpoints []models.WebhookEndpoint
+	if err := r.conn.WithContext(ctx).Order("id").Find(&endpoints).Error; err != nil {
+		return nil, err
+	}
+	return endpoints, nil
+}
+
+func (r *webhookRepo) FindActiveEndpoints(ctx context.Context) ([]models.WebhookEndpoint, error) {
+	var endpoints []models.WebhookEndpoint
+	if err := r.conn.WithContext(ctx).Where("active").Order("id").Find(&endpoints).Error; err != nil {
+		return nil, err
+	}
+	return endpoints, nil
+}
+
+func (r *webhookRepo) FindEndpointByID(ctx context.Context, id uint) (*models.WebhookEndpoint, error) {
+	var endpoint models.WebhookEndpoint
+	if err := r.conn.WithContext(ctx).First(&endpoint, id).Error; err != nil {
+		return nil, err
+	}
+	return &endpoint, nil
+}
+
+func (r *webhookRepo) SaveEndpoint(ctx context.Context, endpoint *models.WebhookEndpoint) error {
+	return r.conn.WithContext(ctx).Save(endpoint).Error
+}
+
+func (r *webhookRepo) DeleteEndpoint(ctx context.Context, id uint) error {
+	result := r.conn.WithContext(ctx).Delete(&models.WebhookEndpoint{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *webhookRepo) CreateDeliveries(ctx context.Context, deliveries []models.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return r.conn.WithContext(ctx).Create(&deliveries).Error
+}
+
+func (r *webhookRepo) FindDeliveryByID(ctx context.Context, id uint) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	if err := r.conn.WithContext(ctx).First(&delivery, id).Error; err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+func (r *webhookRepo) FindDeliveries(ctx context.Context, filter WebhookDeliveryFilter) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	query := r.conn.WithContext(ctx).Order("created_at DESC")
+	if filter.EndpointID != 0 {
+		query = query.Where("endpoint_id = ?", filter.EndpointID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	if err := query.Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// ClaimDueDeliveries จองรายการ pending ที่ถึงเวลาส่ง โดยเลื่อน next_attempt_at ออกไปเท่ากับ lease
+// ใช้ SKIP LOCKED เพื่อให้หลาย instance ทำงานพร้อมกันได้โดยไม่ส่งซ้ำ
+func (r *webhookRepo) ClaimDueDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	err := r.conn.WithContext(ctx).Raw(`
+		UPDATE webhook_deliveries SET next_attempt_at = ?, updated_at = ?
+		WHERE id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = ? AND next_attempt_at <= ?
+			ORDER BY next_attempt_at
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *
+	`, now.Add(lease), now, models.WebhookDeliveryPending, now, limit).Scan(&deliveries).Error
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+func (r *webhookRepo) SaveDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	return r.conn.WithContext(ctx).Save(delivery).Error
+}
@@ -5,7 +5,26 @@ import (
 	"github.com/jaytnw/bms-service/internal/handlers"
 )
 
-func Setup(app fiber.Router, statusHandler *handlers.StatusHandler) {
+// Handlers รวม handler ทั้งหมดที่ใช้ลงทะเบียน route
+type Handlers struct {
+	Status       *handlers.StatusHandler
+	Stream       *handlers.StreamHandler
+	Session      *handlers.SessionHandler
+	Availability *handlers.AvailabilityHandler
+	Registry     *handlers.RegistryHandler
+	Queue        *handlers.QueueHandler
+	Subscription *handlers.SubscriptionHandler
+	Webhook      *handlers.WebhookHandler
+	Command      *handlers.CommandHandler
+	Incident     *handlers.IncidentHandler
+	Maintenance  *handlers.MaintenanceHandler
+	Analytics    *handlers.AnalyticsHandler
+	Rollup       *handlers.RollupHandler
+	Archive      *handlers.ArchiveHandler
+	Ingest       *handlers.IngestHandler
+}
+
+func Setup(app fiber.Router, h Handlers) {
 
 	app.Get("/", func(c fiber.Ctx) error {
 		return c.SendString("Welcome to BMS Service 👋")
@@ -14,10 +33,99 @@ func Setup(app fiber.Router, statusHandler *handlers.StatusHandler) {
 	v1 := app.Group("/v1")
 
 	status := v1.Group("/status")
-	status.Get("/", statusHandler.GetAllStatus)
-	status.Get("/:washerID", statusHandler.GetStatusByWasherID)
-	status.Get("/:washerID/history", statusHandler.GetStatusHistoryByWasherID)
+	status.Get("/", h.Status.GetAllStatus)
+	status.Get("/stream", h.Stream.StreamSSE)
+	status.Get("/stream/ws", h.Stream.StreamWebSocket)
+	status.Get("/:washerID", h.Status.GetStatusByWasherID)
+	status.Get("/:washerID/history", h.Status.GetStatusHistoryByWasherID)
+
+	status.Get("/dorm/report", h.Status.GetDormStatusReport)
+
+	v1.Get("/anomalies", h.Status.GetAnomalies)
+
+	washers := v1.Group("/washers")
+	washers.Get("/:washerID/sessions", h.Session.GetWasherSessions)
+	washers.Get("/:washerID/telemetry", h.Status.GetTelemetry)
+	washers.Get("/:washerID/subscriptions", h.Subscription.ListSubscriptions)
+	washers.Post("/:washerID/subscriptions", h.Subscription.Subscribe)
+	washers.Get("/:washerID/commands", h.Command.GetHistory)
+	washers.Post("/:washerID/commands", h.Command.SendCommand)
+
+	v1.Get("/commands/:commandID", h.Command.GetCommand)
+
+	v1.Delete("/subscriptions/:subscriptionID", h.Subscription.Unsubscribe)
+	v1.Get("/notifications/deliveries", h.Subscription.GetDeliveries)
+
+	dorms := v1.Group("/dorms")
+	dorms.Get("/", h.Registry.ListDorms)
+	dorms.Post("/", h.Registry.CreateDorm)
+	dorms.Get("/availability", h.Availability.GetAllAvailability)
+	dorms.Get("/pending", h.Registry.ListPendingDorms)
+	dorms.Get("/:dormID", h.Registry.GetDorm)
+	dorms.Put("/:dormID", h.Registry.UpdateDorm)
+	dorms.Delete("/:dormID", h.Registry.DeleteDorm)
+	dorms.Post("/:dormID/approve", h.Registry.ApproveDorm)
+	dorms.Get("/:dormID/availability", h.Availability.GetDormAvailability)
+	dorms.Get("/:dormID/sessions", h.Session.GetDormSessions)
+	dorms.Get("/:dormID/queue", h.Queue.GetQueue)
+	dorms.Post("/:dormID/queue", h.Queue.Join)
+	dorms.Get("/:dormID/queue/:entryID", h.Queue.GetEntry)
+	dorms.Post("/:dormID/queue/:entryID/claim", h.Queue.Claim)
+	dorms.Delete("/:dormID/queue/:entryID", h.Queue.Leave)
+
+	machines := v1.Group("/machines")
+	machines.Get("/", h.Registry.ListMachines)
+	machines.Post("/", h.Registry.CreateMachine)
+	machines.Post("/sync", h.Registry.Sync)
+	machines.Get("/pending", h.Registry.ListPendingMachines)
+	machines.Get("/:machineID", h.Registry.GetMachine)
+	machines.Put("/:machineID", h.Registry.UpdateMachine)
+	machines.Delete("/:machineID", h.Registry.DeleteMachine)
+	machines.Post("/:machineID/approve", h.Registry.ApproveMachine)
+
+	v1.Post("/sessions/backfill", h.Session.Backfill)
+
+	incidents := v1.Group("/incidents")
+	incidents.Get("/", h.Incident.ListIncidents)
+	incidents.Get("/mttr", h.Incident.GetMTTR)
+	incidents.Get("/:incidentID", h.Incident.GetIncident)
+	incidents.Post("/:incidentID/ack", h.Incident.Acknowledge)
+	incidents.Post("/:incidentID/comments", h.Incident.AddComment)
+
+	faultCodes := v1.Group("/fault-codes")
+	faultCodes.Get("/", h.Incident.ListFaultCodes)
+	faultCodes.Get("/:code", h.Incident.GetFaultCode)
+	faultCodes.Put("/:code", h.Incident.PutFaultCode)
+	faultCodes.Delete("/:code", h.Incident.DeleteFaultCode)
+
+	analytics := v1.Group("/analytics")
+	analytics.Get("/utilisation", h.Analytics.GetUtilisation)
+	analytics.Get("/washers/:washerID/utilisation", h.Analytics.GetWasherUtilisation)
+	analytics.Get("/dorms/:dormID/heatmap", h.Analytics.GetHeatmap)
+	analytics.Get("/dorms/:dormID/peaks", h.Analytics.GetBusiestPeriods)
+
+	v1.Get("/rollups", h.Rollup.ListRollups)
+
+	archives := v1.Group("/archives")
+	archives.Get("/", h.Archive.ListArchives)
+	archives.Post("/restore", h.Archive.RestoreArchives)
+
+	v1.Get("/ingest/stats", h.Ingest.GetStats)
+	v1.Get("/ingest/spool", h.Ingest.GetSpool)
+
+	maintenance := v1.Group("/maintenance")
+	maintenance.Get("/", h.Maintenance.ListMaintenance)
+	maintenance.Post("/", h.Maintenance.StartMaintenance)
+	maintenance.Post("/:maintenanceID/end", h.Maintenance.EndMaintenance)
 
-	status.Get("/dorm/report", statusHandler.GetDormStatusReport)
+	webhooks := v1.Group("/webhooks")
+	webhooks.Get("/", h.Webhook.ListEndpoints)
+	webhooks.Post("/", h.Webhook.CreateEndpoint)
+	webhooks.Get("/deliveries", h.Webhook.ListDeliveries)
+	webhooks.Post("/deliveries/:deliveryID/redeliver", h.Webhook.Redeliver)
+	webhooks.Get("/:webhookID", h.Webhook.GetEndpoint)
+	webhooks.Put("/:webhookID", h.Webhook.UpdateEndpoint)
+	webhooks.Delete("/:webhookID", h.Webhook.DeleteEndpoint)
+	webhooks.Get("/:webhookID/deliveries", h.Webhook.ListEndpointDeliveries)
 
 }
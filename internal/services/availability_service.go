@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/jaytnw/bms-service/internal/apperr"
+	"github.com/jaytnw/bms-service/internal/models"
+	"github.com/jaytnw/bms-service/internal/repository"
+)
+
+// AvailabilityService สรุปเครื่องว่างต่อหอของเครื่องในทะเบียน โดยอ่านสถานะจาก state store ที่ MQTT handler อัปเดตอยู่ตลอด
+// ไม่อ่านตาราง statuses
+type AvailabilityService interface {
+	GetDormAvailability(ctx context.Context, dormID string) (*models.DormAvailability, error)
+	GetAllAvailability(ctx context.Context) ([]models.DormAvailability, error)
+}
+
+type availabilityService struct {
+	stateStore   WasherStateStore
+	registryRepo repository.RegistryRepository
+	eta          EtaService
+	maintenance  MaintenanceChecker
+	offlineAfter time.Duration
+}
+
+func NewAvailabilityService(stateStore WasherStateStore, registryRepo repository.RegistryRepository, eta EtaService, maintenance MaintenanceChecker, offlineAfter time.Duration) AvailabilityService {
+	return &availabilityService{
+		stateStore:   stateStore,
+		registryRepo: registryRepo,
+		eta:          eta,
+		maintenance:  maintenance,
+		offlineAfter: offlineAfter,
+	}
+}
+
+func (s *availabilityService) GetDormAvailability(ctx context.Context, dormID string) (*models.DormAvailability, error) {
+	reports, err := s.availability(ctx, dormID)
+	if err != nil {
+		return nil, err
+	}
+	if len(reports) == 0 {
+		return nil, apperr.New("NOT_FOUND", "No machines found for dorm", 404, nil)
+	}
+	return &reports[0], nil
+}
+
+func (s *availabilityService) GetAllAvailability(ctx context.Context) ([]models.DormAvailability, error) {
+	return s.availability(ctx, "")
+}
+
+// availability นับเฉพาะเครื่อง active ในทะเบียน (dormID ว่างคือทุกหอ) ส่วนสถานะมาจาก state store
+// เครื่องที่ถูกลบหรือรออนุมัติจึงไม่ถูกนับแม้ยังมี snapshot และเครื่องที่ยังไม่เคยรายงานนับเป็น offline
+func (s *availabilityService) availability(ctx context.Context, dormID string) ([]models.DormAvailability, error) {
+	machines, err := s.registryRepo.FindMachines(ctx, dormID)
+	if err != nil {
+		return nil, apperr.New("DB_ERROR", "Failed to fetch machines", 500, err)
+	}
+
+	snapshots, err := s.stateStore.List(ctx)
+	if err != nil {
+		return nil, apperr.New("CACHE_ERROR", "Failed to read live washer state", 500, err)
+	}
+	byWasher := make(map[string]WasherSnapshot, len(snapshots))
+	for _, snapshot := range snapshots {
+		byWasher[snapshot.WasherID] = snapshot
+	}
+
+	dorms, err := s.registryRepo.FindDorms(ctx)
+	if err != nil {
+		return nil, apperr.New("DB_ERROR", "Failed to fetch dorms", 500, err)
+	}
+	dormNames := make(map[string]string, len(dorms))
+	for _, d := range dorms {
+		dormNames[d.ID] = d.Name
+	}
+
+	now := time.Now()
+	grouped := make(map[string]*models.DormAvailability)
+	for _, registered := range machines {
+		if registered.Status != models.RegistrationActive {
+			continue
+		}
+		snapshot, reported := byWasher[registered.ID]
+		if !reported {
+			snapshot = WasherSnapshot{WasherID: registered.ID, Status: models.WasherOffline}
+		}
+
+		report, ok := grouped[registered.DormID]
+		if !ok {
+			report = &models.DormAvailability{
+				DormID:       registered.DormID,
+				DormName:     dormNames[registered.DormID],
+				Counts:       make(map[models.WasherState]int),
+				FreeMachines: []models.MachineAvailability{},
+			}
+			grouped[registered.DormID] = report
+		}
+
+		machine := models.MachineAvailability{
+			WasherID: registered.ID,
+			Status:   snapshot.Status,
+			Since:    snapshot.Since,
+			Online:   snapshot.Online(now, s.offlineAfter),
+		}
+		// เครื่องที่เงียบเกิน offlineAfter นับเป็น offline แม้ watchdog ยังไม่ได้บันทึก
+		if !machine.Online {
+			machine.Status = models.WasherOffline
+		}
+		// เครื่องที่ซ่อมบำรุงอยู่ไม่นับเป็นเครื่องว่าง ไม่ว่าจะรายงานสถานะใดมา
+		if window := s.maintenance.ActiveWindow(ctx, registered.DormID, registered.ID, now); window != nil {
+			machine.Status = models.WasherMaintenance
+			machine.Maintenance = window
+		}
+
+		report.Total++
+		report.Counts[machine.Status]++
+
+		switch {
+		case machine.Status.Free():
+			report.Free++
+			report.FreeMachines = append(report.FreeMachines, machine)
+		case machine.Status.Busy():
+			eta, err := s.eta.Estimate(ctx, registered.ID, registered.DormID, now)
+			if err != nil {
+				log.Printf("⚠️ Failed to estimate ETA for washer %s: %v", registered.ID, err)
+			}
+			if eta == nil {
+				continue
+			}
+			machine.ETA = eta
+			if report.SoonestFinish == nil || eta.ExpectedFinishAt.Before(report.SoonestFinish.ETA.ExpectedFinishAt) {
+				m := machine
+				report.SoonestFinish = &m
+			}
+		}
+	}
+
+	result := make([]models.DormAvailability, 0, len(grouped))
+	for _, report := range grouped {
+		sort.Slice(report.FreeMachines, func(i, j int) bool {
+			return report.FreeMachines[i].WasherID < report.FreeMachines[j].WasherID
+		})
+		result = append(result, *report)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].DormID < result[j].DormID })
+
+	return result, nil
+}
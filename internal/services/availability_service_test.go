@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jaytnw/bms-service/internal/models"
+	"github.com/jaytnw/bms-service/internal/repository"
+)
+
+type fakeAvailabilityRegistry struct {
+	repository.RegistryRepository
+	machines []models.Machine
+}
+
+func (r *fakeAvailabilityRegistry) FindMachines(ctx context.Context, dormID string) ([]models.Machine, error) {
+	var found []models.Machine
+	for _, machine := range r.machines {
+		if dormID == "" || machine.DormID == dormID {
+			found = append(found, machine)
+		}
+	}
+	return found, nil
+}
+
+func (r *fakeAvailabilityRegistry) FindDorms(ctx context.Context) ([]models.Dorm, error) {
+	return []models.Dorm{{ID: "d1", Name: "Dorm 1"}, {ID: "d2", Name: "Dorm 2"}}, nil
+}
+
+// fakeEta จดเครื่องที่ถูกขอ ETA และคืน ETA คงที่
+type fakeEta struct {
+	mu        sync.Mutex
+	estimated []string
+}
+
+func (e *fakeEta) Estimate(ctx context.Context, washerID, dormID string, now time.Time) (*models.WasherETA, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.estimated = append(e.estimated, washerID)
+	return &models.WasherETA{ExpectedFinishAt: now.Add(10 * time.Minute)}, nil
+}
+
+func TestAvailabilityCountsRegisteredMachines(t *testing.T) {
+	now := time.Now()
+	registry := &fakeAvailabilityRegistry{machines: []models.Machine{
+		{ID: "w1", DormID: "d1", Status: models.RegistrationActive},
+		{ID: "w2", DormID: "d1", Status: models.RegistrationActive},
+		{ID: "w3", DormID: "d1", Status: models.RegistrationActive},
+		{ID: "w4", DormID: "d1", Status: models.RegistrationPending},
+		{ID: "w5", DormID: "d2", Status: models.RegistrationActive},
+	}}
+	state := newFakeStateStore(
+		WasherSnapshot{DormID: "d1", WasherID: "w1", Status: models.WasherIdle, LastSeen: now},
+		WasherSnapshot{DormID: "d1", WasherID: "w2", Status: models.WasherWashing, LastSeen: now},
+		WasherSnapshot{DormID: "d1", WasherID: "w4", Status: models.WasherIdle, LastSeen: now},
+		WasherSnapshot{DormID: "d1", WasherID: "deleted", Status: models.WasherIdle, LastSeen: now},
+		WasherSnapshot{DormID: "old", WasherID: "w5", Status: models.WasherSpinning, LastSeen: now},
+	)
+
+	tests := []struct {
+		name          string
+		dormID        string
+		wantDorms     []string
+		wantTotal     map[string]int
+		wantCounts    map[string]map[models.WasherState]int
+		wantEstimated []string
+	}{
+		{
+			name:      "all dorms",
+			wantDorms: []string{"d1", "d2"},
+			wantTotal: map[string]int{"d1": 3, "d2": 1},
+			wantCounts: map[string]map[models.WasherState]int{
+				"d1": {models.WasherIdle: 1, models.WasherWashing: 1, models.WasherOffline: 1},
+				"d2": {models.WasherSpinning: 1},
+			},
+			wantEstimated: []string{"w2", "w5"},
+		},
+		{
+			name:          "single dorm only estimates its own machines",
+			dormID:        "d1",
+			wantDorms:     []string{"d1"},
+			wantTotal:     map[string]int{"d1": 3},
+			wantCounts:    map[string]map[models.WasherState]int{"d1": {models.WasherIdle: 1, models.WasherWashing: 1, models.WasherOffline: 1}},
+			wantEstimated: []string{"w2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eta := &fakeEta{}
+			service := NewAvailabilityService(state, registry, eta, fakeMaintenance{}, 5*time.Minute)
+
+			var reports []models.DormAvailability
+			if tt.dormID == "" {
+				all, err := service.GetAllAvailability(context.Background())
+				if err != nil {
+					t.Fatal(err)
+				}
+				reports = all
+			} else {
+				report, err := service.GetDormAvailability(context.Background(), tt.dormID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				reports = []models.DormAvailability{*report}
+			}
+
+			var dorms []string
+			for _, report := range reports {
+				dorms = append(dorms, report.DormID)
+				if report.Total != tt.wantTotal[report.DormID] {
+					t.Errorf("dorm %s total = %d, want %d", report.DormID, report.Total, tt.wantTotal[report.DormID])
+				}
+				for state, want := range tt.wantCounts[report.DormID] {
+					if report.Counts[state] != want {
+						t.Errorf("dorm %s counts[%s] = %d, want %d", report.DormID, state, report.Counts[state], want)
+					}
+				}
+			}
+			if !slices.Equal(dorms, tt.wantDorms) {
+				t.Fatalf("dorms = %v, want %v", dorms, tt.wantDorms)
+			}
+			slices.Sort(eta.estimated)
+			if !slices.Equal(eta.estimated, tt.wantEstimated) {
+				t.Fatalf("estimated = %v, want %v", eta.estimated, tt.wantEstimated)
+			}
+		})
+	}
+}
+
+func TestDormAvailabilityWithoutActiveMachines(t *testing.T) {
+	registry := &fakeAvailabilityRegistry{machines: []models.Machine{{ID: "w4", DormID: "d1", Status: models.RegistrationPending}}}
+	service := NewAvailabilityService(newFakeStateStore(), registry, &fakeEta{}, fakeMaintenance{}, 5*time.Minute)
+
+	if _, err := service.GetDormAvailability(context.Background(), "d1"); err == nil {
+		t.Fatal("GetDormAvailability() error = nil, want not found")
+	}
+}
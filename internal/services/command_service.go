@@ -0,0 +1,268 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jaytnw/bms-service/internal/apperr"
+	"github.com/jaytnw/bms-service/internal/models"
+	"github.com/jaytnw/bms-service/internal/mqtt"
+	"github.com/jaytnw/bms-service/internal/repository"
+	"gorm.io/gorm"
+)
+
+// SendCommandRequest คือคำขอส่งคำสั่งไปยังเครื่อง
+type SendCommandRequest struct {
+	Command     models.WasherCommandType `json:"command"`
+	RequestedBy string                   `json:"requestedBy"`
+	Reason      string                   `json:"reason"`
+}
+
+// commandMessage คือ payload ที่ publish ไปยัง washingMachine/<dorm>/<washer>/cmd
+type commandMessage struct {
+	ID       string                   `json:"id"`
+	Command  models.WasherCommandType `json:"command"`
+	IssuedAt time.Time                `json:"issuedAt"`
+}
+
+// commandAck คือ payload ที่เครื่องตอบกลับทาง washingMachine/<dorm>/<washer>/ack
+type commandAck struct {
+	ID      string `json:"id"`
+	OK      bool   `json:"ok"`
+	Message string `json:"message"`
+}
+
+// CommandService ส่งคำสั่งไปยังเครื่องผ่าน MQTT และรอ ack ภายใน ackTimeout
+type CommandService interface {
+	Send(ctx context.Context, washerID string, req SendCommandRequest) (*models.WasherCommand, error)
+	GetCommand(ctx context.Context, id string) (*models.WasherCommand, error)
+	GetHistory(ctx context.Context, washerID string, limit int) ([]models.WasherCommand, error)
+	HandleMQTTAck(topic string, payload []byte)
+}
+
+// lateAckWorkers จำกัดจำนวน goroutine ที่บันทึก ack ซึ่งมาหลังหมดเวลา
+const lateAckWorkers = 8
+
+// commandWaiter คือ request ที่รอ ack ของคำสั่งหนึ่งรายการ ack ต้องมาจาก washerID เดียวกับที่ส่งคำสั่ง
+type commandWaiter struct {
+	washerID string
+	ch       chan commandAck
+}
+
+type commandService struct {
+	commandRepo  repository.CommandRepository
+	registryRepo repository.RegistryRepository
+	mqttClient   mqtt.Client
+	ackTimeout   time.Duration
+
+	mu      sync.Mutex
+	waiters map[string]commandWaiter
+
+	// lateAcks เป็น semaphore ของการบันทึก ack ที่มาช้า ซึ่งทำนอก goroutine ของ MQTT callback
+	lateAcks chan struct{}
+}
+
+func NewCommandService(commandRepo repository.CommandRepository, registryRepo repository.RegistryRepository, mqttClient mqtt.Client, ackTimeout time.Duration) CommandService {
+	return &commandService{
+		commandRepo:  commandRepo,
+		registryRepo: registryRepo,
+		mqttClient:   mqttClient,
+		ackTimeout:   ackTimeout,
+		waiters:      make(map[string]commandWaiter),
+		lateAcks:     make(chan struct{}, lateAckWorkers),
+	}
+}
+
+func commandTopic(dormID, washerID string) string {
+	return fmt.Sprintf("washingMachine/%s/%s/cmd", dormID, washerID)
+}
+
+// Send บันทึกคำสั่ง publish แล้วรอ ack คืนคำสั่งพร้อมผล (acked, rejected หรือ timeout)
+func (s *commandService) Send(ctx context.Context, washerID string, req SendCommandRequest) (*models.WasherCommand, error) {
+	if !req.Command.Valid() {
+		return nil, apperr.New("VALIDATION_ERROR", fmt.Sprintf("unsupported command %q", req.Command), 400, nil)
+	}
+
+	machine, err := s.registryRepo.FindMachineByID(ctx, washerID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperr.New("NOT_FOUND", "Washer not found", 404, err)
+		}
+		return nil, apperr.New("DB_ERROR", "Failed to get washer", 500, err)
+	}
+
+	id, err := randomHex(16)
+	if err != nil {
+		return nil, apperr.New("INTERNAL_ERROR", "Failed to generate command id", 500, err)
+	}
+	command := &models.WasherCommand{
+		ID:          id,
+		DormID:      machine.DormID,
+		WasherID:    washerID,
+		Command:     req.Command,
+		Status:      models.CommandPending,
+		RequestedBy: req.RequestedBy,
+		Reason:      req.Reason,
+	}
+	if err := s.commandRepo.SaveCommand(ctx, command); err != nil {
+		return nil, apperr.New("DB_ERROR", "Failed to save command", 500, err)
+	}
+
+	// ลงทะเบียนรอ ack ก่อน publish เพื่อไม่ให้พลาด ack ที่ตอบกลับเร็ว
+	ackCh := make(chan commandAck, 1)
+	s.mu.Lock()
+	s.waiters[id] = commandWaiter{washerID: washerID, ch: ackCh}
+	s.mu.Unlock()
+
+	sentAt := time.Now()
+	payload, _ := json.Marshal(commandMessage{ID: id, Command: req.Command, IssuedAt: sentAt})
+	if err := s.mqttClient.Publish(commandTopic(machine.DormID, washerID), string(payload)); err != nil {
+		s.removeWaiter(id)
+		command.Status = models.CommandFailed
+		command.Message = err.Error()
+		s.save(command)
+		return nil, apperr.New("MQTT_ERROR", "Failed to publish command", 502, err)
+	}
+	command.SentAt = &sentAt
+	log.Printf("📤 Sent %s command %s to washer %s", req.Command, id, washerID)
+
+	timer := time.NewTimer(s.ackTimeout)
+	defer timer.Stop()
+
+	select {
+	case ack := <-ackCh:
+		applyAck(command, ack)
+		s.save(command)
+		return command, nil
+	case <-timer.C:
+		command.Status = models.CommandTimeout
+		command.Message = fmt.Sprintf("no ack within %v", s.ackTimeout)
+	case <-ctx.Done():
+		command.Status = models.CommandTimeout
+		command.Message = "request cancelled before ack"
+	}
+
+	// บันทึก timeout ก่อนถอน waiter ack ที่มาหลังจากนี้จะถูกบันทึกทับเป็น late ack ได้ถูกลำดับ
+	s.save(command)
+	if !s.removeWaiter(id) {
+		// HandleMQTTAck ถอน waiter ไปแล้ว ack จึงอยู่ใน channel
+		applyAck(command, <-ackCh)
+		s.save(command)
+	}
+	return command, nil
+}
+
+// removeWaiter ถอน waiter ของคำสั่ง คืน false หาก HandleMQTTAck ถอนไปแล้วพร้อมส่ง ack
+func (s *commandService) removeWaiter(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.waiters[id]; !ok {
+		return false
+	}
+	delete(s.waiters, id)
+	return true
+}
+
+func (s *commandService) GetCommand(ctx context.Context, id string) (*models.WasherCommand, error) {
+	command, err := s.commandRepo.FindCommandByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperr.New("NOT_FOUND", "Command not found", 404, err)
+		}
+		return nil, apperr.New("DB_ERROR", "Failed to get command", 500, err)
+	}
+	return command, nil
+}
+
+func (s *commandService) GetHistory(ctx context.Context, washerID string, limit int) ([]models.WasherCommand, error) {
+	commands, err := s.commandRepo.FindCommandsByWasherID(ctx, washerID, limit)
+	if err != nil {
+		return nil, apperr.New("DB_ERROR", "Failed to get command history", 500, err)
+	}
+	return commands, nil
+}
+
+// HandleMQTTAck ส่ง ack ให้ request ที่รออยู่ หากไม่มีใครรอแล้ว (หมดเวลา) จะบันทึกผลลงประวัติโดยตรง
+// ถูกเรียกจาก MQTT callback จึงไม่ทำ IO กับฐานข้อมูลใน goroutine นี้
+func (s *commandService) HandleMQTTAck(topic string, payload []byte) {
+	_, washerID, ok := parseWasherTopic(topic)
+	if !ok {
+		log.Printf("❌ Invalid topic format: %s", topic)
+		return
+	}
+
+	var ack commandAck
+	if err := json.Unmarshal(payload, &ack); err != nil || ack.ID == "" {
+		log.Printf("⚠️ Invalid command ack from washer %s: %q", washerID, payload)
+		return
+	}
+
+	s.mu.Lock()
+	waiter, waiting := s.waiters[ack.ID]
+	if waiting && waiter.washerID == washerID {
+		// ถอน waiter และส่ง ack ภายใต้ lock เดียวกัน Send จึงรู้ได้เสมอว่า ack อยู่ใน channel แล้ว
+		delete(s.waiters, ack.ID)
+		waiter.ch <- ack
+	}
+	s.mu.Unlock()
+	if waiting {
+		if waiter.washerID != washerID {
+			log.Printf("⚠️ Ack for command %s came from washer %s, expected %s", ack.ID, washerID, waiter.washerID)
+		}
+		return
+	}
+
+	select {
+	case s.lateAcks <- struct{}{}:
+	default:
+		log.Printf("⚠️ Dropped late ack for command %s from washer %s: too many pending", ack.ID, washerID)
+		return
+	}
+	go func() {
+		defer func() { <-s.lateAcks }()
+		s.recordLateAck(washerID, ack)
+	}()
+}
+
+// recordLateAck บันทึก ack ที่มาหลังจาก Send เลิกรอแล้ว
+func (s *commandService) recordLateAck(washerID string, ack commandAck) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.ackTimeout)
+	defer cancel()
+
+	command, err := s.commandRepo.FindCommandByID(ctx, ack.ID)
+	if err != nil {
+		log.Printf("⚠️ Ack for unknown command %s from washer %s: %v", ack.ID, washerID, err)
+		return
+	}
+	if command.WasherID != washerID {
+		log.Printf("⚠️ Ack for command %s came from washer %s, expected %s", ack.ID, washerID, command.WasherID)
+		return
+	}
+	log.Printf("⌛ Late ack for command %s from washer %s", ack.ID, washerID)
+	applyAck(command, ack)
+	if err := s.commandRepo.SaveCommand(ctx, command); err != nil {
+		log.Printf("❌ Failed to update command %s: %v", command.ID, err)
+	}
+}
+
+func (s *commandService) save(command *models.WasherCommand) {
+	if err := s.commandRepo.SaveCommand(context.Background(), command); err != nil {
+		log.Printf("❌ Failed to update command %s: %v", command.ID, err)
+	}
+}
+
+func applyAck(command *models.WasherCommand, ack commandAck) {
+	now := time.Now()
+	command.AckedAt = &now
+	command.Message = ack.Message
+	if ack.OK {
+		command.Status = models.CommandAcked
+	} else {
+		command.Status = models.CommandRejected
+	}
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jaytnw/bms-service/internal/models"
+	"github.com/jaytnw/bms-service/internal/mqtt"
+	"github.com/jaytnw/bms-service/internal/repository"
+	"gorm.io/gorm"
+)
+
+type fakeCommandRepo struct {
+	repository.CommandRepository
+	mu       sync.Mutex
+	commands map[string]models.WasherCommand
+}
+
+func (r *fakeCommandRepo) FindCommandByID(ctx context.Context, id string) (*models.WasherCommand, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	command, ok := r.commands[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &command, nil
+}
+
+func (r *fakeCommandRepo) SaveCommand(ctx context.Context, command *models.WasherCommand) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.commands[command.ID] = *command
+	return nil
+}
+
+type fakeMachineRegistry struct {
+	repository.RegistryRepository
+}
+
+func (fakeMachineRegistry) FindMachineByID(ctx context.Context, id string) (*models.Machine, error) {
+	return &models.Machine{ID: id, DormID: "d1"}, nil
+}
+
+// fakeMQTT เรียก onPublish แทนการส่งจริง ใช้จำลองอุปกรณ์ที่ตอบ ack
+type fakeMQTT struct {
+	mqtt.Client
+	onPublish func(topic string, msg commandMessage)
+}
+
+func (c *fakeMQTT) Publish(topic string, payload string) error {
+	var msg commandMessage
+	if err := json.Unmarshal([]byte(payload), &msg); err != nil {
+		return err
+	}
+	if c.onPublish != nil {
+		c.onPublish(topic, msg)
+	}
+	return nil
+}
+
+func newCommandFixture(onPublish func(s *commandService, msg commandMessage)) (*commandService, *fakeCommandRepo) {
+	repo := &fakeCommandRepo{commands: make(map[string]models.WasherCommand)}
+	client := &fakeMQTT{}
+	service := NewCommandService(repo, fakeMachineRegistry{}, client, 50*time.Millisecond).(*commandService)
+	if onPublish != nil {
+		client.onPublish = func(topic string, msg commandMessage) { onPublish(service, msg) }
+	}
+	return service, repo
+}
+
+func ackPayload(id string, ok bool) []byte {
+	return []byte(fmt.Sprintf(`{"id":%q,"ok":%t,"message":"done"}`, id, ok))
+}
+
+func TestCommandSendReceivesAck(t *testing.T) {
+	service, _ := newCommandFixture(func(s *commandService, msg commandMessage) {
+		go s.HandleMQTTAck("washingMachine/d1/w1/ack", ackPayload(msg.ID, true))
+	})
+
+	command, err := service.Send(context.Background(), "w1", SendCommandRequest{Command: models.CommandReset})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if command.Status != models.CommandAcked {
+		t.Fatalf("status = %s, want acked", command.Status)
+	}
+}
+
+func TestCommandSendIgnoresAckFromOtherWasher(t *testing.T) {
+	service, _ := newCommandFixture(func(s *commandService, msg commandMessage) {
+		go s.HandleMQTTAck("washingMachine/d1/w2/ack", ackPayload(msg.ID, true))
+	})
+
+	command, err := service.Send(context.Background(), "w1", SendCommandRequest{Command: models.CommandReset})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if command.Status != models.CommandTimeout {
+		t.Fatalf("status = %s, want timeout when only another washer acks", command.Status)
+	}
+}
+
+func TestCommandLateAckIsRecorded(t *testing.T) {
+	var id string
+	service, repo := newCommandFixture(func(s *commandService, msg commandMessage) {
+		id = msg.ID
+	})
+
+	command, err := service.Send(context.Background(), "w1", SendCommandRequest{Command: models.CommandReset})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if command.Status != models.CommandTimeout {
+		t.Fatalf("status = %s, want timeout", command.Status)
+	}
+
+	service.HandleMQTTAck("washingMachine/d1/w1/ack", ackPayload(id, false))
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if saved, _ := repo.FindCommandByID(context.Background(), id); saved.Status == models.CommandRejected {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	saved, _ := repo.FindCommandByID(context.Background(), id)
+	t.Fatalf("saved status = %s, want rejected from the late ack", saved.Status)
+}
+
+func TestCommandAckAfterWaiterRemovedIsNotLost(t *testing.T) {
+	service, _ := newCommandFixture(nil)
+	ackCh := make(chan commandAck, 1)
+	service.waiters["c1"] = commandWaiter{washerID: "w1", ch: ackCh}
+
+	service.HandleMQTTAck("washingMachine/d1/w1/ack", ackPayload("c1", true))
+
+	// Send หมดเวลาหลัง HandleMQTTAck ถอน waiter ไปแล้ว ack ต้องรออยู่ใน channel
+	if service.removeWaiter("c1") {
+		t.Fatal("removeWaiter() = true, want false after the ack claimed the waiter")
+	}
+	select {
+	case ack := <-ackCh:
+		if !ack.OK {
+			t.Fatalf("ack = %+v, want ok", ack)
+		}
+	default:
+		t.Fatal("ack was dropped")
+	}
+}
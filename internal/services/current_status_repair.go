@@ -0,0 +1,33 @@
+package services
+
+import (
+	"context"
+
+	"github.com/jaytnw/bms-service/internal/repository"
+)
+
+// RepairCurrentStatuses สร้าง washer_current_status ใหม่จากประวัติ (ดู StatusRepository.RebuildCurrentStatuses)
+// เมื่อ force จะ sync washer:state ใน Redis ให้ตรงกับตารางด้วย เพราะ server ที่หยุดอยู่จะเชื่อสถานะใน Redis ก่อนฐานข้อมูลเมื่อเริ่มใหม่
+// โหมดปกติไม่แตะ Redis เพราะ server ที่ทำงานอยู่เขียนสถานะล่าสุดลง Redis เองและ cache ไว้ใน memory
+func RepairCurrentStatuses(ctx context.Context, statusRepo repository.StatusRepository, stateStore WasherStateStore, force bool) (repository.CurrentStatusRebuild, error) {
+	rebuild, err := statusRepo.RebuildCurrentStatuses(ctx, force)
+	if err != nil || !force {
+		return rebuild, err
+	}
+
+	currents, err := statusRepo.FindLatestPerWasher(ctx, "")
+	if err != nil {
+		return rebuild, err
+	}
+	snapshots := make([]WasherSnapshot, 0, len(currents))
+	for _, current := range currents {
+		snapshots = append(snapshots, WasherSnapshot{
+			DormID:   current.DormID,
+			WasherID: current.WasherID,
+			Status:   current.Status,
+			Since:    current.CreatedAt,
+			LastSeen: current.CreatedAt,
+		})
+	}
+	return rebuild, stateStore.Replace(ctx, snapshots)
+}
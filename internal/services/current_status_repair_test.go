@@ -0,0 +1,80 @@
+package services
+
+import (
+	"context"
+	"maps"
+	"testing"
+	"time"
+
+	"github.com/jaytnw/bms-service/internal/models"
+	"github.com/jaytnw/bms-service/internal/repository"
+)
+
+// fakeCurrentStatusRepo จำลอง washer_current_status หลัง rebuild ด้วย currents
+type fakeCurrentStatusRepo struct {
+	repository.StatusRepository
+	currents []models.Status
+	force    *bool
+}
+
+func (r *fakeCurrentStatusRepo) RebuildCurrentStatuses(ctx context.Context, force bool) (repository.CurrentStatusRebuild, error) {
+	r.force = &force
+	return repository.CurrentStatusRebuild{Written: int64(len(r.currents))}, nil
+}
+
+func (r *fakeCurrentStatusRepo) FindLatestPerWasher(ctx context.Context, dormID string) ([]models.Status, error) {
+	return r.currents, nil
+}
+
+func TestRepairCurrentStatuses(t *testing.T) {
+	reported := time.Date(2026, 10, 12, 9, 0, 0, 0, time.UTC)
+	currents := []models.Status{
+		{ID: 10, DormID: "d1", WasherID: "w1", Status: models.WasherIdle, CreatedAt: reported},
+		{ID: 11, DormID: "d1", WasherID: "w2", Status: models.WasherWashing, CreatedAt: reported.Add(time.Minute)},
+	}
+	// w1 ใน Redis เพี้ยนไปเป็นสถานะในอนาคต และ w3 ไม่มีประวัติแล้ว
+	stale := []WasherSnapshot{
+		{DormID: "d1", WasherID: "w1", Status: models.WasherFault, Since: reported.Add(time.Hour), LastSeen: reported.Add(time.Hour)},
+		{DormID: "d2", WasherID: "w3", Status: models.WasherIdle, Since: reported, LastSeen: reported},
+	}
+
+	tests := []struct {
+		name  string
+		force bool
+		want  map[string]WasherSnapshot
+	}{
+		{
+			name: "online rebuild leaves the state store to the running server",
+			want: map[string]WasherSnapshot{"w1": stale[0], "w3": stale[1]},
+		},
+		{
+			name:  "forced rebuild resyncs the state store",
+			force: true,
+			want: map[string]WasherSnapshot{
+				"w1": {DormID: "d1", WasherID: "w1", Status: models.WasherIdle, Since: reported, LastSeen: reported},
+				"w2": {DormID: "d1", WasherID: "w2", Status: models.WasherWashing, Since: reported.Add(time.Minute), LastSeen: reported.Add(time.Minute)},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeCurrentStatusRepo{currents: currents}
+			store := newFakeStateStore(stale...)
+
+			rebuild, err := RepairCurrentStatuses(context.Background(), repo, store, tt.force)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if repo.force == nil || *repo.force != tt.force {
+				t.Fatalf("RebuildCurrentStatuses force = %v, want %v", repo.force, tt.force)
+			}
+			if rebuild.Written != int64(len(currents)) {
+				t.Fatalf("written = %d, want %d", rebuild.Written, len(currents))
+			}
+			if !maps.Equal(store.snapshots, tt.want) {
+				t.Fatalf("state store = %v, want %v", store.snapshots, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package services
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jaytnw/bms-service/internal/models"
+	"github.com/jaytnw/bms-service/internal/repository"
+)
+
+const (
+	etaHistorySize    = 50
+	etaMinSamples     = 5
+	etaStatsTTL       = 10 * time.Minute
+	etaOverdueFactor  = 1.25
+	etaFullConfidence = 20
+	// telemetry ที่เก่ากว่านี้ไม่นำมาใช้ เพราะ remainingSeconds อาจไม่ตรงแล้ว
+	etaTelemetryMaxAge = 2 * time.Minute
+)
+
+// cycleStats สรุประยะเวลารอบซักที่จบแบบ completed ของโปรแกรมซัก เครื่อง หรือหอ
+type cycleStats struct {
+	median     time.Duration
+	p90        time.Duration
+	spread     float64
+	sampleSize int
+	loadedAt   time.Time
+}
+
+// EtaService ประเมินเวลาที่รอบซักที่กำลังทำงานอยู่จะเสร็จ
+type EtaService interface {
+	Estimate(ctx context.Context, washerID, dormID string, now time.Time) (*models.WasherETA, error)
+}
+
+type etaService struct {
+	sessionRepo   repository.SessionRepository
+	telemetryRepo repository.TelemetryRepository
+	mu            sync.Mutex
+	stats         map[string]cycleStats
+}
+
+func NewEtaService(sessionRepo repository.SessionRepository, telemetryRepo repository.TelemetryRepository) EtaService {
+	return &etaService{
+		sessionRepo:   sessionRepo,
+		telemetryRepo: telemetryRepo,
+		stats:         make(map[string]cycleStats),
+	}
+}
+
+// Estimate คืน nil เมื่อเครื่องไม่ได้อยู่ในรอบซัก หรือยังไม่มีประวัติพอจะประเมิน
+// หากเครื่องรายงาน remainingSeconds มาไม่นานจะใช้ค่านั้นก่อน
+// มิฉะนั้นใช้ประวัติของโปรแกรมซักเดียวกันในหอ (ถ้ารู้โปรแกรม) แล้วจึงถอยไปใช้ประวัติของเครื่องนั้นเอง
+// และของทั้งหอ ตามลำดับ เมื่อประวัติระดับก่อนหน้าน้อยกว่า etaMinSamples
+func (s *etaService) Estimate(ctx context.Context, washerID, dormID string, now time.Time) (*models.WasherETA, error) {
+	open, err := s.sessionRepo.FindOpenByWasherID(ctx, washerID)
+	if err != nil || open == nil {
+		return nil, err
+	}
+
+	telemetry, err := s.telemetryRepo.FindLatestTelemetry(ctx, washerID)
+	if err != nil {
+		return nil, err
+	}
+	if telemetry != nil && !telemetry.CreatedAt.After(open.StartedAt) {
+		telemetry = nil
+	}
+	program := open.Program
+	if program == "" && telemetry != nil {
+		program = telemetry.Program
+	}
+
+	var stats cycleStats
+	if program != "" {
+		stats, err = s.cycleStats(ctx, "program:"+dormID+":"+program, repository.SessionQuery{DormID: dormID, Program: program})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if stats.sampleSize < etaMinSamples {
+		stats, err = s.cycleStats(ctx, "washer:"+washerID, repository.SessionQuery{WasherID: washerID})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if stats.sampleSize < etaMinSamples && dormID != "" {
+		stats, err = s.cycleStats(ctx, "dorm:"+dormID, repository.SessionQuery{DormID: dormID})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if telemetry != nil && telemetry.RemainingSeconds != nil && now.Sub(telemetry.CreatedAt) <= etaTelemetryMaxAge {
+		return deviceETA(open.StartedAt, telemetry, stats, now), nil
+	}
+
+	if stats.sampleSize == 0 {
+		return nil, nil
+	}
+
+	elapsed := now.Sub(open.StartedAt)
+	expectedFinish := open.StartedAt.Add(stats.median)
+	remaining := int64(math.Max(0, expectedFinish.Sub(now).Seconds()))
+
+	// ความมั่นใจขึ้นกับจำนวนตัวอย่างและความสม่ำเสมอของระยะเวลารอบซัก
+	confidence := math.Min(float64(stats.sampleSize)/etaFullConfidence, 1) * (1 - math.Min(stats.spread, 1))
+
+	return &models.WasherETA{
+		StartedAt:        open.StartedAt,
+		ExpectedFinishAt: expectedFinish,
+		RemainingSeconds: remaining,
+		Confidence:       math.Round(confidence*100) / 100,
+		SampleSize:       stats.sampleSize,
+		Overdue:          elapsed > time.Duration(float64(stats.p90)*etaOverdueFactor),
+		Source:           models.ETASourceHistory,
+		Program:          program,
+	}, nil
+}
+
+// deviceETA ใช้ remainingSeconds จากเครื่อง ส่วน overdue ยังเทียบกับ p90 ของประวัติ (หากมี)
+func deviceETA(startedAt time.Time, telemetry *models.WasherTelemetry, stats cycleStats, now time.Time) *models.WasherETA {
+	expectedFinish := telemetry.CreatedAt.Add(time.Duration(*telemetry.RemainingSeconds) * time.Second)
+	remaining := int64(math.Max(0, expectedFinish.Sub(now).Seconds()))
+
+	overdue := false
+	if stats.sampleSize > 0 {
+		overdue = now.Sub(startedAt) > time.Duration(float64(stats.p90)*etaOverdueFactor)
+	}
+
+	return &models.WasherETA{
+		StartedAt:        startedAt,
+		ExpectedFinishAt: expectedFinish,
+		RemainingSeconds: remaining,
+		Confidence:       1,
+		SampleSize:       stats.sampleSize,
+		Overdue:          overdue,
+		Source:           models.ETASourceDevice,
+		Program:          telemetry.Program,
+	}
+}
+
+func (s *etaService) cycleStats(ctx context.Context, key string, query repository.SessionQuery) (cycleStats, error) {
+	s.mu.Lock()
+	cached, ok := s.stats[key]
+	s.mu.Unlock()
+	if ok && time.Since(cached.loadedAt) < etaStatsTTL {
+		return cached, nil
+	}
+
+	query.Outcome = models.SessionCompleted
+	query.Limit = etaHistorySize
+	sessions, err := s.sessionRepo.FindSessions(ctx, query)
+	if err != nil {
+		return cycleStats{}, err
+	}
+
+	durations := make([]time.Duration, 0, len(sessions))
+	for _, session := range sessions {
+		if session.DurationSeconds != nil && *session.DurationSeconds > 0 {
+			durations = append(durations, time.Duration(*session.DurationSeconds)*time.Second)
+		}
+	}
+
+	stats := summarizeDurations(durations)
+	stats.loadedAt = time.Now()
+
+	s.mu.Lock()
+	s.stats[key] = stats
+	s.mu.Unlock()
+
+	return stats, nil
+}
+
+// summarizeDurations คำนวณ median, p90 และ spread (IQR / median) ของระยะเวลารอบซัก
+func summarizeDurations(durations []time.Duration) cycleStats {
+	if len(durations) == 0 {
+		return cycleStats{}
+	}
+
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+
+	median := percentile(durations, 0.5)
+	spread := 0.0
+	if median > 0 {
+		spread = float64(percentile(durations, 0.75)-percentile(durations, 0.25)) / float64(median)
+	}
+
+	return cycleStats{
+		median:     median,
+		p90:        percentile(durations, 0.9),
+		spread:     spread,
+		sampleSize: len(durations),
+	}
+}
+
+// percentile ต้องได้ slice ที่เรียงแล้ว
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	idx := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	return sorted[idx]
+}
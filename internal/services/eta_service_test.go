@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jaytnw/bms-service/internal/models"
+)
+
+func TestSummarizeDurations(t *testing.T) {
+	minutes := func(values ...int) []time.Duration {
+		durations := make([]time.Duration, 0, len(values))
+		for _, v := range values {
+			durations = append(durations, time.Duration(v)*time.Minute)
+		}
+		return durations
+	}
+
+	tests := []struct {
+		name       string
+		durations  []time.Duration
+		median     time.Duration
+		p90        time.Duration
+		spread     float64
+		sampleSize int
+	}{
+		{name: "empty", durations: nil},
+		{name: "single", durations: minutes(40), median: 40 * time.Minute, p90: 40 * time.Minute, sampleSize: 1},
+		{name: "unsorted", durations: minutes(50, 30, 40, 60, 20), median: 40 * time.Minute, p90: 60 * time.Minute, spread: 0.5, sampleSize: 5},
+		{name: "uniform", durations: minutes(45, 45, 45, 45), median: 45 * time.Minute, p90: 45 * time.Minute, sampleSize: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := summarizeDurations(tt.durations)
+			if got.median != tt.median || got.p90 != tt.p90 || got.sampleSize != tt.sampleSize || got.spread != tt.spread {
+				t.Errorf("got median=%v p90=%v spread=%v n=%d; want %v %v %v %d",
+					got.median, got.p90, got.spread, got.sampleSize, tt.median, tt.p90, tt.spread, tt.sampleSize)
+			}
+		})
+	}
+}
+
+func completedSession(washerID, dormID string, minutes int64) models.WashSession {
+	seconds := minutes * 60
+	return models.WashSession{WasherID: washerID, DormID: dormID, DurationSeconds: &seconds, Outcome: models.SessionCompleted}
+}
+
+func TestEstimate(t *testing.T) {
+	now := time.Date(2026, 1, 1, 8, 30, 0, 0, time.UTC)
+	startedAt := now.Add(-30 * time.Minute)
+	open := map[string]*models.WashSession{"w1": {WasherID: "w1", DormID: "d1", StartedAt: startedAt}}
+
+	openQuick := map[string]*models.WashSession{"w1": {WasherID: "w1", DormID: "d1", StartedAt: startedAt, Program: "quick"}}
+
+	var own, dorm, quick []models.WashSession
+	for i := 0; i < 5; i++ {
+		own = append(own, completedSession("w1", "d1", 40))
+		dorm = append(dorm, completedSession("w2", "d1", 60))
+		session := completedSession("w2", "d1", 35)
+		session.Program = "quick"
+		quick = append(quick, session)
+	}
+	remaining := 300
+
+	tests := []struct {
+		name       string
+		sessions   []models.WashSession
+		telemetry  []models.WasherTelemetry
+		open       map[string]*models.WashSession
+		wantNil    bool
+		wantSource string
+		wantFinish time.Time
+		overdue    bool
+	}{
+		{name: "no open session", open: map[string]*models.WashSession{}, sessions: own, wantNil: true},
+		{name: "no history", open: open, wantNil: true},
+		{name: "own history", open: open, sessions: own, wantSource: string(models.ETASourceHistory), wantFinish: startedAt.Add(40 * time.Minute)},
+		{name: "dorm fallback", open: open, sessions: append(own[:2:2], dorm...), wantSource: string(models.ETASourceHistory), wantFinish: startedAt.Add(60 * time.Minute)},
+		{name: "program history", open: openQuick, sessions: append(own[:5:5], quick...), wantSource: string(models.ETASourceHistory), wantFinish: startedAt.Add(35 * time.Minute)},
+		{name: "too few program samples falls back to own history", open: openQuick, sessions: append(own[:5:5], quick[:2]...), wantSource: string(models.ETASourceHistory), wantFinish: startedAt.Add(40 * time.Minute)},
+		{
+			name:       "program from telemetry when the session has none",
+			open:       open,
+			sessions:   append(own[:5:5], quick...),
+			telemetry:  []models.WasherTelemetry{{WasherID: "w1", Program: "quick", CreatedAt: startedAt.Add(time.Minute)}},
+			wantSource: string(models.ETASourceHistory),
+			wantFinish: startedAt.Add(35 * time.Minute),
+		},
+		{
+			name:       "telemetry from before the session is ignored",
+			open:       open,
+			sessions:   append(own[:5:5], quick...),
+			telemetry:  []models.WasherTelemetry{{WasherID: "w1", Program: "quick", RemainingSeconds: &remaining, CreatedAt: startedAt.Add(-time.Minute)}},
+			wantSource: string(models.ETASourceHistory),
+			wantFinish: startedAt.Add(40 * time.Minute),
+		},
+		{
+			name:       "fresh device telemetry wins",
+			open:       open,
+			sessions:   own,
+			telemetry:  []models.WasherTelemetry{{WasherID: "w1", RemainingSeconds: &remaining, CreatedAt: now.Add(-time.Minute)}},
+			wantSource: string(models.ETASourceDevice),
+			wantFinish: now.Add(4 * time.Minute),
+		},
+		{
+			name:       "stale device telemetry is ignored",
+			open:       open,
+			sessions:   own,
+			telemetry:  []models.WasherTelemetry{{WasherID: "w1", RemainingSeconds: &remaining, CreatedAt: now.Add(-10 * time.Minute)}},
+			wantSource: string(models.ETASourceHistory),
+			wantFinish: startedAt.Add(40 * time.Minute),
+		},
+		{
+			name:       "overdue against p90",
+			open:       map[string]*models.WashSession{"w1": {WasherID: "w1", DormID: "d1", StartedAt: now.Add(-60 * time.Minute)}},
+			sessions:   own,
+			wantSource: string(models.ETASourceHistory),
+			wantFinish: now.Add(-20 * time.Minute),
+			overdue:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewEtaService(&fakeSessionRepo{open: tt.open, sessions: tt.sessions}, &fakeTelemetryRepo{saved: tt.telemetry})
+			eta, err := service.Estimate(context.Background(), "w1", "d1", now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantNil {
+				if eta != nil {
+					t.Fatalf("want nil, got %+v", eta)
+				}
+				return
+			}
+			if eta == nil {
+				t.Fatal("want estimate, got nil")
+			}
+			if string(eta.Source) != tt.wantSource || !eta.ExpectedFinishAt.Equal(tt.wantFinish) || eta.Overdue != tt.overdue {
+				t.Errorf("got source=%s finish=%v overdue=%v; want %s %v %v", eta.Source, eta.ExpectedFinishAt, eta.Overdue, tt.wantSource, tt.wantFinish, tt.overdue)
+			}
+		})
+	}
+}
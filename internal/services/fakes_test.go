@@ -0,0 +1,494 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/jaytnw/bms-service/internal/models"
+	"github.com/jaytnw/bms-service/internal/notifications"
+	"github.com/jaytnw/bms-service/internal/repository"
+	"gorm.io/gorm"
+)
+
+// fake ของ repository ฝัง interface ไว้ เมธอดที่ test ไม่ได้ใช้จึง panic หากถูกเรียก
+
+type fakeStateStore struct {
+	mu        sync.Mutex
+	snapshots map[string]WasherSnapshot
+}
+
+func newFakeStateStore(snapshots ...WasherSnapshot) *fakeStateStore {
+	s := &fakeStateStore{snapshots: make(map[string]WasherSnapshot)}
+	for _, snapshot := range snapshots {
+		s.snapshots[snapshot.WasherID] = snapshot
+	}
+	return s
+}
+
+func (s *fakeStateStore) Get(ctx context.Context, washerID string) (*WasherSnapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snapshot, ok := s.snapshots[washerID]
+	if !ok {
+		return nil, nil
+	}
+	return &snapshot, nil
+}
+
+func (s *fakeStateStore) List(ctx context.Context) ([]WasherSnapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := make([]WasherSnapshot, 0, len(s.snapshots))
+	for _, snapshot := range s.snapshots {
+		list = append(list, snapshot)
+	}
+	return list, nil
+}
+
+func (s *fakeStateStore) Save(ctx context.Context, snapshot WasherSnapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snapshots[snapshot.WasherID] = snapshot
+	return nil
+}
+
+func (s *fakeStateStore) Touch(ctx context.Context, washerID string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if snapshot, ok := s.snapshots[washerID]; ok && at.After(snapshot.LastSeen) {
+		snapshot.LastSeen = at
+		s.snapshots[washerID] = snapshot
+	}
+	return nil
+}
+
+func (s *fakeStateStore) Replace(ctx context.Context, snapshots []WasherSnapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snapshots = make(map[string]WasherSnapshot)
+	for _, snapshot := range snapshots {
+		s.snapshots[snapshot.WasherID] = snapshot
+	}
+	return nil
+}
+
+type fakeStatusRepo struct {
+	repository.StatusRepository
+	mu       sync.Mutex
+	saved    []models.Status
+	failures []error
+	nextID   uint
+}
+
+func (r *fakeStatusRepo) SaveStatuses(ctx context.Context, statuses []*models.Status) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.failures) > 0 {
+		err := r.failures[0]
+		r.failures = r.failures[1:]
+		if err != nil {
+			return err
+		}
+	}
+	for _, status := range statuses {
+		r.nextID++
+		status.ID = r.nextID
+		r.saved = append(r.saved, *status)
+	}
+	return nil
+}
+
+func (r *fakeStatusRepo) FindLatestByWasherID(ctx context.Context, washerID string) (*models.Status, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := len(r.saved) - 1; i >= 0; i-- {
+		if r.saved[i].WasherID == washerID {
+			latest := r.saved[i]
+			return &latest, nil
+		}
+	}
+	return &models.Status{}, nil
+}
+
+func (r *fakeStatusRepo) savedStatuses() []models.Status {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]models.Status(nil), r.saved...)
+}
+
+type fakeTelemetryRepo struct {
+	repository.TelemetryRepository
+	mu    sync.Mutex
+	saved []models.WasherTelemetry
+}
+
+func (r *fakeTelemetryRepo) FindLatestTelemetry(ctx context.Context, washerID string) (*models.WasherTelemetry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := len(r.saved) - 1; i >= 0; i-- {
+		if r.saved[i].WasherID == washerID {
+			latest := r.saved[i]
+			return &latest, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeTelemetryRepo) SaveTelemetries(ctx context.Context, telemetries []*models.WasherTelemetry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, telemetry := range telemetries {
+		r.saved = append(r.saved, *telemetry)
+	}
+	return nil
+}
+
+type fakeAnomalyRepo struct {
+	repository.AnomalyRepository
+	mu    sync.Mutex
+	saved []models.StatusAnomaly
+}
+
+func (r *fakeAnomalyRepo) SaveAnomaly(ctx context.Context, anomaly *models.StatusAnomaly) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.saved = append(r.saved, *anomaly)
+	return nil
+}
+
+func (r *fakeAnomalyRepo) kinds() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	kinds := make([]string, 0, len(r.saved))
+	for _, anomaly := range r.saved {
+		kinds = append(kinds, string(anomaly.Kind))
+	}
+	return kinds
+}
+
+type fakeMaintenance struct {
+	washers map[string]bool
+}
+
+func (m fakeMaintenance) ActiveWindow(ctx context.Context, dormID, washerID string, at time.Time) *models.MaintenanceWindow {
+	if m.washers[washerID] {
+		return &models.MaintenanceWindow{WasherID: washerID}
+	}
+	return nil
+}
+
+type fakeRegistrar struct {
+	mu   sync.Mutex
+	seen []string
+}
+
+func (r *fakeRegistrar) RegisterSeen(ctx context.Context, dormID, washerID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.seen = append(r.seen, dormID+"/"+washerID)
+}
+
+type fakeBroker struct {
+	mu        sync.Mutex
+	published []StatusChange
+}
+
+func (b *fakeBroker) Publish(ctx context.Context, change StatusChange) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.published = append(b.published, change)
+}
+
+func (b *fakeBroker) Subscribe(filter StatusFilter) (<-chan StatusEvent, func()) {
+	return nil, func() {}
+}
+
+func (b *fakeBroker) AddListener(listener StatusListener) {}
+
+func (b *fakeBroker) changes() []StatusChange {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]StatusChange(nil), b.published...)
+}
+
+var errFakeDB = errors.New("fake db error")
+
+// statusServiceFixture รวม fake ที่ statusService ใช้ในการรับข้อความ
+type statusServiceFixture struct {
+	service   *statusService
+	state     *fakeStateStore
+	statuses  *fakeStatusRepo
+	telemetry *fakeTelemetryRepo
+	anomalies *fakeAnomalyRepo
+	registrar *fakeRegistrar
+	broker    *fakeBroker
+}
+
+func newStatusServiceFixture(snapshots ...WasherSnapshot) *statusServiceFixture {
+	f := &statusServiceFixture{
+		state:     newFakeStateStore(snapshots...),
+		statuses:  &fakeStatusRepo{},
+		telemetry: &fakeTelemetryRepo{},
+		anomalies: &fakeAnomalyRepo{},
+		registrar: &fakeRegistrar{},
+		broker:    &fakeBroker{},
+	}
+	f.service = &statusService{
+		statusRepo:    f.statuses,
+		anomalyRepo:   f.anomalies,
+		telemetryRepo: f.telemetry,
+		registrar:     f.registrar,
+		broker:        f.broker,
+		stateStore:    f.state,
+		maintenance:   fakeMaintenance{},
+		offlineAfter:  5 * time.Minute,
+	}
+	return f
+}
+
+type fakeSessionRepo struct {
+	repository.SessionRepository
+	open     map[string]*models.WashSession
+	sessions []models.WashSession
+}
+
+func (r *fakeSessionRepo) FindOpenByWasherID(ctx context.Context, washerID string) (*models.WashSession, error) {
+	return r.open[washerID], nil
+}
+
+func (r *fakeSessionRepo) SaveSession(ctx context.Context, session *models.WashSession) error {
+	if r.open == nil {
+		r.open = make(map[string]*models.WashSession)
+	}
+	saved := *session
+	if saved.EndedAt == nil {
+		r.open[saved.WasherID] = &saved
+		return nil
+	}
+	delete(r.open, saved.WasherID)
+	r.sessions = append(r.sessions, saved)
+	return nil
+}
+
+func (r *fakeSessionRepo) UpsertSessions(ctx context.Context, sessions []models.WashSession) error {
+	r.sessions = append(r.sessions, sessions...)
+	return nil
+}
+
+func (r *fakeSessionRepo) FindSessions(ctx context.Context, query repository.SessionQuery) ([]models.WashSession, error) {
+	var found []models.WashSession
+	for _, session := range r.sessions {
+		if query.WasherID != "" && session.WasherID != query.WasherID {
+			continue
+		}
+		if query.DormID != "" && session.DormID != query.DormID {
+			continue
+		}
+		if query.Outcome != "" && session.Outcome != query.Outcome {
+			continue
+		}
+		if query.Program != "" && session.Program != query.Program {
+			continue
+		}
+		found = append(found, session)
+	}
+	return found, nil
+}
+
+// fakeQueueRepo เก็บคิวไว้ในหน่วยความจำ และบังคับให้เครื่องหนึ่งถูกถือครองได้ครั้งละหนึ่งคิวเหมือน idx_queue_washer_held
+type fakeQueueRepo struct {
+	repository.QueueRepository
+	mu      sync.Mutex
+	entries map[uint]models.QueueEntry
+	nextID  uint
+}
+
+func newFakeQueueRepo() *fakeQueueRepo {
+	return &fakeQueueRepo{entries: make(map[uint]models.QueueEntry)}
+}
+
+func (r *fakeQueueRepo) FindEntryByID(ctx context.Context, id uint) (*models.QueueEntry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entry, ok := r.entries[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &entry, nil
+}
+
+func (r *fakeQueueRepo) find(match func(models.QueueEntry) bool) []models.QueueEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var found []models.QueueEntry
+	for id := uint(1); id <= r.nextID; id++ {
+		if entry, ok := r.entries[id]; ok && match(entry) {
+			found = append(found, entry)
+		}
+	}
+	return found
+}
+
+func (r *fakeQueueRepo) FindActiveEntries(ctx context.Context, dormID string) ([]models.QueueEntry, error) {
+	return r.find(func(e models.QueueEntry) bool { return e.DormID == dormID && e.Active() }), nil
+}
+
+func (r *fakeQueueRepo) FindActiveByUser(ctx context.Context, dormID, userID string) (*models.QueueEntry, error) {
+	found := r.find(func(e models.QueueEntry) bool { return e.DormID == dormID && e.UserID == userID && e.Active() })
+	if len(found) == 0 {
+		return nil, nil
+	}
+	return &found[0], nil
+}
+
+func (r *fakeQueueRepo) FindHeldByWasherID(ctx context.Context, washerID string) (*models.QueueEntry, error) {
+	found := r.find(func(e models.QueueEntry) bool { return e.WasherID == washerID && queueHeld(e.Status) })
+	if len(found) == 0 {
+		return nil, nil
+	}
+	return &found[0], nil
+}
+
+func (r *fakeQueueRepo) FindExpiredOffers(ctx context.Context, now time.Time) ([]models.QueueEntry, error) {
+	return r.find(func(e models.QueueEntry) bool {
+		return e.Status == models.QueueOffered && e.ClaimExpiresAt != nil && e.ClaimExpiresAt.Before(now)
+	}), nil
+}
+
+func (r *fakeQueueRepo) FindStaleClaims(ctx context.Context, claimedBefore time.Time) ([]models.QueueEntry, error) {
+	return r.find(func(e models.QueueEntry) bool {
+		return e.Status == models.QueueClaimed && e.ClaimedAt != nil && e.ClaimedAt.Before(claimedBefore)
+	}), nil
+}
+
+func (r *fakeQueueRepo) FindWaitingDormIDs(ctx context.Context) ([]string, error) {
+	seen := make(map[string]bool)
+	var dormIDs []string
+	for _, entry := range r.find(func(e models.QueueEntry) bool { return e.Status == models.QueueWaiting }) {
+		if !seen[entry.DormID] {
+			seen[entry.DormID] = true
+			dormIDs = append(dormIDs, entry.DormID)
+		}
+	}
+	return dormIDs, nil
+}
+
+func (r *fakeQueueRepo) SaveEntry(ctx context.Context, entry *models.QueueEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if entry.ID == 0 {
+		r.nextID++
+		entry.ID = r.nextID
+		entry.CreatedAt = time.Now()
+	}
+	r.entries[entry.ID] = *entry
+	return nil
+}
+
+func (r *fakeQueueRepo) UpdateEntryIf(ctx context.Context, entry *models.QueueEntry, from ...models.QueueEntryStatus) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	current, ok := r.entries[entry.ID]
+	if !ok || !slices.Contains(from, current.Status) {
+		return false, nil
+	}
+	if queueHeld(entry.Status) {
+		for id, other := range r.entries {
+			if id != entry.ID && other.WasherID == entry.WasherID && queueHeld(other.Status) {
+				return false, nil
+			}
+		}
+	}
+	r.entries[entry.ID] = *entry
+	return true, nil
+}
+
+func (r *fakeQueueRepo) entry(id uint) models.QueueEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.entries[id]
+}
+
+func queueHeld(status models.QueueEntryStatus) bool {
+	return slices.Contains(models.HeldStatuses, status)
+}
+
+type fakeLiveQueue struct {
+	mu    sync.Mutex
+	dorms map[string][]uint
+}
+
+func newFakeLiveQueue() *fakeLiveQueue {
+	return &fakeLiveQueue{dorms: make(map[string][]uint)}
+}
+
+func (q *fakeLiveQueue) Push(ctx context.Context, dormID string, ids ...uint) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.dorms[dormID] = append(q.dorms[dormID], ids...)
+	return nil
+}
+
+func (q *fakeLiveQueue) PushFront(ctx context.Context, dormID string, id uint) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.dorms[dormID] = append([]uint{id}, q.dorms[dormID]...)
+	return nil
+}
+
+func (q *fakeLiveQueue) Pop(ctx context.Context, dormID string) (uint, bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	ids := q.dorms[dormID]
+	if len(ids) == 0 {
+		return 0, false, nil
+	}
+	q.dorms[dormID] = ids[1:]
+	return ids[0], true, nil
+}
+
+func (q *fakeLiveQueue) Remove(ctx context.Context, dormID string, id uint) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.dorms[dormID] = slices.DeleteFunc(q.dorms[dormID], func(other uint) bool { return other == id })
+	return nil
+}
+
+func (q *fakeLiveQueue) List(ctx context.Context, dormID string) ([]uint, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]uint(nil), q.dorms[dormID]...), nil
+}
+
+type fakeDispatcher struct {
+	mu   sync.Mutex
+	sent []notifications.Message
+}
+
+func (d *fakeDispatcher) Notify(ctx context.Context, msg notifications.Message) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.sent = append(d.sent, msg)
+	return nil
+}
+
+func (d *fakeDispatcher) Drain(ctx context.Context) error {
+	return nil
+}
+
+func (d *fakeDispatcher) Supports(channel string) bool {
+	return channel == notifications.ChannelLog
+}
+
+func (d *fakeDispatcher) subjects() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	subjects := make([]string, 0, len(d.sent))
+	for _, msg := range d.sent {
+		subjects = append(subjects, msg.Subject)
+	}
+	return subjects
+}
@@ -0,0 +1,270 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jaytnw/bms-service/internal/apperr"
+	"github.com/jaytnw/bms-service/internal/models"
+	"github.com/jaytnw/bms-service/internal/repository"
+	"gorm.io/gorm"
+)
+
+// IncidentService เปิด incident เมื่อเครื่องเข้าสถานะ fault และปิดเมื่อเครื่องกลับมาทำงานปกติ
+// พร้อมจัดการ catalogue ของรหัสข้อผิดพลาด
+type IncidentService interface {
+	StatusListener
+	ListFaultCodes(ctx context.Context) ([]models.FaultCode, error)
+	GetFaultCode(ctx context.Context, code string) (*models.FaultCode, error)
+	PutFaultCode(ctx context.Context, code string, input *models.FaultCode) (*models.FaultCode, error)
+	DeleteFaultCode(ctx context.Context, code string) error
+
+	ListIncidents(ctx context.Context, filter repository.IncidentFilter) ([]models.Incident, error)
+	GetIncident(ctx context.Context, id uint) (*models.Incident, error)
+	Acknowledge(ctx context.Context, id uint, by string) (*models.Incident, error)
+	AddComment(ctx context.Context, id uint, comment *models.IncidentComment) error
+	GetMTTR(ctx context.Context, from, to *time.Time) ([]models.DormMTTR, error)
+}
+
+type incidentService struct {
+	incidentRepo repository.IncidentRepository
+}
+
+func NewIncidentService(incidentRepo repository.IncidentRepository) IncidentService {
+	return &incidentService{
+		incidentRepo: incidentRepo,
+	}
+}
+
+// OnStatusChange เปิด incident เมื่อเข้าสถานะ fault และปิดเมื่อเปลี่ยนจาก fault ไปสถานะอื่นที่ไม่ใช่ offline
+// (เครื่องที่เสียแล้วหลุดการเชื่อมต่อยังถือว่าเสียอยู่)
+// fault ระหว่างซ่อมบำรุงเกิดจากช่างทดสอบเครื่องจึงไม่เปิด incident แต่เครื่องที่ช่างซ่อมจนกลับมาปกติยังปิด incident ตามเดิม
+func (s *incidentService) OnStatusChange(ctx context.Context, change StatusChange) {
+	status := change.Status
+
+	switch {
+	case status.Status == models.WasherFault:
+		if !status.Maintenance {
+			s.open(ctx, change)
+		}
+	case status.Status != models.WasherOffline &&
+		(change.Previous == models.WasherFault || change.Previous == models.WasherOffline):
+		s.resolve(ctx, status)
+	}
+}
+
+func (s *incidentService) open(ctx context.Context, change StatusChange) {
+	status := change.Status
+
+	existing, err := s.incidentRepo.FindUnresolvedByWasherID(ctx, status.WasherID)
+	if err != nil {
+		log.Printf("❌ Failed to load incident for washer %s: %v", status.WasherID, err)
+		return
+	}
+
+	var code string
+	if change.Telemetry != nil {
+		code = change.Telemetry.ErrorCode
+	}
+
+	// fault ต่อเนื่อง (เช่น fault -> offline -> fault) ใช้ incident เดิม แต่เติมรหัสหากเพิ่งได้มา
+	if existing != nil {
+		if existing.FaultCode == "" && code != "" {
+			existing.FaultCode = code
+			existing.Severity = s.severityOf(ctx, code)
+			if err := s.incidentRepo.SaveIncident(ctx, existing); err != nil {
+				log.Printf("❌ Failed to update incident %d: %v", existing.ID, err)
+			}
+		}
+		return
+	}
+
+	incident := &models.Incident{
+		DormID:    status.DormID,
+		WasherID:  status.WasherID,
+		FaultCode: code,
+		Severity:  s.severityOf(ctx, code),
+		Status:    models.IncidentOpen,
+		OpenedAt:  status.CreatedAt,
+	}
+	if err := s.incidentRepo.SaveIncident(ctx, incident); err != nil {
+		log.Printf("❌ Failed to open incident for washer %s: %v", status.WasherID, err)
+		return
+	}
+	log.Printf("🚨 Opened incident %d for washer %s (code %q)", incident.ID, status.WasherID, code)
+}
+
+func (s *incidentService) resolve(ctx context.Context, status models.Status) {
+	incident, err := s.incidentRepo.FindUnresolvedByWasherID(ctx, status.WasherID)
+	if err != nil {
+		log.Printf("❌ Failed to load incident for washer %s: %v", status.WasherID, err)
+		return
+	}
+	if incident == nil {
+		return
+	}
+
+	resolvedAt := status.CreatedAt
+	duration := int64(resolvedAt.Sub(incident.OpenedAt).Seconds())
+	incident.Status = models.IncidentResolved
+	incident.ResolvedAt = &resolvedAt
+	incident.DurationSeconds = &duration
+	if err := s.incidentRepo.SaveIncident(ctx, incident); err != nil {
+		log.Printf("❌ Failed to resolve incident %d: %v", incident.ID, err)
+		return
+	}
+	log.Printf("✅ Resolved incident %d for washer %s after %ds", incident.ID, status.WasherID, duration)
+}
+
+// severityOf คืนระดับความรุนแรงจาก catalogue หรือ medium หากไม่รู้จักรหัส
+func (s *incidentService) severityOf(ctx context.Context, code string) string {
+	if code == "" {
+		return models.SeverityMedium
+	}
+	faultCode, err := s.incidentRepo.FindFaultCode(ctx, code)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("⚠️ Failed to look up fault code %s: %v", code, err)
+		}
+		return models.SeverityMedium
+	}
+	return faultCode.Severity
+}
+
+func (s *incidentService) ListFaultCodes(ctx context.Context) ([]models.FaultCode, error) {
+	codes, err := s.incidentRepo.FindFaultCodes(ctx)
+	if err != nil {
+		return nil, apperr.New("DB_ERROR", "Failed to get fault codes", 500, err)
+	}
+	return codes, nil
+}
+
+func (s *incidentService) GetFaultCode(ctx context.Context, code string) (*models.FaultCode, error) {
+	faultCode, err := s.incidentRepo.FindFaultCode(ctx, code)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperr.New("NOT_FOUND", "Fault code not found", 404, err)
+		}
+		return nil, apperr.New("DB_ERROR", "Failed to get fault code", 500, err)
+	}
+	return faultCode, nil
+}
+
+// PutFaultCode เพิ่มหรือแทนที่รหัสข้อผิดพลาด
+func (s *incidentService) PutFaultCode(ctx context.Context, code string, input *models.FaultCode) (*models.FaultCode, error) {
+	input.Code = strings.TrimSpace(code)
+	if input.Code == "" || strings.TrimSpace(input.Description) == "" {
+		return nil, apperr.New("VALIDATION_ERROR", "code and description are required", 400, nil)
+	}
+	if input.Severity == "" {
+		input.Severity = models.SeverityMedium
+	}
+	if !models.ValidSeverity(input.Severity) {
+		return nil, apperr.New("VALIDATION_ERROR", "severity must be low, medium, high or critical", 400, nil)
+	}
+
+	if err := s.incidentRepo.UpsertFaultCode(ctx, input); err != nil {
+		return nil, apperr.New("DB_ERROR", "Failed to save fault code", 500, err)
+	}
+	return s.GetFaultCode(ctx, input.Code)
+}
+
+func (s *incidentService) DeleteFaultCode(ctx context.Context, code string) error {
+	if err := s.incidentRepo.DeleteFaultCode(ctx, code); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperr.New("NOT_FOUND", "Fault code not found", 404, err)
+		}
+		return apperr.New("DB_ERROR", "Failed to delete fault code", 500, err)
+	}
+	return nil
+}
+
+func (s *incidentService) ListIncidents(ctx context.Context, filter repository.IncidentFilter) ([]models.Incident, error) {
+	incidents, err := s.incidentRepo.FindIncidents(ctx, filter)
+	if err != nil {
+		return nil, apperr.New("DB_ERROR", "Failed to get incidents", 500, err)
+	}
+	return incidents, nil
+}
+
+// GetIncident คืน incident พร้อมรายละเอียดรหัสข้อผิดพลาดและความเห็นทั้งหมด
+func (s *incidentService) GetIncident(ctx context.Context, id uint) (*models.Incident, error) {
+	incident, err := s.findIncident(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if incident.FaultCode != "" {
+		if faultCode, err := s.incidentRepo.FindFaultCode(ctx, incident.FaultCode); err == nil {
+			incident.Fault = faultCode
+		}
+	}
+
+	comments, err := s.incidentRepo.FindComments(ctx, id)
+	if err != nil {
+		return nil, apperr.New("DB_ERROR", "Failed to get incident comments", 500, err)
+	}
+	incident.Comments = comments
+	return incident, nil
+}
+
+func (s *incidentService) Acknowledge(ctx context.Context, id uint, by string) (*models.Incident, error) {
+	if strings.TrimSpace(by) == "" {
+		return nil, apperr.New("VALIDATION_ERROR", "acknowledgedBy is required", 400, nil)
+	}
+
+	incident, err := s.findIncident(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if incident.Status != models.IncidentOpen {
+		return nil, apperr.New("CONFLICT", "Only open incidents can be acknowledged", 409, nil)
+	}
+
+	now := time.Now()
+	incident.Status = models.IncidentAcknowledged
+	incident.AcknowledgedAt = &now
+	incident.AcknowledgedBy = by
+	if err := s.incidentRepo.SaveIncident(ctx, incident); err != nil {
+		return nil, apperr.New("DB_ERROR", "Failed to acknowledge incident", 500, err)
+	}
+	return incident, nil
+}
+
+func (s *incidentService) AddComment(ctx context.Context, id uint, comment *models.IncidentComment) error {
+	if strings.TrimSpace(comment.Author) == "" || strings.TrimSpace(comment.Body) == "" {
+		return apperr.New("VALIDATION_ERROR", "author and body are required", 400, nil)
+	}
+	if _, err := s.findIncident(ctx, id); err != nil {
+		return err
+	}
+
+	comment.ID = 0
+	comment.IncidentID = id
+	if err := s.incidentRepo.SaveComment(ctx, comment); err != nil {
+		return apperr.New("DB_ERROR", "Failed to add comment", 500, err)
+	}
+	return nil
+}
+
+func (s *incidentService) GetMTTR(ctx context.Context, from, to *time.Time) ([]models.DormMTTR, error) {
+	rows, err := s.incidentRepo.MTTRByDorm(ctx, from, to)
+	if err != nil {
+		return nil, apperr.New("DB_ERROR", "Failed to compute MTTR", 500, err)
+	}
+	return rows, nil
+}
+
+func (s *incidentService) findIncident(ctx context.Context, id uint) (*models.Incident, error) {
+	incident, err := s.incidentRepo.FindIncidentByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperr.New("NOT_FOUND", "Incident not found", 404, err)
+		}
+		return nil, apperr.New("DB_ERROR", "Failed to get incident", 500, err)
+	}
+	return incident, nil
+}
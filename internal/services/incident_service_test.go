@@ -0,0 +1,208 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jaytnw/bms-service/internal/models"
+	"github.com/jaytnw/bms-service/internal/repository"
+	"gorm.io/gorm"
+)
+
+type fakeIncidentRepo struct {
+	repository.IncidentRepository
+	incidents  []models.Incident
+	faultCodes map[string]models.FaultCode
+	lookupErr  error
+}
+
+func (r *fakeIncidentRepo) FindUnresolvedByWasherID(ctx context.Context, washerID string) (*models.Incident, error) {
+	for i := len(r.incidents) - 1; i >= 0; i-- {
+		if r.incidents[i].WasherID == washerID && r.incidents[i].Status != models.IncidentResolved {
+			incident := r.incidents[i]
+			return &incident, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeIncidentRepo) SaveIncident(ctx context.Context, incident *models.Incident) error {
+	if incident.ID == 0 {
+		incident.ID = uint(len(r.incidents) + 1)
+		r.incidents = append(r.incidents, *incident)
+		return nil
+	}
+	r.incidents[incident.ID-1] = *incident
+	return nil
+}
+
+func (r *fakeIncidentRepo) FindFaultCode(ctx context.Context, code string) (*models.FaultCode, error) {
+	if r.lookupErr != nil {
+		return nil, r.lookupErr
+	}
+	faultCode, ok := r.faultCodes[code]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &faultCode, nil
+}
+
+func TestIncidentListenerOpensAndResolves(t *testing.T) {
+	start := time.Date(2026, 10, 12, 9, 0, 0, 0, time.UTC)
+	change := func(minute int, previous, state models.WasherState, code string) StatusChange {
+		at := start.Add(time.Duration(minute) * time.Minute)
+		c := StatusChange{
+			Status:   models.Status{WasherID: "w1", DormID: "d1", Status: state, CreatedAt: at},
+			Previous: previous,
+		}
+		if code != "" {
+			c.Telemetry = &models.WasherTelemetry{WasherID: "w1", ErrorCode: code, CreatedAt: at}
+		}
+		return c
+	}
+
+	maintenance := func(c StatusChange) StatusChange {
+		c.Status.Maintenance = true
+		return c
+	}
+
+	type incident struct {
+		status   models.IncidentStatus
+		code     string
+		severity string
+		duration int64
+	}
+	tests := []struct {
+		name    string
+		changes []StatusChange
+		want    []incident
+	}{
+		{
+			name:    "fault opens with the reported code",
+			changes: []StatusChange{change(0, models.WasherWashing, models.WasherFault, "E21")},
+			want:    []incident{{status: models.IncidentOpen, code: "E21", severity: models.SeverityHigh}},
+		},
+		{
+			name:    "recovery resolves with the repair time",
+			changes: []StatusChange{change(0, models.WasherWashing, models.WasherFault, "E21"), change(45, models.WasherFault, models.WasherIdle, "")},
+			want:    []incident{{status: models.IncidentResolved, code: "E21", severity: models.SeverityHigh, duration: 45 * 60}},
+		},
+		{
+			name:    "offline after fault keeps the incident open",
+			changes: []StatusChange{change(0, models.WasherIdle, models.WasherFault, ""), change(5, models.WasherFault, models.WasherOffline, "")},
+			want:    []incident{{status: models.IncidentOpen, severity: models.SeverityMedium}},
+		},
+		{
+			name: "fault again after offline reuses the incident and fills the code",
+			changes: []StatusChange{
+				change(0, models.WasherIdle, models.WasherFault, ""),
+				change(5, models.WasherFault, models.WasherOffline, ""),
+				change(10, models.WasherOffline, models.WasherFault, "E21"),
+			},
+			want: []incident{{status: models.IncidentOpen, code: "E21", severity: models.SeverityHigh}},
+		},
+		{
+			name: "coming back from offline resolves",
+			changes: []StatusChange{
+				change(0, models.WasherIdle, models.WasherFault, "E99"),
+				change(5, models.WasherFault, models.WasherOffline, ""),
+				change(30, models.WasherOffline, models.WasherIdle, ""),
+			},
+			want: []incident{{status: models.IncidentResolved, code: "E99", severity: models.SeverityMedium, duration: 30 * 60}},
+		},
+		{
+			name:    "fault during maintenance does not open",
+			changes: []StatusChange{maintenance(change(0, models.WasherIdle, models.WasherFault, "E21"))},
+		},
+		{
+			name: "repair during maintenance resolves and the window ending changes nothing",
+			changes: []StatusChange{
+				change(0, models.WasherWashing, models.WasherFault, "E21"),
+				maintenance(change(30, models.WasherFault, models.WasherFault, "E21")),
+				maintenance(change(90, models.WasherFault, models.WasherIdle, "")),
+				change(120, models.WasherIdle, models.WasherIdle, ""),
+			},
+			want: []incident{{status: models.IncidentResolved, code: "E21", severity: models.SeverityHigh, duration: 90 * 60}},
+		},
+		{
+			name: "machine back online during maintenance resolves",
+			changes: []StatusChange{
+				change(0, models.WasherIdle, models.WasherFault, ""),
+				change(5, models.WasherFault, models.WasherOffline, ""),
+				maintenance(change(60, models.WasherOffline, models.WasherIdle, "")),
+			},
+			want: []incident{{status: models.IncidentResolved, severity: models.SeverityMedium, duration: 60 * 60}},
+		},
+		{
+			name:    "ordinary transitions do nothing",
+			changes: []StatusChange{change(0, models.WasherIdle, models.WasherWashing, ""), change(40, models.WasherWashing, models.WasherDone, "")},
+		},
+		{
+			name: "a new fault after resolving opens a second incident",
+			changes: []StatusChange{
+				change(0, models.WasherIdle, models.WasherFault, ""),
+				change(10, models.WasherFault, models.WasherIdle, ""),
+				change(20, models.WasherIdle, models.WasherFault, "E21"),
+			},
+			want: []incident{
+				{status: models.IncidentResolved, severity: models.SeverityMedium, duration: 10 * 60},
+				{status: models.IncidentOpen, code: "E21", severity: models.SeverityHigh},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeIncidentRepo{faultCodes: map[string]models.FaultCode{"E21": {Code: "E21", Severity: models.SeverityHigh}}}
+			service := NewIncidentService(repo)
+			for _, c := range tt.changes {
+				service.OnStatusChange(context.Background(), c)
+			}
+
+			if len(repo.incidents) != len(tt.want) {
+				t.Fatalf("incidents = %+v, want %d", repo.incidents, len(tt.want))
+			}
+			for i, want := range tt.want {
+				got := repo.incidents[i]
+				var duration int64
+				if got.DurationSeconds != nil {
+					duration = *got.DurationSeconds
+				}
+				if got.Status != want.status || got.FaultCode != want.code || got.Severity != want.severity || duration != want.duration {
+					t.Errorf("incident %d = %s %q %s %ds; want %s %q %s %ds",
+						i, got.Status, got.FaultCode, got.Severity, duration, want.status, want.code, want.severity, want.duration)
+				}
+			}
+		})
+	}
+}
+
+func TestIncidentSeverityOf(t *testing.T) {
+	catalogue := map[string]models.FaultCode{
+		"E21": {Code: "E21", Severity: models.SeverityHigh},
+		"E01": {Code: "E01", Severity: models.SeverityLow},
+	}
+
+	tests := []struct {
+		name      string
+		code      string
+		lookupErr error
+		want      string
+	}{
+		{name: "known code", code: "E21", want: models.SeverityHigh},
+		{name: "another known code", code: "E01", want: models.SeverityLow},
+		{name: "unknown code", code: "E77", want: models.SeverityMedium},
+		{name: "no code", want: models.SeverityMedium},
+		{name: "lookup failure", code: "E21", lookupErr: errFakeDB, want: models.SeverityMedium},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewIncidentService(&fakeIncidentRepo{faultCodes: catalogue, lookupErr: tt.lookupErr}).(*incidentService)
+			if got := service.severityOf(context.Background(), tt.code); got != tt.want {
+				t.Fatalf("severityOf(%q) = %s, want %s", tt.code, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,698 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jaytnw/bms-service/internal/apperr"
+	"github.com/jaytnw/bms-service/internal/models"
+)
+
+// ชนิดของ IngestMessage
+const (
+	IngestStatus   = "status"
+	IngestPresence = "presence"
+	IngestOffline  = "offline"
+)
+
+const (
+	ingestMaxBackoff       = 5 * time.Second
+	spoolFullRetryInterval = 50 * time.Millisecond
+)
+
+// ErrIngestRejected ห่อ error จาก CommitIngest เมื่อฐานข้อมูลปฏิเสธข้อมูลใน batch เอง การลองซ้ำจะไม่สำเร็จ
+var ErrIngestRejected = errors.New("ingest batch rejected")
+
+// IngestMessage คือข้อความหนึ่งรายการที่รอประมวลผล ReceivedAt คือเวลาที่รับเข้าคิว
+// SpoolID คือ ID ใน spool ว่างเมื่อข้อความไม่ได้ผ่าน spool
+type IngestMessage struct {
+	Kind       string
+	DormID     string
+	WasherID   string
+	Payload    []byte
+	ReceivedAt time.Time
+	SpoolID    string
+}
+
+// PendingStatus คือผลของข้อความที่ผ่านการตรวจแล้วและรอบันทึกเป็น batch
+// Status เป็น nil เมื่อสถานะซ้ำกับของเดิมและมีเพียง telemetry ที่ต้องบันทึก
+// Payload คือข้อความดิบ ใช้บันทึก anomaly เมื่อบันทึกรายการนี้ไม่ได้
+type PendingStatus struct {
+	Status    *models.Status
+	Previous  models.WasherState
+	LastSeen  time.Time
+	Telemetry *models.WasherTelemetry
+	Payload   string
+}
+
+// StatusIngester แปลงข้อความเป็นสถานะที่รอบันทึก และบันทึกพร้อมกระจาย event เป็น batch
+// PrepareIngest ถูกเรียกตามลำดับของแต่ละเครื่อง pending คือสถานะของเครื่องที่ยังอยู่ใน batch ที่ยังไม่บันทึก
+// CommitIngest คืน error เมื่อบันทึกสถานะไม่สำเร็จ (ไม่มีแถวใดถูกบันทึก) จึงเรียกซ้ำได้
+// error ที่ห่อ ErrIngestRejected คือข้อมูลผิด รายการนั้นจะถูกส่งให้ QuarantineIngest แล้วทิ้ง
+type StatusIngester interface {
+	PrepareIngest(ctx context.Context, msg IngestMessage, pending map[string]WasherSnapshot) *PendingStatus
+	CommitIngest(ctx context.Context, batch []*PendingStatus) error
+	QuarantineIngest(ctx context.Context, item *PendingStatus, err error)
+}
+
+// IngestOptions คือการตั้งค่า ingestion pipeline
+type IngestOptions struct {
+	Workers int
+	// QueueSize คือความจุรวมของคิว แบ่งเท่ากันให้แต่ละ worker
+	QueueSize     int
+	BatchSize     int
+	FlushInterval time.Duration
+	// EnqueueTimeout คือเวลาที่ยอมให้ MQTT callback รอเมื่อคิวหรือ spool เต็มก่อนทิ้งข้อความ
+	EnqueueTimeout time.Duration
+	MaxAttempts    int
+	// ClaimInterval คือความถี่ในการรับข้อความที่ค้างใน spool จาก consumer อื่น
+	ClaimInterval time.Duration
+}
+
+// IngestStats คือสถิติของ pipeline ตัวนับทั้งหมดนับตั้งแต่ service เริ่ม
+// Blocked คือจำนวนข้อความที่ต้องรอเพราะคิวหรือ spool เต็ม Rejected คือจำนวนที่ถูกทิ้ง
+// Spooled คือจำนวนที่เขียนลง spool SpoolFallbacks คือจำนวนที่เขียนลง spool ไม่ได้และเข้าคิวใน memory แทน
+type IngestStats struct {
+	Durable         bool       `json:"durable"`
+	Workers         int        `json:"workers"`
+	QueueCapacity   int        `json:"queueCapacity"`
+	QueueDepth      int        `json:"queueDepth"`
+	ShardDepths     []int      `json:"shardDepths"`
+	Accepted        uint64     `json:"accepted"`
+	Blocked         uint64     `json:"blocked"`
+	Rejected        uint64     `json:"rejected"`
+	Spooled         uint64     `json:"spooled"`
+	SpoolFallbacks  uint64     `json:"spoolFallbacks"`
+	Processed       uint64     `json:"processed"`
+	Persisted       uint64     `json:"persisted"`
+	Failed          uint64     `json:"failed"`
+	Batches         uint64     `json:"batches"`
+	Retries         uint64     `json:"retries"`
+	LastBatchSize   int64      `json:"lastBatchSize"`
+	LastFlushMillis int64      `json:"lastFlushMillis"`
+	LastFlushAt     *time.Time `json:"lastFlushAt,omitempty"`
+	Draining        bool       `json:"draining"`
+}
+
+// IngestPipeline รับข้อความ MQTT เข้าคิวแบบมีขอบเขตโดยไม่แตะฐานข้อมูลใน callback
+// ข้อความถูกแบ่งให้ worker ตาม washer ID ลำดับของแต่ละเครื่องจึงคงเดิม
+// worker สะสมสถานะเป็น batch แล้วบันทึกเมื่อครบ BatchSize หรือทุก FlushInterval
+// เมื่อมี spool ข้อความจะถูกเขียนลง spool ก่อน และ worker จะ retry error ชั่วคราวจนบันทึกสำเร็จแล้วจึง ack
+// heartbeat ของเครื่องถูกบันทึกทันทีที่รับข้อความ ไม่ต้องรอให้ worker บันทึกสถานะ
+type IngestPipeline interface {
+	HandleMQTTStatusUpdate(topic string, payload []byte)
+	HandleMQTTPresence(topic string, payload []byte)
+	MarkOffline(ctx context.Context, dormID, washerID string)
+	Start()
+	// Drain หยุดรับข้อความใหม่และรอให้ข้อความที่รับแล้วถูกบันทึกจนหมดหรือจนกว่า ctx จะหมดเวลา
+	Drain(ctx context.Context) error
+	Stats() IngestStats
+	SpoolStats(ctx context.Context) (*SpoolStats, error)
+	IngestWatermark
+}
+
+type ingestPipeline struct {
+	ingester   StatusIngester
+	spool      IngestSpool
+	stateStore WasherStateStore
+	opts       IngestOptions
+	shards     []chan IngestMessage
+
+	stopConsumer context.CancelFunc
+	consumerDone chan struct{}
+
+	// mu กันไม่ให้ส่งเข้า channel ที่ Drain ปิดไปแล้ว
+	mu     sync.RWMutex
+	closed bool
+	wg     sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// unsaved นับข้อความที่อยู่ในคิวหรือ batch แต่ยังไม่บันทึก แยกตามวินาทีที่รับ ใช้คำนวณ Watermark
+	unsavedMu sync.Mutex
+	unsaved   map[int64]int
+
+	accepted        atomic.Uint64
+	blocked         atomic.Uint64
+	rejected        atomic.Uint64
+	spooled         atomic.Uint64
+	spoolFallbacks  atomic.Uint64
+	processed       atomic.Uint64
+	persisted       atomic.Uint64
+	failed          atomic.Uint64
+	batches         atomic.Uint64
+	retries         atomic.Uint64
+	lastBatchSize   atomic.Int64
+	lastFlushMillis atomic.Int64
+	lastFlushAt     atomic.Int64
+}
+
+// NewIngestPipeline สร้าง pipeline หาก spool เป็น nil ข้อความจะอยู่ในคิวใน memory เท่านั้น
+func NewIngestPipeline(ingester StatusIngester, spool IngestSpool, stateStore WasherStateStore, opts IngestOptions) IngestPipeline {
+	if opts.Workers <= 0 {
+		opts.Workers = 1
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 1
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 1
+	}
+	// time.NewTicker ไม่รับค่า <= 0
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = time.Second
+	}
+	if opts.ClaimInterval <= 0 {
+		opts.ClaimInterval = time.Minute
+	}
+
+	shardSize := opts.QueueSize / opts.Workers
+	if shardSize <= 0 {
+		shardSize = 1
+	}
+	shards := make([]chan IngestMessage, opts.Workers)
+	for i := range shards {
+		shards[i] = make(chan IngestMessage, shardSize)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &ingestPipeline{
+		ingester:   ingester,
+		spool:      spool,
+		stateStore: stateStore,
+		opts:       opts,
+		shards:     shards,
+		ctx:        ctx,
+		cancel:     cancel,
+		unsaved:    make(map[int64]int),
+	}
+}
+
+func (p *ingestPipeline) HandleMQTTStatusUpdate(topic string, payload []byte) {
+	p.enqueueTopic(IngestStatus, topic, payload)
+}
+
+// HandleMQTTPresence รับ Last Will ("offline") และประกาศตอนเชื่อมต่อ ("online") จาก topic .../lwt
+func (p *ingestPipeline) HandleMQTTPresence(topic string, payload []byte) {
+	p.enqueueTopic(IngestPresence, topic, payload)
+}
+
+// MarkOffline ส่งสถานะ offline เข้าคิวเดียวกับข้อความของเครื่อง ใช้โดย watchdog
+func (p *ingestPipeline) MarkOffline(ctx context.Context, dormID, washerID string) {
+	p.enqueue(IngestMessage{Kind: IngestOffline, DormID: dormID, WasherID: washerID, ReceivedAt: time.Now()})
+}
+
+func (p *ingestPipeline) Start() {
+	for _, shard := range p.shards {
+		p.wg.Add(1)
+		go p.work(shard)
+	}
+	if p.spool != nil {
+		ctx, cancel := context.WithCancel(p.ctx)
+		p.stopConsumer = cancel
+		p.consumerDone = make(chan struct{})
+		go p.consume(ctx)
+	}
+	log.Printf("📥 Ingest pipeline started (%d workers, batch %d, flush every %v, spool %v)", p.opts.Workers, p.opts.BatchSize, p.opts.FlushInterval, p.spool != nil)
+}
+
+func (p *ingestPipeline) Drain(ctx context.Context) error {
+	// หยุดอ่าน spool ก่อนปิดคิว ข้อความที่ยังไม่ได้อ่านจะรออยู่ใน spool จนถึงการรันครั้งถัดไป
+	if p.stopConsumer != nil {
+		p.stopConsumer()
+		<-p.consumerDone
+	}
+
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		for _, shard := range p.shards {
+			close(shard)
+		}
+	}
+	p.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		log.Printf("✅ Ingest pipeline drained (%d persisted, %d failed)", p.persisted.Load(), p.failed.Load())
+		return nil
+	case <-ctx.Done():
+		// ยกเลิกการ retry ที่ค้างอยู่ ข้อความที่เหลือในคิวจะหายไป เว้นแต่อยู่ใน spool ซึ่งยังไม่ถูก ack
+		p.cancel()
+		if p.spool != nil {
+			return fmt.Errorf("ingest drain timed out with %d message(s) still queued, unsaved messages remain in the spool", p.depth())
+		}
+		return fmt.Errorf("ingest drain timed out with %d message(s) still queued", p.depth())
+	}
+}
+
+func (p *ingestPipeline) Stats() IngestStats {
+	p.mu.RLock()
+	draining := p.closed
+	p.mu.RUnlock()
+
+	stats := IngestStats{
+		Durable:         p.spool != nil,
+		Workers:         len(p.shards),
+		ShardDepths:     make([]int, len(p.shards)),
+		Accepted:        p.accepted.Load(),
+		Blocked:         p.blocked.Load(),
+		Rejected:        p.rejected.Load(),
+		Spooled:         p.spooled.Load(),
+		SpoolFallbacks:  p.spoolFallbacks.Load(),
+		Processed:       p.processed.Load(),
+		Persisted:       p.persisted.Load(),
+		Failed:          p.failed.Load(),
+		Batches:         p.batches.Load(),
+		Retries:         p.retries.Load(),
+		LastBatchSize:   p.lastBatchSize.Load(),
+		LastFlushMillis: p.lastFlushMillis.Load(),
+		Draining:        draining,
+	}
+	for i, shard := range p.shards {
+		stats.ShardDepths[i] = len(shard)
+		stats.QueueDepth += len(shard)
+		stats.QueueCapacity += cap(shard)
+	}
+	if at := p.lastFlushAt.Load(); at > 0 {
+		t := time.Unix(0, at)
+		stats.LastFlushAt = &t
+	}
+	return stats
+}
+
+func (p *ingestPipeline) SpoolStats(ctx context.Context) (*SpoolStats, error) {
+	if p.spool == nil {
+		return nil, apperr.New("SPOOL_DISABLED", "Ingest spool is disabled", 404, nil)
+	}
+	stats, err := p.spool.Stats(ctx)
+	if err != nil {
+		return nil, apperr.New("REDIS_ERROR", "Failed to get spool stats", 500, err)
+	}
+	return stats, nil
+}
+
+// Watermark คือเวลาที่เก่าที่สุดของข้อความที่ยังไม่บันทึก ทั้งในคิว ใน batch และใน spool
+// ไม่เกิน now ลบ FlushInterval และเวลารอ enqueue สองช่วง (เขียน spool และรอคิว) ซึ่งครอบข้อความที่รับแล้วแต่ยังไม่ถูกนับ
+func (p *ingestPipeline) Watermark(ctx context.Context) (time.Time, error) {
+	mark := time.Now().Add(-p.opts.FlushInterval - 2*p.opts.EnqueueTimeout)
+
+	p.unsavedMu.Lock()
+	for sec := range p.unsaved {
+		if oldest := time.Unix(sec, 0); oldest.Before(mark) {
+			mark = oldest
+		}
+	}
+	p.unsavedMu.Unlock()
+
+	if p.spool != nil {
+		stats, err := p.spool.Stats(ctx)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if stats.OldestAt != nil && stats.OldestAt.Before(mark) {
+			mark = *stats.OldestAt
+		}
+	}
+	return mark, nil
+}
+
+// track นับข้อความก่อนส่งเข้าคิว ต้องเรียกก่อน worker จะได้รับข้อความ เพื่อไม่ให้ release มาก่อน
+func (p *ingestPipeline) track(at time.Time) {
+	p.unsavedMu.Lock()
+	p.unsaved[at.Unix()]++
+	p.unsavedMu.Unlock()
+}
+
+// release เอาข้อความที่บันทึกแล้ว (หรือถูกทิ้ง) ออกจากการนับของ Watermark
+func (p *ingestPipeline) release(received ...time.Time) {
+	p.unsavedMu.Lock()
+	defer p.unsavedMu.Unlock()
+	for _, at := range received {
+		sec := at.Unix()
+		if p.unsaved[sec]--; p.unsaved[sec] <= 0 {
+			delete(p.unsaved, sec)
+		}
+	}
+}
+
+func (p *ingestPipeline) enqueueTopic(kind, topic string, payload []byte) {
+	dormID, washerID, ok := parseWasherTopic(topic)
+	if !ok {
+		log.Printf("❌ Invalid topic format: %s", topic)
+		return
+	}
+	msg := IngestMessage{
+		Kind:       kind,
+		DormID:     dormID,
+		WasherID:   washerID,
+		Payload:    append([]byte(nil), payload...),
+		ReceivedAt: time.Now(),
+	}
+	// ข้อความสถานะและประกาศ online คือ heartbeat ซึ่งบันทึกทันที เพื่อไม่ให้ watchdog มองว่าเครื่อง offline
+	// ระหว่างที่ข้อความยังค้างใน spool (เช่นตอนฐานข้อมูลล่ม) Last Will ("offline") ไม่นับเป็น heartbeat
+	if kind == IngestStatus || strings.EqualFold(strings.TrimSpace(string(payload)), "online") {
+		p.touch(msg)
+	}
+	p.enqueue(msg)
+}
+
+func (p *ingestPipeline) touch(msg IngestMessage) {
+	ctx, cancel := context.WithTimeout(context.Background(), p.opts.EnqueueTimeout)
+	defer cancel()
+	if err := p.stateStore.Touch(ctx, msg.WasherID, msg.ReceivedAt); err != nil {
+		log.Printf("❌ Failed to update last seen for washer %s: %v", msg.WasherID, err)
+	}
+}
+
+// enqueue เขียนข้อความลง spool ซึ่ง consumer จะส่งต่อให้ worker หาก Redis ใช้ไม่ได้จะเข้าคิวใน memory โดยตรง
+// spool ที่เต็มไม่ใช่ Redis ใช้ไม่ได้: ข้อความรอที่ว่างไม่เกิน EnqueueTimeout แล้วถูกทิ้ง
+// ไม่เข้าคิวใน memory เพราะจะถูกบันทึกก่อนข้อความเก่าที่ค้างใน spool แล้วทำให้ข้อความเหล่านั้นถูกทิ้งเป็นสถานะย้อนหลัง
+func (p *ingestPipeline) enqueue(msg IngestMessage) {
+	if p.spool == nil {
+		p.submit(msg)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.opts.EnqueueTimeout)
+	defer cancel()
+	err := p.spool.Append(ctx, msg)
+	if errors.Is(err, ErrSpoolFull) {
+		p.blocked.Add(1)
+		if err := p.appendWhenSpoolHasRoom(ctx, msg); err != nil {
+			p.rejected.Add(1)
+			log.Printf("⚠️ Ingest spool full, dropped %s message from washer %s: %v", msg.Kind, msg.WasherID, err)
+			return
+		}
+	} else if err != nil {
+		p.spoolFallbacks.Add(1)
+		log.Printf("⚠️ Failed to spool %s message from washer %s, queueing in memory: %v", msg.Kind, msg.WasherID, err)
+		p.submit(msg)
+		return
+	}
+	p.spooled.Add(1)
+}
+
+// appendWhenSpoolHasRoom เขียนข้อความลง spool ซ้ำจนกว่า worker จะ ack ข้อความเก่าจนมีที่ว่าง หรือ ctx หมดเวลา
+func (p *ingestPipeline) appendWhenSpoolHasRoom(ctx context.Context, msg IngestMessage) error {
+	ticker := time.NewTicker(spoolFullRetryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ErrSpoolFull
+		case <-ticker.C:
+		}
+		if err := p.spool.Append(ctx, msg); !errors.Is(err, ErrSpoolFull) {
+			return err
+		}
+	}
+}
+
+// consume อ่านข้อความจาก spool แล้วส่งให้ worker ของเครื่อง ข้อความของ shard ที่คิวเต็มจะรอใน backlog ของ shard นั้น
+// โดยไม่ขวาง shard อื่น เมื่อ backlog รวมเกินความจุของคิวจะหยุดอ่านชั่วคราว (ข้อความยังอยู่ใน spool)
+func (p *ingestPipeline) consume(ctx context.Context) {
+	defer close(p.consumerDone)
+
+	claim := time.NewTicker(p.opts.ClaimInterval)
+	defer claim.Stop()
+
+	limit := 0
+	for _, shard := range p.shards {
+		limit += cap(shard)
+	}
+	backlog := make([][]IngestMessage, len(p.shards))
+
+	backoff := 100 * time.Millisecond
+	for ctx.Err() == nil {
+		if p.offer(backlog) >= limit {
+			select {
+			case <-time.After(p.opts.FlushInterval):
+			case <-ctx.Done():
+			}
+			continue
+		}
+
+		messages, err := p.spool.Read(ctx, p.opts.BatchSize)
+		if err == nil {
+			select {
+			case <-claim.C:
+				var reclaimed []IngestMessage
+				reclaimed, err = p.spool.Reclaim(ctx, p.opts.BatchSize)
+				messages = append(messages, reclaimed...)
+			default:
+			}
+		}
+		if err != nil && ctx.Err() == nil {
+			log.Printf("❌ Failed to read ingest spool: %v", err)
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+			}
+			backoff = min(backoff*2, ingestMaxBackoff)
+		} else {
+			backoff = 100 * time.Millisecond
+		}
+
+		for _, msg := range messages {
+			i := p.shardOf(msg.WasherID)
+			backlog[i] = append(backlog[i], msg)
+		}
+	}
+}
+
+// offer ส่งข้อความใน backlog เข้าคิวของแต่ละ shard ตามลำดับโดยไม่รอ คืนจำนวนที่ยังค้างใน backlog
+func (p *ingestPipeline) offer(backlog [][]IngestMessage) int {
+	queued := 0
+	for i, messages := range backlog {
+		sent := 0
+		for _, msg := range messages {
+			p.track(msg.ReceivedAt)
+			select {
+			case p.shards[i] <- msg:
+				p.accepted.Add(1)
+				sent++
+				continue
+			default:
+				p.release(msg.ReceivedAt)
+			}
+			break
+		}
+		if sent == len(messages) {
+			backlog[i] = nil
+		} else {
+			backlog[i] = messages[sent:]
+		}
+		queued += len(backlog[i])
+	}
+	return queued
+}
+
+// submit ใส่ข้อความเข้าคิวของเครื่อง หากคิวเต็มจะรอไม่เกิน EnqueueTimeout เพื่อชะลอ MQTT client ก่อนทิ้ง
+func (p *ingestPipeline) submit(msg IngestMessage) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		p.rejected.Add(1)
+		log.Printf("⚠️ Ingest pipeline is draining, dropped %s message from washer %s", msg.Kind, msg.WasherID)
+		return false
+	}
+
+	shard := p.shards[p.shardOf(msg.WasherID)]
+	p.track(msg.ReceivedAt)
+	select {
+	case shard <- msg:
+		p.accepted.Add(1)
+		return true
+	default:
+	}
+
+	p.blocked.Add(1)
+	timer := time.NewTimer(p.opts.EnqueueTimeout)
+	defer timer.Stop()
+	select {
+	case shard <- msg:
+		p.accepted.Add(1)
+		return true
+	case <-timer.C:
+		p.release(msg.ReceivedAt)
+		p.rejected.Add(1)
+		log.Printf("⚠️ Ingest queue full, dropped %s message from washer %s", msg.Kind, msg.WasherID)
+		return false
+	}
+}
+
+func (p *ingestPipeline) shardOf(washerID string) int {
+	h := fnv.New32a()
+	h.Write([]byte(washerID))
+	return int(h.Sum32() % uint32(len(p.shards)))
+}
+
+func (p *ingestPipeline) depth() int {
+	depth := 0
+	for _, shard := range p.shards {
+		depth += len(shard)
+	}
+	return depth
+}
+
+// work ประมวลผลข้อความของ shard เดียวตามลำดับ และบันทึก batch ที่เหลือเมื่อ channel ถูกปิด
+func (p *ingestPipeline) work(shard chan IngestMessage) {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(p.opts.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*PendingStatus, 0, p.opts.BatchSize)
+	pending := make(map[string]WasherSnapshot)
+	// spoolIDs รวมข้อความที่ไม่มีอะไรต้องบันทึก (เช่นสถานะซ้ำ) ซึ่ง ack พร้อม batch ได้เลย
+	var spoolIDs []string
+	// received คือเวลารับของทุกข้อความใน batch ซึ่งถูกนับไว้ใน Watermark
+	var received []time.Time
+	flush := func() {
+		committed := true
+		if len(batch) > 0 {
+			committed = p.flush(batch)
+		}
+		if committed && len(spoolIDs) > 0 {
+			if err := p.spool.Ack(p.ctx, spoolIDs); err != nil {
+				log.Printf("❌ Failed to ack %d spooled message(s): %v", len(spoolIDs), err)
+			}
+		}
+		p.release(received...)
+		batch = batch[:0]
+		spoolIDs = spoolIDs[:0]
+		received = received[:0]
+		clear(pending)
+	}
+
+	for {
+		select {
+		case msg, ok := <-shard:
+			if !ok {
+				flush()
+				return
+			}
+			if item := p.ingester.PrepareIngest(p.ctx, msg, pending); item != nil {
+				batch = append(batch, item)
+			}
+			if msg.SpoolID != "" {
+				spoolIDs = append(spoolIDs, msg.SpoolID)
+			}
+			received = append(received, msg.ReceivedAt)
+			p.processed.Add(1)
+			if len(batch) >= p.opts.BatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// flush บันทึก batch หาก batch ถูกปฏิเสธ (หรือ retry ครบเมื่อไม่มี spool) จะบันทึกทีละรายการ
+// เพื่อไม่ให้แถวที่ผิดทำให้ทั้ง batch หาย รายการที่ถูกปฏิเสธถูกกักเป็น anomaly แล้วทิ้ง
+// คืน false หากไม่ได้บันทึกเพราะ pipeline ถูกยกเลิก (ข้อความยังอยู่ใน spool)
+func (p *ingestPipeline) flush(batch []*PendingStatus) bool {
+	start := time.Now()
+	defer func() {
+		p.batches.Add(1)
+		p.lastBatchSize.Store(int64(len(batch)))
+		p.lastFlushMillis.Store(time.Since(start).Milliseconds())
+		p.lastFlushAt.Store(time.Now().UnixNano())
+	}()
+
+	err := p.commit(batch)
+	if err == nil {
+		p.persisted.Add(countStatuses(batch))
+		return true
+	}
+	if p.ctx.Err() != nil {
+		log.Printf("❌ Gave up saving ingest batch of %d: %v", len(batch), err)
+		return false
+	}
+
+	if len(batch) > 1 {
+		log.Printf("⚠️ Failed to save ingest batch of %d, saving item by item: %v", len(batch), err)
+	}
+	for _, item := range batch {
+		single := []*PendingStatus{item}
+		if len(batch) > 1 {
+			err = p.commit(single)
+		}
+		if err == nil {
+			p.persisted.Add(countStatuses(single))
+			continue
+		}
+		if p.ctx.Err() != nil {
+			log.Printf("❌ Gave up saving ingest item for washer %s: %v", pendingWasherID(item), err)
+			return false
+		}
+		p.failed.Add(countStatuses(single))
+		log.Printf("❌ Dropped ingest item for washer %s: %v", pendingWasherID(item), err)
+		if errors.Is(err, ErrIngestRejected) {
+			p.ingester.QuarantineIngest(p.ctx, item, err)
+		}
+	}
+	return true
+}
+
+// commit เรียก CommitIngest พร้อม retry แบบ backoff เมื่อ error เป็นแบบชั่วคราว
+// เมื่อมี spool จะ retry จนสำเร็จหรือจนกว่า pipeline ถูกยกเลิก ไม่มี spool จะลองไม่เกิน MaxAttempts ครั้ง
+// error ที่ห่อ ErrIngestRejected คืนทันทีเพราะการลองซ้ำไม่ช่วย
+func (p *ingestPipeline) commit(batch []*PendingStatus) error {
+	backoff := 100 * time.Millisecond
+	for attempt := 1; ; attempt++ {
+		err := p.ingester.CommitIngest(p.ctx, batch)
+		if err == nil || errors.Is(err, ErrIngestRejected) || p.ctx.Err() != nil {
+			return err
+		}
+		if p.spool == nil && attempt >= p.opts.MaxAttempts {
+			return err
+		}
+		if p.spool != nil && attempt%p.opts.MaxAttempts == 0 {
+			log.Printf("⚠️ Still failing to save ingest batch of %d after %d attempt(s), keeping it spooled: %v", len(batch), attempt, err)
+		}
+
+		p.retries.Add(1)
+		select {
+		case <-time.After(backoff):
+		case <-p.ctx.Done():
+		}
+		backoff = min(backoff*2, ingestMaxBackoff)
+	}
+}
+
+func countStatuses(batch []*PendingStatus) uint64 {
+	var n uint64
+	for _, item := range batch {
+		if item.Status != nil {
+			n++
+		}
+	}
+	return n
+}
+
+func pendingWasherID(item *PendingStatus) string {
+	if item.Status != nil {
+		return item.Status.WasherID
+	}
+	if item.Telemetry != nil {
+		return item.Telemetry.WasherID
+	}
+	return ""
+}
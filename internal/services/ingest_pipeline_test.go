@@ -0,0 +1,490 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jaytnw/bms-service/internal/models"
+)
+
+// fakeIngester ใช้ payload เป็นสถานะตรง ๆ payload "dup" ถือว่าซ้ำ และ payload "bad" ทำให้ batch ที่มีมันถูกปฏิเสธ
+type fakeIngester struct {
+	mu          sync.Mutex
+	batches     [][]string
+	failures    []error
+	quarantined []string
+	// gate (ถ้ามี) ทำให้ CommitIngest รอจนกว่าจะถูกปิดหรือ ctx ถูกยกเลิก เฉพาะ batch ของ gateWasher หากกำหนดไว้
+	gate       chan struct{}
+	gateWasher string
+}
+
+func (f *fakeIngester) PrepareIngest(ctx context.Context, msg IngestMessage, pending map[string]WasherSnapshot) *PendingStatus {
+	if string(msg.Payload) == "dup" {
+		return nil
+	}
+	return &PendingStatus{Status: &models.Status{WasherID: msg.WasherID, Status: models.WasherState(msg.Payload), CreatedAt: msg.ReceivedAt}}
+}
+
+func (f *fakeIngester) CommitIngest(ctx context.Context, batch []*PendingStatus) error {
+	if f.gate != nil && (f.gateWasher == "" || slices.ContainsFunc(batch, func(item *PendingStatus) bool { return item.Status.WasherID == f.gateWasher })) {
+		select {
+		case <-f.gate:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.failures) > 0 {
+		err := f.failures[0]
+		f.failures = f.failures[1:]
+		if err != nil {
+			return err
+		}
+	}
+	var saved []string
+	for _, item := range batch {
+		if item.Status.Status == "bad" {
+			return fmt.Errorf("%w: %w", ErrIngestRejected, errFakeDB)
+		}
+		saved = append(saved, item.Status.WasherID+":"+string(item.Status.Status))
+	}
+	f.batches = append(f.batches, saved)
+	return nil
+}
+
+func (f *fakeIngester) QuarantineIngest(ctx context.Context, item *PendingStatus, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.quarantined = append(f.quarantined, item.Status.WasherID+":"+string(item.Status.Status))
+}
+
+func (f *fakeIngester) saved() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var all []string
+	for _, batch := range f.batches {
+		all = append(all, batch...)
+	}
+	return all
+}
+
+func (f *fakeIngester) batchSizes() []int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	sizes := make([]int, 0, len(f.batches))
+	for _, batch := range f.batches {
+		sizes = append(sizes, len(batch))
+	}
+	return sizes
+}
+
+func newTestPipeline(ingester StatusIngester, opts IngestOptions) *ingestPipeline {
+	if opts.QueueSize == 0 {
+		opts.QueueSize = 1000
+	}
+	if opts.EnqueueTimeout == 0 {
+		opts.EnqueueTimeout = time.Second
+	}
+	if opts.FlushInterval == 0 {
+		opts.FlushInterval = time.Hour
+	}
+	return NewIngestPipeline(ingester, nil, newFakeStateStore(), opts).(*ingestPipeline)
+}
+
+// fakeSpool เก็บข้อความใน slice Read คืนข้อความที่ยังไม่เคยอ่านตามลำดับ
+// maxLen มากกว่า 0 จำกัดจำนวนข้อความที่ยังไม่ ack เหมือน redisIngestSpool
+type fakeSpool struct {
+	mu      sync.Mutex
+	entries []IngestMessage
+	read    int
+	acked   []string
+	maxLen  int
+}
+
+func (s *fakeSpool) Append(ctx context.Context, msg IngestMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.maxLen > 0 && len(s.entries)-len(s.acked) >= s.maxLen {
+		return ErrSpoolFull
+	}
+	msg.SpoolID = fmt.Sprintf("%d-0", len(s.entries)+1)
+	s.entries = append(s.entries, msg)
+	return nil
+}
+
+func (s *fakeSpool) Read(ctx context.Context, count int) ([]IngestMessage, error) {
+	s.mu.Lock()
+	end := min(s.read+count, len(s.entries))
+	messages := append([]IngestMessage(nil), s.entries[s.read:end]...)
+	s.read = end
+	s.mu.Unlock()
+
+	if len(messages) == 0 {
+		// จำลองการรอของ XREADGROUP แบบสั้น
+		select {
+		case <-time.After(5 * time.Millisecond):
+		case <-ctx.Done():
+		}
+	}
+	return messages, nil
+}
+
+func (s *fakeSpool) Reclaim(ctx context.Context, count int) ([]IngestMessage, error) {
+	return nil, nil
+}
+
+func (s *fakeSpool) Ack(ctx context.Context, ids []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.acked = append(s.acked, ids...)
+	return nil
+}
+
+func (s *fakeSpool) Stats(ctx context.Context) (*SpoolStats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return &SpoolStats{Depth: int64(len(s.entries) - len(s.acked))}, nil
+}
+
+func (s *fakeSpool) ackedCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.acked)
+}
+
+func newSpooledPipeline(ingester StatusIngester, state WasherStateStore, opts IngestOptions) (*ingestPipeline, *fakeSpool) {
+	spool := &fakeSpool{}
+	opts.EnqueueTimeout = time.Second
+	if opts.QueueSize == 0 {
+		opts.QueueSize = 1000
+	}
+	if opts.FlushInterval == 0 {
+		opts.FlushInterval = 10 * time.Millisecond
+	}
+	return NewIngestPipeline(ingester, spool, state, opts).(*ingestPipeline), spool
+}
+
+// waitFor รอจนกว่า cond จะเป็นจริง ไม่เกินสองวินาที
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %s", what)
+}
+
+func drainPipeline(t *testing.T, p *ingestPipeline) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := p.Drain(ctx); err != nil {
+		t.Fatalf("Drain() error = %v", err)
+	}
+}
+
+func TestIngestPipelineKeepsOrderPerWasher(t *testing.T) {
+	ingester := &fakeIngester{}
+	p := newTestPipeline(ingester, IngestOptions{Workers: 4, BatchSize: 5})
+	p.Start()
+
+	washers := []string{"w1", "w2", "w3", "w4", "w5"}
+	for i := 0; i < 20; i++ {
+		for _, washer := range washers {
+			p.HandleMQTTStatusUpdate(fmt.Sprintf("washingMachine/d1/%s/status", washer), []byte(fmt.Sprintf("s%02d", i)))
+		}
+	}
+	drainPipeline(t, p)
+
+	got := make(map[string][]string)
+	for _, saved := range ingester.saved() {
+		washer, state := saved[:2], saved[3:]
+		got[washer] = append(got[washer], state)
+	}
+	for _, washer := range washers {
+		if len(got[washer]) != 20 || !slices.IsSorted(got[washer]) {
+			t.Fatalf("washer %s saved %v, want 20 statuses in order", washer, got[washer])
+		}
+	}
+}
+
+func TestIngestPipelineBatchesBySize(t *testing.T) {
+	ingester := &fakeIngester{}
+	p := newTestPipeline(ingester, IngestOptions{Workers: 1, BatchSize: 3})
+	p.Start()
+
+	for _, payload := range []string{"a", "b", "dup", "c", "d", "e", "f", "g"} {
+		p.HandleMQTTStatusUpdate("washingMachine/d1/w1/status", []byte(payload))
+	}
+	// batch สุดท้ายยังไม่ครบ และ FlushInterval ยาว จึงถูกบันทึกตอน Drain
+	drainPipeline(t, p)
+
+	if sizes := ingester.batchSizes(); !slices.Equal(sizes, []int{3, 3, 1}) {
+		t.Fatalf("batch sizes = %v, want [3 3 1]", sizes)
+	}
+	stats := p.Stats()
+	if stats.Processed != 8 || stats.Persisted != 7 {
+		t.Fatalf("processed %d, persisted %d; want 8 and 7", stats.Processed, stats.Persisted)
+	}
+}
+
+func TestIngestPipelineFlushesOnInterval(t *testing.T) {
+	ingester := &fakeIngester{}
+	p := newTestPipeline(ingester, IngestOptions{Workers: 1, BatchSize: 100, FlushInterval: 20 * time.Millisecond})
+	p.Start()
+	defer drainPipeline(t, p)
+
+	p.HandleMQTTStatusUpdate("washingMachine/d1/w1/status", []byte("idle"))
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if len(ingester.saved()) == 1 {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("partial batch was not flushed on the interval")
+}
+
+func TestIngestPipelineRetriesFailedBatch(t *testing.T) {
+	ingester := &fakeIngester{failures: []error{errFakeDB}}
+	p := newTestPipeline(ingester, IngestOptions{Workers: 1, BatchSize: 2, MaxAttempts: 3})
+	p.Start()
+
+	p.HandleMQTTStatusUpdate("washingMachine/d1/w1/status", []byte("a"))
+	p.HandleMQTTStatusUpdate("washingMachine/d1/w1/status", []byte("b"))
+	drainPipeline(t, p)
+
+	if saved := ingester.saved(); !slices.Equal(saved, []string{"w1:a", "w1:b"}) {
+		t.Fatalf("saved = %v, want both statuses after one retry", saved)
+	}
+	if stats := p.Stats(); stats.Retries != 1 || stats.Persisted != 2 || stats.Failed != 0 {
+		t.Fatalf("stats = %+v, want 1 retry and 2 persisted", stats)
+	}
+}
+
+func TestIngestPipelineSavesGoodItemsWhenBatchKeepsFailing(t *testing.T) {
+	ingester := &fakeIngester{failures: []error{errFakeDB, errFakeDB}}
+	p := newTestPipeline(ingester, IngestOptions{Workers: 1, BatchSize: 3, MaxAttempts: 2})
+	p.Start()
+
+	for _, payload := range []string{"a", "bad", "c"} {
+		p.HandleMQTTStatusUpdate("washingMachine/d1/w1/status", []byte(payload))
+	}
+	drainPipeline(t, p)
+
+	if saved := ingester.saved(); !slices.Equal(saved, []string{"w1:a", "w1:c"}) {
+		t.Fatalf("saved = %v, want the items around the bad one", saved)
+	}
+	if stats := p.Stats(); stats.Persisted != 2 || stats.Failed != 1 {
+		t.Fatalf("persisted %d, failed %d; want 2 and 1", stats.Persisted, stats.Failed)
+	}
+}
+
+func TestIngestPipelineQuarantinesRejectedItemsAndAcks(t *testing.T) {
+	ingester := &fakeIngester{}
+	p, spool := newSpooledPipeline(ingester, newFakeStateStore(), IngestOptions{Workers: 1, BatchSize: 3, MaxAttempts: 1})
+	p.Start()
+
+	for _, payload := range []string{"a", "bad", "c"} {
+		p.HandleMQTTStatusUpdate("washingMachine/d1/w1/status", []byte(payload))
+	}
+	waitFor(t, "all entries acked", func() bool { return spool.ackedCount() == 3 })
+	drainPipeline(t, p)
+
+	if saved := ingester.saved(); !slices.Equal(saved, []string{"w1:a", "w1:c"}) {
+		t.Fatalf("saved = %v, want the items around the bad one", saved)
+	}
+	if !slices.Equal(ingester.quarantined, []string{"w1:bad"}) {
+		t.Fatalf("quarantined = %v, want the rejected item", ingester.quarantined)
+	}
+	if stats := p.Stats(); stats.Retries != 0 || stats.Failed != 1 {
+		t.Fatalf("stats = %+v, want a rejected batch not to be retried", stats)
+	}
+}
+
+func TestIngestPipelineRetriesTransientErrorsWithSpool(t *testing.T) {
+	// MaxAttempts ใช้แค่กำหนดความถี่ของ log เมื่อมี spool
+	ingester := &fakeIngester{failures: []error{errFakeDB, errFakeDB, errFakeDB}}
+	p, spool := newSpooledPipeline(ingester, newFakeStateStore(), IngestOptions{Workers: 1, BatchSize: 1, MaxAttempts: 1})
+	p.Start()
+
+	p.HandleMQTTStatusUpdate("washingMachine/d1/w1/status", []byte("a"))
+	waitFor(t, "the entry to be acked", func() bool { return spool.ackedCount() == 1 })
+	drainPipeline(t, p)
+
+	if saved := ingester.saved(); !slices.Equal(saved, []string{"w1:a"}) {
+		t.Fatalf("saved = %v, want the status after the database recovers", saved)
+	}
+	if stats := p.Stats(); stats.Retries != 3 || stats.Failed != 0 || len(ingester.quarantined) != 0 {
+		t.Fatalf("stats = %+v, quarantined %v; want 3 retries and nothing dropped", stats, ingester.quarantined)
+	}
+}
+
+func TestIngestPipelineConsumeSkipsFullShard(t *testing.T) {
+	ingester := &fakeIngester{gate: make(chan struct{}), gateWasher: "slow"}
+	p, _ := newSpooledPipeline(ingester, newFakeStateStore(), IngestOptions{Workers: 2, QueueSize: 4, BatchSize: 1, MaxAttempts: 1})
+	fast := ""
+	for i := 0; fast == ""; i++ {
+		if candidate := fmt.Sprintf("w%d", i); p.shardOf(candidate) != p.shardOf("slow") {
+			fast = candidate
+		}
+	}
+	p.Start()
+
+	// shard ของ slow ค้างอยู่กับฐานข้อมูล คิวของมันเต็ม แต่ข้อความของ shard อื่นยังต้องถูกบันทึก
+	for i := 0; i < 4; i++ {
+		p.HandleMQTTStatusUpdate("washingMachine/d1/slow/status", []byte(fmt.Sprintf("s%d", i)))
+	}
+	p.HandleMQTTStatusUpdate("washingMachine/d1/"+fast+"/status", []byte("idle"))
+	waitFor(t, "the other shard to be saved", func() bool { return slices.Contains(ingester.saved(), fast+":idle") })
+
+	close(ingester.gate)
+	waitFor(t, "the slow shard to catch up", func() bool { return len(ingester.saved()) == 5 })
+	drainPipeline(t, p)
+
+	var slow []string
+	for _, saved := range ingester.saved() {
+		if saved != fast+":idle" {
+			slow = append(slow, saved)
+		}
+	}
+	if !slices.Equal(slow, []string{"slow:s0", "slow:s1", "slow:s2", "slow:s3"}) {
+		t.Fatalf("slow washer saved %v, want its messages in order", slow)
+	}
+}
+
+func TestIngestPipelineWaitsForRoomInFullSpool(t *testing.T) {
+	// ฐานข้อมูลค้าง spool จึงเต็มที่สองข้อความ
+	ingester := &fakeIngester{gate: make(chan struct{})}
+	p, spool := newSpooledPipeline(ingester, newFakeStateStore(), IngestOptions{Workers: 1, BatchSize: 1, MaxAttempts: 1})
+	spool.maxLen = 2
+	p.Start()
+
+	p.HandleMQTTStatusUpdate("washingMachine/d1/w1/status", []byte("a"))
+	p.HandleMQTTStatusUpdate("washingMachine/d1/w1/status", []byte("b"))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		p.HandleMQTTStatusUpdate("washingMachine/d1/w1/status", []byte("c"))
+	}()
+	waitFor(t, "the third message to wait for room", func() bool { return p.Stats().Blocked == 1 })
+
+	close(ingester.gate)
+	<-done
+	waitFor(t, "every message to be saved", func() bool { return len(ingester.saved()) == 3 })
+	drainPipeline(t, p)
+
+	if saved := ingester.saved(); !slices.Equal(saved, []string{"w1:a", "w1:b", "w1:c"}) {
+		t.Fatalf("saved = %v, want every message in order", saved)
+	}
+	if stats := p.Stats(); stats.Spooled != 3 || stats.Rejected != 0 || stats.SpoolFallbacks != 0 {
+		t.Fatalf("stats = %+v, want the blocked message spooled once there was room", stats)
+	}
+}
+
+func TestIngestPipelineRejectsWhenSpoolStaysFull(t *testing.T) {
+	ingester := &fakeIngester{gate: make(chan struct{})}
+	p, spool := newSpooledPipeline(ingester, newFakeStateStore(), IngestOptions{Workers: 1, BatchSize: 1, MaxAttempts: 1})
+	p.opts.EnqueueTimeout = 50 * time.Millisecond
+	spool.maxLen = 2
+	p.Start()
+
+	for _, payload := range []string{"a", "b", "c"} {
+		p.HandleMQTTStatusUpdate("washingMachine/d1/w1/status", []byte(payload))
+	}
+
+	// ข้อความใหม่ถูกทิ้ง ข้อความเก่าใน spool ไม่ถูกตัดและไม่ข้ามไปเข้าคิวใน memory
+	stats := p.Stats()
+	if stats.Spooled != 2 || stats.Blocked != 1 || stats.Rejected != 1 || stats.SpoolFallbacks != 0 {
+		t.Fatalf("stats = %+v, want the third message rejected", stats)
+	}
+
+	close(ingester.gate)
+	waitFor(t, "the spooled messages to be saved", func() bool { return spool.ackedCount() == 2 })
+	drainPipeline(t, p)
+	if saved := ingester.saved(); !slices.Equal(saved, []string{"w1:a", "w1:b"}) {
+		t.Fatalf("saved = %v, want the messages already in the spool", saved)
+	}
+}
+
+func TestIngestPipelineTouchesLastSeenOnReceive(t *testing.T) {
+	old := time.Now().Add(-time.Hour)
+	state := newFakeStateStore(
+		WasherSnapshot{DormID: "d1", WasherID: "w1", Status: models.WasherIdle, LastSeen: old},
+		WasherSnapshot{DormID: "d1", WasherID: "w2", Status: models.WasherIdle, LastSeen: old},
+	)
+	// ฐานข้อมูลค้าง ข้อความจึงยังไม่ถูกบันทึก
+	ingester := &fakeIngester{gate: make(chan struct{})}
+	p, _ := newSpooledPipeline(ingester, state, IngestOptions{Workers: 1, BatchSize: 1, MaxAttempts: 1})
+	p.Start()
+	defer func() {
+		close(ingester.gate)
+		drainPipeline(t, p)
+	}()
+
+	p.HandleMQTTStatusUpdate("washingMachine/d1/w1/status", []byte("idle"))
+	p.HandleMQTTPresence("washingMachine/d1/w2/lwt", []byte("offline"))
+
+	if snapshot, _ := state.Get(context.Background(), "w1"); !snapshot.LastSeen.After(old) {
+		t.Fatalf("w1 last seen = %v, want updated when the message is received", snapshot.LastSeen)
+	}
+	if snapshot, _ := state.Get(context.Background(), "w2"); !snapshot.LastSeen.Equal(old) {
+		t.Fatalf("w2 last seen = %v, want the last will not to count as a heartbeat", snapshot.LastSeen)
+	}
+}
+
+func TestIngestPipelineDrainTimesOut(t *testing.T) {
+	ingester := &fakeIngester{gate: make(chan struct{})}
+	p := newTestPipeline(ingester, IngestOptions{Workers: 1, BatchSize: 1})
+	p.Start()
+
+	p.HandleMQTTStatusUpdate("washingMachine/d1/w1/status", []byte("a"))
+	p.HandleMQTTStatusUpdate("washingMachine/d1/w1/status", []byte("b"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := p.Drain(ctx); err == nil {
+		t.Fatal("Drain() error = nil, want timeout while the database is stuck")
+	}
+
+	// หลัง Drain ข้อความใหม่ถูกปฏิเสธทันที
+	p.HandleMQTTStatusUpdate("washingMachine/d1/w1/status", []byte("c"))
+	if stats := p.Stats(); stats.Rejected != 1 || !stats.Draining {
+		t.Fatalf("stats = %+v, want the message after drain rejected", stats)
+	}
+}
+
+func TestIngestPipelineWatermarkWaitsForUnsavedMessages(t *testing.T) {
+	ingester := &fakeIngester{gate: make(chan struct{})}
+	p := newTestPipeline(ingester, IngestOptions{Workers: 1, BatchSize: 1, FlushInterval: 10 * time.Millisecond, EnqueueTimeout: 10 * time.Millisecond})
+	p.Start()
+
+	received := time.Now().Add(-2 * time.Hour)
+	p.submit(IngestMessage{Kind: IngestStatus, DormID: "d1", WasherID: "w1", Payload: []byte("a"), ReceivedAt: received})
+
+	mark, err := p.Watermark(context.Background())
+	if err != nil {
+		t.Fatalf("Watermark() error = %v", err)
+	}
+	if mark.After(received) {
+		t.Fatalf("watermark = %v, want at or before the unsaved message at %v", mark, received)
+	}
+
+	close(ingester.gate)
+	drainPipeline(t, p)
+
+	mark, _ = p.Watermark(context.Background())
+	if settle := time.Since(mark); settle > time.Second {
+		t.Fatalf("watermark is %v behind after everything was saved, want about the settle delay", settle)
+	}
+}
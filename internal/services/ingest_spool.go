@@ -0,0 +1,329 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const ingestSpoolBlock = time.Second
+
+// ErrSpoolFull คือ Append ที่ถูกปฏิเสธเพราะ spool มีข้อความที่ยังไม่บันทึกครบ maxLen แล้ว
+var ErrSpoolFull = errors.New("ingest spool is full")
+
+// spoolAppendScript เพิ่ม entry เฉพาะเมื่อ stream ยาวไม่ถึง ARGV[1] คืน 0 หาก stream เต็ม
+// entry ที่ ack แล้วถูกลบออก ความยาว stream จึงเท่ากับจำนวนข้อความที่ยังไม่บันทึก
+var spoolAppendScript = redis.NewScript(`
+if redis.call('XLEN', KEYS[1]) >= tonumber(ARGV[1]) then
+	return 0
+end
+redis.call('XADD', KEYS[1], '*', unpack(ARGV, 2))
+return 1
+`)
+
+// SpoolStats คือสถานะของ spool Depth คือจำนวนข้อความที่ยังไม่ถูกบันทึกลงฐานข้อมูล
+// OldestAt คือเวลาที่รับข้อความที่เก่าที่สุดซึ่งยังไม่ถูกบันทึก
+// Pending คือจำนวนที่ส่งให้ worker แล้วแต่ยังไม่ ack ข้อความที่ ack แล้วจะถูกลบออกจาก stream
+// Full คือ spool มีข้อความครบ MaxLen แล้ว ข้อความใหม่จะต้องรอจนกว่าจะมีที่ว่าง
+type SpoolStats struct {
+	Stream           string     `json:"stream"`
+	Group            string     `json:"group"`
+	Consumer         string     `json:"consumer"`
+	Depth            int64      `json:"depth"`
+	Pending          int64      `json:"pending"`
+	InFlight         int        `json:"inFlight"`
+	OldestID         string     `json:"oldestId,omitempty"`
+	OldestAt         *time.Time `json:"oldestAt,omitempty"`
+	OldestAgeSeconds float64    `json:"oldestAgeSeconds"`
+	MaxLen           int64      `json:"maxLen"`
+	Full             bool       `json:"full"`
+}
+
+// IngestSpool คือ write-ahead log ของข้อความ MQTT ข้อความถูกเขียนลง spool ก่อนประมวลผล
+// และถูก ack (ลบ) หลังบันทึกลงฐานข้อมูลสำเร็จแล้วเท่านั้น จึงไม่หายเมื่อฐานข้อมูลล่มหรือ service restart
+// Read และ Reclaim ต้องถูกเรียกจาก goroutine เดียว
+type IngestSpool interface {
+	Append(ctx context.Context, msg IngestMessage) error
+	// Read คืนข้อความที่ค้างของ consumer นี้ (จากการรันครั้งก่อน) ก่อน แล้วจึงข้อความใหม่ รอไม่เกินหนึ่งวินาที
+	Read(ctx context.Context, count int) ([]IngestMessage, error)
+	// Reclaim รับข้อความที่ consumer อื่นค้างไว้นานกว่า claimIdle (เช่น instance ที่ตายไป) มาประมวลผลแทน
+	Reclaim(ctx context.Context, count int) ([]IngestMessage, error)
+	Ack(ctx context.Context, ids []string) error
+	Stats(ctx context.Context) (*SpoolStats, error)
+}
+
+type redisIngestSpool struct {
+	redisClient *redis.Client
+	stream      string
+	group       string
+	consumer    string
+	claimIdle   time.Duration
+	maxLen      int64
+
+	groupReady bool
+	recovering bool
+	cursor     string
+	claimFrom  string
+
+	// inFlight คือข้อความที่ส่งให้ worker แล้วและยังไม่ ack ใช้กันไม่ให้ Reclaim ส่งซ้ำ
+	mu       sync.Mutex
+	inFlight map[string]struct{}
+}
+
+// NewRedisIngestSpool สร้าง spool บน Redis Stream โดยใช้ consumer group group และชื่อ consumer ที่คงที่ข้าม restart
+// maxLen จำกัดจำนวนข้อความที่ยังไม่บันทึก เพื่อไม่ให้ Redis หน่วยความจำเต็มเมื่อฐานข้อมูลล่มนาน 0 คือไม่จำกัด
+// เมื่อเต็ม Append จะคืน ErrSpoolFull แทนการตัดข้อความเก่าที่ยังไม่บันทึกทิ้ง
+func NewRedisIngestSpool(redisClient *redis.Client, stream, group, consumer string, claimIdle time.Duration, maxLen int64) IngestSpool {
+	return &redisIngestSpool{
+		redisClient: redisClient,
+		stream:      stream,
+		group:       group,
+		consumer:    consumer,
+		claimIdle:   claimIdle,
+		maxLen:      maxLen,
+		recovering:  true,
+		cursor:      "0",
+		claimFrom:   "0-0",
+		inFlight:    make(map[string]struct{}),
+	}
+}
+
+func (s *redisIngestSpool) Append(ctx context.Context, msg IngestMessage) error {
+	values := []interface{}{
+		"kind", msg.Kind,
+		"dorm", msg.DormID,
+		"washer", msg.WasherID,
+		"payload", msg.Payload,
+		"at", strconv.FormatInt(msg.ReceivedAt.UnixNano(), 10),
+	}
+	if s.maxLen <= 0 {
+		return s.redisClient.XAdd(ctx, &redis.XAddArgs{Stream: s.stream, Values: values}).Err()
+	}
+
+	added, err := spoolAppendScript.Run(ctx, s.redisClient, []string{s.stream}, append([]interface{}{s.maxLen}, values...)...).Int64()
+	if err != nil {
+		return err
+	}
+	if added == 0 {
+		return ErrSpoolFull
+	}
+	return nil
+}
+
+func (s *redisIngestSpool) Read(ctx context.Context, count int) ([]IngestMessage, error) {
+	if err := s.ensureGroup(ctx); err != nil {
+		return nil, err
+	}
+
+	id := ">"
+	if s.recovering {
+		id = s.cursor
+	}
+	streams, err := s.redisClient.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    s.group,
+		Consumer: s.consumer,
+		Streams:  []string{s.stream, id},
+		Count:    int64(count),
+		Block:    ingestSpoolBlock,
+	}).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		s.resetGroup(err)
+		return nil, err
+	}
+
+	var entries []redis.XMessage
+	if len(streams) > 0 {
+		entries = streams[0].Messages
+	}
+	if s.recovering {
+		if len(entries) == 0 {
+			s.recovering = false
+		} else {
+			s.cursor = entries[len(entries)-1].ID
+			log.Printf("♻️ Recovering %d spooled message(s) left from the previous run", len(entries))
+		}
+	}
+	return s.decode(ctx, entries), nil
+}
+
+func (s *redisIngestSpool) Reclaim(ctx context.Context, count int) ([]IngestMessage, error) {
+	if err := s.ensureGroup(ctx); err != nil {
+		return nil, err
+	}
+
+	entries, next, err := s.redisClient.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+		Stream:   s.stream,
+		Group:    s.group,
+		Consumer: s.consumer,
+		MinIdle:  s.claimIdle,
+		Start:    s.claimFrom,
+		Count:    int64(count),
+	}).Result()
+	if err != nil {
+		s.resetGroup(err)
+		return nil, err
+	}
+	s.claimFrom = next
+
+	// ข้อความของ consumer นี้ที่ยังรอบันทึกอยู่ (เช่นระหว่างฐานข้อมูลล่ม) ก็ถูก claim ได้ ต้องไม่ส่งซ้ำ
+	s.mu.Lock()
+	claimed := entries[:0]
+	for _, entry := range entries {
+		if _, ok := s.inFlight[entry.ID]; !ok {
+			claimed = append(claimed, entry)
+		}
+	}
+	s.mu.Unlock()
+	if len(claimed) > 0 {
+		log.Printf("♻️ Reclaimed %d spooled message(s) idle for over %v", len(claimed), s.claimIdle)
+	}
+	return s.decode(ctx, claimed), nil
+}
+
+func (s *redisIngestSpool) Ack(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	pipe := s.redisClient.TxPipeline()
+	pipe.XAck(ctx, s.stream, s.group, ids...)
+	pipe.XDel(ctx, s.stream, ids...)
+	_, err := pipe.Exec(ctx)
+
+	// ข้อความที่ ack ไม่สำเร็จยังค้างใน stream และจะถูก Reclaim ในภายหลัง
+	s.mu.Lock()
+	for _, id := range ids {
+		delete(s.inFlight, id)
+	}
+	s.mu.Unlock()
+	return err
+}
+
+func (s *redisIngestSpool) Stats(ctx context.Context) (*SpoolStats, error) {
+	s.mu.Lock()
+	inFlight := len(s.inFlight)
+	s.mu.Unlock()
+
+	stats := &SpoolStats{Stream: s.stream, Group: s.group, Consumer: s.consumer, InFlight: inFlight, MaxLen: s.maxLen}
+
+	depth, err := s.redisClient.XLen(ctx, s.stream).Result()
+	if err != nil {
+		return nil, err
+	}
+	stats.Depth = depth
+	stats.Full = s.maxLen > 0 && depth >= s.maxLen
+	if depth == 0 {
+		return stats, nil
+	}
+
+	pending, err := s.redisClient.XPending(ctx, s.stream, s.group).Result()
+	if err != nil && !isNoGroup(err) {
+		return nil, err
+	}
+	if pending != nil {
+		stats.Pending = pending.Count
+	}
+
+	oldest, err := s.redisClient.XRangeN(ctx, s.stream, "-", "+", 1).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(oldest) > 0 {
+		stats.OldestID = oldest[0].ID
+		at, ok := streamIDTime(oldest[0].ID)
+		if msg, decoded := decodeSpoolEntry(oldest[0]); decoded {
+			at, ok = msg.ReceivedAt, true
+		}
+		if ok {
+			stats.OldestAt = &at
+			stats.OldestAgeSeconds = time.Since(at).Seconds()
+		}
+	}
+	return stats, nil
+}
+
+// ensureGroup สร้าง stream และ consumer group หากยังไม่มี group ใหม่เริ่มอ่านจากข้อความแรกสุด
+func (s *redisIngestSpool) ensureGroup(ctx context.Context) error {
+	if s.groupReady {
+		return nil
+	}
+	err := s.redisClient.XGroupCreateMkStream(ctx, s.stream, s.group, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("create spool group: %w", err)
+	}
+	s.groupReady = true
+	return nil
+}
+
+// resetGroup ให้สร้าง group ใหม่ในครั้งถัดไปหาก stream ถูกลบไป
+func (s *redisIngestSpool) resetGroup(err error) {
+	if isNoGroup(err) {
+		s.groupReady = false
+	}
+}
+
+// decode แปลง entry เป็น IngestMessage และบันทึกว่ากำลังประมวลผล entry ที่อ่านไม่ได้จะถูก ack ทิ้ง
+func (s *redisIngestSpool) decode(ctx context.Context, entries []redis.XMessage) []IngestMessage {
+	messages := make([]IngestMessage, 0, len(entries))
+	var malformed []string
+
+	s.mu.Lock()
+	for _, entry := range entries {
+		msg, ok := decodeSpoolEntry(entry)
+		if !ok {
+			log.Printf("⚠️ Dropped malformed spool entry %s", entry.ID)
+			malformed = append(malformed, entry.ID)
+			continue
+		}
+		s.inFlight[entry.ID] = struct{}{}
+		messages = append(messages, msg)
+	}
+	s.mu.Unlock()
+
+	if err := s.Ack(ctx, malformed); err != nil {
+		log.Printf("❌ Failed to ack malformed spool entries: %v", err)
+	}
+	return messages
+}
+
+func decodeSpoolEntry(entry redis.XMessage) (IngestMessage, bool) {
+	field := func(name string) string {
+		value, _ := entry.Values[name].(string)
+		return value
+	}
+	at, err := strconv.ParseInt(field("at"), 10, 64)
+	if err != nil || field("kind") == "" || field("washer") == "" {
+		return IngestMessage{}, false
+	}
+	return IngestMessage{
+		Kind:       field("kind"),
+		DormID:     field("dorm"),
+		WasherID:   field("washer"),
+		Payload:    []byte(field("payload")),
+		ReceivedAt: time.Unix(0, at),
+		SpoolID:    entry.ID,
+	}, true
+}
+
+// streamIDTime คืนเวลาที่ Redis สร้าง entry จาก ID รูปแบบ <unix ms>-<seq>
+func streamIDTime(id string) (time.Time, bool) {
+	ms, _, _ := strings.Cut(id, "-")
+	n, err := strconv.ParseInt(ms, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.UnixMilli(n), true
+}
+
+func isNoGroup(err error) bool {
+	return err != nil && strings.HasPrefix(err.Error(), "NOGROUP")
+}
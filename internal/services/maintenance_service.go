@@ -0,0 +1,157 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jaytnw/bms-service/internal/apperr"
+	"github.com/jaytnw/bms-service/internal/models"
+	"github.com/jaytnw/bms-service/internal/repository"
+	"gorm.io/gorm"
+)
+
+// maintenanceCacheTTL คือระยะเวลาที่เก็บรายการช่วงซ่อมบำรุงไว้ใน memory
+// เพราะ MaintenanceChecker ถูกเรียกทุกข้อความ MQTT
+const maintenanceCacheTTL = 15 * time.Second
+
+// MaintenanceChecker ตรวจว่าเครื่องอยู่ระหว่างซ่อมบำรุงหรือไม่ คืน nil หากไม่อยู่
+type MaintenanceChecker interface {
+	ActiveWindow(ctx context.Context, dormID, washerID string, at time.Time) *models.MaintenanceWindow
+}
+
+// MaintenanceService จัดการช่วงซ่อมบำรุงของเครื่องหรือทั้งหอ
+type MaintenanceService interface {
+	MaintenanceChecker
+	List(ctx context.Context, filter repository.MaintenanceFilter) ([]models.MaintenanceWindow, error)
+	Start(ctx context.Context, window *models.MaintenanceWindow) error
+	End(ctx context.Context, id uint, by string) (*models.MaintenanceWindow, error)
+}
+
+type maintenanceService struct {
+	maintenanceRepo repository.MaintenanceRepository
+	registryRepo    repository.RegistryRepository
+
+	mu       sync.Mutex
+	active   []models.MaintenanceWindow
+	loadedAt time.Time
+}
+
+func NewMaintenanceService(maintenanceRepo repository.MaintenanceRepository, registryRepo repository.RegistryRepository) MaintenanceService {
+	return &maintenanceService{
+		maintenanceRepo: maintenanceRepo,
+		registryRepo:    registryRepo,
+	}
+}
+
+// ActiveWindow ใช้ cache ของช่วงที่ยังไม่ปิด หากโหลดจากฐานข้อมูลไม่ได้จะใช้ cache เดิม
+func (s *maintenanceService) ActiveWindow(ctx context.Context, dormID, washerID string, at time.Time) *models.MaintenanceWindow {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if time.Since(s.loadedAt) > maintenanceCacheTTL {
+		// โหลดช่วงที่ยังไม่ปิดทั้งหมดโดยไม่กรองเวลา cache จึงใช้ได้กับ at ของทุกข้อความ
+		// (ข้อความจาก spool อาจเก่ากว่าเวลาที่โหลด) ส่วนเงื่อนไขเวลาตรวจด้วย ActiveAt ด้านล่าง
+		windows, err := s.maintenanceRepo.FindWindows(ctx, repository.MaintenanceFilter{Open: true})
+		if err != nil {
+			log.Printf("⚠️ Failed to refresh maintenance windows: %v", err)
+		} else {
+			s.active = windows
+			s.loadedAt = time.Now()
+		}
+	}
+
+	for i := range s.active {
+		if s.active[i].Covers(dormID, washerID) && s.active[i].ActiveAt(at) {
+			window := s.active[i]
+			return &window
+		}
+	}
+	return nil
+}
+
+func (s *maintenanceService) List(ctx context.Context, filter repository.MaintenanceFilter) ([]models.MaintenanceWindow, error) {
+	windows, err := s.maintenanceRepo.FindWindows(ctx, filter)
+	if err != nil {
+		return nil, apperr.New("DB_ERROR", "Failed to get maintenance windows", 500, err)
+	}
+	return windows, nil
+}
+
+// Start เปิดช่วงซ่อมบำรุง หากระบุเฉพาะ washerId จะหา dormId จากทะเบียนให้
+func (s *maintenanceService) Start(ctx context.Context, window *models.MaintenanceWindow) error {
+	if strings.TrimSpace(window.Reason) == "" {
+		return apperr.New("VALIDATION_ERROR", "reason is required", 400, nil)
+	}
+
+	if window.WasherID != "" {
+		machine, err := s.registryRepo.FindMachineByID(ctx, window.WasherID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return apperr.New("NOT_FOUND", "Washer not found", 404, err)
+			}
+			return apperr.New("DB_ERROR", "Failed to get washer", 500, err)
+		}
+		window.DormID = machine.DormID
+	} else {
+		if window.DormID == "" {
+			return apperr.New("VALIDATION_ERROR", "dormId or washerId is required", 400, nil)
+		}
+		if _, err := s.registryRepo.FindDormByID(ctx, window.DormID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return apperr.New("NOT_FOUND", "Dorm not found", 404, err)
+			}
+			return apperr.New("DB_ERROR", "Failed to get dorm", 500, err)
+		}
+	}
+
+	now := time.Now()
+	if window.StartsAt.IsZero() {
+		window.StartsAt = now
+	}
+	if window.ExpectedEndAt != nil && !window.ExpectedEndAt.After(window.StartsAt) {
+		return apperr.New("VALIDATION_ERROR", "expectedEndAt must be after startsAt", 400, nil)
+	}
+
+	window.ID = 0
+	window.EndedAt = nil
+	window.EndedBy = ""
+	if err := s.maintenanceRepo.SaveWindow(ctx, window); err != nil {
+		return apperr.New("DB_ERROR", "Failed to start maintenance", 500, err)
+	}
+	s.invalidate()
+	log.Printf("🔧 Maintenance %d started for dorm %s washer %q: %s", window.ID, window.DormID, window.WasherID, window.Reason)
+	return nil
+}
+
+func (s *maintenanceService) End(ctx context.Context, id uint, by string) (*models.MaintenanceWindow, error) {
+	window, err := s.maintenanceRepo.FindWindowByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperr.New("NOT_FOUND", "Maintenance window not found", 404, err)
+		}
+		return nil, apperr.New("DB_ERROR", "Failed to get maintenance window", 500, err)
+	}
+	if window.EndedAt != nil {
+		return nil, apperr.New("CONFLICT", "Maintenance window has already ended", 409, nil)
+	}
+
+	now := time.Now()
+	window.EndedAt = &now
+	window.EndedBy = by
+	if err := s.maintenanceRepo.SaveWindow(ctx, window); err != nil {
+		return nil, apperr.New("DB_ERROR", "Failed to end maintenance", 500, err)
+	}
+	s.invalidate()
+	log.Printf("🔧 Maintenance %d ended", window.ID)
+	return window, nil
+}
+
+func (s *maintenanceService) invalidate() {
+	s.mu.Lock()
+	s.loadedAt = time.Time{}
+	s.mu.Unlock()
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jaytnw/bms-service/internal/models"
+	"github.com/jaytnw/bms-service/internal/repository"
+)
+
+type fakeMaintenanceRepo struct {
+	repository.MaintenanceRepository
+	windows []models.MaintenanceWindow
+	filters []repository.MaintenanceFilter
+}
+
+func (r *fakeMaintenanceRepo) FindWindows(ctx context.Context, filter repository.MaintenanceFilter) ([]models.MaintenanceWindow, error) {
+	r.filters = append(r.filters, filter)
+	var found []models.MaintenanceWindow
+	for _, window := range r.windows {
+		if filter.Open && window.EndedAt != nil {
+			continue
+		}
+		if filter.ActiveAt != nil && !window.ActiveAt(*filter.ActiveAt) {
+			continue
+		}
+		found = append(found, window)
+	}
+	return found, nil
+}
+
+func TestMaintenanceActiveWindowChecksTimePerCall(t *testing.T) {
+	now := time.Now()
+	endsAt := now.Add(time.Hour)
+	ended := now.Add(-time.Minute)
+	repo := &fakeMaintenanceRepo{windows: []models.MaintenanceWindow{
+		{ID: 1, DormID: "d1", WasherID: "w1", StartsAt: now.Add(10 * time.Minute), ExpectedEndAt: &endsAt},
+		{ID: 2, DormID: "d1", WasherID: "w2", StartsAt: now.Add(-time.Hour), EndedAt: &ended},
+	}}
+	service := NewMaintenanceService(repo, nil)
+	ctx := context.Background()
+
+	// โหลด cache ตอนที่ช่วงของ w1 ยังไม่เริ่ม
+	if window := service.ActiveWindow(ctx, "d1", "w1", now); window != nil {
+		t.Fatalf("ActiveWindow(now) = %+v, want nil before the window starts", window)
+	}
+	if window := service.ActiveWindow(ctx, "d1", "w1", now.Add(20*time.Minute)); window == nil || window.ID != 1 {
+		t.Fatalf("ActiveWindow(+20m) = %+v, want window 1 from the same cache", window)
+	}
+	if window := service.ActiveWindow(ctx, "d1", "w1", now.Add(2*time.Hour)); window != nil {
+		t.Fatalf("ActiveWindow(+2h) = %+v, want nil after the expected end", window)
+	}
+	if window := service.ActiveWindow(ctx, "d1", "w2", now); window != nil {
+		t.Fatalf("ActiveWindow(w2) = %+v, want nil for an ended window", window)
+	}
+
+	if len(repo.filters) != 1 || !repo.filters[0].Open || repo.filters[0].ActiveAt != nil {
+		t.Fatalf("filters = %+v, want one load of open windows without a time filter", repo.filters)
+	}
+}
+
+func TestMaintenanceStatusesSkipSessionsAndWebhooks(t *testing.T) {
+	ctx := context.Background()
+	change := StatusChange{
+		Status:   models.Status{DormID: "d1", WasherID: "w1", Status: models.WasherWashing, Maintenance: true},
+		Previous: models.WasherIdle,
+	}
+
+	// fake ที่ฝัง interface จะ panic หาก listener แตะ repository
+	sessions := NewSessionService(&fakeSessionRepo{}, nil)
+	sessions.OnStatusChange(ctx, change)
+
+	webhooks := NewWebhookService(&fakeWebhookRepo{}, WebhookOptions{})
+	webhooks.OnStatusChange(ctx, change)
+}
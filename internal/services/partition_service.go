@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jaytnw/bms-service/internal/repository"
+)
+
+// PartitionService ดูแล partition รายเดือนของตาราง statuses
+// สร้าง partition ล่วงหน้า monthsAhead เดือน เพราะไม่มี default partition แถวที่ไม่มี partition รองรับจะบันทึกไม่ได้
+// และลบ partition ที่เก่ากว่า retention ทั้งเดือนเมื่อ retention job archive และลบแถวออกหมดแล้ว
+type PartitionService interface {
+	Run(ctx context.Context, interval time.Duration)
+}
+
+type partitionService struct {
+	statusRepo  repository.StatusRepository
+	monthsAhead int
+	retention   time.Duration
+}
+
+// NewPartitionService retention เป็น 0 คือไม่ลบ partition (ใช้เมื่อปิด retention)
+func NewPartitionService(statusRepo repository.StatusRepository, monthsAhead int, retention time.Duration) PartitionService {
+	return &partitionService{
+		statusRepo:  statusRepo,
+		monthsAhead: monthsAhead,
+		retention:   retention,
+	}
+}
+
+// Run ดูแล partition ทันที แล้วทำซ้ำทุก interval จนกว่า ctx จะถูกยกเลิก
+func (s *partitionService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	log.Printf("🧱 Status partition maintenance started (every %v)", interval)
+	for {
+		s.maintain(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *partitionService) maintain(ctx context.Context) {
+	now := time.Now().UTC()
+	until := time.Date(now.Year(), now.Month()+time.Month(s.monthsAhead)+1, 1, 0, 0, 0, 0, time.UTC)
+	if err := s.statusRepo.EnsurePartitions(ctx, now, until); err != nil {
+		log.Printf("❌ Failed to create status partitions: %v", err)
+	}
+
+	if s.retention <= 0 {
+		return
+	}
+	partitions, err := s.statusRepo.FindPartitions(ctx)
+	if err != nil {
+		log.Printf("❌ Failed to list status partitions: %v", err)
+		return
+	}
+
+	cutoff := now.Add(-s.retention)
+	for _, partition := range partitions {
+		if partition.To.After(cutoff) {
+			break
+		}
+		dropped, err := s.statusRepo.DropPartitionIfEmpty(ctx, partition)
+		if err != nil {
+			log.Printf("❌ Failed to drop status partition %s: %v", partition.Name, err)
+			continue
+		}
+		if dropped {
+			log.Printf("🗑️ Dropped expired status partition %s", partition.Name)
+		}
+	}
+}
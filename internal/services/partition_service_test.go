@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jaytnw/bms-service/internal/repository"
+)
+
+// fakePartitionRepo เก็บ partition รายเดือนตามชื่อ partition ที่มีแถวอยู่จะไม่ถูกลบ
+type fakePartitionRepo struct {
+	repository.StatusRepository
+	partitions []repository.StatusPartition
+	nonEmpty   map[string]bool
+	dropErr    map[string]error
+	ensured    [][2]time.Time
+	dropped    []string
+}
+
+func (r *fakePartitionRepo) EnsurePartitions(ctx context.Context, from, to time.Time) error {
+	r.ensured = append(r.ensured, [2]time.Time{from, to})
+	return nil
+}
+
+func (r *fakePartitionRepo) FindPartitions(ctx context.Context) ([]repository.StatusPartition, error) {
+	return r.partitions, nil
+}
+
+func (r *fakePartitionRepo) DropPartitionIfEmpty(ctx context.Context, partition repository.StatusPartition) (bool, error) {
+	if err := r.dropErr[partition.Name]; err != nil {
+		return false, err
+	}
+	if r.nonEmpty[partition.Name] {
+		return false, nil
+	}
+	r.dropped = append(r.dropped, partition.Name)
+	return true, nil
+}
+
+func monthlyPartitions(from time.Time, months int) []repository.StatusPartition {
+	var partitions []repository.StatusPartition
+	for i := 0; i < months; i++ {
+		month := from.AddDate(0, i, 0)
+		partitions = append(partitions, repository.StatusPartition{
+			Name: "statuses_p" + month.Format("200601"),
+			From: month,
+			To:   month.AddDate(0, 1, 0),
+		})
+	}
+	return partitions
+}
+
+func TestPartitionMaintenance(t *testing.T) {
+	now := time.Now().UTC()
+	thisMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	// partition ย้อนหลัง 12 เดือนจนถึงเดือนปัจจุบัน
+	partitions := monthlyPartitions(thisMonth.AddDate(0, -12, 0), 13)
+	name := func(monthsAgo int) string { return "statuses_p" + thisMonth.AddDate(0, -monthsAgo, 0).Format("200601") }
+	// cutoff ตกกลางเดือนเมื่อ 6 เดือนก่อน partition ของเดือนนั้นจึงยังไม่หมดอายุ
+	retention := now.Sub(thisMonth.AddDate(0, -6, 14))
+
+	tests := []struct {
+		name      string
+		retention time.Duration
+		nonEmpty  []string
+		dropErr   []string
+		want      []string
+	}{
+		{name: "retention disabled keeps every partition", retention: 0},
+		{
+			// partition ที่หมดอายุคือเดือนที่สิ้นสุดก่อน cutoff ทั้งเดือน
+			name:      "drops partitions that ended before retention",
+			retention: retention,
+			want:      []string{name(12), name(11), name(10), name(9), name(8), name(7)},
+		},
+		{
+			name:      "skips partitions that still hold rows or fail",
+			retention: retention,
+			nonEmpty:  []string{name(11)},
+			dropErr:   []string{name(9)},
+			want:      []string{name(12), name(10), name(8), name(7)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakePartitionRepo{partitions: partitions, nonEmpty: map[string]bool{}, dropErr: map[string]error{}}
+			for _, name := range tt.nonEmpty {
+				repo.nonEmpty[name] = true
+			}
+			for _, name := range tt.dropErr {
+				repo.dropErr[name] = errors.New("lock timeout")
+			}
+
+			service := NewPartitionService(repo, 3, tt.retention).(*partitionService)
+			service.maintain(context.Background())
+
+			if len(repo.ensured) != 1 {
+				t.Fatalf("EnsurePartitions called %d times, want 1", len(repo.ensured))
+			}
+			if until := repo.ensured[0][1]; !until.Equal(thisMonth.AddDate(0, 4, 0)) {
+				t.Fatalf("partitions ensured until %v, want %v", until, thisMonth.AddDate(0, 4, 0))
+			}
+			if !slices.Equal(repo.dropped, tt.want) {
+				t.Fatalf("dropped = %v, want %v", repo.dropped, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,602 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jaytnw/bms-service/internal/apperr"
+	"github.com/jaytnw/bms-service/internal/models"
+	"github.com/jaytnw/bms-service/internal/notifications"
+	"github.com/jaytnw/bms-service/internal/repository"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// JoinQueueRequest คือข้อมูลสำหรับต่อคิว Channel/Target ใช้ส่งแจ้งเตือนเมื่อถึงคิว
+type JoinQueueRequest struct {
+	UserID  string `json:"userId"`
+	Channel string `json:"channel"`
+	Target  string `json:"target"`
+}
+
+// QueueService จัดคิวเครื่องซักผ้าต่อหอ เมื่อเครื่องว่างตามสถานะ live จาก MQTT จะเสนอเครื่องให้คิวแรก
+// และให้เวลา claim ตาม claimWindow ก่อนส่งต่อให้คิวถัดไป เครื่องที่อยู่ระหว่างซ่อมบำรุงจะไม่ถูกเสนอ
+// เครื่องที่ claim แล้วถูกถือครองไว้จนเริ่มทำงาน หรือจนครบ claimWindow อีกรอบหลัง claim
+type QueueService interface {
+	StatusListener
+	Join(ctx context.Context, dormID string, req JoinQueueRequest) (*models.QueueEntry, error)
+	GetQueue(ctx context.Context, dormID string) ([]models.QueueEntry, error)
+	GetEntry(ctx context.Context, dormID string, entryID uint) (*models.QueueEntry, error)
+	Claim(ctx context.Context, dormID string, entryID uint) (*models.QueueEntry, error)
+	Leave(ctx context.Context, dormID string, entryID uint) error
+	RunExpiry(ctx context.Context, interval time.Duration)
+}
+
+// การเปลี่ยนสถานะของคิวใช้ QueueRepository.UpdateEntryIf แทน lock เพราะ OnStatusChange ถูกเรียกจาก
+// ingest worker หลายตัวพร้อมกัน ฐานข้อมูลกันไม่ให้เครื่องเดียวถูกถือครองโดยสองคิว
+type queueService struct {
+	queueRepo    repository.QueueRepository
+	registryRepo repository.RegistryRepository
+	stateStore   WasherStateStore
+	live         liveQueue
+	dispatcher   notifications.Dispatcher
+	maintenance  MaintenanceChecker
+	claimWindow  time.Duration
+	offlineAfter time.Duration
+
+	// joinMu กันผู้ใช้คนเดียวต่อคิวซ้ำจาก request ที่มาพร้อมกัน
+	joinMu sync.Mutex
+}
+
+func NewQueueService(
+	queueRepo repository.QueueRepository,
+	registryRepo repository.RegistryRepository,
+	stateStore WasherStateStore,
+	redisClient *redis.Client,
+	dispatcher notifications.Dispatcher,
+	maintenance MaintenanceChecker,
+	claimWindow, offlineAfter time.Duration,
+) QueueService {
+	return &queueService{
+		queueRepo:    queueRepo,
+		registryRepo: registryRepo,
+		stateStore:   stateStore,
+		live:         redisLiveQueue{redisClient: redisClient},
+		dispatcher:   dispatcher,
+		maintenance:  maintenance,
+		claimWindow:  claimWindow,
+		offlineAfter: offlineAfter,
+	}
+}
+
+// liveQueue คือลำดับของ entry ID ที่ยังรออยู่ในแต่ละหอ Postgres ยังเป็นข้อมูลหลัก
+type liveQueue interface {
+	Push(ctx context.Context, dormID string, ids ...uint) error
+	PushFront(ctx context.Context, dormID string, id uint) error
+	// Pop คืน false เมื่อคิวว่าง
+	Pop(ctx context.Context, dormID string) (uint, bool, error)
+	Remove(ctx context.Context, dormID string, id uint) error
+	List(ctx context.Context, dormID string) ([]uint, error)
+}
+
+// redisLiveQueue เก็บลำดับคิวเป็น Redis list ต่อหอ
+type redisLiveQueue struct {
+	redisClient *redis.Client
+}
+
+// liveQueueKey คือ Redis list ของ entry ID ที่ยังรออยู่ เรียงตามลำดับคิว
+func liveQueueKey(dormID string) string {
+	return "queue:dorm:" + dormID
+}
+
+func (q redisLiveQueue) Push(ctx context.Context, dormID string, ids ...uint) error {
+	values := make([]any, 0, len(ids))
+	for _, id := range ids {
+		values = append(values, id)
+	}
+	return q.redisClient.RPush(ctx, liveQueueKey(dormID), values...).Err()
+}
+
+func (q redisLiveQueue) PushFront(ctx context.Context, dormID string, id uint) error {
+	return q.redisClient.LPush(ctx, liveQueueKey(dormID), id).Err()
+}
+
+func (q redisLiveQueue) Pop(ctx context.Context, dormID string) (uint, bool, error) {
+	for {
+		raw, err := q.redisClient.LPop(ctx, liveQueueKey(dormID)).Result()
+		if errors.Is(err, redis.Nil) {
+			return 0, false, nil
+		}
+		if err != nil {
+			return 0, false, err
+		}
+		if id, err := strconv.ParseUint(raw, 10, 64); err == nil {
+			return uint(id), true, nil
+		}
+	}
+}
+
+func (q redisLiveQueue) Remove(ctx context.Context, dormID string, id uint) error {
+	return q.redisClient.LRem(ctx, liveQueueKey(dormID), 0, id).Err()
+}
+
+func (q redisLiveQueue) List(ctx context.Context, dormID string) ([]uint, error) {
+	raw, err := q.redisClient.LRange(ctx, liveQueueKey(dormID), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uint, 0, len(raw))
+	for _, r := range raw {
+		if id, err := strconv.ParseUint(r, 10, 64); err == nil {
+			ids = append(ids, uint(id))
+		}
+	}
+	return ids, nil
+}
+
+func (s *queueService) Join(ctx context.Context, dormID string, req JoinQueueRequest) (*models.QueueEntry, error) {
+	req.UserID = strings.TrimSpace(req.UserID)
+	if req.UserID == "" {
+		return nil, apperr.New("VALIDATION_ERROR", "userId is required", 400, nil)
+	}
+	if req.Channel == "" {
+		req.Channel = notifications.ChannelLog
+	}
+	if !s.dispatcher.Supports(req.Channel) {
+		return nil, apperr.New("VALIDATION_ERROR", fmt.Sprintf("unsupported channel %q", req.Channel), 400, nil)
+	}
+
+	if _, err := s.registryRepo.FindDormByID(ctx, dormID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperr.New("NOT_FOUND", "Dorm not found", 404, err)
+		}
+		return nil, apperr.New("DB_ERROR", "Failed to get dorm", 500, err)
+	}
+
+	s.joinMu.Lock()
+	existing, err := s.queueRepo.FindActiveByUser(ctx, dormID, req.UserID)
+	if err != nil {
+		s.joinMu.Unlock()
+		return nil, apperr.New("DB_ERROR", "Failed to check queue", 500, err)
+	}
+	if existing != nil {
+		s.joinMu.Unlock()
+		return nil, apperr.New("CONFLICT", "User is already in the queue", 409, nil)
+	}
+
+	entry := &models.QueueEntry{
+		DormID:        dormID,
+		UserID:        req.UserID,
+		Status:        models.QueueWaiting,
+		NotifyChannel: req.Channel,
+		NotifyTarget:  req.Target,
+	}
+	err = s.queueRepo.SaveEntry(ctx, entry)
+	s.joinMu.Unlock()
+	if err != nil {
+		return nil, apperr.New("DB_ERROR", "Failed to join queue", 500, err)
+	}
+	if err := s.live.Push(ctx, dormID, entry.ID); err != nil {
+		log.Printf("⚠️ Failed to push queue entry %d to Redis: %v", entry.ID, err)
+	}
+
+	// อาจมีเครื่องว่างอยู่แล้ว ไม่ต้องรอ MQTT
+	s.dispatch(ctx, dormID)
+
+	return s.withPosition(ctx, entry.ID)
+}
+
+func (s *queueService) GetQueue(ctx context.Context, dormID string) ([]models.QueueEntry, error) {
+	entries, err := s.queueRepo.FindActiveEntries(ctx, dormID)
+	if err != nil {
+		return nil, apperr.New("DB_ERROR", "Failed to get queue", 500, err)
+	}
+
+	positions := s.positions(ctx, dormID, entries)
+	for i := range entries {
+		entries[i].Position = positions[entries[i].ID]
+	}
+	return entries, nil
+}
+
+func (s *queueService) GetEntry(ctx context.Context, dormID string, entryID uint) (*models.QueueEntry, error) {
+	if _, err := s.findEntry(ctx, dormID, entryID); err != nil {
+		return nil, err
+	}
+	return s.withPosition(ctx, entryID)
+}
+
+// Claim ยืนยันว่าผู้ใช้รับเครื่องที่ถูกเสนอแล้ว ต้องทำภายใน ClaimExpiresAt
+func (s *queueService) Claim(ctx context.Context, dormID string, entryID uint) (*models.QueueEntry, error) {
+	entry, err := s.findEntry(ctx, dormID, entryID)
+	if err != nil {
+		return nil, err
+	}
+	if entry.Status != models.QueueOffered {
+		return nil, apperr.New("CONFLICT", "Queue entry has no washer to claim", 409, nil)
+	}
+
+	now := time.Now()
+	if entry.ClaimExpiresAt != nil && now.After(*entry.ClaimExpiresAt) {
+		return nil, apperr.New("CONFLICT", "Claim window has expired", 409, nil)
+	}
+
+	entry.Status = models.QueueClaimed
+	entry.ClaimedAt = &now
+	ok, err := s.queueRepo.UpdateEntryIf(ctx, entry, models.QueueOffered)
+	if err != nil {
+		return nil, apperr.New("DB_ERROR", "Failed to claim washer", 500, err)
+	}
+	if !ok {
+		// expiry หรือ OnStatusChange เปลี่ยนสถานะไปก่อน
+		return nil, apperr.New("CONFLICT", "Queue entry has no washer to claim", 409, nil)
+	}
+	return entry, nil
+}
+
+// Leave ยกเลิกคิว หากคิวนี้ถือครองเครื่องอยู่ เครื่องจะถูกส่งต่อให้คิวถัดไป
+func (s *queueService) Leave(ctx context.Context, dormID string, entryID uint) error {
+	entry, err := s.findEntry(ctx, dormID, entryID)
+	if err != nil {
+		return err
+	}
+	if !entry.Active() {
+		return apperr.New("CONFLICT", "Queue entry is no longer active", 409, nil)
+	}
+
+	held := entry.Status != models.QueueWaiting
+	entry.Status = models.QueueCancelled
+	ok, err := s.queueRepo.UpdateEntryIf(ctx, entry, models.QueueWaiting, models.QueueOffered, models.QueueClaimed)
+	if err != nil {
+		return apperr.New("DB_ERROR", "Failed to leave queue", 500, err)
+	}
+	if !ok {
+		return apperr.New("CONFLICT", "Queue entry is no longer active", 409, nil)
+	}
+	if err := s.live.Remove(ctx, dormID, entry.ID); err != nil {
+		log.Printf("⚠️ Failed to remove queue entry %d from Redis: %v", entry.ID, err)
+	}
+
+	if held {
+		s.dispatch(ctx, dormID)
+	}
+	return nil
+}
+
+// OnStatusChange เสนอเครื่องที่เพิ่งว่างให้คิวแรก และปล่อยเครื่องที่ถือครองไว้เมื่อเครื่องนั้นเริ่มทำงาน
+func (s *queueService) OnStatusChange(ctx context.Context, change StatusChange) {
+	status := change.Status
+
+	// เครื่องที่เพิ่งว่างไม่ต้องดูคิวที่ถือครอง dispatch จะตรวจเอง
+	if !status.Maintenance && status.Status.Free() {
+		if !change.Previous.Free() {
+			s.dispatch(ctx, status.DormID)
+		}
+		return
+	}
+
+	held, err := s.queueRepo.FindHeldByWasherID(ctx, status.WasherID)
+	if err != nil {
+		log.Printf("❌ Failed to load queue hold for washer %s: %v", status.WasherID, err)
+		return
+	}
+	if held == nil {
+		return
+	}
+
+	if !status.Maintenance && status.Status.Busy() {
+		now := time.Now()
+		from := held.Status
+		held.Status = models.QueueStarted
+		if held.ClaimedAt == nil {
+			held.ClaimedAt = &now
+		}
+		if _, err := s.queueRepo.UpdateEntryIf(ctx, held, from); err != nil {
+			log.Printf("❌ Failed to start queue entry %d: %v", held.ID, err)
+		}
+		return
+	}
+
+	// เครื่องที่ถือครองไว้เข้าซ่อมบำรุง เสีย หรือ offline: คืนคิวไว้หัวแถวแล้วหาเครื่องอื่นให้
+	s.requeue(ctx, held)
+	s.dispatch(ctx, status.DormID)
+}
+
+// RunExpiry ตรวจข้อเสนอที่หมดเวลา claim ทุก interval จนกว่า ctx จะถูกยกเลิก
+// และเสนอเครื่องให้หอที่ยังมีคิวรอ เพราะเครื่องที่พ้นช่วงซ่อมบำรุงอาจไม่ส่งสถานะใหม่มา
+func (s *queueService) RunExpiry(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	log.Printf("⏳ Queue expiry started (claim window %v)", s.claimWindow)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.expireOffers(ctx)
+		}
+	}
+}
+
+func (s *queueService) expireOffers(ctx context.Context) {
+	now := time.Now()
+	dorms := make(map[string]struct{})
+
+	expired, err := s.queueRepo.FindExpiredOffers(ctx, now)
+	if err != nil {
+		log.Printf("❌ Failed to load expired queue offers: %v", err)
+	}
+	for i := range expired {
+		if s.expire(ctx, &expired[i], models.QueueOffered) {
+			log.Printf("⌛ Queue entry %d missed washer %s", expired[i].ID, expired[i].WasherID)
+			s.notify(ctx, &expired[i], "Claim window expired",
+				fmt.Sprintf("You did not claim washer %s in time, so it was passed to the next person.", expired[i].WasherID))
+			dorms[expired[i].DormID] = struct{}{}
+		}
+	}
+
+	// คิวที่ claim แล้วแต่ไม่เริ่มใช้เครื่องภายใน claimWindow จะปล่อยเครื่องให้คิวถัดไป
+	stale, err := s.queueRepo.FindStaleClaims(ctx, now.Add(-s.claimWindow))
+	if err != nil {
+		log.Printf("❌ Failed to load stale queue claims: %v", err)
+	}
+	for i := range stale {
+		if s.expire(ctx, &stale[i], models.QueueClaimed) {
+			log.Printf("⌛ Queue entry %d did not start washer %s", stale[i].ID, stale[i].WasherID)
+			s.notify(ctx, &stale[i], "Washer released",
+				fmt.Sprintf("Washer %s was not started in time after your claim, so it was passed to the next person.", stale[i].WasherID))
+			dorms[stale[i].DormID] = struct{}{}
+		}
+	}
+
+	waiting, err := s.queueRepo.FindWaitingDormIDs(ctx)
+	if err != nil {
+		log.Printf("❌ Failed to load dorms with waiting queue: %v", err)
+	}
+	for _, dormID := range waiting {
+		dorms[dormID] = struct{}{}
+	}
+
+	for dormID := range dorms {
+		s.dispatch(ctx, dormID)
+	}
+}
+
+// expire เปลี่ยนคิวเป็น expired หากสถานะยังเป็น from คืน true เมื่อเปลี่ยนสำเร็จ
+func (s *queueService) expire(ctx context.Context, entry *models.QueueEntry, from models.QueueEntryStatus) bool {
+	entry.Status = models.QueueExpired
+	ok, err := s.queueRepo.UpdateEntryIf(ctx, entry, from)
+	if err != nil {
+		log.Printf("❌ Failed to expire queue entry %d: %v", entry.ID, err)
+	}
+	return ok
+}
+
+// dispatch เสนอเครื่องที่ว่างและยังไม่ถูกคิวใดถือครองให้คิวที่รออยู่ตามลำดับ
+// dispatch หลายตัวทำงานพร้อมกันได้ หากเครื่องถูกเสนอให้คิวอื่นไปก่อน คิวที่ดึงมาจะถูกคืนหัวแถว
+func (s *queueService) dispatch(ctx context.Context, dormID string) {
+	snapshots, err := s.stateStore.List(ctx)
+	if err != nil {
+		log.Printf("❌ Failed to list washers for queue dispatch: %v", err)
+		return
+	}
+
+	now := time.Now()
+	for _, snapshot := range snapshots {
+		if snapshot.DormID != dormID || !snapshot.Status.Free() || !snapshot.Online(now, s.offlineAfter) {
+			continue
+		}
+		if s.maintenance.ActiveWindow(ctx, dormID, snapshot.WasherID, now) != nil {
+			continue
+		}
+
+		held, err := s.queueRepo.FindHeldByWasherID(ctx, snapshot.WasherID)
+		if err != nil {
+			log.Printf("❌ Failed to load queue hold for washer %s: %v", snapshot.WasherID, err)
+			return
+		}
+		if held != nil {
+			continue
+		}
+
+		entry, err := s.nextWaiting(ctx, dormID)
+		if err != nil {
+			log.Printf("❌ Failed to pop queue for dorm %s: %v", dormID, err)
+			return
+		}
+		if entry == nil {
+			return
+		}
+
+		expiresAt := now.Add(s.claimWindow)
+		entry.Status = models.QueueOffered
+		entry.WasherID = snapshot.WasherID
+		entry.OfferedAt = &now
+		entry.ClaimExpiresAt = &expiresAt
+		ok, err := s.queueRepo.UpdateEntryIf(ctx, entry, models.QueueWaiting)
+		if err != nil {
+			log.Printf("❌ Failed to offer washer %s to queue entry %d: %v", snapshot.WasherID, entry.ID, err)
+			s.pushBack(ctx, entry)
+			return
+		}
+		if !ok {
+			// เครื่องถูกเสนอให้คิวอื่นไปแล้ว หรือคิวนี้ถูกยกเลิกระหว่างนั้น
+			s.pushBack(ctx, entry)
+			continue
+		}
+
+		log.Printf("🧺 Offered washer %s to queue entry %d (dorm %s)", snapshot.WasherID, entry.ID, dormID)
+		s.notify(ctx, entry, "Washer available",
+			fmt.Sprintf("Washer %s in dorm %s is free for you. Claim it within %v.", snapshot.WasherID, dormID, s.claimWindow))
+	}
+}
+
+// pushBack คืนคิวที่ดึงมาแล้วแต่เสนอเครื่องไม่สำเร็จไว้หัวแถว หากยังรออยู่
+func (s *queueService) pushBack(ctx context.Context, entry *models.QueueEntry) {
+	current, err := s.queueRepo.FindEntryByID(ctx, entry.ID)
+	if err != nil || current.Status != models.QueueWaiting {
+		return
+	}
+	if err := s.live.PushFront(ctx, entry.DormID, entry.ID); err != nil {
+		log.Printf("⚠️ Failed to return queue entry %d to Redis: %v", entry.ID, err)
+	}
+}
+
+// nextWaiting ดึงคิวถัดไปจาก Redis โดยข้าม entry ที่ไม่ได้รออยู่แล้ว
+// หาก list ว่างแต่ Postgres ยังมีคิวรออยู่ (เช่น Redis ถูกล้าง) จะสร้าง list ใหม่จาก Postgres
+func (s *queueService) nextWaiting(ctx context.Context, dormID string) (*models.QueueEntry, error) {
+	for {
+		id, ok, err := s.live.Pop(ctx, dormID)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return s.rebuildLiveQueue(ctx, dormID)
+		}
+
+		entry, err := s.queueRepo.FindEntryByID(ctx, id)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if entry.Status == models.QueueWaiting {
+			return entry, nil
+		}
+	}
+}
+
+// rebuildLiveQueue คืนคิวแรกที่รออยู่ใน Postgres และใส่คิวที่เหลือกลับเข้า Redis
+func (s *queueService) rebuildLiveQueue(ctx context.Context, dormID string) (*models.QueueEntry, error) {
+	entries, err := s.queueRepo.FindActiveEntries(ctx, dormID)
+	if err != nil {
+		return nil, err
+	}
+
+	var first *models.QueueEntry
+	var rest []uint
+	for i := range entries {
+		if entries[i].Status != models.QueueWaiting {
+			continue
+		}
+		if first == nil {
+			first = &entries[i]
+			continue
+		}
+		rest = append(rest, entries[i].ID)
+	}
+
+	if len(rest) > 0 {
+		if err := s.live.Push(ctx, dormID, rest...); err != nil {
+			log.Printf("⚠️ Failed to rebuild live queue for dorm %s: %v", dormID, err)
+		}
+	}
+	return first, nil
+}
+
+// requeue คืนคิวที่ถือครองเครื่องอยู่กลับไปหัวแถว
+func (s *queueService) requeue(ctx context.Context, entry *models.QueueEntry) {
+	entry.Status = models.QueueWaiting
+	entry.WasherID = ""
+	entry.OfferedAt = nil
+	entry.ClaimExpiresAt = nil
+	entry.ClaimedAt = nil
+	ok, err := s.queueRepo.UpdateEntryIf(ctx, entry, models.HeldStatuses...)
+	if err != nil {
+		log.Printf("❌ Failed to requeue entry %d: %v", entry.ID, err)
+		return
+	}
+	if !ok {
+		return
+	}
+	if err := s.live.PushFront(ctx, entry.DormID, entry.ID); err != nil {
+		log.Printf("⚠️ Failed to requeue entry %d in Redis: %v", entry.ID, err)
+	}
+}
+
+func (s *queueService) notify(ctx context.Context, entry *models.QueueEntry, subject, body string) {
+	msg := notifications.Message{
+		Channel:   entry.NotifyChannel,
+		Recipient: entry.NotifyTarget,
+		Subject:   subject,
+		Body:      body,
+		Data: map[string]any{
+			"entryId":        entry.ID,
+			"dormId":         entry.DormID,
+			"washerId":       entry.WasherID,
+			"status":         entry.Status,
+			"claimExpiresAt": entry.ClaimExpiresAt,
+		},
+	}
+	if err := s.dispatcher.Notify(ctx, msg); err != nil {
+		log.Printf("❌ Failed to notify queue entry %d: %v", entry.ID, err)
+	}
+}
+
+func (s *queueService) findEntry(ctx context.Context, dormID string, entryID uint) (*models.QueueEntry, error) {
+	entry, err := s.queueRepo.FindEntryByID(ctx, entryID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperr.New("NOT_FOUND", "Queue entry not found", 404, err)
+		}
+		return nil, apperr.New("DB_ERROR", "Failed to get queue entry", 500, err)
+	}
+	if entry.DormID != dormID {
+		return nil, apperr.New("NOT_FOUND", "Queue entry not found", 404, nil)
+	}
+	return entry, nil
+}
+
+func (s *queueService) withPosition(ctx context.Context, entryID uint) (*models.QueueEntry, error) {
+	entry, err := s.queueRepo.FindEntryByID(ctx, entryID)
+	if err != nil {
+		return nil, apperr.New("DB_ERROR", "Failed to get queue entry", 500, err)
+	}
+	if entry.Status != models.QueueWaiting {
+		return entry, nil
+	}
+
+	entries, err := s.queueRepo.FindActiveEntries(ctx, entry.DormID)
+	if err != nil {
+		return nil, apperr.New("DB_ERROR", "Failed to get queue", 500, err)
+	}
+	entry.Position = s.positions(ctx, entry.DormID, entries)[entry.ID]
+	return entry, nil
+}
+
+// positions คืนลำดับคิว (เริ่มที่ 1) ของ entry ที่รออยู่ ตามลำดับใน Redis
+// entry ที่ไม่อยู่ใน Redis จะต่อท้ายตามลำดับใน Postgres
+func (s *queueService) positions(ctx context.Context, dormID string, entries []models.QueueEntry) map[uint]int {
+	waiting := make(map[uint]bool)
+	for _, e := range entries {
+		if e.Status == models.QueueWaiting {
+			waiting[e.ID] = true
+		}
+	}
+
+	positions := make(map[uint]int, len(waiting))
+	ids, err := s.live.List(ctx, dormID)
+	if err != nil {
+		log.Printf("⚠️ Failed to read live queue for dorm %s: %v", dormID, err)
+	}
+	for _, id := range ids {
+		if !waiting[id] || positions[id] > 0 {
+			continue
+		}
+		positions[id] = len(positions) + 1
+	}
+	for _, e := range entries {
+		if waiting[e.ID] && positions[e.ID] == 0 {
+			positions[e.ID] = len(positions) + 1
+		}
+	}
+	return positions
+}
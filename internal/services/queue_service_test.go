@@ -0,0 +1,210 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jaytnw/bms-service/internal/models"
+	"github.com/jaytnw/bms-service/internal/repository"
+)
+
+type fakeDormRegistry struct {
+	repository.RegistryRepository
+}
+
+func (fakeDormRegistry) FindDormByID(ctx context.Context, id string) (*models.Dorm, error) {
+	return &models.Dorm{ID: id}, nil
+}
+
+type queueServiceFixture struct {
+	service    *queueService
+	state      *fakeStateStore
+	queue      *fakeQueueRepo
+	live       *fakeLiveQueue
+	dispatcher *fakeDispatcher
+}
+
+func newQueueServiceFixture(maintenance map[string]bool, snapshots ...WasherSnapshot) *queueServiceFixture {
+	f := &queueServiceFixture{
+		state:      newFakeStateStore(snapshots...),
+		queue:      newFakeQueueRepo(),
+		live:       newFakeLiveQueue(),
+		dispatcher: &fakeDispatcher{},
+	}
+	f.service = &queueService{
+		queueRepo:    f.queue,
+		registryRepo: fakeDormRegistry{},
+		stateStore:   f.state,
+		live:         f.live,
+		dispatcher:   f.dispatcher,
+		maintenance:  fakeMaintenance{washers: maintenance},
+		claimWindow:  2 * time.Minute,
+		offlineAfter: 5 * time.Minute,
+	}
+	return f
+}
+
+func freeWasher(washerID string) WasherSnapshot {
+	return WasherSnapshot{DormID: "d1", WasherID: washerID, Status: models.WasherIdle, LastSeen: time.Now()}
+}
+
+func (f *queueServiceFixture) join(t *testing.T, userID string) *models.QueueEntry {
+	t.Helper()
+	entry, err := f.service.Join(context.Background(), "d1", JoinQueueRequest{UserID: userID})
+	if err != nil {
+		t.Fatalf("Join(%s) error = %v", userID, err)
+	}
+	return entry
+}
+
+func (f *queueServiceFixture) setStatus(washerID string, status models.WasherState) StatusChange {
+	previous, _ := f.state.Get(context.Background(), washerID)
+	snapshot := WasherSnapshot{DormID: "d1", WasherID: washerID, Status: status, LastSeen: time.Now()}
+	f.state.Save(context.Background(), snapshot)
+	return StatusChange{
+		Status:   models.Status{DormID: "d1", WasherID: washerID, Status: status},
+		Previous: previous.Status,
+	}
+}
+
+func TestQueueJoinOffersFreeWasherToFirstEntry(t *testing.T) {
+	f := newQueueServiceFixture(nil, freeWasher("w1"))
+
+	first := f.join(t, "u1")
+	second := f.join(t, "u2")
+
+	if first.Status != models.QueueOffered || first.WasherID != "w1" {
+		t.Fatalf("first entry = %s/%s, want offered w1", first.Status, first.WasherID)
+	}
+	if second.Status != models.QueueWaiting || second.Position != 1 {
+		t.Fatalf("second entry = %s at %d, want waiting at 1", second.Status, second.Position)
+	}
+}
+
+func TestQueueClaimedWasherIsNotOfferedAgain(t *testing.T) {
+	ctx := context.Background()
+	f := newQueueServiceFixture(nil, freeWasher("w1"))
+	first := f.join(t, "u1")
+	if _, err := f.service.Claim(ctx, "d1", first.ID); err != nil {
+		t.Fatalf("Claim() error = %v", err)
+	}
+
+	second := f.join(t, "u2")
+	f.service.dispatch(ctx, "d1")
+
+	if got := f.queue.entry(second.ID); got.Status != models.QueueWaiting {
+		t.Fatalf("second entry = %s/%s, want waiting while w1 is claimed", got.Status, got.WasherID)
+	}
+	if got := f.queue.entry(first.ID); got.Status != models.QueueClaimed {
+		t.Fatalf("first entry = %s, want claimed", got.Status)
+	}
+}
+
+func TestQueueStartedWasherPassesToNextEntryWhenFree(t *testing.T) {
+	ctx := context.Background()
+	f := newQueueServiceFixture(nil, freeWasher("w1"))
+	first := f.join(t, "u1")
+	second := f.join(t, "u2")
+	if _, err := f.service.Claim(ctx, "d1", first.ID); err != nil {
+		t.Fatalf("Claim() error = %v", err)
+	}
+
+	f.service.OnStatusChange(ctx, f.setStatus("w1", models.WasherWashing))
+	if got := f.queue.entry(first.ID); got.Status != models.QueueStarted {
+		t.Fatalf("first entry = %s, want started once the washer runs", got.Status)
+	}
+	if got := f.queue.entry(second.ID); got.Status != models.QueueWaiting {
+		t.Fatalf("second entry = %s, want waiting while w1 runs", got.Status)
+	}
+
+	f.service.OnStatusChange(ctx, f.setStatus("w1", models.WasherDone))
+	if got := f.queue.entry(second.ID); got.Status != models.QueueOffered || got.WasherID != "w1" {
+		t.Fatalf("second entry = %s/%s, want offered w1", got.Status, got.WasherID)
+	}
+}
+
+func TestQueueFaultRequeuesHeldEntry(t *testing.T) {
+	ctx := context.Background()
+	f := newQueueServiceFixture(nil, freeWasher("w1"))
+	first := f.join(t, "u1")
+
+	f.service.OnStatusChange(ctx, f.setStatus("w1", models.WasherFault))
+
+	got := f.queue.entry(first.ID)
+	if got.Status != models.QueueWaiting || got.WasherID != "" {
+		t.Fatalf("entry = %s/%s, want waiting without washer", got.Status, got.WasherID)
+	}
+	if ids, _ := f.live.List(ctx, "d1"); len(ids) != 1 || ids[0] != first.ID {
+		t.Fatalf("live queue = %v, want [%d]", ids, first.ID)
+	}
+}
+
+func TestQueueDispatchSkipsMaintenance(t *testing.T) {
+	f := newQueueServiceFixture(map[string]bool{"w1": true}, freeWasher("w1"))
+
+	entry := f.join(t, "u1")
+
+	if entry.Status != models.QueueWaiting {
+		t.Fatalf("entry = %s/%s, want waiting while w1 is under maintenance", entry.Status, entry.WasherID)
+	}
+}
+
+func TestQueueStaleClaimReleasesWasher(t *testing.T) {
+	ctx := context.Background()
+	f := newQueueServiceFixture(nil, freeWasher("w1"))
+	first := f.join(t, "u1")
+	second := f.join(t, "u2")
+
+	claimedAt := time.Now().Add(-time.Hour)
+	stale := f.queue.entry(first.ID)
+	stale.Status = models.QueueClaimed
+	stale.ClaimedAt = &claimedAt
+	f.queue.SaveEntry(ctx, &stale)
+
+	f.service.expireOffers(ctx)
+
+	if got := f.queue.entry(first.ID); got.Status != models.QueueExpired {
+		t.Fatalf("first entry = %s, want expired", got.Status)
+	}
+	if got := f.queue.entry(second.ID); got.Status != models.QueueOffered || got.WasherID != "w1" {
+		t.Fatalf("second entry = %s/%s, want offered w1", got.Status, got.WasherID)
+	}
+}
+
+func TestQueueConcurrentDispatchOffersWasherOnce(t *testing.T) {
+	ctx := context.Background()
+	f := newQueueServiceFixture(nil)
+	var ids []uint
+	for _, user := range []string{"u1", "u2", "u3", "u4", "u5"} {
+		ids = append(ids, f.join(t, user).ID)
+	}
+	f.state.Save(ctx, freeWasher("w1"))
+
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f.service.dispatch(ctx, "d1")
+		}()
+	}
+	wg.Wait()
+
+	offered := 0
+	for _, id := range ids {
+		if f.queue.entry(id).Status == models.QueueOffered {
+			offered++
+		}
+	}
+	if offered != 1 {
+		t.Fatalf("offered entries = %d, want 1", offered)
+	}
+	if live, _ := f.live.List(ctx, "d1"); len(live) != len(ids)-1 {
+		t.Fatalf("live queue = %v, want the %d entries still waiting", live, len(ids)-1)
+	}
+	if got := f.dispatcher.subjects(); len(got) != 1 {
+		t.Fatalf("notifications = %v, want one offer", got)
+	}
+}
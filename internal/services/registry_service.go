@@ -0,0 +1,396 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jaytnw/bms-service/internal/apperr"
+	"github.com/jaytnw/bms-service/internal/models"
+	"github.com/jaytnw/bms-service/internal/repository"
+	"gorm.io/gorm"
+)
+
+// RegistrySyncResult สรุปผลการนำเข้าทะเบียนจาก external API
+type RegistrySyncResult struct {
+	Dorms    int   `json:"dorms"`
+	Machines int   `json:"machines"`
+	Removed  int64 `json:"removed"`
+}
+
+// RegistryService จัดการทะเบียนหอและเครื่องซักผ้าในฐานข้อมูลของระบบเอง
+// external API เป็นเพียงแหล่งนำเข้า (ผ่าน SyncFromExternal) ไม่ใช่ dependency ตอน runtime
+type RegistryService interface {
+	ListDorms(ctx context.Context) ([]models.Dorm, error)
+	GetDorm(ctx context.Context, id string) (*models.Dorm, error)
+	CreateDorm(ctx context.Context, dorm *models.Dorm) error
+	UpdateDorm(ctx context.Context, id string, dorm *models.Dorm) (*models.Dorm, error)
+	DeleteDorm(ctx context.Context, id string) error
+
+	ListMachines(ctx context.Context, dormID string) ([]models.Machine, error)
+	GetMachine(ctx context.Context, id string) (*models.Machine, error)
+	CreateMachine(ctx context.Context, machine *models.Machine) error
+	UpdateMachine(ctx context.Context, id string, machine *models.Machine) (*models.Machine, error)
+	DeleteMachine(ctx context.Context, id string) error
+
+	ListPendingDorms(ctx context.Context) ([]models.Dorm, error)
+	ListPendingMachines(ctx context.Context) ([]models.Machine, error)
+	ApproveDorm(ctx context.Context, id string, input *models.Dorm) (*models.Dorm, error)
+	ApproveMachine(ctx context.Context, id string, input *models.Machine) (*models.Machine, error)
+
+	SyncFromExternal(ctx context.Context) (*RegistrySyncResult, error)
+	RunSync(ctx context.Context, interval time.Duration)
+
+	WasherRegistrar
+}
+
+// WasherRegistrar ลงทะเบียนเครื่อง/หอที่ไม่รู้จักซึ่งพบจาก MQTT เป็น pending
+// ถูกเรียกทุกข้อความที่รับ ไม่ใช่เฉพาะเมื่อสถานะเปลี่ยน เพราะข้อความที่ซ้ำกับสถานะเดิมจะไม่เกิด event
+type WasherRegistrar interface {
+	RegisterSeen(ctx context.Context, dormID, washerID string)
+}
+
+type registryService struct {
+	registryRepo repository.RegistryRepository
+	externalAPI  ExternalAPIService
+	known        sync.Map
+}
+
+// NewRegistryService รับ externalAPI เป็น nil ได้ หากไม่ต้องการนำเข้าจากภายนอก
+func NewRegistryService(registryRepo repository.RegistryRepository, externalAPI ExternalAPIService) RegistryService {
+	return &registryService{
+		registryRepo: registryRepo,
+		externalAPI:  externalAPI,
+	}
+}
+
+func (s *registryService) ListDorms(ctx context.Context) ([]models.Dorm, error) {
+	dorms, err := s.registryRepo.FindDorms(ctx)
+	if err != nil {
+		return nil, apperr.New("DB_ERROR", "Failed to get dorms", 500, err)
+	}
+	return dorms, nil
+}
+
+func (s *registryService) GetDorm(ctx context.Context, id string) (*models.Dorm, error) {
+	dorm, err := s.registryRepo.FindDormByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperr.New("NOT_FOUND", "Dorm not found", 404, err)
+		}
+		return nil, apperr.New("DB_ERROR", "Failed to get dorm", 500, err)
+	}
+	return dorm, nil
+}
+
+func (s *registryService) CreateDorm(ctx context.Context, dorm *models.Dorm) error {
+	dorm.ID = strings.TrimSpace(dorm.ID)
+	if dorm.ID == "" || strings.TrimSpace(dorm.Name) == "" {
+		return apperr.New("VALIDATION_ERROR", "id and name are required", 400, nil)
+	}
+	if _, err := s.registryRepo.FindDormByID(ctx, dorm.ID); err == nil {
+		return apperr.New("CONFLICT", "Dorm already exists", 409, nil)
+	}
+
+	// ID ของหอที่ถูก soft-delete ยังอยู่ในตาราง จึงสร้างซ้ำไม่ได้
+	now := time.Now()
+	dorm.Source = models.SourceManual
+	dorm.Status = models.RegistrationActive
+	dorm.EditedAt = &now
+	created, err := s.registryRepo.CreateDormIfMissing(ctx, dorm)
+	if err != nil {
+		return apperr.New("DB_ERROR", "Failed to create dorm", 500, err)
+	}
+	if !created {
+		return apperr.New("CONFLICT", "Dorm ID belongs to a deleted dorm", 409, nil)
+	}
+	return nil
+}
+
+func (s *registryService) UpdateDorm(ctx context.Context, id string, input *models.Dorm) (*models.Dorm, error) {
+	dorm, err := s.GetDorm(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(input.Name) == "" {
+		return nil, apperr.New("VALIDATION_ERROR", "name is required", 400, nil)
+	}
+
+	now := time.Now()
+	dorm.Name = input.Name
+	dorm.EditedAt = &now
+	if err := s.registryRepo.SaveDorm(ctx, dorm); err != nil {
+		return nil, apperr.New("DB_ERROR", "Failed to update dorm", 500, err)
+	}
+	return dorm, nil
+}
+
+func (s *registryService) DeleteDorm(ctx context.Context, id string) error {
+	if err := s.registryRepo.DeleteDorm(ctx, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperr.New("NOT_FOUND", "Dorm not found", 404, err)
+		}
+		return apperr.New("DB_ERROR", "Failed to delete dorm", 500, err)
+	}
+	return nil
+}
+
+func (s *registryService) ListMachines(ctx context.Context, dormID string) ([]models.Machine, error) {
+	machines, err := s.registryRepo.FindMachines(ctx, dormID)
+	if err != nil {
+		return nil, apperr.New("DB_ERROR", "Failed to get machines", 500, err)
+	}
+	return machines, nil
+}
+
+func (s *registryService) GetMachine(ctx context.Context, id string) (*models.Machine, error) {
+	machine, err := s.registryRepo.FindMachineByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperr.New("NOT_FOUND", "Machine not found", 404, err)
+		}
+		return nil, apperr.New("DB_ERROR", "Failed to get machine", 500, err)
+	}
+	return machine, nil
+}
+
+func (s *registryService) CreateMachine(ctx context.Context, machine *models.Machine) error {
+	machine.ID = strings.TrimSpace(machine.ID)
+	if machine.ID == "" || strings.TrimSpace(machine.DormID) == "" {
+		return apperr.New("VALIDATION_ERROR", "id and dormId are required", 400, nil)
+	}
+	if _, err := s.GetDorm(ctx, machine.DormID); err != nil {
+		return err
+	}
+	if _, err := s.registryRepo.FindMachineByID(ctx, machine.ID); err == nil {
+		return apperr.New("CONFLICT", "Machine already exists", 409, nil)
+	}
+
+	// ID ของเครื่องที่ถูก soft-delete ยังอยู่ในตาราง จึงสร้างซ้ำไม่ได้
+	now := time.Now()
+	machine.Source = models.SourceManual
+	machine.Status = models.RegistrationActive
+	machine.EditedAt = &now
+	created, err := s.registryRepo.CreateMachineIfMissing(ctx, machine)
+	if err != nil {
+		return apperr.New("DB_ERROR", "Failed to create machine", 500, err)
+	}
+	if !created {
+		return apperr.New("CONFLICT", "Machine ID belongs to a deleted machine", 409, nil)
+	}
+	return nil
+}
+
+func (s *registryService) UpdateMachine(ctx context.Context, id string, input *models.Machine) (*models.Machine, error) {
+	machine, err := s.GetMachine(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.DormID != "" && input.DormID != machine.DormID {
+		if _, err := s.GetDorm(ctx, input.DormID); err != nil {
+			return nil, err
+		}
+		machine.DormID = input.DormID
+	}
+	if input.Name != "" {
+		machine.Name = input.Name
+	}
+	now := time.Now()
+	machine.EditedAt = &now
+
+	if err := s.registryRepo.SaveMachine(ctx, machine); err != nil {
+		return nil, apperr.New("DB_ERROR", "Failed to update machine", 500, err)
+	}
+	return machine, nil
+}
+
+func (s *registryService) DeleteMachine(ctx context.Context, id string) error {
+	if err := s.registryRepo.DeleteMachine(ctx, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperr.New("NOT_FOUND", "Machine not found", 404, err)
+		}
+		return apperr.New("DB_ERROR", "Failed to delete machine", 500, err)
+	}
+	return nil
+}
+
+func (s *registryService) ListPendingDorms(ctx context.Context) ([]models.Dorm, error) {
+	dorms, err := s.registryRepo.FindDormsByStatus(ctx, models.RegistrationPending)
+	if err != nil {
+		return nil, apperr.New("DB_ERROR", "Failed to get pending dorms", 500, err)
+	}
+	return dorms, nil
+}
+
+func (s *registryService) ListPendingMachines(ctx context.Context) ([]models.Machine, error) {
+	machines, err := s.registryRepo.FindMachinesByStatus(ctx, models.RegistrationPending)
+	if err != nil {
+		return nil, apperr.New("DB_ERROR", "Failed to get pending machines", 500, err)
+	}
+	return machines, nil
+}
+
+// ApproveDorm เปลี่ยนหอ pending เป็น active และตั้งชื่อ (ถ้าส่งมา)
+func (s *registryService) ApproveDorm(ctx context.Context, id string, input *models.Dorm) (*models.Dorm, error) {
+	dorm, err := s.GetDorm(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if dorm.Status != models.RegistrationPending {
+		return nil, apperr.New("CONFLICT", "Dorm is not pending approval", 409, nil)
+	}
+
+	if strings.TrimSpace(input.Name) != "" {
+		dorm.Name = input.Name
+	}
+	now := time.Now()
+	dorm.Status = models.RegistrationActive
+	dorm.EditedAt = &now
+	if err := s.registryRepo.SaveDorm(ctx, dorm); err != nil {
+		return nil, apperr.New("DB_ERROR", "Failed to approve dorm", 500, err)
+	}
+	return dorm, nil
+}
+
+// ApproveMachine เปลี่ยนเครื่อง pending เป็น active พร้อมตั้งชื่อหรือย้ายหอ (ถ้าส่งมา)
+func (s *registryService) ApproveMachine(ctx context.Context, id string, input *models.Machine) (*models.Machine, error) {
+	machine, err := s.GetMachine(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if machine.Status != models.RegistrationPending {
+		return nil, apperr.New("CONFLICT", "Machine is not pending approval", 409, nil)
+	}
+
+	if input.DormID != "" && input.DormID != machine.DormID {
+		if _, err := s.GetDorm(ctx, input.DormID); err != nil {
+			return nil, err
+		}
+		machine.DormID = input.DormID
+	}
+	if strings.TrimSpace(input.Name) != "" {
+		machine.Name = input.Name
+	}
+	now := time.Now()
+	machine.Status = models.RegistrationActive
+	machine.EditedAt = &now
+	if err := s.registryRepo.SaveMachine(ctx, machine); err != nil {
+		return nil, apperr.New("DB_ERROR", "Failed to approve machine", 500, err)
+	}
+	return machine, nil
+}
+
+// RegisterSeen บันทึกเครื่องและหอที่ยังไม่อยู่ในทะเบียนเป็น pending
+// เครื่องที่เคยเห็นแล้วจะถูกจำไว้ใน memory เพื่อไม่ต้องถามฐานข้อมูลทุกข้อความ
+func (s *registryService) RegisterSeen(ctx context.Context, dormID, washerID string) {
+	if _, ok := s.known.Load(washerID); ok {
+		return
+	}
+
+	created, err := s.registryRepo.CreateDormIfMissing(ctx, &models.Dorm{
+		ID:     dormID,
+		Name:   dormID,
+		Source: models.SourceMQTT,
+		Status: models.RegistrationPending,
+	})
+	if err != nil {
+		log.Printf("❌ Failed to register dorm %s: %v", dormID, err)
+		return
+	}
+	if created {
+		log.Printf("🆕 Registered unknown dorm %s as pending", dormID)
+	}
+
+	created, err = s.registryRepo.CreateMachineIfMissing(ctx, &models.Machine{
+		ID:     washerID,
+		DormID: dormID,
+		Source: models.SourceMQTT,
+		Status: models.RegistrationPending,
+	})
+	if err != nil {
+		log.Printf("❌ Failed to register washer %s: %v", washerID, err)
+		return
+	}
+	if created {
+		log.Printf("🆕 Registered unknown washer %s (dorm %s) as pending", washerID, dormID)
+	}
+
+	s.known.Store(washerID, struct{}{})
+}
+
+// SyncFromExternal upsert หอและเครื่องจาก external API แล้ว soft-delete เครื่องที่นำเข้าไว้แต่หายไปจาก API
+// เครื่องที่เพิ่มเองผ่าน API (source=manual) จะไม่ถูกลบ ส่วนแถวที่ผู้ดูแลแก้ไขหรือลบแล้ว (EditedAt)
+// จะไม่ถูกย้ายหอ เปลี่ยนชื่อ กู้คืน หรือลบโดยการ sync
+func (s *registryService) SyncFromExternal(ctx context.Context) (*RegistrySyncResult, error) {
+	if s.externalAPI == nil {
+		return nil, apperr.New("SYNC_DISABLED", "External registry import is disabled", 409, nil)
+	}
+
+	washingMachines, err := s.externalAPI.FetchWashingMachines()
+	if err != nil {
+		return nil, apperr.New("API_ERROR", "Failed to fetch machines", 502, err)
+	}
+	// API ตอบกลับสำเร็จแต่ไม่มีข้อมูลเลย มักเป็นปัญหาฝั่งต้นทาง ไม่ควรลบทะเบียนทั้งหมดทิ้ง
+	if len(washingMachines) == 0 {
+		return nil, apperr.New("API_ERROR", "External API returned no machines", 502, nil)
+	}
+
+	dormMap := make(map[string]models.Dorm)
+	machines := make([]models.Machine, 0, len(washingMachines))
+	ids := make([]string, 0, len(washingMachines))
+	for _, wm := range washingMachines {
+		if wm.IDWashingMachine == "" || wm.IDDorm == "" {
+			continue
+		}
+		dormMap[wm.IDDorm] = models.Dorm{ID: wm.IDDorm, Name: wm.DormName, Source: models.SourceExternal, Status: models.RegistrationActive}
+		machines = append(machines, models.Machine{ID: wm.IDWashingMachine, DormID: wm.IDDorm, Source: models.SourceExternal, Status: models.RegistrationActive})
+		ids = append(ids, wm.IDWashingMachine)
+	}
+
+	dorms := make([]models.Dorm, 0, len(dormMap))
+	for _, dorm := range dormMap {
+		dorms = append(dorms, dorm)
+	}
+
+	if err := s.registryRepo.UpsertDorms(ctx, dorms); err != nil {
+		return nil, apperr.New("DB_ERROR", "Failed to upsert dorms", 500, err)
+	}
+	if err := s.registryRepo.UpsertMachines(ctx, machines); err != nil {
+		return nil, apperr.New("DB_ERROR", "Failed to upsert machines", 500, err)
+	}
+	removed, err := s.registryRepo.SoftDeleteMachinesNotIn(ctx, models.SourceExternal, ids)
+	if err != nil {
+		return nil, apperr.New("DB_ERROR", "Failed to remove missing machines", 500, err)
+	}
+
+	return &RegistrySyncResult{Dorms: len(dorms), Machines: len(machines), Removed: removed}, nil
+}
+
+// RunSync นำเข้าทะเบียนทันทีหนึ่งครั้ง แล้วทำซ้ำทุก interval จนกว่า ctx จะถูกยกเลิก
+func (s *registryService) RunSync(ctx context.Context, interval time.Duration) {
+	syncOnce := func() {
+		result, err := s.SyncFromExternal(ctx)
+		if err != nil {
+			log.Printf("❌ Registry sync failed: %v", err)
+			return
+		}
+		log.Printf("✅ Registry synced: %d dorms, %d machines, %d removed", result.Dorms, result.Machines, result.Removed)
+	}
+
+	syncOnce()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			syncOnce()
+		}
+	}
+}
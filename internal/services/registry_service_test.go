@@ -0,0 +1,329 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jaytnw/bms-service/internal/apperr"
+	"github.com/jaytnw/bms-service/internal/models"
+	"github.com/jaytnw/bms-service/internal/repository"
+	"gorm.io/gorm"
+)
+
+// fakeRegistryRepo เก็บหอและเครื่องไว้ใน map และจำลอง CreateXIfMissing ที่ไม่ทับแถวเดิม
+type fakeRegistryRepo struct {
+	repository.RegistryRepository
+	dorms    map[string]models.Dorm
+	machines map[string]models.Machine
+	creates  int
+}
+
+func newFakeRegistryRepo(dorms []models.Dorm, machines []models.Machine) *fakeRegistryRepo {
+	r := &fakeRegistryRepo{dorms: make(map[string]models.Dorm), machines: make(map[string]models.Machine)}
+	for _, dorm := range dorms {
+		r.dorms[dorm.ID] = dorm
+	}
+	for _, machine := range machines {
+		r.machines[machine.ID] = machine
+	}
+	return r
+}
+
+func (r *fakeRegistryRepo) FindDormByID(ctx context.Context, id string) (*models.Dorm, error) {
+	dorm, ok := r.dorms[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &dorm, nil
+}
+
+func (r *fakeRegistryRepo) SaveDorm(ctx context.Context, dorm *models.Dorm) error {
+	r.dorms[dorm.ID] = *dorm
+	return nil
+}
+
+func (r *fakeRegistryRepo) FindDorms(ctx context.Context) ([]models.Dorm, error) {
+	dorms := make([]models.Dorm, 0, len(r.dorms))
+	for _, dorm := range r.dorms {
+		dorms = append(dorms, dorm)
+	}
+	return dorms, nil
+}
+
+func (r *fakeRegistryRepo) CreateDormIfMissing(ctx context.Context, dorm *models.Dorm) (bool, error) {
+	r.creates++
+	if _, ok := r.dorms[dorm.ID]; ok {
+		return false, nil
+	}
+	r.dorms[dorm.ID] = *dorm
+	return true, nil
+}
+
+func (r *fakeRegistryRepo) FindMachineByID(ctx context.Context, id string) (*models.Machine, error) {
+	machine, ok := r.machines[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &machine, nil
+}
+
+func (r *fakeRegistryRepo) SaveMachine(ctx context.Context, machine *models.Machine) error {
+	r.machines[machine.ID] = *machine
+	return nil
+}
+
+func (r *fakeRegistryRepo) FindMachines(ctx context.Context, dormID string) ([]models.Machine, error) {
+	var machines []models.Machine
+	for _, machine := range r.machines {
+		if dormID == "" || machine.DormID == dormID {
+			machines = append(machines, machine)
+		}
+	}
+	slices.SortFunc(machines, func(a, b models.Machine) int { return strings.Compare(a.DormID+a.ID, b.DormID+b.ID) })
+	return machines, nil
+}
+
+func (r *fakeRegistryRepo) CreateMachineIfMissing(ctx context.Context, machine *models.Machine) (bool, error) {
+	r.creates++
+	if _, ok := r.machines[machine.ID]; ok {
+		return false, nil
+	}
+	r.machines[machine.ID] = *machine
+	return true, nil
+}
+
+func TestAdminChangesMarkRowsEdited(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(RegistryService) error
+		dorm   string
+		washer string
+	}{
+		{
+			name: "update machine",
+			change: func(s RegistryService) error {
+				_, err := s.UpdateMachine(context.Background(), "w1", &models.Machine{DormID: "d2"})
+				return err
+			},
+			washer: "w1",
+		},
+		{
+			name: "approve machine",
+			change: func(s RegistryService) error {
+				_, err := s.ApproveMachine(context.Background(), "w9", &models.Machine{})
+				return err
+			},
+			washer: "w9",
+		},
+		{
+			name: "create machine",
+			change: func(s RegistryService) error {
+				return s.CreateMachine(context.Background(), &models.Machine{ID: "w5", DormID: "d1"})
+			},
+			washer: "w5",
+		},
+		{
+			name: "update dorm",
+			change: func(s RegistryService) error {
+				_, err := s.UpdateDorm(context.Background(), "d1", &models.Dorm{Name: "Renamed"})
+				return err
+			},
+			dorm: "d1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeRegistryRepo(
+				[]models.Dorm{
+					{ID: "d1", Name: "Dorm 1", Source: models.SourceExternal, Status: models.RegistrationActive},
+					{ID: "d2", Name: "Dorm 2", Source: models.SourceExternal, Status: models.RegistrationActive},
+				},
+				[]models.Machine{
+					{ID: "w1", DormID: "d1", Source: models.SourceExternal, Status: models.RegistrationActive},
+					{ID: "w9", DormID: "d1", Source: models.SourceMQTT, Status: models.RegistrationPending},
+				},
+			)
+			if err := tt.change(NewRegistryService(repo, nil)); err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.washer != "" && repo.machines[tt.washer].EditedAt == nil {
+				t.Fatalf("machine %s is not marked as edited", tt.washer)
+			}
+			if tt.dorm != "" && repo.dorms[tt.dorm].EditedAt == nil {
+				t.Fatalf("dorm %s is not marked as edited", tt.dorm)
+			}
+		})
+	}
+}
+
+func TestRegisterSeen(t *testing.T) {
+	tests := []struct {
+		name        string
+		dorms       []models.Dorm
+		machines    []models.Machine
+		wantDorm    models.Dorm
+		wantMachine models.Machine
+	}{
+		{
+			name:        "unknown dorm and washer are pending",
+			wantDorm:    models.Dorm{ID: "d9", Name: "d9", Source: models.SourceMQTT, Status: models.RegistrationPending},
+			wantMachine: models.Machine{ID: "w9", DormID: "d9", Source: models.SourceMQTT, Status: models.RegistrationPending},
+		},
+		{
+			name:        "unknown washer in a known dorm keeps the dorm",
+			dorms:       []models.Dorm{{ID: "d9", Name: "Dorm 9", Source: models.SourceExternal, Status: models.RegistrationActive}},
+			wantDorm:    models.Dorm{ID: "d9", Name: "Dorm 9", Source: models.SourceExternal, Status: models.RegistrationActive},
+			wantMachine: models.Machine{ID: "w9", DormID: "d9", Source: models.SourceMQTT, Status: models.RegistrationPending},
+		},
+		{
+			name:        "known washer is left alone",
+			dorms:       []models.Dorm{{ID: "d9", Name: "Dorm 9", Source: models.SourceExternal, Status: models.RegistrationActive}},
+			machines:    []models.Machine{{ID: "w9", DormID: "d1", Name: "Washer 9", Source: models.SourceExternal, Status: models.RegistrationActive}},
+			wantDorm:    models.Dorm{ID: "d9", Name: "Dorm 9", Source: models.SourceExternal, Status: models.RegistrationActive},
+			wantMachine: models.Machine{ID: "w9", DormID: "d1", Name: "Washer 9", Source: models.SourceExternal, Status: models.RegistrationActive},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeRegistryRepo(tt.dorms, tt.machines)
+			service := NewRegistryService(repo, nil)
+
+			service.RegisterSeen(context.Background(), "d9", "w9")
+			if got := repo.dorms["d9"]; got != tt.wantDorm {
+				t.Errorf("dorm = %+v, want %+v", got, tt.wantDorm)
+			}
+			if got := repo.machines["w9"]; got != tt.wantMachine {
+				t.Errorf("machine = %+v, want %+v", got, tt.wantMachine)
+			}
+
+			creates := repo.creates
+			service.RegisterSeen(context.Background(), "d9", "w9")
+			if repo.creates != creates {
+				t.Errorf("second RegisterSeen hit the database %d more time(s)", repo.creates-creates)
+			}
+		})
+	}
+}
+
+func TestApproveMachine(t *testing.T) {
+	tests := []struct {
+		name       string
+		id         string
+		input      models.Machine
+		wantCode   string
+		wantDormID string
+		wantName   string
+	}{
+		{name: "approve as reported", id: "w9", wantDormID: "d9"},
+		{name: "approve with name and dorm", id: "w9", input: models.Machine{DormID: "d1", Name: "Washer 9"}, wantDormID: "d1", wantName: "Washer 9"},
+		{name: "unknown target dorm", id: "w9", input: models.Machine{DormID: "missing"}, wantCode: "NOT_FOUND"},
+		{name: "already active", id: "w1", wantCode: "CONFLICT"},
+		{name: "unknown machine", id: "missing", wantCode: "NOT_FOUND"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeRegistryRepo(
+				[]models.Dorm{
+					{ID: "d1", Name: "Dorm 1", Source: models.SourceExternal, Status: models.RegistrationActive},
+					{ID: "d9", Name: "d9", Source: models.SourceMQTT, Status: models.RegistrationPending},
+				},
+				[]models.Machine{
+					{ID: "w1", DormID: "d1", Source: models.SourceExternal, Status: models.RegistrationActive},
+					{ID: "w9", DormID: "d9", Source: models.SourceMQTT, Status: models.RegistrationPending},
+				},
+			)
+			input := tt.input
+
+			machine, err := NewRegistryService(repo, nil).ApproveMachine(context.Background(), tt.id, &input)
+			if tt.wantCode != "" {
+				var appErr *apperr.AppError
+				if !errors.As(err, &appErr) || appErr.Code != tt.wantCode {
+					t.Fatalf("ApproveMachine() error = %v, want %s", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			saved := repo.machines[tt.id]
+			if machine.Status != models.RegistrationActive || saved.Status != models.RegistrationActive {
+				t.Fatalf("status = %s (saved %s), want active", machine.Status, saved.Status)
+			}
+			if saved.DormID != tt.wantDormID || saved.Name != tt.wantName {
+				t.Fatalf("saved dorm/name = %s/%q, want %s/%q", saved.DormID, saved.Name, tt.wantDormID, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestApproveDorm(t *testing.T) {
+	repo := newFakeRegistryRepo([]models.Dorm{{ID: "d9", Name: "d9", Source: models.SourceMQTT, Status: models.RegistrationPending}}, nil)
+	service := NewRegistryService(repo, nil)
+
+	if _, err := service.ApproveDorm(context.Background(), "d9", &models.Dorm{Name: "Dorm 9"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := repo.dorms["d9"]; got.Status != models.RegistrationActive || got.Name != "Dorm 9" {
+		t.Fatalf("dorm = %+v, want active and renamed", got)
+	}
+
+	_, err := service.ApproveDorm(context.Background(), "d9", &models.Dorm{})
+	var appErr *apperr.AppError
+	if !errors.As(err, &appErr) || appErr.Code != "CONFLICT" {
+		t.Fatalf("second ApproveDorm() error = %v, want CONFLICT", err)
+	}
+}
+
+func TestDormReportListsPendingMachinesUnderTheirDorm(t *testing.T) {
+	now := time.Now()
+	registry := newFakeRegistryRepo(
+		[]models.Dorm{
+			{ID: "d1", Name: "Dorm 1", Source: models.SourceExternal, Status: models.RegistrationActive},
+			{ID: "d9", Name: "", Source: models.SourceMQTT, Status: models.RegistrationPending},
+		},
+		[]models.Machine{
+			{ID: "w1", DormID: "d1", Source: models.SourceExternal, Status: models.RegistrationActive},
+			{ID: "w2", DormID: "d1", Source: models.SourceMQTT, Status: models.RegistrationPending},
+			{ID: "w9", DormID: "d9", Source: models.SourceMQTT, Status: models.RegistrationPending},
+		},
+	)
+	statuses := &fakeHistoryRepo{statuses: []models.Status{
+		{WasherID: "w1", DormID: "d1", Status: models.WasherIdle, CreatedAt: now},
+		{WasherID: "w2", DormID: "d1", Status: models.WasherIdle, CreatedAt: now},
+		{WasherID: "w9", DormID: "d9", Status: models.WasherIdle, CreatedAt: now},
+	}}
+	service := &statusService{statusRepo: statuses, registryRepo: registry}
+
+	reports, err := service.GetDormStatusReport(context.Background(), DormReportOptions{Fields: map[string]bool{ReportFieldHistory: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type row struct {
+		dormID, dormName, washerID string
+		pending                    bool
+	}
+	var got []row
+	for _, report := range reports {
+		for _, machine := range report.Machines {
+			got = append(got, row{report.DormID, report.DormName, machine.WasherID, machine.Pending})
+		}
+	}
+	want := []row{
+		{"d1", "Dorm 1", "w1", false},
+		{"d1", "Dorm 1", "w2", true},
+		{"d9", "d9", "w9", true},
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("report rows = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,356 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jaytnw/bms-service/internal/apperr"
+	"github.com/jaytnw/bms-service/internal/archive"
+	"github.com/jaytnw/bms-service/internal/models"
+	"github.com/jaytnw/bms-service/internal/repository"
+)
+
+// RetentionOptions คือการตั้งค่าการเก็บรักษาตาราง statuses
+type RetentionOptions struct {
+	// Retention คืออายุของแถวดิบที่เก็บไว้ในตาราง
+	Retention time.Duration
+	// BatchSize คือจำนวนแถวต่อการอ่านหรือลบหนึ่งครั้ง
+	BatchSize        int
+	MaxDaysPerRun    int
+	RestoreTTL       time.Duration
+	MaxRestoreWindow time.Duration
+	// Location กำหนดขอบเขตวันของไฟล์ archive
+	Location *time.Location
+}
+
+// RetentionService ย้ายสถานะที่เก่ากว่า Retention ออกจากตาราง statuses ทีละวัน
+// แต่ละวันถูก export เป็นไฟล์ NDJSON.gz ลง archive store และบันทึก manifest ก่อน แล้วจึงลบทีละ batch
+// จะไม่ลบวันที่ rollup ยังประมวลผลไม่ถึง ข้อมูลที่ restore กลับมาจะถูกเก็บไว้อีก RestoreTTL ก่อนลบซ้ำ
+type RetentionService interface {
+	Run(ctx context.Context, interval time.Duration)
+	ListArchives(ctx context.Context, query repository.ArchiveQuery) ([]models.StatusArchive, error)
+	Restore(ctx context.Context, from, to time.Time) (*models.ArchiveRestore, error)
+}
+
+type retentionService struct {
+	statusRepo  repository.StatusRepository
+	archiveRepo repository.ArchiveRepository
+	rollupRepo  repository.RollupRepository
+	store       archive.Store
+	opts        RetentionOptions
+	// mu กันไม่ให้ job ลบวันเดียวกับที่กำลัง restore
+	mu sync.Mutex
+}
+
+func NewRetentionService(statusRepo repository.StatusRepository, archiveRepo repository.ArchiveRepository, rollupRepo repository.RollupRepository, store archive.Store, opts RetentionOptions) RetentionService {
+	return &retentionService{
+		statusRepo:  statusRepo,
+		archiveRepo: archiveRepo,
+		rollupRepo:  rollupRepo,
+		store:       store,
+		opts:        opts,
+	}
+}
+
+// Run ทำ retention ทันที แล้วทำซ้ำทุก interval จนกว่า ctx จะถูกยกเลิก
+func (s *retentionService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	log.Printf("🗄️ Status retention started (keep %v, every %v, store %s)", s.opts.Retention, interval, s.store.Name())
+	for {
+		s.retain(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *retentionService) ListArchives(ctx context.Context, query repository.ArchiveQuery) ([]models.StatusArchive, error) {
+	archives, err := s.archiveRepo.FindArchives(ctx, query)
+	if err != nil {
+		return nil, apperr.New("DB_ERROR", "Failed to get archives", 500, err)
+	}
+	return archives, nil
+}
+
+// Restore นำสถานะจากทุก archive ที่ทับกับช่วง [from, to) กลับเข้าตาราง statuses (ทั้งไฟล์ของแต่ละวัน)
+func (s *retentionService) Restore(ctx context.Context, from, to time.Time) (*models.ArchiveRestore, error) {
+	if from.IsZero() || to.IsZero() || !from.Before(to) {
+		return nil, apperr.New("VALIDATION_ERROR", "from and to are required and from must be before to", 400, nil)
+	}
+	if s.opts.MaxRestoreWindow > 0 && to.Sub(from) > s.opts.MaxRestoreWindow {
+		return nil, apperr.New("VALIDATION_ERROR", fmt.Sprintf("restore range must not exceed %v", s.opts.MaxRestoreWindow), 400, nil)
+	}
+
+	manifests, err := s.archiveRepo.FindArchives(ctx, repository.ArchiveQuery{From: &from, To: &to})
+	if err != nil {
+		return nil, apperr.New("DB_ERROR", "Failed to get archives", 500, err)
+	}
+	if len(manifests) == 0 {
+		return nil, apperr.New("NOT_FOUND", "No archives in range", 404, nil)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := &models.ArchiveRestore{}
+	for i := range manifests {
+		rows, err := s.restore(ctx, &manifests[i])
+		if err != nil {
+			return nil, apperr.New("ARCHIVE_ERROR", fmt.Sprintf("Failed to restore archive %d", manifests[i].ID), 500, err)
+		}
+		result.Rows += rows
+	}
+	result.Archives = manifests
+	return result, nil
+}
+
+func (s *retentionService) retain(ctx context.Context) {
+	cutoff, ok := s.cutoff(ctx)
+	if !ok {
+		return
+	}
+
+	// วันที่ถูกข้าม (เพิ่ง restore) ไม่นับรวมใน MaxDaysPerRun และจุดเริ่มจะเลื่อนผ่านไปวันถัดไปเสมอ
+	var from *time.Time
+	for days := 0; days < s.opts.MaxDaysPerRun; {
+		oldest, err := s.statusRepo.FindStatuses(ctx, repository.StatusQuery{From: from, To: &cutoff, Ascending: true, Limit: 1})
+		if err != nil {
+			log.Printf("❌ Failed to find statuses to archive: %v", err)
+			return
+		}
+		if len(oldest) == 0 {
+			return
+		}
+
+		dayStart := s.dayStart(oldest[0].CreatedAt)
+		dayEnd := dayStart.AddDate(0, 0, 1)
+		handled, err := s.retainDay(ctx, dayStart, dayEnd)
+		if err != nil {
+			log.Printf("❌ Failed to archive statuses of %s: %v", dayStart.Format(time.DateOnly), err)
+			return
+		}
+		if handled {
+			days++
+		}
+		from = &dayEnd
+	}
+}
+
+// cutoff คือเวลาที่แถวก่อนหน้านั้นถูกลบได้: ต้นวันของ now-Retention และไม่เกินตำแหน่งที่ rollup ประมวลผลแล้ว
+func (s *retentionService) cutoff(ctx context.Context) (time.Time, bool) {
+	checkpoint, err := s.rollupRepo.FindCheckpoint(ctx, models.StatusRollupCheckpoint)
+	if err != nil {
+		log.Printf("❌ Failed to load rollup checkpoint for retention: %v", err)
+		return time.Time{}, false
+	}
+	if checkpoint == nil {
+		return time.Time{}, false
+	}
+
+	cutoff := s.dayStart(time.Now().Add(-s.opts.Retention))
+	if rolledUp := s.dayStart(checkpoint.Position); rolledUp.Before(cutoff) {
+		cutoff = rolledUp
+	}
+	return cutoff, true
+}
+
+func (s *retentionService) dayStart(t time.Time) time.Time {
+	local := t.In(s.opts.Location)
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, s.opts.Location)
+}
+
+// retainDay export วัน [from, to) หากยังไม่มี archive แล้วลบแถวที่ archive แล้วออก
+// แถวที่เข้ามาหลัง export (id มากกว่า MaxStatusID ของทุก part) จะถูก export เป็น part ถัดไปก่อนลบ
+// วันที่มี archive เพิ่งถูก restore จะถูกข้ามจนกว่าจะครบ RestoreTTL โดยคืน false
+func (s *retentionService) retainDay(ctx context.Context, from, to time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	manifests, err := s.archiveRepo.FindArchivesByRangeFrom(ctx, from)
+	if err != nil {
+		return false, err
+	}
+	var maxID uint
+	for i := range manifests {
+		if restoredAt := manifests[i].RestoredAt; restoredAt != nil && time.Since(*restoredAt) < s.opts.RestoreTTL {
+			return false, nil
+		}
+		if manifests[i].MaxStatusID > maxID {
+			maxID = manifests[i].MaxStatusID
+		}
+	}
+
+	for i := range manifests {
+		if manifests[i].PurgedAt != nil {
+			continue
+		}
+		if err := s.purge(ctx, &manifests[i]); err != nil {
+			return false, err
+		}
+	}
+
+	manifest, err := s.export(ctx, from, to, len(manifests), maxID)
+	if err != nil {
+		return false, err
+	}
+	if manifest != nil {
+		if err := s.purge(ctx, manifest); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// export เขียนแถวของวัน [from, to) ที่ id มากกว่า afterID เป็น part หนึ่งของวันนั้น คืน nil หากไม่มีแถว
+func (s *retentionService) export(ctx context.Context, from, to time.Time, part int, afterID uint) (*models.StatusArchive, error) {
+	enc := archive.NewEncoder()
+	var maxID uint
+	var after *repository.StatusCursor
+	for {
+		page, err := s.statusRepo.FindStatuses(ctx, repository.StatusQuery{From: &from, To: &to, AfterID: afterID, Ascending: true, After: after, Limit: s.opts.BatchSize})
+		if err != nil {
+			return nil, err
+		}
+		for _, st := range page {
+			if err := enc.Encode(st); err != nil {
+				return nil, err
+			}
+			if st.ID > maxID {
+				maxID = st.ID
+			}
+		}
+		if len(page) < s.opts.BatchSize {
+			break
+		}
+		last := page[len(page)-1]
+		after = &repository.StatusCursor{CreatedAt: last.CreatedAt, ID: last.ID}
+	}
+	if enc.Count() == 0 {
+		return nil, nil
+	}
+
+	data, err := enc.Close()
+	if err != nil {
+		return nil, err
+	}
+	key := fmt.Sprintf("statuses/%s.%s", from.Format("2006/01/02"), archive.FormatNDJSONGzip)
+	if part > 0 {
+		key = fmt.Sprintf("statuses/%s.part%d.%s", from.Format("2006/01/02"), part, archive.FormatNDJSONGzip)
+	}
+	if err := s.store.Put(ctx, key, data); err != nil {
+		return nil, fmt.Errorf("upload %s: %w", key, err)
+	}
+
+	manifest := &models.StatusArchive{
+		RangeFrom:   from,
+		RangeTo:     to,
+		Part:        part,
+		Store:       s.store.Name(),
+		Key:         key,
+		Format:      archive.FormatNDJSONGzip,
+		Rows:        enc.Count(),
+		Bytes:       int64(len(data)),
+		Checksum:    archive.Checksum(data),
+		MaxStatusID: maxID,
+	}
+	if err := s.archiveRepo.SaveArchive(ctx, manifest); err != nil {
+		return nil, err
+	}
+
+	log.Printf("🗄️ Archived %d status rows of %s (part %d) to %s:%s", manifest.Rows, from.Format(time.DateOnly), part, manifest.Store, key)
+	return manifest, nil
+}
+
+// purge ตรวจไฟล์ archive กับ checksum ก่อน แล้วลบแถวของวันนั้นที่ id ไม่เกิน MaxStatusID ทีละ batch
+func (s *retentionService) purge(ctx context.Context, manifest *models.StatusArchive) error {
+	if _, err := s.load(ctx, manifest); err != nil {
+		return err
+	}
+
+	var deleted int64
+	for {
+		n, err := s.statusRepo.DeleteStatusesBetween(ctx, manifest.RangeFrom, manifest.RangeTo, manifest.MaxStatusID, s.opts.BatchSize)
+		if err != nil {
+			return err
+		}
+		deleted += n
+		if n < int64(s.opts.BatchSize) {
+			break
+		}
+	}
+	now := time.Now()
+	manifest.PurgedAt = &now
+	manifest.RestoredAt = nil
+	if err := s.archiveRepo.SaveArchive(ctx, manifest); err != nil {
+		return err
+	}
+
+	log.Printf("🧹 Deleted %d archived status rows of %s", deleted, manifest.RangeFrom.Format(time.DateOnly))
+	return nil
+}
+
+func (s *retentionService) restore(ctx context.Context, manifest *models.StatusArchive) (int64, error) {
+	data, err := s.load(ctx, manifest)
+	if err != nil {
+		return 0, err
+	}
+	// partition ของเดือนนั้นอาจถูกลบไปแล้วหลัง retention
+	if err := s.statusRepo.EnsurePartitions(ctx, manifest.RangeFrom, manifest.RangeTo); err != nil {
+		return 0, err
+	}
+
+	var restored int64
+	batch := make([]models.Status, 0, s.opts.BatchSize)
+	flush := func() error {
+		n, err := s.statusRepo.InsertStatuses(ctx, batch)
+		restored += n
+		batch = batch[:0]
+		return err
+	}
+	err = archive.Decode(data, func(st models.Status) error {
+		batch = append(batch, st)
+		if len(batch) < s.opts.BatchSize {
+			return nil
+		}
+		return flush()
+	})
+	if err != nil {
+		return restored, err
+	}
+	if err := flush(); err != nil {
+		return restored, err
+	}
+
+	now := time.Now()
+	manifest.RestoredAt = &now
+	manifest.PurgedAt = nil
+	if err := s.archiveRepo.SaveArchive(ctx, manifest); err != nil {
+		return restored, err
+	}
+
+	log.Printf("♻️ Restored %d status rows of %s from archive %d", restored, manifest.RangeFrom.Format(time.DateOnly), manifest.ID)
+	return restored, nil
+}
+
+// load อ่านไฟล์ของ archive และตรวจว่าตรงกับ checksum ใน manifest
+func (s *retentionService) load(ctx context.Context, manifest *models.StatusArchive) ([]byte, error) {
+	if manifest.Store != s.store.Name() {
+		return nil, fmt.Errorf("archive %d is in store %s but %s is configured", manifest.ID, manifest.Store, s.store.Name())
+	}
+
+	data, err := s.store.Get(ctx, manifest.Key)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", manifest.Key, err)
+	}
+	if checksum := archive.Checksum(data); checksum != manifest.Checksum {
+		return nil, fmt.Errorf("archive %d checksum mismatch: %s != %s", manifest.ID, checksum, manifest.Checksum)
+	}
+	return data, nil
+}
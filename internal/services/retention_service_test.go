@@ -0,0 +1,177 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/jaytnw/bms-service/internal/archive"
+	"github.com/jaytnw/bms-service/internal/models"
+	"github.com/jaytnw/bms-service/internal/repository"
+)
+
+// fakeRetentionStatusRepo เก็บสถานะใน slice และรองรับเฉพาะ query ที่ retention ใช้
+type fakeRetentionStatusRepo struct {
+	repository.StatusRepository
+	statuses []models.Status
+}
+
+func (r *fakeRetentionStatusRepo) FindStatuses(ctx context.Context, q repository.StatusQuery) ([]models.Status, error) {
+	var found []models.Status
+	for _, st := range r.statuses {
+		if q.From != nil && st.CreatedAt.Before(*q.From) {
+			continue
+		}
+		if q.To != nil && !st.CreatedAt.Before(*q.To) {
+			continue
+		}
+		if st.ID <= q.AfterID {
+			continue
+		}
+		if q.After != nil && (st.CreatedAt.Before(q.After.CreatedAt) || st.CreatedAt.Equal(q.After.CreatedAt) && st.ID <= q.After.ID) {
+			continue
+		}
+		found = append(found, st)
+	}
+	sort.Slice(found, func(i, j int) bool {
+		if !found[i].CreatedAt.Equal(found[j].CreatedAt) {
+			return found[i].CreatedAt.Before(found[j].CreatedAt)
+		}
+		return found[i].ID < found[j].ID
+	})
+	if q.Limit > 0 && len(found) > q.Limit {
+		found = found[:q.Limit]
+	}
+	return found, nil
+}
+
+func (r *fakeRetentionStatusRepo) DeleteStatusesBetween(ctx context.Context, from, to time.Time, maxID uint, limit int) (int64, error) {
+	var kept []models.Status
+	var deleted int64
+	for _, st := range r.statuses {
+		if deleted < int64(limit) && !st.CreatedAt.Before(from) && st.CreatedAt.Before(to) && st.ID <= maxID {
+			deleted++
+			continue
+		}
+		kept = append(kept, st)
+	}
+	r.statuses = kept
+	return deleted, nil
+}
+
+type fakeArchiveRepo struct {
+	repository.ArchiveRepository
+	archives []models.StatusArchive
+}
+
+func (r *fakeArchiveRepo) FindArchivesByRangeFrom(ctx context.Context, rangeFrom time.Time) ([]models.StatusArchive, error) {
+	var found []models.StatusArchive
+	for _, a := range r.archives {
+		if a.RangeFrom.Equal(rangeFrom) {
+			found = append(found, a)
+		}
+	}
+	return found, nil
+}
+
+func (r *fakeArchiveRepo) SaveArchive(ctx context.Context, a *models.StatusArchive) error {
+	if a.ID == 0 {
+		a.ID = uint(len(r.archives) + 1)
+		r.archives = append(r.archives, *a)
+		return nil
+	}
+	r.archives[a.ID-1] = *a
+	return nil
+}
+
+func newRetentionFixture(t *testing.T, statuses []models.Status, maxDays int) (*retentionService, *fakeRetentionStatusRepo, *fakeArchiveRepo) {
+	statusRepo := &fakeRetentionStatusRepo{statuses: statuses}
+	archiveRepo := &fakeArchiveRepo{}
+	rollupRepo := &fakeRollupRepo{checkpoint: &models.RollupCheckpoint{Position: time.Now()}}
+	service := NewRetentionService(statusRepo, archiveRepo, rollupRepo, archive.NewLocalStore(t.TempDir()), RetentionOptions{
+		Retention:     24 * time.Hour,
+		BatchSize:     2,
+		MaxDaysPerRun: maxDays,
+		RestoreTTL:    time.Hour,
+		Location:      time.UTC,
+	}).(*retentionService)
+	return service, statusRepo, archiveRepo
+}
+
+func TestRetentionArchivesLateRowsAsNextPart(t *testing.T) {
+	day := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	service, statusRepo, archiveRepo := newRetentionFixture(t, []models.Status{
+		{ID: 1, WasherID: "w1", CreatedAt: day.Add(time.Hour)},
+		{ID: 2, WasherID: "w1", CreatedAt: day.Add(2 * time.Hour)},
+		{ID: 3, WasherID: "w1", CreatedAt: day.Add(3 * time.Hour)},
+	}, 5)
+	ctx := context.Background()
+
+	service.retain(ctx)
+	if len(statusRepo.statuses) != 0 || len(archiveRepo.archives) != 1 {
+		t.Fatalf("after first run: %d rows, %d archives; want 0 rows, 1 archive", len(statusRepo.statuses), len(archiveRepo.archives))
+	}
+
+	// แถวของวันเดิมเข้ามาช้าหลัง archive ไปแล้ว
+	statusRepo.statuses = append(statusRepo.statuses, models.Status{ID: 9, WasherID: "w2", CreatedAt: day.Add(90 * time.Minute)})
+	service.retain(ctx)
+
+	if len(statusRepo.statuses) != 0 {
+		t.Fatalf("rows left = %+v, want the late row archived and deleted", statusRepo.statuses)
+	}
+	if len(archiveRepo.archives) != 2 {
+		t.Fatalf("archives = %d, want 2 parts", len(archiveRepo.archives))
+	}
+	part := archiveRepo.archives[1]
+	if part.Part != 1 || part.Rows != 1 || part.MaxStatusID != 9 || part.PurgedAt == nil {
+		t.Fatalf("follow-up part = %+v, want part 1 with the late row purged", part)
+	}
+	if part.Key == archiveRepo.archives[0].Key {
+		t.Fatalf("follow-up part reuses key %s", part.Key)
+	}
+
+	// restore ต้องได้แถวจากทุก part ของวันนั้น
+	statusRepo.statuses = nil
+	var restored []models.Status
+	for i := range archiveRepo.archives {
+		data, err := service.load(ctx, &archiveRepo.archives[i])
+		if err != nil {
+			t.Fatalf("load part %d: %v", i, err)
+		}
+		if err := archive.Decode(data, func(st models.Status) error { restored = append(restored, st); return nil }); err != nil {
+			t.Fatalf("decode part %d: %v", i, err)
+		}
+	}
+	if len(restored) != 4 {
+		t.Fatalf("restored rows = %d, want 4", len(restored))
+	}
+}
+
+func TestRetentionSkipsRestoredDaysWithoutUsingBudget(t *testing.T) {
+	day1 := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 1)
+	service, statusRepo, archiveRepo := newRetentionFixture(t, []models.Status{
+		{ID: 1, WasherID: "w1", CreatedAt: day1.Add(time.Hour)},
+		{ID: 2, WasherID: "w1", CreatedAt: day2.Add(time.Hour)},
+	}, 1)
+	ctx := context.Background()
+
+	// day1 เพิ่งถูก restore จึงต้องถูกข้าม แต่ไม่กินโควตา MaxDaysPerRun
+	manifest, err := service.export(ctx, day1, day2, 0, 0)
+	if err != nil {
+		t.Fatalf("export() error = %v", err)
+	}
+	restoredAt := time.Now()
+	manifest.RestoredAt = &restoredAt
+	archiveRepo.archives[manifest.ID-1] = *manifest
+
+	service.retain(ctx)
+
+	if len(statusRepo.statuses) != 1 || statusRepo.statuses[0].ID != 1 {
+		t.Fatalf("rows left = %+v, want only the restored row of day 1", statusRepo.statuses)
+	}
+	if len(archiveRepo.archives) != 2 || !archiveRepo.archives[1].RangeFrom.Equal(day2) {
+		t.Fatalf("archives = %+v, want day 2 archived in the same run", archiveRepo.archives)
+	}
+}
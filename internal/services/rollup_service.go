@@ -0,0 +1,313 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jaytnw/bms-service/internal/apperr"
+	"github.com/jaytnw/bms-service/internal/models"
+	"github.com/jaytnw/bms-service/internal/repository"
+)
+
+// RollupService สรุปตาราง statuses เป็น rollup รายชั่วโมงและรายวันแบบ incremental
+// ประมวลผลเฉพาะชั่วโมงที่จบแล้ว ต่อจาก checkpoint ครั้งละไม่เกิน batchHours ชั่วโมง
+// ชั่วโมงและวันนับตาม location เดียวกับ analytics เพื่อให้ bucket ตรงกับ heatmap
+// ชั่วโมงที่ ingest ยังบันทึกไม่ครบ (ตาม IngestWatermark) จะยังไม่ถูกปิด
+type RollupService interface {
+	Run(ctx context.Context, interval time.Duration)
+	GetRollups(ctx context.Context, query repository.RollupQuery) ([]models.StatusRollup, error)
+}
+
+// IngestWatermark บอกเวลาที่ข้อความทุกข้อความที่รับก่อนหน้านั้นถูกบันทึกลงตาราง statuses แล้ว
+type IngestWatermark interface {
+	Watermark(ctx context.Context) (time.Time, error)
+}
+
+type rollupService struct {
+	statusRepo repository.StatusRepository
+	rollupRepo repository.RollupRepository
+	watermark  IngestWatermark
+	location   *time.Location
+	batchHours int
+}
+
+// NewRollupService สร้าง rollup service หาก watermark เป็น nil จะปิดชั่วโมงทันทีที่ชั่วโมงจบ
+func NewRollupService(statusRepo repository.StatusRepository, rollupRepo repository.RollupRepository, watermark IngestWatermark, location *time.Location, batchHours int) RollupService {
+	return &rollupService{
+		statusRepo: statusRepo,
+		rollupRepo: rollupRepo,
+		watermark:  watermark,
+		location:   location,
+		batchHours: batchHours,
+	}
+}
+
+// Run สรุปชั่วโมงที่ค้างอยู่ทันที แล้วทำซ้ำทุก interval จนกว่า ctx จะถูกยกเลิก
+func (s *rollupService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	log.Printf("📚 Status rollup started (every %v)", interval)
+	for {
+		s.rollup(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *rollupService) GetRollups(ctx context.Context, query repository.RollupQuery) ([]models.StatusRollup, error) {
+	if query.Granularity == "" {
+		query.Granularity = models.RollupHourly
+	}
+	if query.Granularity != models.RollupHourly && query.Granularity != models.RollupDaily {
+		return nil, apperr.New("VALIDATION_ERROR", "granularity must be hour or day", 400, nil)
+	}
+
+	rollups, err := s.rollupRepo.FindRollups(ctx, query)
+	if err != nil {
+		return nil, apperr.New("DB_ERROR", "Failed to get rollups", 500, err)
+	}
+	return rollups, nil
+}
+
+func (s *rollupService) rollup(ctx context.Context) {
+	position, ok := s.position(ctx)
+	if !ok {
+		return
+	}
+
+	carry, err := s.carryIn(ctx, position)
+	if err != nil {
+		log.Printf("❌ Failed to load rollup state before %v: %v", position, err)
+		return
+	}
+
+	target, ok := s.target(ctx)
+	if !ok {
+		return
+	}
+	processed := 0
+	for hour := position; hour.Before(target) && processed < s.batchHours; hour = hour.Add(time.Hour) {
+		end := hour.Add(time.Hour)
+		statuses, err := s.statusRepo.FindStatuses(ctx, repository.StatusQuery{From: &hour, To: &end, Ascending: true})
+		if err != nil {
+			log.Printf("❌ Failed to load statuses for rollup at %v: %v", hour, err)
+			return
+		}
+
+		// ลำดับ hourly -> daily -> checkpoint ทำให้รันซ้ำได้หากล้มกลางทาง
+		if err := s.rollupRepo.UpsertRollups(ctx, rollupHour(hour, carry, statuses)); err != nil {
+			log.Printf("❌ Failed to save hourly rollup at %v: %v", hour, err)
+			return
+		}
+		if err := s.rollupDay(ctx, hour); err != nil {
+			log.Printf("❌ Failed to save daily rollup for %v: %v", hour, err)
+			return
+		}
+		if err := s.rollupRepo.SaveCheckpoint(ctx, &models.RollupCheckpoint{Name: models.StatusRollupCheckpoint, Position: end}); err != nil {
+			log.Printf("❌ Failed to save rollup checkpoint: %v", err)
+			return
+		}
+		processed++
+	}
+
+	if processed > 0 {
+		log.Printf("📚 Rolled up %d hour(s) of status history up to %v", processed, position.Add(time.Duration(processed)*time.Hour))
+	}
+}
+
+// target คือต้นชั่วโมงแรกที่ยังปิดไม่ได้: ชั่วโมงปัจจุบัน หรือชั่วโมงของข้อความเก่าสุดที่ ingest ยังไม่บันทึก
+func (s *rollupService) target(ctx context.Context) (time.Time, bool) {
+	settled := time.Now()
+	if s.watermark != nil {
+		mark, err := s.watermark.Watermark(ctx)
+		if err != nil {
+			log.Printf("❌ Failed to get ingest watermark for rollup: %v", err)
+			return time.Time{}, false
+		}
+		if mark.Before(settled) {
+			settled = mark
+		}
+	}
+	return models.HourStart(settled, s.location), true
+}
+
+// position คืนชั่วโมงถัดไปที่ต้องสรุป ครั้งแรกเริ่มจากชั่วโมงของสถานะแรกสุด คืน false หากยังไม่มีข้อมูล
+func (s *rollupService) position(ctx context.Context) (time.Time, bool) {
+	checkpoint, err := s.rollupRepo.FindCheckpoint(ctx, models.StatusRollupCheckpoint)
+	if err != nil {
+		log.Printf("❌ Failed to load rollup checkpoint: %v", err)
+		return time.Time{}, false
+	}
+	if checkpoint != nil {
+		// checkpoint จากก่อนเปลี่ยน timezone เป็นแบบ offset ไม่เต็มชั่วโมง ทำต่อไม่ได้เพราะ bucket จะซ้อนกัน
+		if !models.HourStart(checkpoint.Position, s.location).Equal(checkpoint.Position) {
+			log.Printf("❌ Rollup checkpoint %v is not on an hour boundary in %s, delete status rollups and the checkpoint to rebuild", checkpoint.Position, s.location)
+			return time.Time{}, false
+		}
+		return checkpoint.Position, true
+	}
+
+	first, err := s.statusRepo.FindStatuses(ctx, repository.StatusQuery{Ascending: true, Limit: 1})
+	if err != nil {
+		log.Printf("❌ Failed to load first status for rollup: %v", err)
+		return time.Time{}, false
+	}
+	if len(first) == 0 {
+		return time.Time{}, false
+	}
+	return models.HourStart(first[0].CreatedAt, s.location), true
+}
+
+// carryIn คืนสถานะของแต่ละเครื่อง ณ ต้นชั่วโมง position จาก EndState ของ rollup ชั่วโมงก่อนหน้า
+// หากไม่มี (รันครั้งแรก) จะหาจากตาราง statuses
+func (s *rollupService) carryIn(ctx context.Context, position time.Time) (map[string]rollupCarry, error) {
+	carry := make(map[string]rollupCarry)
+
+	previous := position.Add(-time.Hour)
+	rollups, err := s.rollupRepo.FindRollups(ctx, repository.RollupQuery{Granularity: models.RollupHourly, From: &previous, To: &position})
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range rollups {
+		carry[r.WasherID] = rollupCarry{DormID: r.DormID, State: r.EndState, InCycle: r.EndInCycle}
+	}
+	if len(carry) > 0 {
+		return carry, nil
+	}
+
+	statuses, err := s.statusRepo.FindLatestPerWasherBefore(ctx, position)
+	if err != nil {
+		return nil, err
+	}
+	for _, st := range statuses {
+		carry[st.WasherID] = rollupCarry{DormID: st.DormID, State: st.Status, InCycle: st.Status.Busy()}
+	}
+	return carry, nil
+}
+
+// rollupDay รวม rollup รายชั่วโมงของวัน (ตาม timezone ของหอ) ที่ชั่วโมง hour อยู่ เป็น rollup รายวัน
+func (s *rollupService) rollupDay(ctx context.Context, hour time.Time) error {
+	local := hour.In(s.location)
+	dayStart := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, s.location)
+	dayEnd := dayStart.AddDate(0, 0, 1)
+
+	hourly, err := s.rollupRepo.FindRollups(ctx, repository.RollupQuery{Granularity: models.RollupHourly, From: &dayStart, To: &dayEnd})
+	if err != nil {
+		return err
+	}
+	return s.rollupRepo.UpsertRollups(ctx, sumRollups(models.RollupDaily, dayStart, hourly))
+}
+
+// rollupCarry คือสถานะของเครื่องที่ส่งต่อข้าม bucket InCycle คงค่าไว้ระหว่าง offline
+// เพื่อให้ busy -> offline -> busy นับเป็นรอบเดียวกับที่ SessionService ทำ
+type rollupCarry struct {
+	DormID  string
+	State   models.WasherState
+	InCycle bool
+}
+
+// rollupHour สรุปเวลาในแต่ละสถานะของทุกเครื่องในชั่วโมงที่เริ่มที่ start และอัปเดต carry เป็นสถานะเมื่อจบชั่วโมง
+// statuses ต้องเรียงตามเวลา เครื่องที่ยังไม่เคยมีสถานะจะเริ่มนับตั้งแต่สถานะแรก
+func rollupHour(start time.Time, carry map[string]rollupCarry, statuses []models.Status) []models.StatusRollup {
+	end := start.Add(time.Hour)
+
+	byWasher := make(map[string][]models.Status)
+	for _, st := range statuses {
+		byWasher[st.WasherID] = append(byWasher[st.WasherID], st)
+	}
+	washerIDs := make(map[string]struct{}, len(carry)+len(byWasher))
+	for id := range carry {
+		washerIDs[id] = struct{}{}
+	}
+	for id := range byWasher {
+		washerIDs[id] = struct{}{}
+	}
+
+	rollups := make([]models.StatusRollup, 0, len(washerIDs))
+	for id := range washerIDs {
+		current, known := carry[id]
+		cursor := start
+		durations := make(map[models.WasherState]time.Duration)
+		rollup := models.StatusRollup{Granularity: models.RollupHourly, WasherID: id, BucketStart: start}
+
+		for _, st := range byWasher[id] {
+			if known {
+				durations[current.State] += st.CreatedAt.Sub(cursor)
+				rollup.Transitions++
+			}
+			inCycle := current.InCycle
+			switch {
+			case st.Status.Busy():
+				if !inCycle {
+					rollup.Cycles++
+				}
+				inCycle = true
+			case st.Status == models.WasherOffline:
+			case st.Status == models.WasherDone:
+				if inCycle {
+					rollup.Completed++
+				}
+				inCycle = false
+			default:
+				inCycle = false
+			}
+			current, known, cursor = rollupCarry{DormID: st.DormID, State: st.Status, InCycle: inCycle}, true, st.CreatedAt
+		}
+		durations[current.State] += end.Sub(cursor)
+
+		rollup.DormID = current.DormID
+		rollup.EndState = current.State
+		rollup.EndInCycle = current.InCycle
+		rollup.StateSeconds = make(map[models.WasherState]int64, len(durations))
+		for state, d := range durations {
+			seconds := int64(d / time.Second)
+			rollup.StateSeconds[state] = seconds
+			if state.Busy() {
+				rollup.BusySeconds += seconds
+			}
+		}
+		carry[id] = current
+		rollups = append(rollups, rollup)
+	}
+	return rollups
+}
+
+// sumRollups รวม rollup (เรียงตามเวลา) เป็น bucket เดียวต่อเครื่อง EndState มาจาก bucket สุดท้าย
+func sumRollups(granularity string, bucketStart time.Time, rollups []models.StatusRollup) []models.StatusRollup {
+	byWasher := make(map[string]*models.StatusRollup)
+	var order []string
+	for _, r := range rollups {
+		sum, ok := byWasher[r.WasherID]
+		if !ok {
+			sum = &models.StatusRollup{
+				Granularity:  granularity,
+				WasherID:     r.WasherID,
+				BucketStart:  bucketStart,
+				StateSeconds: make(map[models.WasherState]int64),
+			}
+			byWasher[r.WasherID] = sum
+			order = append(order, r.WasherID)
+		}
+		for state, seconds := range r.StateSeconds {
+			sum.StateSeconds[state] += seconds
+		}
+		sum.BusySeconds += r.BusySeconds
+		sum.Transitions += r.Transitions
+		sum.Cycles += r.Cycles
+		sum.Completed += r.Completed
+		sum.DormID = r.DormID
+		sum.EndState = r.EndState
+		sum.EndInCycle = r.EndInCycle
+	}
+
+	result := make([]models.StatusRollup, 0, len(order))
+	for _, id := range order {
+		result = append(result, *byWasher[id])
+	}
+	return result
+}
@@ -0,0 +1,189 @@
+package services
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jaytnw/bms-service/internal/models"
+	"github.com/jaytnw/bms-service/internal/repository"
+)
+
+type fakeRollupRepo struct {
+	repository.RollupRepository
+	checkpoint *models.RollupCheckpoint
+}
+
+func (r *fakeRollupRepo) FindCheckpoint(ctx context.Context, name string) (*models.RollupCheckpoint, error) {
+	return r.checkpoint, nil
+}
+
+type fakeHistoryRepo struct {
+	repository.StatusRepository
+	statuses []models.Status
+}
+
+func (r *fakeHistoryRepo) FindStatuses(ctx context.Context, query repository.StatusQuery) ([]models.Status, error) {
+	if query.Limit > 0 && len(r.statuses) > query.Limit {
+		return r.statuses[:query.Limit], nil
+	}
+	return r.statuses, nil
+}
+
+func (r *fakeHistoryRepo) FindRecentHistoryByWasherIDs(ctx context.Context, washerIDs []string, perWasher int, since *time.Time) ([]models.Status, error) {
+	var found []models.Status
+	for _, status := range r.statuses {
+		if slices.Contains(washerIDs, status.WasherID) {
+			found = append(found, status)
+		}
+	}
+	return found, nil
+}
+
+func (r *fakeHistoryRepo) ForEachStatus(ctx context.Context, fn func(models.Status) error) error {
+	for _, status := range r.statuses {
+		if err := fn(status); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestRollupPositionUsesLocalHours(t *testing.T) {
+	ist := time.FixedZone("IST", 5*3600+30*60)
+	first := time.Date(2026, 10, 12, 9, 10, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		checkpoint *models.RollupCheckpoint
+		want       time.Time
+		wantOK     bool
+	}{
+		{
+			name:   "first run starts at the local hour of the first status",
+			want:   time.Date(2026, 10, 12, 8, 30, 0, 0, time.UTC),
+			wantOK: true,
+		},
+		{
+			name:       "aligned checkpoint",
+			checkpoint: &models.RollupCheckpoint{Position: time.Date(2026, 10, 12, 11, 30, 0, 0, time.UTC)},
+			want:       time.Date(2026, 10, 12, 11, 30, 0, 0, time.UTC),
+			wantOK:     true,
+		},
+		{
+			name:       "checkpoint from utc hours is not continued",
+			checkpoint: &models.RollupCheckpoint{Position: time.Date(2026, 10, 12, 11, 0, 0, 0, time.UTC)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewRollupService(
+				&fakeHistoryRepo{statuses: []models.Status{{WasherID: "w1", CreatedAt: first}}},
+				&fakeRollupRepo{checkpoint: tt.checkpoint},
+				nil, ist, 24,
+			).(*rollupService)
+
+			got, ok := service.position(context.Background())
+			if ok != tt.wantOK || (ok && !got.Equal(tt.want)) {
+				t.Fatalf("position() = %v, %v; want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+type fakeWatermark time.Time
+
+func (w fakeWatermark) Watermark(ctx context.Context) (time.Time, error) {
+	return time.Time(w), nil
+}
+
+func TestRollupTargetWaitsForIngestWatermark(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name      string
+		watermark IngestWatermark
+		want      time.Time
+	}{
+		{name: "without watermark closes up to the current hour", want: models.HourStart(now, time.UTC)},
+		{name: "settled watermark", watermark: fakeWatermark(now.Add(-time.Second)), want: models.HourStart(now.Add(-time.Second), time.UTC)},
+		{name: "backlog holds the hour of the oldest unsaved message", watermark: fakeWatermark(now.Add(-3 * time.Hour)), want: models.HourStart(now.Add(-3*time.Hour), time.UTC)},
+		{name: "watermark ahead of now is capped", watermark: fakeWatermark(now.Add(2 * time.Hour)), want: models.HourStart(now, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewRollupService(&fakeHistoryRepo{}, &fakeRollupRepo{}, tt.watermark, time.UTC, 24).(*rollupService)
+			got, ok := service.target(context.Background())
+			if !ok || !got.Equal(tt.want) {
+				t.Fatalf("target() = %v, %v; want %v", got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestRollupHourKeepsCycleAcrossOffline(t *testing.T) {
+	start := time.Date(2026, 10, 12, 9, 0, 0, 0, time.UTC)
+	at := func(minute int, state models.WasherState) models.Status {
+		return models.Status{WasherID: "w1", DormID: "d1", Status: state, CreatedAt: start.Add(time.Duration(minute) * time.Minute)}
+	}
+
+	tests := []struct {
+		name          string
+		carry         map[string]rollupCarry
+		statuses      []models.Status
+		wantCycles    int
+		wantCompleted int
+		wantInCycle   bool
+	}{
+		{
+			name:          "offline mid-cycle then busy again is one cycle",
+			statuses:      []models.Status{at(0, models.WasherIdle), at(5, models.WasherWashing), at(10, models.WasherOffline), at(15, models.WasherWashing), at(40, models.WasherDone)},
+			wantCycles:    1,
+			wantCompleted: 1,
+		},
+		{
+			name:        "cycle carried in while offline continues",
+			carry:       map[string]rollupCarry{"w1": {DormID: "d1", State: models.WasherOffline, InCycle: true}},
+			statuses:    []models.Status{at(5, models.WasherSpinning)},
+			wantInCycle: true,
+		},
+		{
+			name:       "offline after idle then busy starts a new cycle",
+			carry:      map[string]rollupCarry{"w1": {DormID: "d1", State: models.WasherOffline}},
+			statuses:   []models.Status{at(5, models.WasherWashing), at(30, models.WasherIdle)},
+			wantCycles: 1,
+		},
+		{
+			name:          "done after offline completes the open cycle",
+			carry:         map[string]rollupCarry{"w1": {DormID: "d1", State: models.WasherWashing, InCycle: true}},
+			statuses:      []models.Status{at(5, models.WasherOffline), at(20, models.WasherDone)},
+			wantCompleted: 1,
+		},
+		{
+			name:        "bucket ending offline mid-cycle stays in cycle",
+			statuses:    []models.Status{at(5, models.WasherWashing), at(20, models.WasherOffline)},
+			wantCycles:  1,
+			wantInCycle: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			carry := tt.carry
+			if carry == nil {
+				carry = make(map[string]rollupCarry)
+			}
+			rollups := rollupHour(start, carry, tt.statuses)
+			if len(rollups) != 1 {
+				t.Fatalf("rollupHour() returned %d rollups, want 1", len(rollups))
+			}
+			got := rollups[0]
+			if got.Cycles != tt.wantCycles || got.Completed != tt.wantCompleted || got.EndInCycle != tt.wantInCycle {
+				t.Fatalf("cycles=%d completed=%d endInCycle=%v; want %d, %d, %v",
+					got.Cycles, got.Completed, got.EndInCycle, tt.wantCycles, tt.wantCompleted, tt.wantInCycle)
+			}
+		})
+	}
+}
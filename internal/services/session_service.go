@@ -0,0 +1,168 @@
+package services
+
+import (
+	"context"
+	"log"
+
+	"github.com/jaytnw/bms-service/internal/apperr"
+	"github.com/jaytnw/bms-service/internal/models"
+	"github.com/jaytnw/bms-service/internal/repository"
+)
+
+// SessionService สร้างรอบซัก (wash session) จากการเปลี่ยนสถานะ ทั้งแบบ live จาก MQTT และแบบ backfill จากประวัติเดิม
+type SessionService interface {
+	StatusListener
+	Backfill(ctx context.Context) (int, error)
+	GetSessions(ctx context.Context, query repository.SessionQuery) ([]models.WashSession, error)
+}
+
+type sessionService struct {
+	sessionRepo repository.SessionRepository
+	statusRepo  repository.StatusRepository
+}
+
+func NewSessionService(sessionRepo repository.SessionRepository, statusRepo repository.StatusRepository) SessionService {
+	return &sessionService{
+		sessionRepo: sessionRepo,
+		statusRepo:  statusRepo,
+	}
+}
+
+// OnStatusChange เปิดรอบซักเมื่อเครื่องเข้าสถานะ busy และปิดรอบเมื่อเครื่องเป็น done, fault หรือ idle
+// สถานะระหว่างซ่อมบำรุง (เช่น ช่างทดสอบเครื่อง) ไม่นับเป็นรอบซัก
+// โปรแกรมซักจาก telemetry ถูกบันทึกลงรอบ เครื่องบางรุ่นรายงานโปรแกรมหลังเริ่มรอบไปแล้วจึงเติมให้รอบที่เปิดอยู่ด้วย
+func (s *sessionService) OnStatusChange(ctx context.Context, change StatusChange) {
+	status := change.Status
+	if status.Maintenance {
+		return
+	}
+
+	open, err := s.sessionRepo.FindOpenByWasherID(ctx, status.WasherID)
+	if err != nil {
+		log.Printf("❌ Failed to load open session for washer %s: %v", status.WasherID, err)
+		return
+	}
+
+	closed, opened := applySessionTransition(open, status)
+	program := ""
+	if change.Telemetry != nil {
+		program = change.Telemetry.Program
+	}
+	switch {
+	case opened != nil:
+		opened.Program = program
+	case open != nil && open.Program == "" && program != "":
+		open.Program = program
+		if closed == nil {
+			if err := s.sessionRepo.SaveSession(ctx, open); err != nil {
+				log.Printf("❌ Failed to save program for washer %s: %v", status.WasherID, err)
+			}
+		}
+	}
+
+	if closed != nil {
+		if err := s.sessionRepo.SaveSession(ctx, closed); err != nil {
+			log.Printf("❌ Failed to close session for washer %s: %v", status.WasherID, err)
+		}
+	}
+	if opened != nil {
+		if err := s.sessionRepo.SaveSession(ctx, opened); err != nil {
+			log.Printf("❌ Failed to open session for washer %s: %v", status.WasherID, err)
+		}
+	}
+}
+
+// Backfill สร้างรอบซักใหม่ทั้งหมดจากตาราง statuses แล้ว upsert ลง wash_sessions คืนจำนวนรอบที่เขียน
+func (s *sessionService) Backfill(ctx context.Context) (int, error) {
+	const batchSize = 500
+
+	var (
+		open     *models.WashSession
+		washerID string
+		batch    []models.WashSession
+		total    int
+	)
+
+	flush := func() error {
+		if err := s.sessionRepo.UpsertSessions(ctx, batch); err != nil {
+			return err
+		}
+		total += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+
+	err := s.statusRepo.ForEachStatus(ctx, func(status models.Status) error {
+		// เปลี่ยนเครื่อง: รอบที่ค้างอยู่ของเครื่องก่อนหน้ายังคงเป็น in_progress
+		if status.WasherID != washerID {
+			if open != nil {
+				batch = append(batch, *open)
+			}
+			open = nil
+			washerID = status.WasherID
+		}
+
+		// แถวเก่าที่บันทึกก่อนมี state machine อาจเป็น payload ที่ไม่รู้จัก ส่วนแถวระหว่างซ่อมบำรุงข้ามเหมือน OnStatusChange
+		if !status.Status.Valid() || status.Maintenance {
+			return nil
+		}
+
+		closed, opened := applySessionTransition(open, status)
+		if closed != nil {
+			batch = append(batch, *closed)
+			open = nil
+		}
+		if opened != nil {
+			open = opened
+		}
+
+		if len(batch) >= batchSize {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return total, apperr.New("DB_ERROR", "Failed to backfill sessions", 500, err)
+	}
+
+	if open != nil {
+		batch = append(batch, *open)
+	}
+	if err := flush(); err != nil {
+		return total, apperr.New("DB_ERROR", "Failed to backfill sessions", 500, err)
+	}
+
+	log.Printf("✅ Backfilled %d wash sessions", total)
+	return total, nil
+}
+
+func (s *sessionService) GetSessions(ctx context.Context, query repository.SessionQuery) ([]models.WashSession, error) {
+	sessions, err := s.sessionRepo.FindSessions(ctx, query)
+	if err != nil {
+		return nil, apperr.New("DB_ERROR", "Failed to get sessions", 500, err)
+	}
+	return sessions, nil
+}
+
+// applySessionTransition คำนวณว่าสถานะใหม่ทำให้ต้องปิดรอบที่เปิดอยู่หรือเปิดรอบใหม่
+// offline ไม่ปิดรอบ เพราะเครื่องที่หลุดการเชื่อมต่อกลางรอบมักกลับมารายงาน busy ต่อในรอบเดิม
+func applySessionTransition(open *models.WashSession, status models.Status) (closed, opened *models.WashSession) {
+	if status.Status.Busy() {
+		if open != nil {
+			return nil, nil
+		}
+		return nil, &models.WashSession{
+			WasherID:  status.WasherID,
+			DormID:    status.DormID,
+			StartedAt: status.CreatedAt,
+			Outcome:   models.SessionInProgress,
+		}
+	}
+
+	if open == nil || status.Status == models.WasherOffline {
+		return nil, nil
+	}
+
+	open.Close(status.CreatedAt, models.OutcomeFor(status.Status))
+	return open, nil
+}
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jaytnw/bms-service/internal/models"
+)
+
+func TestApplySessionTransition(t *testing.T) {
+	started := time.Date(2026, 10, 12, 9, 0, 0, 0, time.UTC)
+	at := started.Add(40 * time.Minute)
+
+	tests := []struct {
+		name        string
+		open        bool
+		state       models.WasherState
+		wantClosed  models.SessionOutcome
+		wantOpened  bool
+		wantNothing bool
+	}{
+		{name: "busy opens a session", state: models.WasherWashing, wantOpened: true},
+		{name: "busy keeps the open session", open: true, state: models.WasherSpinning, wantNothing: true},
+		{name: "offline keeps the open session", open: true, state: models.WasherOffline, wantNothing: true},
+		{name: "done completes the session", open: true, state: models.WasherDone, wantClosed: models.SessionCompleted},
+		{name: "fault closes as fault", open: true, state: models.WasherFault, wantClosed: models.SessionFault},
+		{name: "idle aborts the session", open: true, state: models.WasherIdle, wantClosed: models.SessionAborted},
+		{name: "idle without a session does nothing", state: models.WasherIdle, wantNothing: true},
+		{name: "offline without a session does nothing", state: models.WasherOffline, wantNothing: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var open *models.WashSession
+			if tt.open {
+				open = &models.WashSession{WasherID: "w1", DormID: "d1", StartedAt: started, Outcome: models.SessionInProgress}
+			}
+			closed, opened := applySessionTransition(open, models.Status{WasherID: "w1", DormID: "d1", Status: tt.state, CreatedAt: at})
+
+			switch {
+			case tt.wantNothing:
+				if closed != nil || opened != nil {
+					t.Fatalf("got closed=%v opened=%v, want no change", closed, opened)
+				}
+			case tt.wantOpened:
+				if closed != nil || opened == nil || !opened.StartedAt.Equal(at) || opened.Outcome != models.SessionInProgress {
+					t.Fatalf("got closed=%v opened=%v, want a session opened at %v", closed, opened, at)
+				}
+			default:
+				if opened != nil || closed == nil || closed.Outcome != tt.wantClosed || closed.EndedAt == nil || !closed.EndedAt.Equal(at) {
+					t.Fatalf("got closed=%v opened=%v, want closed as %s at %v", closed, opened, tt.wantClosed, at)
+				}
+				if *closed.DurationSeconds != int64(40*60) {
+					t.Fatalf("duration = %d, want %d", *closed.DurationSeconds, 40*60)
+				}
+			}
+		})
+	}
+}
+
+func TestBackfillSessions(t *testing.T) {
+	start := time.Date(2026, 10, 12, 9, 0, 0, 0, time.UTC)
+	status := func(washerID string, minute int, state models.WasherState) models.Status {
+		return models.Status{WasherID: washerID, DormID: "d1", Status: state, CreatedAt: start.Add(time.Duration(minute) * time.Minute)}
+	}
+
+	type session struct {
+		washerID string
+		started  int
+		outcome  models.SessionOutcome
+	}
+	tests := []struct {
+		name     string
+		statuses []models.Status
+		want     []session
+	}{
+		{
+			name: "offline mid-cycle stays one session",
+			statuses: []models.Status{
+				status("w1", 0, models.WasherWashing), status("w1", 10, models.WasherOffline),
+				status("w1", 15, models.WasherRinsing), status("w1", 40, models.WasherDone),
+			},
+			want: []session{{"w1", 0, models.SessionCompleted}},
+		},
+		{
+			name: "idle and fault close sessions",
+			statuses: []models.Status{
+				status("w1", 0, models.WasherWashing), status("w1", 5, models.WasherIdle),
+				status("w1", 10, models.WasherWashing), status("w1", 20, models.WasherFault),
+			},
+			want: []session{{"w1", 0, models.SessionAborted}, {"w1", 10, models.SessionFault}},
+		},
+		{
+			name: "maintenance and unknown rows are skipped",
+			statuses: []models.Status{
+				{WasherID: "w1", DormID: "d1", Status: models.WasherWashing, Maintenance: true, CreatedAt: start},
+				status("w1", 5, "legacy"),
+				status("w1", 10, models.WasherIdle),
+			},
+		},
+		{
+			name: "session still open at the next washer stays in progress",
+			statuses: []models.Status{
+				status("w1", 0, models.WasherWashing), status("w1", 5, models.WasherOffline),
+				status("w2", 0, models.WasherWashing), status("w2", 30, models.WasherDone),
+			},
+			want: []session{{"w1", 0, models.SessionInProgress}, {"w2", 0, models.SessionCompleted}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sessions := &fakeSessionRepo{}
+			service := NewSessionService(sessions, &fakeHistoryRepo{statuses: tt.statuses})
+
+			total, err := service.Backfill(context.Background())
+			if err != nil {
+				t.Fatalf("Backfill() error = %v", err)
+			}
+			if total != len(tt.want) || len(sessions.sessions) != len(tt.want) {
+				t.Fatalf("Backfill() wrote %d sessions (%d saved), want %d", total, len(sessions.sessions), len(tt.want))
+			}
+			for i, want := range tt.want {
+				got := sessions.sessions[i]
+				if got.WasherID != want.washerID || !got.StartedAt.Equal(start.Add(time.Duration(want.started)*time.Minute)) || got.Outcome != want.outcome {
+					t.Fatalf("session %d = %s started %v %s; want %s started +%dm %s",
+						i, got.WasherID, got.StartedAt, got.Outcome, want.washerID, want.started, want.outcome)
+				}
+			}
+		})
+	}
+}
+
+func TestSessionRecordsProgram(t *testing.T) {
+	start := time.Date(2026, 10, 12, 9, 0, 0, 0, time.UTC)
+	change := func(minute int, state models.WasherState, program string) StatusChange {
+		at := start.Add(time.Duration(minute) * time.Minute)
+		return StatusChange{
+			Status:    models.Status{WasherID: "w1", DormID: "d1", Status: state, CreatedAt: at},
+			Telemetry: &models.WasherTelemetry{WasherID: "w1", Status: state, Program: program, CreatedAt: at},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		changes []StatusChange
+		want    string
+	}{
+		{name: "program reported with the first busy status", changes: []StatusChange{change(0, models.WasherWashing, "cotton"), change(40, models.WasherDone, "")}, want: "cotton"},
+		{name: "program reported later in the cycle", changes: []StatusChange{change(0, models.WasherWashing, ""), change(10, models.WasherRinsing, "quick"), change(30, models.WasherDone, "")}, want: "quick"},
+		{name: "program reported with done", changes: []StatusChange{change(0, models.WasherWashing, ""), change(30, models.WasherDone, "eco")}, want: "eco"},
+		{name: "first program is kept", changes: []StatusChange{change(0, models.WasherWashing, "cotton"), change(10, models.WasherRinsing, "quick"), change(40, models.WasherDone, "")}, want: "cotton"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sessions := &fakeSessionRepo{}
+			service := NewSessionService(sessions, &fakeHistoryRepo{})
+			for _, c := range tt.changes {
+				service.OnStatusChange(context.Background(), c)
+			}
+			if len(sessions.sessions) != 1 || sessions.sessions[0].Program != tt.want {
+				t.Fatalf("closed sessions = %+v, want one with program %q", sessions.sessions, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jaytnw/bms-service/internal/models"
+)
+
+const (
+	StatusEventSnapshot  = "snapshot"
+	StatusEventUpdate    = "status"
+	StatusEventHeartbeat = "heartbeat"
+)
+
+// StatusEvent คือข้อความที่ส่งให้ client ที่เชื่อมต่อแบบ stream
+type StatusEvent struct {
+	Type   string         `json:"type"`
+	Status *models.Status `json:"status,omitempty"`
+	At     time.Time      `json:"at"`
+}
+
+// StatusChange คือการเปลี่ยนสถานะที่บันทึกแล้ว Previous ว่างเมื่อเป็นสถานะแรกของเครื่อง
+// Telemetry เป็น nil เมื่อเครื่องส่ง payload แบบ string เดิม
+type StatusChange struct {
+	Status    models.Status
+	Previous  models.WasherState
+	Telemetry *models.WasherTelemetry
+}
+
+// StatusListener ถูกเรียกแบบ synchronous ทุกครั้งที่มีการเปลี่ยนสถานะ ใช้สำหรับระบบภายในที่ต้องไม่พลาด event
+type StatusListener interface {
+	OnStatusChange(ctx context.Context, change StatusChange)
+}
+
+// StatusFilter กรอง event ตาม dorm หรือ washer (ค่าว่างคือไม่กรอง)
+type StatusFilter struct {
+	DormID   string
+	WasherID string
+}
+
+func (f StatusFilter) Match(s models.Status) bool {
+	if f.DormID != "" && f.DormID != s.DormID {
+		return false
+	}
+	if f.WasherID != "" && f.WasherID != s.WasherID {
+		return false
+	}
+	return true
+}
+
+// StatusBroker กระจาย status ที่บันทึกแล้วไปยัง listener ภายในและผู้ subscribe แบบ stream ทุกราย
+type StatusBroker interface {
+	Publish(ctx context.Context, change StatusChange)
+	Subscribe(filter StatusFilter) (<-chan StatusEvent, func())
+	AddListener(listener StatusListener)
+}
+
+type statusSubscriber struct {
+	filter StatusFilter
+	ch     chan StatusEvent
+}
+
+type statusBroker struct {
+	mu          sync.RWMutex
+	subscribers map[*statusSubscriber]struct{}
+	listeners   []StatusListener
+	bufferSize  int
+}
+
+func NewStatusBroker(bufferSize int) StatusBroker {
+	if bufferSize <= 0 {
+		bufferSize = 32
+	}
+	return &statusBroker{
+		subscribers: make(map[*statusSubscriber]struct{}),
+		bufferSize:  bufferSize,
+	}
+}
+
+func (b *statusBroker) AddListener(listener StatusListener) {
+	b.mu.Lock()
+	b.listeners = append(b.listeners, listener)
+	b.mu.Unlock()
+}
+
+// Publish เรียก listener ทุกตัวตามลำดับก่อน แล้วจึงส่งให้ subscriber แบบไม่ block:
+// subscriber ที่อ่านไม่ทันจะถูกข้าม event นั้นไป
+func (b *statusBroker) Publish(ctx context.Context, change StatusChange) {
+	b.mu.RLock()
+	listeners := b.listeners
+	b.mu.RUnlock()
+
+	for _, listener := range listeners {
+		listener.OnStatusChange(ctx, change)
+	}
+
+	status := change.Status
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for sub := range b.subscribers {
+		if !sub.filter.Match(status) {
+			continue
+		}
+
+		s := status
+		event := StatusEvent{Type: StatusEventUpdate, Status: &s, At: time.Now()}
+		select {
+		case sub.ch <- event:
+		default:
+			log.Printf("⚠️ Stream subscriber too slow, dropping status for washer %s", status.WasherID)
+		}
+	}
+}
+
+func (b *statusBroker) Subscribe(filter StatusFilter) (<-chan StatusEvent, func()) {
+	sub := &statusSubscriber{
+		filter: filter,
+		ch:     make(chan StatusEvent, b.bufferSize),
+	}
+
+	b.mu.Lock()
+	b.subscribers[sub] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subscribers, sub)
+			b.mu.Unlock()
+			close(sub.ch)
+		})
+	}
+
+	return sub.ch, cancel
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jaytnw/bms-service/internal/apperr"
+	"github.com/jaytnw/bms-service/internal/repository"
+)
+
+// EncodeStatusCursor แปลงตำแหน่งแถวสุดท้ายเป็น cursor ทึบสำหรับส่งให้ client
+// cursor จำลำดับ (asc/desc) ที่ใช้ออกไว้ด้วย เพราะตำแหน่งเดียวกันหมายถึงคนละช่วงเมื่อกลับลำดับ
+func EncodeStatusCursor(cursor repository.StatusCursor, ascending bool) string {
+	raw := fmt.Sprintf("%s:%d:%d", cursorOrder(ascending), cursor.CreatedAt.UnixNano(), cursor.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeStatusCursor แปลง cursor จาก client กลับเป็นตำแหน่ง คืน nil หาก cursor ว่าง
+// cursor ที่ออกให้ลำดับอื่นนอกจาก ascending จะถูกปฏิเสธ
+func DecodeStatusCursor(cursor string, ascending bool) (*repository.StatusCursor, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+
+	invalid := apperr.New("INVALID_CURSOR", "cursor is invalid", 400, nil)
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, invalid
+	}
+	order, position, ok := strings.Cut(string(raw), ":")
+	if !ok || (order != cursorOrder(true) && order != cursorOrder(false)) {
+		return nil, invalid
+	}
+	if order != cursorOrder(ascending) {
+		return nil, apperr.New("INVALID_CURSOR", "cursor was issued for order="+order, 400, nil)
+	}
+	nanos, id, ok := strings.Cut(position, ":")
+	if !ok {
+		return nil, invalid
+	}
+	ts, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return nil, invalid
+	}
+	rowID, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return nil, invalid
+	}
+
+	return &repository.StatusCursor{CreatedAt: time.Unix(0, ts), ID: uint(rowID)}, nil
+}
+
+func cursorOrder(ascending bool) string {
+	if ascending {
+		return "asc"
+	}
+	return "desc"
+}
@@ -0,0 +1,149 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jaytnw/bms-service/internal/apperr"
+	"github.com/jaytnw/bms-service/internal/models"
+	"github.com/jaytnw/bms-service/internal/repository"
+)
+
+func TestStatusCursorRoundTrip(t *testing.T) {
+	at := time.Date(2026, 10, 12, 9, 30, 15, 123456789, time.UTC)
+	for _, ascending := range []bool{false, true} {
+		encoded := EncodeStatusCursor(repository.StatusCursor{CreatedAt: at, ID: 42}, ascending)
+		decoded, err := DecodeStatusCursor(encoded, ascending)
+		if err != nil {
+			t.Fatalf("DecodeStatusCursor(ascending=%v) error = %v", ascending, err)
+		}
+		if !decoded.CreatedAt.Equal(at) || decoded.ID != 42 {
+			t.Fatalf("DecodeStatusCursor(ascending=%v) = %+v, want %v/42", ascending, decoded, at)
+		}
+	}
+
+	if cursor, err := DecodeStatusCursor("", false); cursor != nil || err != nil {
+		t.Fatalf("DecodeStatusCursor(\"\") = %v, %v; want nil, nil", cursor, err)
+	}
+}
+
+func TestStatusCursorRejectsInvalidInput(t *testing.T) {
+	encode := func(raw string) string { return base64.RawURLEncoding.EncodeToString([]byte(raw)) }
+	valid := EncodeStatusCursor(repository.StatusCursor{CreatedAt: time.Now(), ID: 7}, false)
+
+	tests := []struct {
+		name      string
+		cursor    string
+		ascending bool
+	}{
+		{name: "not base64", cursor: "%%%"},
+		{name: "padded base64", cursor: base64.URLEncoding.EncodeToString([]byte("desc:1:1"))},
+		{name: "missing order", cursor: encode("1760000000000000000:5")},
+		{name: "unknown order", cursor: encode("up:1760000000000000000:5")},
+		{name: "missing id", cursor: encode("desc:1760000000000000000")},
+		{name: "non-numeric time", cursor: encode("desc:yesterday:5")},
+		{name: "negative id", cursor: encode("desc:1760000000000000000:-5")},
+		{name: "desc cursor used for asc", cursor: valid, ascending: true},
+		{name: "asc cursor used for desc", cursor: EncodeStatusCursor(repository.StatusCursor{CreatedAt: time.Now(), ID: 7}, true)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cursor, err := DecodeStatusCursor(tt.cursor, tt.ascending)
+			var appErr *apperr.AppError
+			if !errors.As(err, &appErr) || appErr.Code != "INVALID_CURSOR" || appErr.Status != 400 {
+				t.Fatalf("DecodeStatusCursor() = %v, %v; want INVALID_CURSOR", cursor, err)
+			}
+		})
+	}
+}
+
+// keysetStatusRepo ใช้ keyset (created_at, id) แบบเดียวกับ statusRepo.FindStatuses บนข้อมูลในหน่วยความจำ
+type keysetStatusRepo struct {
+	repository.StatusRepository
+	statuses []models.Status
+}
+
+func (r *keysetStatusRepo) FindStatuses(ctx context.Context, q repository.StatusQuery) ([]models.Status, error) {
+	compare := func(a, b models.Status) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return int(a.ID) - int(b.ID)
+	}
+	sorted := slices.Clone(r.statuses)
+	slices.SortFunc(sorted, compare)
+	if !q.Ascending {
+		slices.Reverse(sorted)
+	}
+
+	var found []models.Status
+	for _, status := range sorted {
+		if q.After != nil {
+			c := compare(status, models.Status{CreatedAt: q.After.CreatedAt, ID: q.After.ID})
+			if (q.Ascending && c <= 0) || (!q.Ascending && c >= 0) {
+				continue
+			}
+		}
+		found = append(found, status)
+		if q.Limit > 0 && len(found) == q.Limit {
+			break
+		}
+	}
+	return found, nil
+}
+
+func TestStatusPagingFollowsCursor(t *testing.T) {
+	start := time.Date(2026, 10, 12, 9, 0, 0, 0, time.UTC)
+	// แถวที่ created_at เท่ากันต้องแบ่งหน้าด้วย id ไม่ให้ซ้ำหรือหาย
+	var statuses []models.Status
+	for i := 1; i <= 7; i++ {
+		statuses = append(statuses, models.Status{ID: uint(i), WasherID: "w1", Status: models.WasherIdle, CreatedAt: start.Add(time.Duration(i/2) * time.Minute)})
+	}
+
+	tests := []struct {
+		name      string
+		ascending bool
+		want      []uint
+	}{
+		{name: "desc", want: []uint{7, 6, 5, 4, 3, 2, 1}},
+		{name: "asc", ascending: true, want: []uint{1, 2, 3, 4, 5, 6, 7}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &statusService{statusRepo: &keysetStatusRepo{statuses: statuses}}
+
+			var got []uint
+			query := repository.StatusQuery{Ascending: tt.ascending, Limit: 3}
+			for pages := 0; ; pages++ {
+				if pages > len(statuses) {
+					t.Fatal("paging did not terminate")
+				}
+				page, err := service.GetStatusHistoryByWasherID(context.Background(), "w1", query)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, status := range page.Items {
+					got = append(got, status.ID)
+				}
+				if page.NextCursor == "" {
+					break
+				}
+				if query.After, err = DecodeStatusCursor(page.NextCursor, tt.ascending); err != nil {
+					t.Fatalf("next cursor is not valid for the same order: %v", err)
+				}
+				if _, err := DecodeStatusCursor(page.NextCursor, !tt.ascending); err == nil {
+					t.Fatal("next cursor is accepted for the opposite order")
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("paged ids = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,9 +1,10 @@
 package services
 
 import (
+	"bytes"
 	"context"
-	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
 	"strings"
 	"time"
@@ -11,63 +12,393 @@ import (
 	"github.com/jaytnw/bms-service/internal/apperr"
 	"github.com/jaytnw/bms-service/internal/models"
 	"github.com/jaytnw/bms-service/internal/repository"
-	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
 )
 
+// defaultReportHistoryLimit คือจำนวนสถานะต่อเครื่องใน dorm report เมื่อไม่ได้ระบุ
+const defaultReportHistoryLimit = 100
+
+// ส่วนของ dorm report ที่เลือกได้ผ่าน DormReportOptions.Fields
+const (
+	ReportFieldHistory  = "history"
+	ReportFieldETA      = "eta"
+	ReportFieldPresence = "presence"
+)
+
+var allReportFields = map[string]bool{
+	ReportFieldHistory:  true,
+	ReportFieldETA:      true,
+	ReportFieldPresence: true,
+}
+
+// ValidReportField บอกว่าเป็นชื่อ field ที่ dorm report รองรับหรือไม่
+func ValidReportField(field string) bool {
+	return allReportFields[field]
+}
+
+// DormReportOptions กำหนดขอบเขตของ dorm report ค่าว่างคือทุกหอ ทุกช่วงเวลา และทุก field
+type DormReportOptions struct {
+	DormID string
+	// Limit คือจำนวนสถานะล่าสุดต่อเครื่อง
+	Limit  int
+	Since  *time.Time
+	Fields map[string]bool
+}
+
+// StatusService บันทึกสถานะผ่าน StatusIngester (เรียกโดย IngestPipeline) และให้บริการอ่านสถานะ
 type StatusService interface {
-	GetAllStatus(ctx context.Context) ([]models.Status, error)
-	HandleMQTTStatusUpdate(topic string, payload []byte)
-	GetStatusByWasherID(ctx context.Context, washerID string) (*models.Status, error)
-	GetStatusHistoryByWasherID(ctx context.Context, washerID string) ([]models.Status, error)
-	GetDormStatusReport(ctx context.Context) ([]models.DormStatusReport, error)
+	StatusIngester
+	GetAllStatus(ctx context.Context, query repository.StatusQuery) (*models.StatusPage, error)
+	GetStatusByWasherID(ctx context.Context, washerID string) (*models.WasherStatusDetail, error)
+	GetStatusHistoryByWasherID(ctx context.Context, washerID string, query repository.StatusQuery) (*models.StatusPage, error)
+	GetDormStatusReport(ctx context.Context, opts DormReportOptions) ([]models.DormStatusReport, error)
+	GetCurrentStatuses(ctx context.Context, filter StatusFilter) ([]models.Status, error)
+	SubscribeStatus(filter StatusFilter) (<-chan StatusEvent, func())
+	GetAnomalies(ctx context.Context, filter repository.AnomalyFilter) ([]models.StatusAnomaly, error)
+	GetTelemetry(ctx context.Context, query repository.TelemetryQuery) ([]models.WasherTelemetry, error)
 }
 
 type statusService struct {
-	statusRepo  repository.StatusRepository
-	externalAPI ExternalAPIService
-	redisClient *redis.Client
+	statusRepo    repository.StatusRepository
+	anomalyRepo   repository.AnomalyRepository
+	telemetryRepo repository.TelemetryRepository
+	registryRepo  repository.RegistryRepository
+	registrar     WasherRegistrar
+	broker        StatusBroker
+	stateStore    WasherStateStore
+	eta           EtaService
+	maintenance   MaintenanceChecker
+	offlineAfter  time.Duration
 }
 
-func NewStatusService(repo repository.StatusRepository, api ExternalAPIService, redisClient *redis.Client) StatusService {
+func NewStatusService(repo repository.StatusRepository, anomalyRepo repository.AnomalyRepository, telemetryRepo repository.TelemetryRepository, registryRepo repository.RegistryRepository, registrar WasherRegistrar, broker StatusBroker, stateStore WasherStateStore, eta EtaService, maintenance MaintenanceChecker, offlineAfter time.Duration) StatusService {
 	return &statusService{
-		statusRepo:  repo,
-		externalAPI: api,
-		redisClient: redisClient,
+		statusRepo:    repo,
+		anomalyRepo:   anomalyRepo,
+		telemetryRepo: telemetryRepo,
+		registryRepo:  registryRepo,
+		registrar:     registrar,
+		broker:        broker,
+		stateStore:    stateStore,
+		eta:           eta,
+		maintenance:   maintenance,
+		offlineAfter:  offlineAfter,
 	}
 }
 
-func (s *statusService) GetAllStatus(ctx context.Context) ([]models.Status, error) {
-	statuses, err := s.statusRepo.FindAll(ctx)
+func (s *statusService) GetAllStatus(ctx context.Context, query repository.StatusQuery) (*models.StatusPage, error) {
+	page, err := s.findStatusPage(ctx, query)
 	if err != nil {
-		log.Printf("[StatusService] failed to FindAll: %v", err)
+		log.Printf("[StatusService] failed to FindStatuses: %v", err)
+		return nil, apperr.New("DB_ERROR", "Failed to get statuses", 500, err)
+	}
+	return page, nil
+}
+
+// PrepareIngest ตรวจข้อความหนึ่งรายการและคืนสถานะที่ต้องบันทึก หรือ nil หากไม่มีอะไรต้องบันทึก
+func (s *statusService) PrepareIngest(ctx context.Context, msg IngestMessage, pending map[string]WasherSnapshot) *PendingStatus {
+	switch msg.Kind {
+	case IngestStatus:
+		s.registrar.RegisterSeen(ctx, msg.DormID, msg.WasherID)
+		return s.prepareStatusUpdate(ctx, msg, pending)
+	case IngestPresence:
+		s.registrar.RegisterSeen(ctx, msg.DormID, msg.WasherID)
+		return s.preparePresence(ctx, msg, pending)
+	case IngestOffline:
+		return s.prepareOffline(ctx, msg, pending)
+	}
+	log.Printf("⚠️ Unknown ingest message kind %q from washer %s", msg.Kind, msg.WasherID)
+	return nil
+}
+
+func (s *statusService) prepareStatusUpdate(ctx context.Context, msg IngestMessage, pending map[string]WasherSnapshot) *PendingStatus {
+	// payload ที่ไม่รู้จักหรือไม่ผ่าน schema จะถูกกักไว้ในตาราง anomaly และไม่บันทึกเป็นสถานะ
+	reading, err := models.ParseDevicePayload(msg.Payload)
+	if err != nil {
+		log.Printf("⚠️ Quarantined payload from washer %s: %v", msg.WasherID, err)
+		kind := models.AnomalyUnknownPayload
+		if bytes.HasPrefix(bytes.TrimSpace(msg.Payload), []byte("{")) {
+			kind = models.AnomalyInvalidPayload
+		}
+		s.recordAnomaly(ctx, &models.StatusAnomaly{
+			Kind:     kind,
+			DormID:   msg.DormID,
+			WasherID: msg.WasherID,
+			Payload:  string(msg.Payload),
+			Detail:   err.Error(),
+		})
+		return nil
+	}
+
+	return s.prepareState(ctx, pending, msg.DormID, msg.WasherID, reading.State, string(msg.Payload), msg.ReceivedAt, msg.ReceivedAt, reading.Telemetry)
+}
+
+// preparePresence รับ Last Will ("offline") และประกาศตอนเชื่อมต่อ ("online") จาก topic .../lwt
+func (s *statusService) preparePresence(ctx context.Context, msg IngestMessage, pending map[string]WasherSnapshot) *PendingStatus {
+	switch strings.ToLower(strings.TrimSpace(string(msg.Payload))) {
+	case "offline":
+		return s.prepareOffline(ctx, msg, pending)
+	case "online":
+		// heartbeat ถูกบันทึกตั้งแต่ pipeline รับข้อความแล้ว
+	default:
+		log.Printf("⚠️ Unknown presence payload from washer %s: %q", msg.WasherID, msg.Payload)
+	}
+	return nil
+}
+
+// prepareOffline สร้างสถานะ offline โดยไม่แตะ last seen ใช้โดย watchdog และ Last Will
+// ทั้งสองแบบถูกทิ้งหากได้รับข้อความของเครื่องหลังเวลาของ marker แล้ว (เช่น Last Will ที่ค้างในคิวขณะที่เครื่องกลับมา)
+// watchdog อ่าน snapshot ก่อนส่ง marker เข้าคิว จึงต้องตรวจ last seen อีกครั้งตอนนี้
+// หากมีข้อความเข้ามาภายใน offlineAfter ก่อนเวลาของ marker จะไม่บันทึก offline เช่นกัน
+func (s *statusService) prepareOffline(ctx context.Context, msg IngestMessage, pending map[string]WasherSnapshot) *PendingStatus {
+	lastSeen := msg.ReceivedAt
+	if previous := s.previousStatus(ctx, msg.WasherID, pending); previous != nil {
+		if previous.LastSeen.After(msg.ReceivedAt) {
+			log.Printf("📶 Washer %s reported at %s after the offline marker, dropped it", msg.WasherID, previous.LastSeen.Format(time.RFC3339))
+			return nil
+		}
+		if msg.Kind == IngestOffline && previous.Online(msg.ReceivedAt, s.offlineAfter) {
+			log.Printf("📶 Washer %s reported since %s, dropped offline marker", msg.WasherID, previous.LastSeen.Format(time.RFC3339))
+			return nil
+		}
+		lastSeen = previous.LastSeen
+	}
+	return s.prepareState(ctx, pending, msg.DormID, msg.WasherID, models.WasherOffline, string(models.WasherOffline), msg.ReceivedAt, lastSeen, nil)
+}
+
+// prepareState สร้างสถานะใหม่เมื่อแตกต่างจากสถานะล่าสุด (รวมสถานะที่ยังรอบันทึกใน pending)
+// telemetry (ถ้ามี) ถูกบันทึกทุกข้อความ เพราะค่าอย่าง remainingSeconds เปลี่ยนแม้สถานะไม่เปลี่ยน
+func (s *statusService) prepareState(ctx context.Context, pending map[string]WasherSnapshot, dormId, washerId string, state models.WasherState, payload string, at, lastSeen time.Time, telemetry *models.WasherTelemetry) *PendingStatus {
+	if telemetry != nil {
+		telemetry.DormID = dormId
+		telemetry.WasherID = washerId
+	}
+	previous := s.previousStatus(ctx, washerId, pending)
+
+	// ข้อความที่รับก่อนการเปลี่ยนสถานะล่าสุด (เช่น entry ที่ reclaim มาจาก consumer อื่นหลังเครื่องส่งข้อความใหม่ไปแล้ว)
+	// ถูกทิ้ง เพื่อไม่ให้สถานะย้อนกลับ
+	if previous != nil && at.Before(previous.Since) {
+		log.Printf("⏪ Dropped %s from washer %s received at %s, before its latest status at %s", state, washerId, at.Format(time.RFC3339), previous.Since.Format(time.RFC3339))
+		return nil
+	}
+
+	// ข้อความซ้ำ (อุปกรณ์ส่งซ้ำหรือ broker ส่ง retained message ตอน reconnect) ไม่ต้องบันทึกแถวใหม่
+	// แต่ last seen ของสถานะที่รอบันทึกต้องขยับตาม เพื่อให้ prepareOffline เห็นข้อความล่าสุด
+	if previous != nil && previous.Status == state {
+		if snapshot, ok := pending[washerId]; ok && lastSeen.After(snapshot.LastSeen) {
+			snapshot.LastSeen = lastSeen
+			pending[washerId] = snapshot
+		}
+		if telemetry == nil {
+			return nil
+		}
+		return &PendingStatus{Telemetry: telemetry, Payload: payload}
+	}
+
+	// การเปลี่ยนสถานะที่ผิด transition table ยังคงบันทึก แต่จะถูกบันทึกเป็น anomaly ด้วย
+	if previous != nil && previous.Status.Valid() && !models.CanTransition(previous.Status, state) {
+		log.Printf("⚠️ Illegal transition for washer %s: %s -> %s", washerId, previous.Status, state)
+		s.recordAnomaly(ctx, &models.StatusAnomaly{
+			Kind:       models.AnomalyIllegalTransition,
+			DormID:     dormId,
+			WasherID:   washerId,
+			Payload:    payload,
+			FromStatus: previous.Status,
+			ToStatus:   state,
+		})
+	}
+
+	// สถานะระหว่างซ่อมบำรุงยังถูกบันทึก แต่ติด flag ไว้ให้ listener ข้าม
+	// CreatedAt คือเวลาที่รับข้อความ ไม่ใช่เวลาที่ batch ถูกบันทึก
+	item := &PendingStatus{
+		Status: &models.Status{
+			DormID:      dormId,
+			WasherID:    washerId,
+			Status:      state,
+			Maintenance: s.maintenance.ActiveWindow(ctx, dormId, washerId, at) != nil,
+			CreatedAt:   at,
+		},
+		LastSeen:  lastSeen,
+		Telemetry: telemetry,
+		Payload:   payload,
+	}
+	if previous != nil {
+		item.Previous = previous.Status
+	}
+	pending[washerId] = WasherSnapshot{DormID: dormId, WasherID: washerId, Status: state, Since: at, LastSeen: lastSeen}
+	return item
+}
+
+// previousStatus คืนสถานะที่รอบันทึกใน batch ปัจจุบันก่อน แล้วจึงอ่านสถานะล่าสุดที่บันทึกแล้ว
+func (s *statusService) previousStatus(ctx context.Context, washerID string, pending map[string]WasherSnapshot) *WasherSnapshot {
+	if snapshot, ok := pending[washerID]; ok {
+		return &snapshot
+	}
+	return s.lastKnownStatus(ctx, washerID)
+}
+
+// CommitIngest บันทึกสถานะทั้ง batch ใน transaction เดียว แล้วบันทึก telemetry อัปเดต state store
+// และกระจายการเปลี่ยนแปลงผ่าน broker ตามลำดับ ความผิดพลาดหลังบันทึกสถานะแล้วจะถูก log เท่านั้น
+func (s *statusService) CommitIngest(ctx context.Context, batch []*PendingStatus) error {
+	statuses := make([]*models.Status, 0, len(batch))
+	for _, item := range batch {
+		if item.Status != nil {
+			statuses = append(statuses, item.Status)
+		}
+	}
+	if err := s.statusRepo.SaveStatuses(ctx, statuses); err != nil {
+		// id ที่ได้จาก insert ที่ถูก rollback ต้องล้างก่อนเรียกซ้ำ
+		for _, status := range statuses {
+			status.ID = 0
+		}
+		if repository.IsDataError(err) {
+			return fmt.Errorf("%w: %w", ErrIngestRejected, err)
+		}
+		return err
+	}
+
+	telemetries := make([]*models.WasherTelemetry, 0, len(batch))
+	for _, item := range batch {
+		if item.Telemetry == nil {
+			continue
+		}
+		if item.Status != nil {
+			item.Telemetry.StatusID = &item.Status.ID
+		}
+		telemetries = append(telemetries, item.Telemetry)
+	}
+	if err := s.telemetryRepo.SaveTelemetries(ctx, telemetries); err != nil {
+		log.Printf("❌ Failed to save %d telemetry row(s): %v", len(telemetries), err)
 	}
 
-	return statuses, err
+	for _, item := range batch {
+		if item.Status == nil {
+			continue
+		}
+		status := item.Status
+		if err := s.stateStore.Save(ctx, WasherSnapshot{
+			DormID:   status.DormID,
+			WasherID: status.WasherID,
+			Status:   status.Status,
+			Since:    status.CreatedAt,
+			LastSeen: item.LastSeen,
+		}); err != nil {
+			log.Printf("❌ Failed to cache status for washer %s: %v", status.WasherID, err)
+		}
+
+		s.broker.Publish(ctx, StatusChange{Status: *status, Previous: item.Previous, Telemetry: item.Telemetry})
+	}
+	return nil
+}
+
+// QuarantineIngest บันทึกรายการที่ฐานข้อมูลปฏิเสธเป็น anomaly ก่อนที่ pipeline จะ ack ทิ้ง
+func (s *statusService) QuarantineIngest(ctx context.Context, item *PendingStatus, err error) {
+	anomaly := &models.StatusAnomaly{
+		Kind:    models.AnomalyUnsaved,
+		Payload: item.Payload,
+		Detail:  err.Error(),
+	}
+	switch {
+	case item.Status != nil:
+		anomaly.DormID = item.Status.DormID
+		anomaly.WasherID = item.Status.WasherID
+		anomaly.FromStatus = item.Previous
+		anomaly.ToStatus = item.Status.Status
+	case item.Telemetry != nil:
+		anomaly.DormID = item.Telemetry.DormID
+		anomaly.WasherID = item.Telemetry.WasherID
+	}
+	s.recordAnomaly(ctx, anomaly)
 }
 
-func (s *statusService) HandleMQTTStatusUpdate(topic string, payload []byte) {
+// parseWasherTopic แยก dorm และ washer ID จาก topic รูปแบบ washingMachine/<dorm>/<washer>/<suffix>
+func parseWasherTopic(topic string) (dormID, washerID string, ok bool) {
 	parts := strings.Split(topic, "/")
 	if len(parts) < 4 {
-		log.Printf("❌ Invalid topic format: %s", topic)
-		return
+		return "", "", false
 	}
+	return parts[1], parts[2], true
+}
 
-	dormId := parts[1]
-	washerId := parts[2]
+// lastKnownStatus อ่านสถานะล่าสุดจาก state store ก่อน หากไม่มีจึงดึงจากฐานข้อมูล คืน nil หากไม่เคยเห็นเครื่องนี้
+func (s *statusService) lastKnownStatus(ctx context.Context, washerID string) *WasherSnapshot {
+	snapshot, err := s.stateStore.Get(ctx, washerID)
+	if err != nil {
+		log.Printf("⚠️ Failed to read cached status for washer %s: %v", washerID, err)
+	}
+	if snapshot != nil {
+		return snapshot
+	}
 
-	status := &models.Status{
-		DormID:   dormId,
-		WasherID: washerId,
-		Status:   string(payload),
+	latest, err := s.statusRepo.FindLatestByWasherID(ctx, washerID)
+	if err != nil {
+		log.Printf("❌ Failed to load previous status for washer %s: %v", washerID, err)
+		return nil
+	}
+	if latest.WasherID == "" {
+		return nil
 	}
 
-	if err := s.statusRepo.SaveStatus(context.Background(), status); err != nil {
-		log.Printf("❌ Failed to save status: %v", err)
+	snapshot = &WasherSnapshot{
+		DormID:   latest.DormID,
+		WasherID: latest.WasherID,
+		Status:   latest.Status,
+		Since:    latest.CreatedAt,
+		LastSeen: latest.CreatedAt,
+	}
+	if err := s.stateStore.Save(ctx, *snapshot); err != nil {
+		log.Printf("❌ Failed to cache status for washer %s: %v", washerID, err)
 	}
+	return snapshot
 }
 
-func (s *statusService) GetStatusByWasherID(ctx context.Context, washerID string) (*models.Status, error) {
+func (s *statusService) recordAnomaly(ctx context.Context, anomaly *models.StatusAnomaly) {
+	if err := s.anomalyRepo.SaveAnomaly(ctx, anomaly); err != nil {
+		log.Printf("❌ Failed to save anomaly: %v", err)
+	}
+}
+
+func (s *statusService) GetAnomalies(ctx context.Context, filter repository.AnomalyFilter) ([]models.StatusAnomaly, error) {
+	anomalies, err := s.anomalyRepo.FindAnomalies(ctx, filter)
+	if err != nil {
+		return nil, apperr.New("DB_ERROR", "Failed to get anomalies", 500, err)
+	}
+	return anomalies, nil
+}
+
+func (s *statusService) GetTelemetry(ctx context.Context, query repository.TelemetryQuery) ([]models.WasherTelemetry, error) {
+	telemetry, err := s.telemetryRepo.FindTelemetry(ctx, query)
+	if err != nil {
+		return nil, apperr.New("DB_ERROR", "Failed to get telemetry", 500, err)
+	}
+	return telemetry, nil
+}
+
+// GetCurrentStatuses คืนสถานะล่าสุดของแต่ละเครื่องตาม filter ใช้ replay ตอน client เริ่มเชื่อมต่อ stream
+func (s *statusService) GetCurrentStatuses(ctx context.Context, filter StatusFilter) ([]models.Status, error) {
+	if filter.WasherID != "" {
+		status, err := s.statusRepo.FindLatestByWasherID(ctx, filter.WasherID)
+		if err != nil {
+			return nil, apperr.New("DB_ERROR", "Failed to get current status", 500, err)
+		}
+		if status.WasherID == "" || !filter.Match(*status) {
+			return []models.Status{}, nil
+		}
+		return []models.Status{*status}, nil
+	}
+
+	statuses, err := s.statusRepo.FindLatestPerWasher(ctx, filter.DormID)
+	if err != nil {
+		return nil, apperr.New("DB_ERROR", "Failed to get current statuses", 500, err)
+	}
+	return statuses, nil
+}
+
+func (s *statusService) SubscribeStatus(filter StatusFilter) (<-chan StatusEvent, func()) {
+	return s.broker.Subscribe(filter)
+}
+
+func (s *statusService) GetStatusByWasherID(ctx context.Context, washerID string) (*models.WasherStatusDetail, error) {
 	status, err := s.statusRepo.FindLatestByWasherID(ctx, washerID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -75,20 +406,81 @@ func (s *statusService) GetStatusByWasherID(ctx context.Context, washerID string
 		}
 		return nil, apperr.New("DB_ERROR", "Failed to get status", 500, err)
 	}
-	return status, nil
+	if status.WasherID == "" {
+		return nil, apperr.New("NOT_FOUND", "Status not found", 404, nil)
+	}
+
+	detail := &models.WasherStatusDetail{Status: *status}
+	if status.Status.Busy() {
+		detail.ETA = s.estimate(ctx, status.WasherID, status.DormID)
+	}
+	detail.Online, detail.LastSeenAt = s.presence(ctx, status.WasherID)
+	detail.MaintenanceWindow = s.maintenance.ActiveWindow(ctx, status.DormID, status.WasherID, time.Now())
+	return detail, nil
+}
+
+// presence คืนสถานะออนไลน์และเวลาที่ได้รับข้อความล่าสุดจาก state store
+func (s *statusService) presence(ctx context.Context, washerID string) (bool, *time.Time) {
+	snapshot, err := s.stateStore.Get(ctx, washerID)
+	if err != nil {
+		log.Printf("⚠️ Failed to read cached status for washer %s: %v", washerID, err)
+	}
+	if snapshot == nil || snapshot.LastSeen.IsZero() {
+		return false, nil
+	}
+
+	lastSeen := snapshot.LastSeen
+	return snapshot.Online(time.Now(), s.offlineAfter), &lastSeen
 }
 
-func (s *statusService) GetStatusHistoryByWasherID(ctx context.Context, washerID string) ([]models.Status, error) {
-	statuses, err := s.statusRepo.FindHistoryByWasherID(ctx, washerID)
+// estimate ไม่ทำให้ request ล้มเหลว: หากคำนวณ ETA ไม่ได้จะคืน nil
+func (s *statusService) estimate(ctx context.Context, washerID, dormID string) *models.WasherETA {
+	eta, err := s.eta.Estimate(ctx, washerID, dormID, time.Now())
+	if err != nil {
+		log.Printf("⚠️ Failed to estimate ETA for washer %s: %v", washerID, err)
+		return nil
+	}
+	return eta
+}
+
+// GetStatusHistoryByWasherID คืน 404 เฉพาะเมื่อเครื่องไม่มีประวัติเลย หน้าที่ว่างเพราะตัวกรองหรือ cursor คืนรายการว่าง
+func (s *statusService) GetStatusHistoryByWasherID(ctx context.Context, washerID string, query repository.StatusQuery) (*models.StatusPage, error) {
+	query.WasherID = washerID
+	page, err := s.findStatusPage(ctx, query)
 	if err != nil {
 		return nil, apperr.New("DB_ERROR", "Failed to get status history", 500, err)
 	}
 
-	if len(statuses) == 0 {
+	unfiltered := query.After == nil && query.From == nil && query.To == nil && len(query.Statuses) == 0
+	if len(page.Items) == 0 && unfiltered {
 		return nil, apperr.New("NOT_FOUND", "No status history found", 404, nil)
 	}
 
-	return statuses, nil
+	return page, nil
+}
+
+// findStatusPage ขอเกิน Limit หนึ่งแถวเพื่อรู้ว่ามีหน้าถัดไปหรือไม่
+func (s *statusService) findStatusPage(ctx context.Context, query repository.StatusQuery) (*models.StatusPage, error) {
+	limit := query.Limit
+	if limit > 0 {
+		query.Limit = limit + 1
+	}
+
+	statuses, err := s.statusRepo.FindStatuses(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.StatusPage{Items: statuses}
+	if limit > 0 && len(statuses) > limit {
+		page.Items = statuses[:limit]
+		last := page.Items[limit-1]
+		page.NextCursor = EncodeStatusCursor(repository.StatusCursor{CreatedAt: last.CreatedAt, ID: last.ID}, query.Ascending)
+	}
+	if page.Items == nil {
+		page.Items = []models.Status{}
+	}
+	return page, nil
 }
 
 // func (s *statusService) GetDormStatusReport(ctx context.Context) ([]models.DormStatusReport, error) {
@@ -150,41 +542,58 @@ func (s *statusService) GetStatusHistoryByWasherID(ctx context.Context, washerID
 // 	return result, nil
 // }
 
-func (s *statusService) GetDormStatusReport(ctx context.Context) ([]models.DormStatusReport, error) {
+func (s *statusService) GetDormStatusReport(ctx context.Context, opts DormReportOptions) ([]models.DormStatusReport, error) {
 	// totalStart := time.Now()
-	const cacheDuration = 24 * time.Hour
-
-	// Step 1: Load washing machines from Redis or API
-	var machines []WashingMachine
-	const cacheKey = "external_api:washing_machines"
-	if cached, err := s.redisClient.Get(ctx, cacheKey).Result(); err == nil {
-		if err := json.Unmarshal([]byte(cached), &machines); err == nil {
-			// log.Println("⚡ Loaded WashingMachines from Redis")
+
+	if opts.DormID != "" {
+		if _, err := s.registryRepo.FindDormByID(ctx, opts.DormID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, apperr.New("NOT_FOUND", "Dorm not found", 404, err)
+			}
+			return nil, apperr.New("DB_ERROR", "Failed to get dorm", 500, err)
 		}
 	}
-	if len(machines) == 0 {
-		apiMachines, err := s.externalAPI.FetchWashingMachines()
-		if err != nil {
-			return nil, apperr.New("API_ERROR", "Failed to fetch machines", 500, err)
-		}
-		machines = apiMachines
-		if bytes, err := json.Marshal(machines); err == nil {
-			s.redisClient.Set(ctx, cacheKey, bytes, cacheDuration)
-			// log.Println("✅ Cached WashingMachines to Redis")
-		}
+	if opts.Limit <= 0 {
+		opts.Limit = defaultReportHistoryLimit
+	}
+	fields := opts.Fields
+	if len(fields) == 0 {
+		fields = allReportFields
 	}
-	// log.Printf("📡 FetchWashingMachines took: %v", time.Since(totalStart))
+
+	// Step 1: Load dorms and machines from the local registry
+	machines, err := s.registryRepo.FindMachines(ctx, opts.DormID)
+	if err != nil {
+		return nil, apperr.New("DB_ERROR", "Failed to fetch machines", 500, err)
+	}
+	dorms, err := s.registryRepo.FindDorms(ctx)
+	if err != nil {
+		return nil, apperr.New("DB_ERROR", "Failed to fetch dorms", 500, err)
+	}
+	dormNames := make(map[string]string, len(dorms))
+	for _, d := range dorms {
+		dormNames[d.ID] = d.Name
+	}
+	// log.Printf("📡 Load registry took: %v", time.Since(totalStart))
 
 	// Step 2: Prepare washerIDs and machineMap
 	// start := time.Now()
 	washerIDs := make([]string, 0, len(machines))
-	machineMap := make(map[string]WashingMachine)
+	machineMap := make(map[string]models.Machine)
 	for _, m := range machines {
-		washerIDs = append(washerIDs, m.IDWashingMachine)
-		machineMap[m.IDWashingMachine] = m
+		washerIDs = append(washerIDs, m.ID)
+		machineMap[m.ID] = m
 	}
 
-	histories, err := s.statusRepo.FindLatest50HistoryByWasherIDs(ctx, washerIDs)
+	// ETA ต้องรู้สถานะล่าสุด เมื่อไม่ได้ขอ history จึงอ่านจาก washer_current_status แทน
+	// history ของรายงานคือการเปลี่ยนสถานะแต่ละครั้งล่าสุด opts.Limit รายการต่อเครื่อง ซึ่ง rollup ไม่ได้เก็บไว้
+	// จึงต้องอ่านจาก statuses แต่เป็น query ที่จำกัดจำนวนต่อเครื่องตาม index (washer_id, created_at) ไม่ได้ scan ทั้งช่วงเวลา
+	var histories []models.Status
+	if fields[ReportFieldHistory] {
+		histories, err = s.statusRepo.FindRecentHistoryByWasherIDs(ctx, washerIDs, opts.Limit, opts.Since)
+	} else {
+		histories, err = s.currentSince(ctx, washerIDs, opts.Since)
+	}
 	if err != nil {
 		return nil, apperr.New("DB_ERROR", "Failed to fetch histories", 500, err)
 	}
@@ -200,30 +609,31 @@ func (s *statusService) GetDormStatusReport(ctx context.Context) ([]models.DormS
 
 	// 🔁 สร้างลำดับหอจาก machines โดยตรง
 	for _, m := range machines {
-		if !seenDorms[m.IDDorm] {
-			dormOrder = append(dormOrder, m.IDDorm)
-			seenDorms[m.IDDorm] = true
+		if !seenDorms[m.DormID] {
+			dormOrder = append(dormOrder, m.DormID)
+			seenDorms[m.DormID] = true
 		}
 	}
 
 	// 🔁 รวมสถานะต่อเครื่อง
 	for _, h := range histories {
 		m := machineMap[h.WasherID]
-		report, ok := grouped[m.IDDorm]
+		report, ok := grouped[m.DormID]
 		if !ok {
 			report = &models.DormStatusReport{
-				DormID:   m.IDDorm,
-				DormName: m.DormName,
+				DormID:   m.DormID,
+				DormName: dormNameOrID(dormNames, m.DormID),
 				Machines: []*models.WasherStatusHistory{},
 			}
-			grouped[m.IDDorm] = report
+			grouped[m.DormID] = report
 		}
 
-		key := m.IDDorm + "::" + h.WasherID
+		key := m.DormID + "::" + h.WasherID
 		machineHistory, ok := washerHistoryMap[key]
 		if !ok {
 			machineHistory = &models.WasherStatusHistory{
 				WasherID: h.WasherID,
+				Pending:  m.Status == models.RegistrationPending,
 				History:  []models.StatusDTO{},
 			}
 			report.Machines = append(report.Machines, machineHistory)
@@ -234,6 +644,20 @@ func (s *statusService) GetDormStatusReport(ctx context.Context) ([]models.DormS
 	}
 	// log.Printf("🔀 Grouping took: %v", time.Since(start))
 
+	// ⏱️ ประเมินเวลาเสร็จของเครื่องที่กำลังซักอยู่ (history เรียงจากใหม่ไปเก่า) และสถานะออนไลน์
+	for _, machineHistory := range washerHistoryMap {
+		if fields[ReportFieldETA] && len(machineHistory.History) > 0 && machineHistory.History[0].Status.Busy() {
+			machineHistory.ETA = s.estimate(ctx, machineHistory.WasherID, machineMap[machineHistory.WasherID].DormID)
+		}
+		if fields[ReportFieldPresence] {
+			online, lastSeen := s.presence(ctx, machineHistory.WasherID)
+			machineHistory.Online, machineHistory.LastSeenAt = &online, lastSeen
+		}
+		if !fields[ReportFieldHistory] {
+			machineHistory.History = nil
+		}
+	}
+
 	// Step 4: Convert to slice and preserve dorm order
 	// start = time.Now()
 	result := make([]models.DormStatusReport, 0, len(dormOrder))
@@ -248,6 +672,29 @@ func (s *statusService) GetDormStatusReport(ctx context.Context) ([]models.DormS
 	return result, nil
 }
 
+// currentSince คืนสถานะล่าสุดของแต่ละเครื่อง ตัดเครื่องที่สถานะล่าสุดเก่ากว่า since ออกเหมือน history
+func (s *statusService) currentSince(ctx context.Context, washerIDs []string, since *time.Time) ([]models.Status, error) {
+	statuses, err := s.statusRepo.FindLatestByWasherIDs(ctx, washerIDs)
+	if err != nil || since == nil {
+		return statuses, err
+	}
+
+	recent := statuses[:0]
+	for _, st := range statuses {
+		if !st.CreatedAt.Before(*since) {
+			recent = append(recent, st)
+		}
+	}
+	return recent, nil
+}
+
+func dormNameOrID(dormNames map[string]string, dormID string) string {
+	if name := dormNames[dormID]; name != "" {
+		return name
+	}
+	return dormID
+}
+
 // func extractWasherIDs(machines []WashingMachine) []string {
 // 	ids := make([]string, 0, len(machines))
 // 	for _, m := range machines {
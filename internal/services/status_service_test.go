@@ -0,0 +1,232 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jaytnw/bms-service/internal/models"
+)
+
+func statusMessage(washerID, payload string, at time.Time) IngestMessage {
+	return IngestMessage{Kind: IngestStatus, DormID: "d1", WasherID: washerID, Payload: []byte(payload), ReceivedAt: at}
+}
+
+func TestPrepareIngestDeduplicates(t *testing.T) {
+	base := time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC)
+	known := WasherSnapshot{DormID: "d1", WasherID: "w1", Status: models.WasherWashing, Since: base, LastSeen: base}
+
+	tests := []struct {
+		name          string
+		snapshots     []WasherSnapshot
+		payload       string
+		wantStatus    models.WasherState
+		wantPrevious  models.WasherState
+		wantTelemetry bool
+		wantNil       bool
+	}{
+		{name: "first status is saved", payload: "washing", wantStatus: models.WasherWashing},
+		{name: "same state is dropped", snapshots: []WasherSnapshot{known}, payload: "running", wantNil: true},
+		{name: "same state keeps telemetry", snapshots: []WasherSnapshot{known}, payload: `{"status":"washing","remainingSeconds":600}`, wantTelemetry: true},
+		{name: "changed state is saved", snapshots: []WasherSnapshot{known}, payload: "rinse", wantStatus: models.WasherRinsing, wantPrevious: models.WasherWashing},
+		{name: "changed json state carries telemetry", snapshots: []WasherSnapshot{known}, payload: `{"status":"done"}`, wantStatus: models.WasherDone, wantPrevious: models.WasherWashing, wantTelemetry: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newStatusServiceFixture(tt.snapshots...)
+			item := f.service.PrepareIngest(context.Background(), statusMessage("w1", tt.payload, base.Add(time.Minute)), map[string]WasherSnapshot{})
+			if tt.wantNil {
+				if item != nil {
+					t.Fatalf("want nil, got %+v", item)
+				}
+				return
+			}
+			if item == nil {
+				t.Fatal("want item, got nil")
+			}
+			if tt.wantStatus == "" {
+				if item.Status != nil {
+					t.Fatalf("want telemetry only, got status %s", item.Status.Status)
+				}
+			} else {
+				if item.Status == nil || item.Status.Status != tt.wantStatus {
+					t.Fatalf("want status %s, got %+v", tt.wantStatus, item.Status)
+				}
+				if !item.Status.CreatedAt.Equal(base.Add(time.Minute)) {
+					t.Errorf("CreatedAt = %v, want receive time", item.Status.CreatedAt)
+				}
+			}
+			if item.Previous != tt.wantPrevious {
+				t.Errorf("Previous = %q, want %q", item.Previous, tt.wantPrevious)
+			}
+			if (item.Telemetry != nil) != tt.wantTelemetry {
+				t.Errorf("Telemetry = %+v, want present %v", item.Telemetry, tt.wantTelemetry)
+			}
+		})
+	}
+}
+
+func TestPrepareIngestUsesPendingBatch(t *testing.T) {
+	f := newStatusServiceFixture()
+	pending := map[string]WasherSnapshot{}
+	at := time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC)
+
+	var states []models.WasherState
+	for i, payload := range []string{"idle", "idle", "washing", "washing", "done"} {
+		if item := f.service.PrepareIngest(context.Background(), statusMessage("w1", payload, at.Add(time.Duration(i)*time.Second)), pending); item != nil && item.Status != nil {
+			states = append(states, item.Status.Status)
+		}
+	}
+	want := []models.WasherState{models.WasherIdle, models.WasherWashing, models.WasherDone}
+	if len(states) != len(want) {
+		t.Fatalf("states = %v, want %v", states, want)
+	}
+	for i := range want {
+		if states[i] != want[i] {
+			t.Fatalf("states = %v, want %v", states, want)
+		}
+	}
+}
+
+func TestPrepareIngestQuarantinesPayloads(t *testing.T) {
+	tests := []struct {
+		payload string
+		kind    string
+	}{
+		{"exploded", string(models.AnomalyUnknownPayload)},
+		{`{"status":"washing","door":"ajar"}`, string(models.AnomalyInvalidPayload)},
+	}
+	for _, tt := range tests {
+		f := newStatusServiceFixture()
+		if item := f.service.PrepareIngest(context.Background(), statusMessage("w1", tt.payload, time.Now()), map[string]WasherSnapshot{}); item != nil {
+			t.Errorf("%s: want nil, got %+v", tt.payload, item)
+		}
+		if kinds := f.anomalies.kinds(); len(kinds) != 1 || kinds[0] != tt.kind {
+			t.Errorf("%s: anomalies = %v, want [%s]", tt.payload, kinds, tt.kind)
+		}
+	}
+}
+
+func TestPrepareIngestRecordsIllegalTransition(t *testing.T) {
+	f := newStatusServiceFixture(WasherSnapshot{DormID: "d1", WasherID: "w1", Status: models.WasherIdle})
+	item := f.service.PrepareIngest(context.Background(), statusMessage("w1", "spinning", time.Now()), map[string]WasherSnapshot{})
+	if item == nil || item.Status == nil || item.Status.Status != models.WasherSpinning {
+		t.Fatalf("illegal transition should still be saved, got %+v", item)
+	}
+	if kinds := f.anomalies.kinds(); len(kinds) != 1 || kinds[0] != string(models.AnomalyIllegalTransition) {
+		t.Errorf("anomalies = %v", kinds)
+	}
+}
+
+func TestPrepareIngestOfflineMarker(t *testing.T) {
+	now := time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC)
+	marker := IngestMessage{Kind: IngestOffline, DormID: "d1", WasherID: "w1", ReceivedAt: now}
+	lastWill := IngestMessage{Kind: IngestPresence, DormID: "d1", WasherID: "w1", Payload: []byte("offline"), ReceivedAt: now}
+
+	tests := []struct {
+		name     string
+		lastSeen time.Time
+		pending  map[string]WasherSnapshot
+		lastWill bool
+		wantSave bool
+	}{
+		{name: "silent washer goes offline", lastSeen: now.Add(-10 * time.Minute), wantSave: true},
+		{name: "message after list keeps washer online", lastSeen: now.Add(-time.Minute)},
+		{name: "message after marker keeps washer online", lastSeen: now.Add(time.Minute)},
+		{
+			name:     "pending message in batch keeps washer online",
+			lastSeen: now.Add(-10 * time.Minute),
+			pending:  map[string]WasherSnapshot{"w1": {DormID: "d1", WasherID: "w1", Status: models.WasherDone, LastSeen: now.Add(-30 * time.Second)}},
+		},
+		{name: "last will is saved right away", lastSeen: now.Add(-time.Minute), lastWill: true, wantSave: true},
+		{name: "last will before a newer message is dropped", lastSeen: now.Add(time.Minute), lastWill: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newStatusServiceFixture(WasherSnapshot{DormID: "d1", WasherID: "w1", Status: models.WasherIdle, LastSeen: tt.lastSeen})
+			pending := tt.pending
+			if pending == nil {
+				pending = map[string]WasherSnapshot{}
+			}
+			msg := marker
+			if tt.lastWill {
+				msg = lastWill
+			}
+			item := f.service.PrepareIngest(context.Background(), msg, pending)
+			if saved := item != nil && item.Status != nil && item.Status.Status == models.WasherOffline; saved != tt.wantSave {
+				t.Errorf("offline saved = %v, want %v", saved, tt.wantSave)
+			}
+		})
+	}
+}
+
+func TestPrepareIngestDropsMessagesBeforeLatestStatus(t *testing.T) {
+	since := time.Date(2026, 1, 1, 8, 10, 0, 0, time.UTC)
+	f := newStatusServiceFixture(WasherSnapshot{DormID: "d1", WasherID: "w1", Status: models.WasherWashing, Since: since, LastSeen: since})
+	pending := map[string]WasherSnapshot{}
+
+	// entry ที่ reclaim มาจาก consumer อื่นรับไว้ก่อนสถานะล่าสุด
+	if item := f.service.PrepareIngest(context.Background(), statusMessage("w1", "idle", since.Add(-5*time.Minute)), pending); item != nil {
+		t.Fatalf("stale message should be dropped, got %+v", item.Status)
+	}
+	if item := f.service.PrepareIngest(context.Background(), statusMessage("w1", "done", since.Add(5*time.Minute)), pending); item == nil || item.Status == nil {
+		t.Fatal("newer message should be saved")
+	}
+}
+
+func TestPrepareIngestDuplicateAdvancesPendingLastSeen(t *testing.T) {
+	f := newStatusServiceFixture()
+	pending := map[string]WasherSnapshot{}
+	at := time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC)
+
+	f.service.PrepareIngest(context.Background(), statusMessage("w1", "idle", at), pending)
+	f.service.PrepareIngest(context.Background(), statusMessage("w1", "idle", at.Add(10*time.Minute)), pending)
+
+	marker := IngestMessage{Kind: IngestOffline, DormID: "d1", WasherID: "w1", ReceivedAt: at.Add(11 * time.Minute)}
+	if item := f.service.PrepareIngest(context.Background(), marker, pending); item != nil {
+		t.Fatalf("offline marker should be dropped, got %+v", item.Status)
+	}
+}
+
+func TestPrepareIngestRegistersDuplicates(t *testing.T) {
+	f := newStatusServiceFixture(WasherSnapshot{DormID: "d1", WasherID: "w1", Status: models.WasherIdle})
+	if item := f.service.PrepareIngest(context.Background(), statusMessage("w1", "idle", time.Now()), map[string]WasherSnapshot{}); item != nil {
+		t.Fatalf("duplicate should not be saved, got %+v", item)
+	}
+	if len(f.registrar.seen) != 1 || f.registrar.seen[0] != "d1/w1" {
+		t.Errorf("registered = %v, want [d1/w1]", f.registrar.seen)
+	}
+}
+
+func TestCommitIngestCanBeRetried(t *testing.T) {
+	f := newStatusServiceFixture()
+	f.statuses.failures = []error{errFakeDB}
+	pending := map[string]WasherSnapshot{}
+	at := time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC)
+
+	var batch []*PendingStatus
+	for i, payload := range []string{"idle", `{"status":"washing","remainingSeconds":600}`} {
+		batch = append(batch, f.service.PrepareIngest(context.Background(), statusMessage("w1", payload, at.Add(time.Duration(i)*time.Minute)), pending))
+	}
+
+	if err := f.service.CommitIngest(context.Background(), batch); err == nil {
+		t.Fatal("CommitIngest() error = nil, want the database error")
+	}
+	if len(f.broker.changes()) != 0 || batch[0].Status.ID != 0 {
+		t.Fatalf("failed commit published %d change(s) and left id %d", len(f.broker.changes()), batch[0].Status.ID)
+	}
+
+	if err := f.service.CommitIngest(context.Background(), batch); err != nil {
+		t.Fatalf("retry CommitIngest() error = %v", err)
+	}
+	changes := f.broker.changes()
+	if len(changes) != 2 || changes[0].Status.Status != models.WasherIdle || changes[1].Status.Status != models.WasherWashing || changes[1].Previous != models.WasherIdle {
+		t.Fatalf("changes = %+v, want idle then washing", changes)
+	}
+	if len(f.telemetry.saved) != 1 || f.telemetry.saved[0].StatusID == nil || *f.telemetry.saved[0].StatusID != batch[1].Status.ID {
+		t.Fatalf("telemetry = %+v, want linked to status %d", f.telemetry.saved, batch[1].Status.ID)
+	}
+	if snapshot, _ := f.state.Get(context.Background(), "w1"); snapshot == nil || snapshot.Status != models.WasherWashing {
+		t.Fatalf("state = %+v, want washing", snapshot)
+	}
+}
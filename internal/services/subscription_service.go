@@ -0,0 +1,191 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jaytnw/bms-service/internal/apperr"
+	"github.com/jaytnw/bms-service/internal/models"
+	"github.com/jaytnw/bms-service/internal/notifications"
+	"github.com/jaytnw/bms-service/internal/repository"
+	"gorm.io/gorm"
+)
+
+// SubscribeRequest คือคำขอรับแจ้งเตือนของเครื่องหนึ่งเครื่อง Events ว่างหมายถึง ["done"]
+type SubscribeRequest struct {
+	UserID     string   `json:"userId"`
+	Channel    string   `json:"channel"`
+	Target     string   `json:"target"`
+	Events     []string `json:"events"`
+	Persistent bool     `json:"persistent"`
+}
+
+// SubscriptionService แจ้งเตือนผู้ที่ subscribe เครื่องเมื่อซักเสร็จหรือเครื่องเสีย จากการเปลี่ยนสถานะที่มาจาก MQTT
+type SubscriptionService interface {
+	StatusListener
+	Subscribe(ctx context.Context, washerID string, req SubscribeRequest) (*models.WasherSubscription, error)
+	ListSubscriptions(ctx context.Context, washerID string) ([]models.WasherSubscription, error)
+	Unsubscribe(ctx context.Context, id uint) error
+	GetDeliveries(ctx context.Context, filter repository.DeliveryFilter) ([]models.NotificationDelivery, error)
+}
+
+type subscriptionService struct {
+	notificationRepo repository.NotificationRepository
+	registryRepo     repository.RegistryRepository
+	dispatcher       notifications.Dispatcher
+}
+
+func NewSubscriptionService(notificationRepo repository.NotificationRepository, registryRepo repository.RegistryRepository, dispatcher notifications.Dispatcher) SubscriptionService {
+	return &subscriptionService{
+		notificationRepo: notificationRepo,
+		registryRepo:     registryRepo,
+		dispatcher:       dispatcher,
+	}
+}
+
+func (s *subscriptionService) Subscribe(ctx context.Context, washerID string, req SubscribeRequest) (*models.WasherSubscription, error) {
+	req.UserID = strings.TrimSpace(req.UserID)
+	if req.UserID == "" {
+		return nil, apperr.New("VALIDATION_ERROR", "userId is required", 400, nil)
+	}
+	if !s.dispatcher.Supports(req.Channel) {
+		return nil, apperr.New("VALIDATION_ERROR", fmt.Sprintf("unsupported channel %q", req.Channel), 400, nil)
+	}
+	if req.Channel != notifications.ChannelLog && strings.TrimSpace(req.Target) == "" {
+		return nil, apperr.New("VALIDATION_ERROR", "target is required", 400, nil)
+	}
+
+	subscription := &models.WasherSubscription{
+		WasherID:   washerID,
+		UserID:     req.UserID,
+		Channel:    req.Channel,
+		Target:     req.Target,
+		Persistent: req.Persistent,
+		Active:     true,
+	}
+	if len(req.Events) == 0 {
+		req.Events = []string{models.SubscriptionEventDone}
+	}
+	for _, event := range req.Events {
+		switch event {
+		case models.SubscriptionEventDone:
+			subscription.OnDone = true
+		case models.SubscriptionEventFault:
+			subscription.OnFault = true
+		default:
+			return nil, apperr.New("VALIDATION_ERROR", fmt.Sprintf("unsupported event %q", event), 400, nil)
+		}
+	}
+
+	if _, err := s.registryRepo.FindMachineByID(ctx, washerID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperr.New("NOT_FOUND", "Washer not found", 404, err)
+		}
+		return nil, apperr.New("DB_ERROR", "Failed to get washer", 500, err)
+	}
+
+	if err := s.notificationRepo.SaveSubscription(ctx, subscription); err != nil {
+		return nil, apperr.New("DB_ERROR", "Failed to create subscription", 500, err)
+	}
+	return subscription, nil
+}
+
+func (s *subscriptionService) ListSubscriptions(ctx context.Context, washerID string) ([]models.WasherSubscription, error) {
+	subscriptions, err := s.notificationRepo.FindActiveSubscriptions(ctx, washerID)
+	if err != nil {
+		return nil, apperr.New("DB_ERROR", "Failed to get subscriptions", 500, err)
+	}
+	return subscriptions, nil
+}
+
+func (s *subscriptionService) Unsubscribe(ctx context.Context, id uint) error {
+	subscription, err := s.notificationRepo.FindSubscriptionByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperr.New("NOT_FOUND", "Subscription not found", 404, err)
+		}
+		return apperr.New("DB_ERROR", "Failed to get subscription", 500, err)
+	}
+
+	subscription.Active = false
+	if err := s.notificationRepo.SaveSubscription(ctx, subscription); err != nil {
+		return apperr.New("DB_ERROR", "Failed to cancel subscription", 500, err)
+	}
+	return nil
+}
+
+func (s *subscriptionService) GetDeliveries(ctx context.Context, filter repository.DeliveryFilter) ([]models.NotificationDelivery, error) {
+	deliveries, err := s.notificationRepo.FindDeliveries(ctx, filter)
+	if err != nil {
+		return nil, apperr.New("DB_ERROR", "Failed to get notification deliveries", 500, err)
+	}
+	return deliveries, nil
+}
+
+// OnStatusChange แจ้งเตือน "done" เมื่อเครื่องออกจากสถานะ busy ไปสถานะว่าง และ "fault" เมื่อเครื่องเข้าสถานะ fault
+// สถานะระหว่างซ่อมบำรุงไม่ทำให้เกิดการแจ้งเตือน
+func (s *subscriptionService) OnStatusChange(ctx context.Context, change StatusChange) {
+	status := change.Status
+	if status.Maintenance {
+		return
+	}
+
+	var event string
+	switch {
+	case status.Status == models.WasherFault && change.Previous != models.WasherFault:
+		event = models.SubscriptionEventFault
+	case change.Previous.Busy() && status.Status.Free():
+		event = models.SubscriptionEventDone
+	default:
+		return
+	}
+
+	subscriptions, err := s.notificationRepo.FindActiveSubscriptions(ctx, status.WasherID)
+	if err != nil {
+		log.Printf("❌ Failed to load subscriptions for washer %s: %v", status.WasherID, err)
+		return
+	}
+
+	now := time.Now()
+	for i := range subscriptions {
+		subscription := &subscriptions[i]
+		if !subscription.Wants(event) {
+			continue
+		}
+
+		msg := notifications.Message{
+			Channel:   subscription.Channel,
+			Recipient: subscription.Target,
+			Data: map[string]any{
+				"subscriptionId": subscription.ID,
+				"event":          event,
+				"dormId":         status.DormID,
+				"washerId":       status.WasherID,
+				"status":         status.Status,
+				"at":             status.CreatedAt,
+			},
+		}
+		if event == models.SubscriptionEventFault {
+			msg.Subject = "Washer fault"
+			msg.Body = fmt.Sprintf("Washer %s in dorm %s reported a fault.", status.WasherID, status.DormID)
+		} else {
+			msg.Subject = "Laundry done"
+			msg.Body = fmt.Sprintf("Washer %s in dorm %s has finished its cycle.", status.WasherID, status.DormID)
+		}
+
+		if err := s.dispatcher.Notify(ctx, msg); err != nil {
+			log.Printf("❌ Failed to notify subscription %d: %v", subscription.ID, err)
+			continue
+		}
+
+		subscription.NotifiedAt = &now
+		subscription.Active = subscription.Persistent
+		if err := s.notificationRepo.SaveSubscription(ctx, subscription); err != nil {
+			log.Printf("❌ Failed to update subscription %d: %v", subscription.ID, err)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jaytnw/bms-service/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	washerStateKey    = "washer:state"
+	washerLastSeenKey = "washer:last_seen"
+)
+
+// lastSeenScript เขียน last seen (unix ms) ของเครื่อง ARGV[1] เป็น ARGV[2] เฉพาะเมื่อใหม่กว่าค่าเดิม
+var lastSeenScript = redis.NewScript(`
+local current = tonumber(redis.call('HGET', KEYS[1], ARGV[1]))
+if current == nil or current < tonumber(ARGV[2]) then
+	redis.call('HSET', KEYS[1], ARGV[1], ARGV[2])
+end
+return 0
+`)
+
+// WasherSnapshot คือสถานะล่าสุดที่รู้ของเครื่องหนึ่งเครื่อง
+// Since คือเวลาที่สถานะเปลี่ยนครั้งล่าสุด ส่วน LastSeen คือเวลาที่ได้รับข้อความล่าสุด (heartbeat)
+type WasherSnapshot struct {
+	DormID   string             `json:"dormId"`
+	WasherID string             `json:"washerId"`
+	Status   models.WasherState `json:"status"`
+	Since    time.Time          `json:"since"`
+	LastSeen time.Time          `json:"lastSeen"`
+}
+
+// Online บอกว่าเครื่องยังส่งข้อความมาภายในช่วง offlineAfter และไม่ได้อยู่ในสถานะ offline
+func (w WasherSnapshot) Online(now time.Time, offlineAfter time.Duration) bool {
+	return w.Status != models.WasherOffline && now.Sub(w.LastSeen) <= offlineAfter
+}
+
+// WasherStateStore เก็บ last known status ต่อเครื่องไว้ใน memory และ Redis
+// LastSeen ไม่ย้อนกลับ: Save และ Touch ที่มีเวลาเก่ากว่าค่าเดิมจะคง last seen เดิมไว้
+type WasherStateStore interface {
+	Get(ctx context.Context, washerID string) (*WasherSnapshot, error)
+	List(ctx context.Context) ([]WasherSnapshot, error)
+	Save(ctx context.Context, snapshot WasherSnapshot) error
+	Touch(ctx context.Context, washerID string, at time.Time) error
+	Replace(ctx context.Context, snapshots []WasherSnapshot) error
+}
+
+type washerStateStore struct {
+	redisClient *redis.Client
+	mu          sync.RWMutex
+	snapshots   map[string]WasherSnapshot
+}
+
+func NewWasherStateStore(redisClient *redis.Client) WasherStateStore {
+	return &washerStateStore{
+		redisClient: redisClient,
+		snapshots:   make(map[string]WasherSnapshot),
+	}
+}
+
+// Get คืน nil เมื่อไม่รู้จักเครื่องนี้ทั้งใน memory และ Redis
+func (s *washerStateStore) Get(ctx context.Context, washerID string) (*WasherSnapshot, error) {
+	s.mu.RLock()
+	snapshot, ok := s.snapshots[washerID]
+	s.mu.RUnlock()
+	if ok {
+		return &snapshot, nil
+	}
+
+	raw, err := s.redisClient.HGet(ctx, washerStateKey, washerID).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(raw), &snapshot); err != nil {
+		return nil, err
+	}
+
+	if lastSeen, err := s.redisClient.HGet(ctx, washerLastSeenKey, washerID).Int64(); err == nil {
+		snapshot.LastSeen = time.UnixMilli(lastSeen)
+	}
+
+	s.mu.Lock()
+	s.snapshots[washerID] = snapshot
+	s.mu.Unlock()
+
+	return &snapshot, nil
+}
+
+// List คืน snapshot ของทุกเครื่องจาก Redis และเติมลง memory (ใช้ตอนเริ่มระบบใหม่ที่ memory ยังว่าง)
+func (s *washerStateStore) List(ctx context.Context) ([]WasherSnapshot, error) {
+	states, err := s.redisClient.HGetAll(ctx, washerStateKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	lastSeen, err := s.redisClient.HGetAll(ctx, washerLastSeenKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for washerID, raw := range states {
+		if _, ok := s.snapshots[washerID]; ok {
+			continue
+		}
+
+		var snapshot WasherSnapshot
+		if err := json.Unmarshal([]byte(raw), &snapshot); err != nil {
+			continue
+		}
+		if ms, err := strconv.ParseInt(lastSeen[washerID], 10, 64); err == nil {
+			snapshot.LastSeen = time.UnixMilli(ms)
+		}
+		s.snapshots[washerID] = snapshot
+	}
+
+	snapshots := make([]WasherSnapshot, 0, len(s.snapshots))
+	for _, snapshot := range s.snapshots {
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots, nil
+}
+
+func (s *washerStateStore) Save(ctx context.Context, snapshot WasherSnapshot) error {
+	s.mu.Lock()
+	if current, ok := s.snapshots[snapshot.WasherID]; ok && current.LastSeen.After(snapshot.LastSeen) {
+		snapshot.LastSeen = current.LastSeen
+	}
+	s.snapshots[snapshot.WasherID] = snapshot
+	s.mu.Unlock()
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	pipe := s.redisClient.TxPipeline()
+	pipe.HSet(ctx, washerStateKey, snapshot.WasherID, data)
+	lastSeenScript.Eval(ctx, pipe, []string{washerLastSeenKey}, snapshot.WasherID, snapshot.LastSeen.UnixMilli())
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// Touch อัปเดตเฉพาะ heartbeat โดยไม่แตะสถานะ ข้อความที่ถูกประมวลผลช้า (เวลาเก่ากว่า) จะไม่ทำให้ last seen ถอยหลัง
+func (s *washerStateStore) Touch(ctx context.Context, washerID string, at time.Time) error {
+	s.mu.Lock()
+	if snapshot, ok := s.snapshots[washerID]; ok && at.After(snapshot.LastSeen) {
+		snapshot.LastSeen = at
+		s.snapshots[washerID] = snapshot
+	}
+	s.mu.Unlock()
+
+	return lastSeenScript.Run(ctx, s.redisClient, []string{washerLastSeenKey}, washerID, at.UnixMilli()).Err()
+}
+
+// Replace แทน snapshot ทั้งหมดด้วย snapshots เครื่องที่ไม่อยู่ใน snapshots ถูกลบทั้งสถานะและ last seen
+// last seen ของเครื่องที่เหลือยังไม่ย้อนกลับเช่นเดียวกับ Save ใช้ sync state store ให้ตรงกับ washer_current_status หลัง repair
+func (s *washerStateStore) Replace(ctx context.Context, snapshots []WasherSnapshot) error {
+	lastSeen, err := s.redisClient.HGetAll(ctx, washerLastSeenKey).Result()
+	if err != nil {
+		return err
+	}
+
+	kept := make(map[string]bool, len(snapshots))
+	pipe := s.redisClient.TxPipeline()
+	pipe.Del(ctx, washerStateKey)
+	for _, snapshot := range snapshots {
+		data, err := json.Marshal(snapshot)
+		if err != nil {
+			return err
+		}
+		pipe.HSet(ctx, washerStateKey, snapshot.WasherID, data)
+		lastSeenScript.Eval(ctx, pipe, []string{washerLastSeenKey}, snapshot.WasherID, snapshot.LastSeen.UnixMilli())
+		kept[snapshot.WasherID] = true
+	}
+	for washerID := range lastSeen {
+		if !kept[washerID] {
+			pipe.HDel(ctx, washerLastSeenKey, washerID)
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+
+	// ล้าง memory ให้ Get และ List โหลดจาก Redis ใหม่พร้อม last seen ที่รวมค่าเดิมแล้ว
+	s.mu.Lock()
+	s.snapshots = make(map[string]WasherSnapshot)
+	s.mu.Unlock()
+	return nil
+}
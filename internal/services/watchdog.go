@@ -0,0 +1,70 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jaytnw/bms-service/internal/models"
+)
+
+// Watchdog ตรวจหาเครื่องที่เงียบหายไปนานกว่า offlineAfter แล้วบันทึกเป็นสถานะ offline
+type Watchdog interface {
+	Run(ctx context.Context)
+}
+
+// OfflineMarker บันทึกสถานะ offline ของเครื่อง (IngestPipeline ส่งเข้าคิวเดียวกับข้อความ MQTT)
+type OfflineMarker interface {
+	MarkOffline(ctx context.Context, dormID, washerID string)
+}
+
+type watchdog struct {
+	stateStore   WasherStateStore
+	marker       OfflineMarker
+	offlineAfter time.Duration
+	interval     time.Duration
+}
+
+func NewWatchdog(stateStore WasherStateStore, marker OfflineMarker, offlineAfter, interval time.Duration) Watchdog {
+	return &watchdog{
+		stateStore:   stateStore,
+		marker:       marker,
+		offlineAfter: offlineAfter,
+		interval:     interval,
+	}
+}
+
+// Run ทำงานจนกว่า ctx จะถูกยกเลิก
+func (w *watchdog) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	log.Printf("🐶 Watchdog started (offline after %v)", w.offlineAfter)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.check(ctx)
+		}
+	}
+}
+
+func (w *watchdog) check(ctx context.Context) {
+	snapshots, err := w.stateStore.List(ctx)
+	if err != nil {
+		log.Printf("❌ Watchdog failed to list washers: %v", err)
+		return
+	}
+
+	now := time.Now()
+	for _, snapshot := range snapshots {
+		if snapshot.Status == "" || snapshot.Status == models.WasherOffline || snapshot.Online(now, w.offlineAfter) {
+			continue
+		}
+
+		// snapshot อาจเก่าแล้วเมื่อ marker ถูกประมวลผล prepareOffline จะตรวจ last seen อีกครั้งก่อนบันทึก
+		log.Printf("📴 Washer %s silent since %s, marking offline", snapshot.WasherID, snapshot.LastSeen.Format(time.RFC3339))
+		w.marker.MarkOffline(ctx, snapshot.DormID, snapshot.WasherID)
+	}
+}
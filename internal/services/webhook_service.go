@@ -0,0 +1,416 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/jaytnw/bms-service/internal/apperr"
+	"github.com/jaytnw/bms-service/internal/models"
+	"github.com/jaytnw/bms-service/internal/repository"
+	"gorm.io/gorm"
+)
+
+const (
+	webhookSignatureHeader = "X-BMS-Signature"
+	webhookEventHeader     = "X-BMS-Event"
+	webhookDeliveryHeader  = "X-BMS-Delivery"
+	webhookMaxBackoff      = time.Hour
+)
+
+// WebhookEvent คือ payload ที่ส่งไปยัง webhook endpoint
+type WebhookEvent struct {
+	ID        string           `json:"id"`
+	Type      string           `json:"type"`
+	CreatedAt time.Time        `json:"createdAt"`
+	Data      WebhookEventData `json:"data"`
+}
+
+type WebhookEventData struct {
+	Status   models.Status      `json:"status"`
+	Previous models.WasherState `json:"previous,omitempty"`
+}
+
+// WebhookOptions คือการตั้งค่าการส่ง webhook
+type WebhookOptions struct {
+	MaxAttempts    int
+	RetryBaseDelay time.Duration
+	Timeout        time.Duration
+	BatchSize      int
+}
+
+// WebhookService จัดการทะเบียน webhook และส่ง event การเปลี่ยนสถานะแบบ durable
+// event ถูกบันทึกลง webhook_deliveries ก่อน แล้ว worker จึงส่งพร้อม retry จนสำเร็จหรือกลายเป็น dead
+type WebhookService interface {
+	StatusListener
+	ListEndpoints(ctx context.Context) ([]models.WebhookEndpoint, error)
+	GetEndpoint(ctx context.Context, id uint) (*models.WebhookEndpoint, error)
+	CreateEndpoint(ctx context.Context, endpoint *models.WebhookEndpoint) error
+	UpdateEndpoint(ctx context.Context, id uint, input *models.WebhookEndpoint) (*models.WebhookEndpoint, error)
+	DeleteEndpoint(ctx context.Context, id uint) error
+	ListDeliveries(ctx context.Context, filter repository.WebhookDeliveryFilter) ([]models.WebhookDelivery, error)
+	Redeliver(ctx context.Context, deliveryID uint) (*models.WebhookDelivery, error)
+	RunDeliveries(ctx context.Context, interval time.Duration)
+}
+
+type webhookService struct {
+	webhookRepo repository.WebhookRepository
+	client      *resty.Client
+	opts        WebhookOptions
+}
+
+func NewWebhookService(webhookRepo repository.WebhookRepository, opts WebhookOptions) WebhookService {
+	return &webhookService{
+		webhookRepo: webhookRepo,
+		client:      newWebhookClient(opts.Timeout),
+		opts:        opts,
+	}
+}
+
+// errWebhookTargetBlocked คืนเมื่อ URL ของ webhook ชี้ไปยัง loopback หรือ link-local (เช่น metadata ของ cloud)
+var errWebhookTargetBlocked = errors.New("webhook target address is not allowed")
+
+// newWebhookClient ตรวจ IP ที่ resolve ได้จริงตอน dial ทุกครั้ง จึงกันกรณี DNS ชี้กลับเข้าเครื่องหลังลงทะเบียนแล้ว
+func newWebhookClient(timeout time.Duration) *resty.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addr, err := netip.ParseAddrPort(address)
+			if err != nil || webhookAddrBlocked(addr.Addr()) {
+				return fmt.Errorf("%w: %s", errWebhookTargetBlocked, address)
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return resty.NewWithClient(&http.Client{Transport: transport}).SetTimeout(timeout)
+}
+
+func webhookAddrBlocked(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsLoopback() || addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsUnspecified()
+}
+
+// SignWebhookPayload คืนลายเซ็นในรูปแบบ "t=<unix>,v1=<hex>" โดย v1 คือ HMAC-SHA256 ของ "<unix>.<body>"
+// ผู้รับตรวจสอบได้โดยคำนวณซ้ำด้วย secret ของ endpoint
+func SignWebhookPayload(secret string, timestamp int64, body []byte) string {
+	ts := strconv.FormatInt(timestamp, 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "t=" + ts + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *webhookService) ListEndpoints(ctx context.Context) ([]models.WebhookEndpoint, error) {
+	endpoints, err := s.webhookRepo.FindEndpoints(ctx)
+	if err != nil {
+		return nil, apperr.New("DB_ERROR", "Failed to get webhooks", 500, err)
+	}
+	for i := range endpoints {
+		endpoints[i].Secret = ""
+	}
+	return endpoints, nil
+}
+
+func (s *webhookService) GetEndpoint(ctx context.Context, id uint) (*models.WebhookEndpoint, error) {
+	endpoint, err := s.findEndpoint(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	endpoint.Secret = ""
+	return endpoint, nil
+}
+
+// CreateEndpoint สร้าง secret ให้หากไม่ได้ระบุ secret จะถูกคืนเฉพาะตอนสร้างเท่านั้น
+func (s *webhookService) CreateEndpoint(ctx context.Context, endpoint *models.WebhookEndpoint) error {
+	if err := validateWebhookURL(endpoint.URL); err != nil {
+		return err
+	}
+	if endpoint.Secret == "" {
+		secret, err := randomHex(32)
+		if err != nil {
+			return apperr.New("INTERNAL_ERROR", "Failed to generate webhook secret", 500, err)
+		}
+		endpoint.Secret = secret
+	}
+
+	endpoint.ID = 0
+	endpoint.Active = true
+	if err := s.webhookRepo.SaveEndpoint(ctx, endpoint); err != nil {
+		return apperr.New("DB_ERROR", "Failed to create webhook", 500, err)
+	}
+	return nil
+}
+
+// UpdateEndpoint แทนที่ URL, ตัวกรอง และสถานะ active ส่วน secret จะเปลี่ยนเฉพาะเมื่อส่งค่าใหม่มา
+func (s *webhookService) UpdateEndpoint(ctx context.Context, id uint, input *models.WebhookEndpoint) (*models.WebhookEndpoint, error) {
+	endpoint, err := s.findEndpoint(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateWebhookURL(input.URL); err != nil {
+		return nil, err
+	}
+
+	endpoint.URL = input.URL
+	endpoint.Description = input.Description
+	endpoint.Events = input.Events
+	endpoint.DormID = input.DormID
+	endpoint.WasherID = input.WasherID
+	endpoint.Active = input.Active
+	if input.Secret != "" {
+		endpoint.Secret = input.Secret
+	}
+
+	if err := s.webhookRepo.SaveEndpoint(ctx, endpoint); err != nil {
+		return nil, apperr.New("DB_ERROR", "Failed to update webhook", 500, err)
+	}
+	endpoint.Secret = ""
+	return endpoint, nil
+}
+
+func (s *webhookService) DeleteEndpoint(ctx context.Context, id uint) error {
+	if err := s.webhookRepo.DeleteEndpoint(ctx, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperr.New("NOT_FOUND", "Webhook not found", 404, err)
+		}
+		return apperr.New("DB_ERROR", "Failed to delete webhook", 500, err)
+	}
+	return nil
+}
+
+func (s *webhookService) ListDeliveries(ctx context.Context, filter repository.WebhookDeliveryFilter) ([]models.WebhookDelivery, error) {
+	deliveries, err := s.webhookRepo.FindDeliveries(ctx, filter)
+	if err != nil {
+		return nil, apperr.New("DB_ERROR", "Failed to get webhook deliveries", 500, err)
+	}
+	return deliveries, nil
+}
+
+// Redeliver นำรายการ (รวมถึงรายการ dead หรือที่สำเร็จแล้ว) กลับเข้าคิวส่งใหม่ด้วยจำนวน retry เต็ม
+func (s *webhookService) Redeliver(ctx context.Context, deliveryID uint) (*models.WebhookDelivery, error) {
+	delivery, err := s.webhookRepo.FindDeliveryByID(ctx, deliveryID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperr.New("NOT_FOUND", "Webhook delivery not found", 404, err)
+		}
+		return nil, apperr.New("DB_ERROR", "Failed to get webhook delivery", 500, err)
+	}
+	if delivery.Status == models.WebhookDeliveryPending {
+		return nil, apperr.New("CONFLICT", "Webhook delivery is already pending", 409, nil)
+	}
+
+	delivery.Status = models.WebhookDeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = time.Now()
+	if err := s.webhookRepo.SaveDelivery(ctx, delivery); err != nil {
+		return nil, apperr.New("DB_ERROR", "Failed to redeliver webhook", 500, err)
+	}
+	return delivery, nil
+}
+
+// OnStatusChange บันทึก delivery สำหรับทุก endpoint ที่ตรงกับ event worker จะเป็นผู้ส่งจริง
+// สถานะระหว่างซ่อมบำรุงไม่ส่ง event เช่นเดียวกับการแจ้งเตือนผู้ใช้
+func (s *webhookService) OnStatusChange(ctx context.Context, change StatusChange) {
+	if change.Status.Maintenance {
+		return
+	}
+
+	endpoints, err := s.webhookRepo.FindActiveEndpoints(ctx)
+	if err != nil {
+		log.Printf("❌ Failed to load webhooks: %v", err)
+		return
+	}
+
+	eventType := models.WebhookEventType(change.Status.Status)
+	var matched []models.WebhookEndpoint
+	for _, endpoint := range endpoints {
+		if endpoint.Matches(eventType, change.Status) {
+			matched = append(matched, endpoint)
+		}
+	}
+	if len(matched) == 0 {
+		return
+	}
+
+	eventID, err := randomHex(16)
+	if err != nil {
+		log.Printf("❌ Failed to generate webhook event id: %v", err)
+		return
+	}
+	payload, err := json.Marshal(WebhookEvent{
+		ID:        eventID,
+		Type:      eventType,
+		CreatedAt: change.Status.CreatedAt,
+		Data:      WebhookEventData{Status: change.Status, Previous: change.Previous},
+	})
+	if err != nil {
+		log.Printf("❌ Failed to encode webhook event: %v", err)
+		return
+	}
+
+	now := time.Now()
+	deliveries := make([]models.WebhookDelivery, 0, len(matched))
+	for _, endpoint := range matched {
+		deliveries = append(deliveries, models.WebhookDelivery{
+			EndpointID:    endpoint.ID,
+			EventID:       eventID,
+			EventType:     eventType,
+			Payload:       string(payload),
+			Status:        models.WebhookDeliveryPending,
+			NextAttemptAt: now,
+		})
+	}
+	if err := s.webhookRepo.CreateDeliveries(ctx, deliveries); err != nil {
+		log.Printf("❌ Failed to enqueue webhook deliveries for washer %s: %v", change.Status.WasherID, err)
+	}
+}
+
+// RunDeliveries ส่ง delivery ที่ถึงกำหนดทุก interval จนกว่า ctx จะถูกยกเลิก
+func (s *webhookService) RunDeliveries(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	log.Printf("🪝 Webhook delivery worker started")
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.deliverDue(ctx)
+		}
+	}
+}
+
+func (s *webhookService) deliverDue(ctx context.Context) {
+	// lease ต้องนานกว่าเวลาที่ใช้ส่งทั้ง batch เพื่อไม่ให้ instance อื่นหยิบซ้ำ
+	lease := s.opts.Timeout*time.Duration(s.opts.BatchSize) + time.Minute
+	deliveries, err := s.webhookRepo.ClaimDueDeliveries(ctx, time.Now(), lease, s.opts.BatchSize)
+	if err != nil {
+		log.Printf("❌ Failed to claim webhook deliveries: %v", err)
+		return
+	}
+
+	for i := range deliveries {
+		delivery := &deliveries[i]
+
+		// โหลด endpoint ใหม่ทุกครั้งก่อนส่ง endpoint ที่ถูกปิดระหว่างส่ง batch จะไม่ได้รับ event อีก
+		endpoint, err := s.webhookRepo.FindEndpointByID(ctx, delivery.EndpointID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("❌ Failed to load webhook %d: %v", delivery.EndpointID, err)
+			continue
+		}
+
+		s.attempt(ctx, endpoint, delivery)
+		if err := s.webhookRepo.SaveDelivery(ctx, delivery); err != nil {
+			log.Printf("❌ Failed to update webhook delivery %d: %v", delivery.ID, err)
+		}
+	}
+}
+
+// attempt ส่ง delivery หนึ่งครั้งแล้วอัปเดตสถานะ/เวลาส่งครั้งถัดไปใน delivery
+func (s *webhookService) attempt(ctx context.Context, endpoint *models.WebhookEndpoint, delivery *models.WebhookDelivery) {
+	now := time.Now()
+	delivery.Attempts++
+
+	// endpoint ถูกลบหรือถูกปิดไปแล้ว ไม่มีที่ให้ส่ง (Redeliver ได้หลังเปิด endpoint อีกครั้ง)
+	if endpoint == nil {
+		delivery.Status = models.WebhookDeliveryDead
+		delivery.LastError = "webhook endpoint was deleted"
+		return
+	}
+	if !endpoint.Active {
+		delivery.Status = models.WebhookDeliveryDead
+		delivery.LastError = "webhook endpoint is inactive"
+		return
+	}
+
+	body := []byte(delivery.Payload)
+	resp, err := s.client.R().
+		SetContext(ctx).
+		SetHeader("Content-Type", "application/json").
+		SetHeader(webhookEventHeader, delivery.EventType).
+		SetHeader(webhookDeliveryHeader, strconv.FormatUint(uint64(delivery.ID), 10)).
+		SetHeader(webhookSignatureHeader, SignWebhookPayload(endpoint.Secret, now.Unix(), body)).
+		SetBody(body).
+		Post(endpoint.URL)
+
+	if err == nil {
+		delivery.LastStatusCode = resp.StatusCode()
+		if resp.IsSuccess() {
+			delivery.Status = models.WebhookDeliverySucceeded
+			delivery.DeliveredAt = &now
+			delivery.LastError = ""
+			return
+		}
+		err = fmt.Errorf("unexpected status %d", resp.StatusCode())
+	}
+	delivery.LastError = err.Error()
+
+	if delivery.Attempts >= s.opts.MaxAttempts || errors.Is(err, errWebhookTargetBlocked) {
+		delivery.Status = models.WebhookDeliveryDead
+		log.Printf("💀 Webhook delivery %d to %s dead after %d attempts: %v", delivery.ID, endpoint.URL, delivery.Attempts, err)
+		return
+	}
+
+	backoff := s.opts.RetryBaseDelay * time.Duration(1<<(delivery.Attempts-1))
+	if backoff <= 0 || backoff > webhookMaxBackoff {
+		backoff = webhookMaxBackoff
+	}
+	delivery.NextAttemptAt = now.Add(backoff)
+}
+
+func (s *webhookService) findEndpoint(ctx context.Context, id uint) (*models.WebhookEndpoint, error) {
+	endpoint, err := s.webhookRepo.FindEndpointByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperr.New("NOT_FOUND", "Webhook not found", 404, err)
+		}
+		return nil, apperr.New("DB_ERROR", "Failed to get webhook", 500, err)
+	}
+	return endpoint, nil
+}
+
+// validateWebhookURL ปฏิเสธ URL ที่ไม่ใช่ http(s) และ host ที่เป็น loopback หรือ link-local
+// ชื่อ host อื่นจะถูกตรวจอีกครั้งด้วย IP จริงตอนส่ง (ดู newWebhookClient)
+func validateWebhookURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return apperr.New("VALIDATION_ERROR", "url must be an absolute http(s) URL", 400, err)
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return apperr.New("VALIDATION_ERROR", "url must not point to a loopback or link-local address", 400, nil)
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && webhookAddrBlocked(addr) {
+		return apperr.New("VALIDATION_ERROR", "url must not point to a loopback or link-local address", 400, nil)
+	}
+	return nil
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
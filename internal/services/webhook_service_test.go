@@ -0,0 +1,146 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/jaytnw/bms-service/internal/models"
+	"github.com/jaytnw/bms-service/internal/repository"
+	"gorm.io/gorm"
+)
+
+type fakeWebhookRepo struct {
+	repository.WebhookRepository
+	mu         sync.Mutex
+	endpoints  map[uint]models.WebhookEndpoint
+	due        []models.WebhookDelivery
+	deliveries map[uint]models.WebhookDelivery
+}
+
+func (r *fakeWebhookRepo) FindEndpointByID(ctx context.Context, id uint) (*models.WebhookEndpoint, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	endpoint, ok := r.endpoints[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &endpoint, nil
+}
+
+func (r *fakeWebhookRepo) ClaimDueDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error) {
+	return r.due, nil
+}
+
+func (r *fakeWebhookRepo) SaveDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.deliveries[delivery.ID] = *delivery
+	return nil
+}
+
+func (r *fakeWebhookRepo) deactivate(id uint) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	endpoint := r.endpoints[id]
+	endpoint.Active = false
+	r.endpoints[id] = endpoint
+}
+
+func newWebhookFixture(url string, deliveries int) (*webhookService, *fakeWebhookRepo) {
+	repo := &fakeWebhookRepo{
+		endpoints:  map[uint]models.WebhookEndpoint{1: {ID: 1, URL: url, Secret: "s", Active: true}},
+		deliveries: make(map[uint]models.WebhookDelivery),
+	}
+	for i := 1; i <= deliveries; i++ {
+		repo.due = append(repo.due, models.WebhookDelivery{
+			ID:         uint(i),
+			EndpointID: 1,
+			EventType:  "washer.washing",
+			Payload:    `{}`,
+			Status:     models.WebhookDeliveryPending,
+		})
+	}
+	service := NewWebhookService(repo, WebhookOptions{MaxAttempts: 3, RetryBaseDelay: time.Second, Timeout: time.Second, BatchSize: 10}).(*webhookService)
+	return service, repo
+}
+
+func TestValidateWebhookURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		wantErr bool
+	}{
+		{url: "https://example.com/hook"},
+		{url: "http://10.0.0.5:8080/hook"},
+		{url: "ftp://example.com/hook", wantErr: true},
+		{url: "/relative", wantErr: true},
+		{url: "http://localhost:8080/hook", wantErr: true},
+		{url: "http://api.localhost/hook", wantErr: true},
+		{url: "http://127.0.0.1/hook", wantErr: true},
+		{url: "http://[::1]/hook", wantErr: true},
+		{url: "http://169.254.169.254/latest/meta-data", wantErr: true},
+		{url: "http://[fe80::1]/hook", wantErr: true},
+		{url: "http://0.0.0.0/hook", wantErr: true},
+		{url: "http://[::ffff:127.0.0.1]/hook", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		if err := validateWebhookURL(tt.url); (err != nil) != tt.wantErr {
+			t.Errorf("validateWebhookURL(%q) error = %v, wantErr %v", tt.url, err, tt.wantErr)
+		}
+	}
+}
+
+func TestWebhookDeliveryBlocksLoopbackAtDial(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer server.Close()
+
+	// URL ถูกตรวจตอนลงทะเบียนแล้ว แต่ชื่อ host อาจ resolve เป็น loopback ทีหลัง dialer จึงต้องกันอีกชั้น
+	service, repo := newWebhookFixture(server.URL, 1)
+
+	service.deliverDue(context.Background())
+
+	got := repo.deliveries[1]
+	if got.Status != models.WebhookDeliveryDead || !strings.Contains(got.LastError, "not allowed") {
+		t.Fatalf("delivery = %s (%s), want dead because the target is loopback", got.Status, got.LastError)
+	}
+	if calls.Load() != 0 {
+		t.Fatalf("server calls = %d, want 0", calls.Load())
+	}
+}
+
+func TestWebhookDeliveryChecksActiveAtSendTime(t *testing.T) {
+	var calls atomic.Int32
+	var repo *fakeWebhookRepo
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		// endpoint ถูกปิดระหว่างส่ง batch
+		repo.deactivate(1)
+	}))
+	defer server.Close()
+
+	var service *webhookService
+	service, repo = newWebhookFixture(server.URL, 2)
+	service.client = resty.New().SetTimeout(time.Second)
+
+	service.deliverDue(context.Background())
+
+	if calls.Load() != 1 {
+		t.Fatalf("server calls = %d, want 1", calls.Load())
+	}
+	if got := repo.deliveries[1]; got.Status != models.WebhookDeliverySucceeded {
+		t.Fatalf("first delivery = %s, want succeeded", got.Status)
+	}
+	if got := repo.deliveries[2]; got.Status != models.WebhookDeliveryDead || got.LastError != "webhook endpoint is inactive" {
+		t.Fatalf("second delivery = %s (%s), want dead because the endpoint is inactive", got.Status, got.LastError)
+	}
+}
@@ -0,0 +1,16 @@
+-- Create "status_anomalies" table
+CREATE TABLE "public"."status_anomalies" (
+  "id" bigserial NOT NULL,
+  "kind" character varying(50) NOT NULL,
+  "dorm_id" character varying(100) NOT NULL,
+  "washer_id" character varying(100) NOT NULL,
+  "payload" text NOT NULL,
+  "from_status" character varying(50) NULL,
+  "to_status" character varying(50) NULL,
+  "created_at" timestamptz NULL,
+  PRIMARY KEY ("id")
+);
+-- Create index "idx_status_anomalies_kind" to table: "status_anomalies"
+CREATE INDEX "idx_status_anomalies_kind" ON "public"."status_anomalies" ("kind");
+-- Create index "idx_anomaly_washer_created" to table: "status_anomalies"
+CREATE INDEX "idx_anomaly_washer_created" ON "public"."status_anomalies" ("washer_id", "created_at");
@@ -0,0 +1,17 @@
+-- Create "wash_sessions" table
+CREATE TABLE "public"."wash_sessions" (
+  "id" bigserial NOT NULL,
+  "washer_id" character varying(100) NOT NULL,
+  "dorm_id" character varying(100) NOT NULL,
+  "started_at" timestamptz NOT NULL,
+  "ended_at" timestamptz NULL,
+  "duration_seconds" bigint NULL,
+  "outcome" character varying(20) NOT NULL,
+  "created_at" timestamptz NULL,
+  "updated_at" timestamptz NULL,
+  PRIMARY KEY ("id")
+);
+-- Create index "idx_session_washer_started" to table: "wash_sessions"
+CREATE UNIQUE INDEX "idx_session_washer_started" ON "public"."wash_sessions" ("washer_id", "started_at");
+-- Create index "idx_session_dorm_started" to table: "wash_sessions"
+CREATE INDEX "idx_session_dorm_started" ON "public"."wash_sessions" ("dorm_id", "started_at");
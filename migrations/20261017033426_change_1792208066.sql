@@ -0,0 +1,27 @@
+-- Create "dorms" table
+CREATE TABLE "public"."dorms" (
+  "id" character varying(100) NOT NULL,
+  "name" character varying(255) NOT NULL,
+  "source" character varying(20) NOT NULL,
+  "created_at" timestamptz NULL,
+  "updated_at" timestamptz NULL,
+  "deleted_at" timestamptz NULL,
+  PRIMARY KEY ("id")
+);
+-- Create index "idx_dorms_deleted_at" to table: "dorms"
+CREATE INDEX "idx_dorms_deleted_at" ON "public"."dorms" ("deleted_at");
+-- Create "machines" table
+CREATE TABLE "public"."machines" (
+  "id" character varying(100) NOT NULL,
+  "dorm_id" character varying(100) NOT NULL,
+  "name" character varying(255) NULL,
+  "source" character varying(20) NOT NULL,
+  "created_at" timestamptz NULL,
+  "updated_at" timestamptz NULL,
+  "deleted_at" timestamptz NULL,
+  PRIMARY KEY ("id")
+);
+-- Create index "idx_machines_dorm_id" to table: "machines"
+CREATE INDEX "idx_machines_dorm_id" ON "public"."machines" ("dorm_id");
+-- Create index "idx_machines_deleted_at" to table: "machines"
+CREATE INDEX "idx_machines_deleted_at" ON "public"."machines" ("deleted_at");
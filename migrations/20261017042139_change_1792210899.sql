@@ -0,0 +1,8 @@
+-- Modify "dorms" table
+ALTER TABLE "public"."dorms" ADD COLUMN "status" character varying(20) NOT NULL DEFAULT 'active';
+-- Create index "idx_dorms_status" to table: "dorms"
+CREATE INDEX "idx_dorms_status" ON "public"."dorms" ("status");
+-- Modify "machines" table
+ALTER TABLE "public"."machines" ADD COLUMN "status" character varying(20) NOT NULL DEFAULT 'active';
+-- Create index "idx_machines_status" to table: "machines"
+CREATE INDEX "idx_machines_status" ON "public"."machines" ("status");
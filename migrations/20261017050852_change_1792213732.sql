@@ -0,0 +1,20 @@
+-- Create "queue_entries" table
+CREATE TABLE "public"."queue_entries" (
+  "id" bigserial NOT NULL,
+  "dorm_id" character varying(100) NOT NULL,
+  "user_id" character varying(100) NOT NULL,
+  "status" character varying(20) NOT NULL,
+  "washer_id" character varying(100) NULL,
+  "notify_channel" character varying(20) NOT NULL,
+  "notify_target" character varying(500) NULL,
+  "offered_at" timestamptz NULL,
+  "claim_expires_at" timestamptz NULL,
+  "claimed_at" timestamptz NULL,
+  "created_at" timestamptz NULL,
+  "updated_at" timestamptz NULL,
+  PRIMARY KEY ("id")
+);
+-- Create index "idx_queue_dorm_status" to table: "queue_entries"
+CREATE INDEX "idx_queue_dorm_status" ON "public"."queue_entries" ("dorm_id", "status");
+-- Create index "idx_queue_entries_user_id" to table: "queue_entries"
+CREATE INDEX "idx_queue_entries_user_id" ON "public"."queue_entries" ("user_id");
@@ -0,0 +1,38 @@
+-- Create "notification_deliveries" table
+CREATE TABLE "public"."notification_deliveries" (
+  "id" bigserial NOT NULL,
+  "channel" character varying(20) NOT NULL,
+  "recipient" character varying(500) NOT NULL,
+  "subject" character varying(255) NULL,
+  "body" text NULL,
+  "status" character varying(20) NOT NULL,
+  "attempts" bigint NOT NULL,
+  "last_error" text NULL,
+  "delivered_at" timestamptz NULL,
+  "created_at" timestamptz NULL,
+  PRIMARY KEY ("id")
+);
+-- Create index "idx_delivery_channel_created" to table: "notification_deliveries"
+CREATE INDEX "idx_delivery_channel_created" ON "public"."notification_deliveries" ("channel", "created_at");
+-- Create index "idx_notification_deliveries_status" to table: "notification_deliveries"
+CREATE INDEX "idx_notification_deliveries_status" ON "public"."notification_deliveries" ("status");
+-- Create "washer_subscriptions" table
+CREATE TABLE "public"."washer_subscriptions" (
+  "id" bigserial NOT NULL,
+  "washer_id" character varying(100) NOT NULL,
+  "user_id" character varying(100) NOT NULL,
+  "channel" character varying(20) NOT NULL,
+  "target" character varying(500) NULL,
+  "on_done" boolean NOT NULL,
+  "on_fault" boolean NOT NULL,
+  "persistent" boolean NOT NULL,
+  "active" boolean NOT NULL,
+  "notified_at" timestamptz NULL,
+  "created_at" timestamptz NULL,
+  "updated_at" timestamptz NULL,
+  PRIMARY KEY ("id")
+);
+-- Create index "idx_subscription_washer_active" to table: "washer_subscriptions"
+CREATE INDEX "idx_subscription_washer_active" ON "public"."washer_subscriptions" ("washer_id", "active");
+-- Create index "idx_washer_subscriptions_user_id" to table: "washer_subscriptions"
+CREATE INDEX "idx_washer_subscriptions_user_id" ON "public"."washer_subscriptions" ("user_id");
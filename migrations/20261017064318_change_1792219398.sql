@@ -0,0 +1,38 @@
+-- Create "webhook_endpoints" table
+CREATE TABLE "public"."webhook_endpoints" (
+  "id" bigserial NOT NULL,
+  "url" character varying(500) NOT NULL,
+  "secret" character varying(100) NOT NULL,
+  "description" character varying(255) NULL,
+  "events" text NULL,
+  "dorm_id" character varying(100) NULL,
+  "washer_id" character varying(100) NULL,
+  "active" boolean NOT NULL,
+  "created_at" timestamptz NULL,
+  "updated_at" timestamptz NULL,
+  "deleted_at" timestamptz NULL,
+  PRIMARY KEY ("id")
+);
+-- Create index "idx_webhook_endpoints_deleted_at" to table: "webhook_endpoints"
+CREATE INDEX "idx_webhook_endpoints_deleted_at" ON "public"."webhook_endpoints" ("deleted_at");
+-- Create "webhook_deliveries" table
+CREATE TABLE "public"."webhook_deliveries" (
+  "id" bigserial NOT NULL,
+  "endpoint_id" bigint NOT NULL,
+  "event_id" character varying(50) NOT NULL,
+  "event_type" character varying(50) NOT NULL,
+  "payload" text NOT NULL,
+  "status" character varying(20) NOT NULL,
+  "attempts" bigint NOT NULL,
+  "next_attempt_at" timestamptz NULL,
+  "last_status_code" bigint NULL,
+  "last_error" text NULL,
+  "delivered_at" timestamptz NULL,
+  "created_at" timestamptz NULL,
+  "updated_at" timestamptz NULL,
+  PRIMARY KEY ("id")
+);
+-- Create index "idx_webhook_deliveries_endpoint_id" to table: "webhook_deliveries"
+CREATE INDEX "idx_webhook_deliveries_endpoint_id" ON "public"."webhook_deliveries" ("endpoint_id");
+-- Create index "idx_webhook_delivery_due" to table: "webhook_deliveries"
+CREATE INDEX "idx_webhook_delivery_due" ON "public"."webhook_deliveries" ("status", "next_attempt_at");
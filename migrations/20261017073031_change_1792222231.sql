@@ -0,0 +1,20 @@
+-- Create "washer_commands" table
+CREATE TABLE "public"."washer_commands" (
+  "id" character varying(50) NOT NULL,
+  "dorm_id" character varying(100) NOT NULL,
+  "washer_id" character varying(100) NOT NULL,
+  "command" character varying(20) NOT NULL,
+  "status" character varying(20) NOT NULL,
+  "requested_by" character varying(100) NULL,
+  "reason" character varying(255) NULL,
+  "message" text NULL,
+  "sent_at" timestamptz NULL,
+  "acked_at" timestamptz NULL,
+  "created_at" timestamptz NULL,
+  "updated_at" timestamptz NULL,
+  PRIMARY KEY ("id")
+);
+-- Create index "idx_command_washer_created" to table: "washer_commands"
+CREATE INDEX "idx_command_washer_created" ON "public"."washer_commands" ("washer_id", "created_at");
+-- Create index "idx_washer_commands_status" to table: "washer_commands"
+CREATE INDEX "idx_washer_commands_status" ON "public"."washer_commands" ("status");
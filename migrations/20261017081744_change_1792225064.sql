@@ -0,0 +1,22 @@
+-- Modify "status_anomalies" table
+ALTER TABLE "public"."status_anomalies" ADD COLUMN "detail" text NULL;
+-- Create "washer_telemetry" table
+CREATE TABLE "public"."washer_telemetry" (
+  "id" bigserial NOT NULL,
+  "status_id" bigint NULL,
+  "dorm_id" character varying(100) NOT NULL,
+  "washer_id" character varying(100) NOT NULL,
+  "status" character varying(50) NOT NULL,
+  "payload_version" bigint NOT NULL,
+  "program" character varying(50) NULL,
+  "remaining_seconds" bigint NULL,
+  "door" character varying(20) NULL,
+  "water_temp" double precision NULL,
+  "error_code" character varying(50) NULL,
+  "firmware" character varying(50) NULL,
+  "rssi" bigint NULL,
+  "created_at" timestamptz NULL,
+  PRIMARY KEY ("id")
+);
+-- Create index "idx_telemetry_washer_created" to table: "washer_telemetry"
+CREATE INDEX "idx_telemetry_washer_created" ON "public"."washer_telemetry" ("washer_id", "created_at");
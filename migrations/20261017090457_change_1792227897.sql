@@ -0,0 +1,44 @@
+-- Create "fault_codes" table
+CREATE TABLE "public"."fault_codes" (
+  "code" character varying(50) NOT NULL,
+  "description" character varying(255) NOT NULL,
+  "severity" character varying(20) NOT NULL,
+  "suggested_action" text NULL,
+  "created_at" timestamptz NULL,
+  "updated_at" timestamptz NULL,
+  PRIMARY KEY ("code")
+);
+-- Create "incidents" table
+CREATE TABLE "public"."incidents" (
+  "id" bigserial NOT NULL,
+  "dorm_id" character varying(100) NOT NULL,
+  "washer_id" character varying(100) NOT NULL,
+  "fault_code" character varying(50) NULL,
+  "severity" character varying(20) NOT NULL,
+  "status" character varying(20) NOT NULL,
+  "opened_at" timestamptz NOT NULL,
+  "acknowledged_at" timestamptz NULL,
+  "acknowledged_by" character varying(100) NULL,
+  "resolved_at" timestamptz NULL,
+  "duration_seconds" bigint NULL,
+  "created_at" timestamptz NULL,
+  "updated_at" timestamptz NULL,
+  PRIMARY KEY ("id")
+);
+-- Create index "idx_incident_dorm_opened" to table: "incidents"
+CREATE INDEX "idx_incident_dorm_opened" ON "public"."incidents" ("dorm_id", "opened_at");
+-- Create index "idx_incident_washer_status" to table: "incidents"
+CREATE INDEX "idx_incident_washer_status" ON "public"."incidents" ("washer_id", "status");
+-- Create index "idx_incidents_resolved_at" to table: "incidents"
+CREATE INDEX "idx_incidents_resolved_at" ON "public"."incidents" ("resolved_at");
+-- Create "incident_comments" table
+CREATE TABLE "public"."incident_comments" (
+  "id" bigserial NOT NULL,
+  "incident_id" bigint NOT NULL,
+  "author" character varying(100) NOT NULL,
+  "body" text NOT NULL,
+  "created_at" timestamptz NULL,
+  PRIMARY KEY ("id")
+);
+-- Create index "idx_incident_comments_incident_id" to table: "incident_comments"
+CREATE INDEX "idx_incident_comments_incident_id" ON "public"."incident_comments" ("incident_id");
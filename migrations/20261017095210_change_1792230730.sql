@@ -0,0 +1,23 @@
+-- Modify "statuses" table
+ALTER TABLE "public"."statuses" ADD COLUMN "maintenance" boolean NOT NULL DEFAULT false;
+-- Create "maintenance_windows" table
+CREATE TABLE "public"."maintenance_windows" (
+  "id" bigserial NOT NULL,
+  "dorm_id" character varying(100) NOT NULL,
+  "washer_id" character varying(100) NULL,
+  "reason" character varying(255) NOT NULL,
+  "started_by" character varying(100) NULL,
+  "starts_at" timestamptz NOT NULL,
+  "expected_end_at" timestamptz NULL,
+  "ended_at" timestamptz NULL,
+  "ended_by" character varying(100) NULL,
+  "created_at" timestamptz NULL,
+  "updated_at" timestamptz NULL,
+  PRIMARY KEY ("id")
+);
+-- Create index "idx_maintenance_windows_dorm_id" to table: "maintenance_windows"
+CREATE INDEX "idx_maintenance_windows_dorm_id" ON "public"."maintenance_windows" ("dorm_id");
+-- Create index "idx_maintenance_windows_ended_at" to table: "maintenance_windows"
+CREATE INDEX "idx_maintenance_windows_ended_at" ON "public"."maintenance_windows" ("ended_at");
+-- Create index "idx_maintenance_windows_washer_id" to table: "maintenance_windows"
+CREATE INDEX "idx_maintenance_windows_washer_id" ON "public"."maintenance_windows" ("washer_id");
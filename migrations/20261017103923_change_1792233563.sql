@@ -0,0 +1,2 @@
+-- Create index "idx_status_created_id" to table: "statuses"
+CREATE INDEX "idx_status_created_id" ON "public"."statuses" ("created_at", "id");
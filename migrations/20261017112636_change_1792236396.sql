@@ -0,0 +1,28 @@
+-- Create "rollup_checkpoints" table
+CREATE TABLE "public"."rollup_checkpoints" (
+  "name" character varying(50) NOT NULL,
+  "position" timestamptz NOT NULL,
+  "updated_at" timestamptz NULL,
+  PRIMARY KEY ("name")
+);
+-- Create "status_rollups" table
+CREATE TABLE "public"."status_rollups" (
+  "id" bigserial NOT NULL,
+  "granularity" character varying(10) NOT NULL,
+  "washer_id" character varying(100) NOT NULL,
+  "bucket_start" timestamptz NOT NULL,
+  "dorm_id" character varying(100) NOT NULL,
+  "state_seconds" text NULL,
+  "busy_seconds" bigint NOT NULL,
+  "transitions" bigint NOT NULL,
+  "cycles" bigint NOT NULL,
+  "completed" bigint NOT NULL,
+  "end_state" character varying(50) NOT NULL,
+  "created_at" timestamptz NULL,
+  "updated_at" timestamptz NULL,
+  PRIMARY KEY ("id")
+);
+-- Create index "idx_rollup_bucket" to table: "status_rollups"
+CREATE UNIQUE INDEX "idx_rollup_bucket" ON "public"."status_rollups" ("granularity", "washer_id", "bucket_start");
+-- Create index "idx_rollup_dorm_bucket" to table: "status_rollups"
+CREATE INDEX "idx_rollup_dorm_bucket" ON "public"."status_rollups" ("dorm_id", "bucket_start");
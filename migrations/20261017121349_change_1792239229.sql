@@ -0,0 +1,20 @@
+-- Create "status_archives" table
+CREATE TABLE "public"."status_archives" (
+  "id" bigserial NOT NULL,
+  "range_from" timestamptz NOT NULL,
+  "range_to" timestamptz NOT NULL,
+  "store" character varying(20) NOT NULL,
+  "key" character varying(255) NOT NULL,
+  "format" character varying(20) NOT NULL,
+  "rows" bigint NOT NULL,
+  "bytes" bigint NOT NULL,
+  "checksum" character varying(64) NOT NULL,
+  "max_status_id" bigint NOT NULL,
+  "purged_at" timestamptz NULL,
+  "restored_at" timestamptz NULL,
+  "created_at" timestamptz NULL,
+  "updated_at" timestamptz NULL,
+  PRIMARY KEY ("id")
+);
+-- Create index "idx_archive_range_from" to table: "status_archives"
+CREATE UNIQUE INDEX "idx_archive_range_from" ON "public"."status_archives" ("range_from");
@@ -0,0 +1,45 @@
+-- Rename "statuses" table, its rows are moved into the partitioned table below
+ALTER TABLE "public"."statuses" RENAME TO "statuses_unpartitioned";
+ALTER SEQUENCE "public"."statuses_id_seq" OWNED BY NONE;
+-- Create "statuses" table partitioned by month of "created_at"
+CREATE TABLE "public"."statuses" (
+  "id" bigint NOT NULL DEFAULT nextval('"public"."statuses_id_seq"'::regclass),
+  "dorm_id" character varying(100) NOT NULL,
+  "washer_id" character varying(100) NOT NULL,
+  "status" character varying(50) NOT NULL,
+  "maintenance" boolean NOT NULL DEFAULT false,
+  "created_at" timestamptz NOT NULL,
+  "updated_at" timestamptz NULL
+) PARTITION BY RANGE ("created_at");
+ALTER SEQUENCE "public"."statuses_id_seq" OWNED BY "public"."statuses"."id";
+-- Create default partition for rows outside the monthly partitions
+CREATE TABLE "public"."statuses_default" PARTITION OF "public"."statuses" DEFAULT;
+-- Create monthly partitions (UTC) from the oldest row until three months ahead
+DO $$
+DECLARE
+  partition_month timestamp := date_trunc('month', COALESCE((SELECT min("created_at") FROM "public"."statuses_unpartitioned"), now()) AT TIME ZONE 'UTC');
+BEGIN
+  WHILE partition_month < date_trunc('month', now() AT TIME ZONE 'UTC') + interval '4 months' LOOP
+    EXECUTE format(
+      'CREATE TABLE "public".%I PARTITION OF "public"."statuses" FOR VALUES FROM (%L) TO (%L)',
+      'statuses_p' || to_char(partition_month, 'YYYYMM'),
+      partition_month::text || '+00',
+      (partition_month + interval '1 month')::text || '+00'
+    );
+    partition_month := partition_month + interval '1 month';
+  END LOOP;
+END
+$$;
+-- Copy rows into "statuses" and drop the old table
+INSERT INTO "public"."statuses" ("id", "dorm_id", "washer_id", "status", "maintenance", "created_at", "updated_at")
+SELECT "id", "dorm_id", "washer_id", "status", "maintenance", COALESCE("created_at", "updated_at", now()), "updated_at"
+FROM "public"."statuses_unpartitioned";
+DROP TABLE "public"."statuses_unpartitioned";
+-- Modify "statuses" table
+ALTER TABLE "public"."statuses" ADD PRIMARY KEY ("id", "created_at");
+-- Create index "idx_statuses_id" to table: "statuses"
+CREATE INDEX "idx_statuses_id" ON "public"."statuses" ("id");
+-- Create index "idx_washer_created" to table: "statuses"
+CREATE INDEX "idx_washer_created" ON "public"."statuses" ("washer_id", "created_at");
+-- Create index "idx_status_created_id" to table: "statuses"
+CREATE INDEX "idx_status_created_id" ON "public"."statuses" ("created_at", "id");
@@ -0,0 +1,18 @@
+-- Create "washer_current_status" table
+CREATE TABLE "public"."washer_current_status" (
+  "washer_id" character varying(100) NOT NULL,
+  "dorm_id" character varying(100) NOT NULL,
+  "status_id" bigint NOT NULL,
+  "status" character varying(50) NOT NULL,
+  "maintenance" boolean NOT NULL DEFAULT false,
+  "reported_at" timestamptz NOT NULL,
+  "updated_at" timestamptz NULL,
+  PRIMARY KEY ("washer_id")
+);
+-- Create index "idx_current_status_dorm" to table: "washer_current_status"
+CREATE INDEX "idx_current_status_dorm" ON "public"."washer_current_status" ("dorm_id");
+-- Backfill "washer_current_status" from the latest row of every washer
+INSERT INTO "public"."washer_current_status" ("washer_id", "dorm_id", "status_id", "status", "maintenance", "reported_at", "updated_at")
+SELECT DISTINCT ON ("washer_id") "washer_id", "dorm_id", "id", "status", "maintenance", "created_at", now()
+FROM "public"."statuses"
+ORDER BY "washer_id", "created_at" DESC, "id" DESC;
@@ -0,0 +1,4 @@
+-- Backfill "queue_entries": claimed entries were already started washers
+UPDATE "public"."queue_entries" SET "status" = 'started' WHERE "status" = 'claimed';
+-- Create index "idx_queue_washer_held" to table: "queue_entries"
+CREATE UNIQUE INDEX "idx_queue_washer_held" ON "public"."queue_entries" ("washer_id") WHERE (((status)::text = 'offered'::text) OR ((status)::text = 'claimed'::text));
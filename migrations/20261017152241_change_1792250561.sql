@@ -0,0 +1,6 @@
+-- Modify "status_archives" table
+ALTER TABLE "public"."status_archives" ADD COLUMN "part" bigint NOT NULL DEFAULT 0;
+-- Drop index "idx_archive_range_from" from table: "status_archives"
+DROP INDEX "public"."idx_archive_range_from";
+-- Create index "idx_archive_range_part" to table: "status_archives"
+CREATE UNIQUE INDEX "idx_archive_range_part" ON "public"."status_archives" ("range_from", "part");
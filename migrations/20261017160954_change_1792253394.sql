@@ -0,0 +1,4 @@
+-- Modify "status_rollups" table
+ALTER TABLE "public"."status_rollups" ADD COLUMN "end_in_cycle" boolean NOT NULL DEFAULT false;
+-- Backfill "end_in_cycle" for buckets that ended mid-cycle
+UPDATE "public"."status_rollups" SET "end_in_cycle" = true WHERE "end_state" IN ('washing', 'rinsing', 'spinning');
@@ -0,0 +1,10 @@
+-- Modify "wash_sessions" table
+ALTER TABLE "public"."wash_sessions" ADD COLUMN "program" character varying(50) NULL;
+-- Backfill "program" from the first telemetry row that reported one during the session
+UPDATE "public"."wash_sessions" AS s SET "program" = (
+  SELECT t."program" FROM "public"."washer_telemetry" AS t
+  WHERE t."washer_id" = s."washer_id" AND t."created_at" >= s."started_at"
+    AND (s."ended_at" IS NULL OR t."created_at" <= s."ended_at")
+    AND t."program" IS NOT NULL AND t."program" <> ''
+  ORDER BY t."created_at" LIMIT 1
+);
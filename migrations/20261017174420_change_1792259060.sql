@@ -0,0 +1,7 @@
+-- Modify "dorms" table
+ALTER TABLE "public"."dorms" ADD COLUMN "edited_at" timestamptz NULL;
+-- Modify "machines" table
+ALTER TABLE "public"."machines" ADD COLUMN "edited_at" timestamptz NULL;
+-- Backfill "edited_at" for rows created through the API
+UPDATE "public"."dorms" SET "edited_at" = "updated_at" WHERE "source" = 'manual';
+UPDATE "public"."machines" SET "edited_at" = "updated_at" WHERE "source" = 'manual';
@@ -0,0 +1,24 @@
+-- Detach default partition "statuses_default" from "statuses", DETACH PARTITION ... CONCURRENTLY is not allowed while it exists
+ALTER TABLE "public"."statuses" DETACH PARTITION "public"."statuses_default";
+-- Move rows of "statuses_default" into monthly partitions (UTC)
+DO $$
+DECLARE
+  partition_month timestamp;
+BEGIN
+  FOR partition_month IN
+    SELECT DISTINCT date_trunc('month', "created_at" AT TIME ZONE 'UTC') FROM "public"."statuses_default"
+  LOOP
+    EXECUTE format(
+      'CREATE TABLE IF NOT EXISTS "public".%I PARTITION OF "public"."statuses" FOR VALUES FROM (%L) TO (%L)',
+      'statuses_p' || to_char(partition_month, 'YYYYMM'),
+      partition_month::text || '+00',
+      (partition_month + interval '1 month')::text || '+00'
+    );
+  END LOOP;
+END
+$$;
+INSERT INTO "public"."statuses" ("id", "dorm_id", "washer_id", "status", "maintenance", "created_at", "updated_at")
+SELECT "id", "dorm_id", "washer_id", "status", "maintenance", "created_at", "updated_at"
+FROM "public"."statuses_default";
+-- Drop "statuses_default" table
+DROP TABLE "public"."statuses_default";